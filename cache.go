@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+///////////////////
+// Program Cache //
+///////////////////
+
+// programCache keeps programs that went through the lexer, parser and passes
+// so running the same file again can skip all of them. Entries are named by
+// a hash of the source along with everything else that changes what it
+// parses to, an edited file or a different build of a0 just misses.
+type programCache struct {
+	dir      string
+	settings string
+}
+
+// cacheEntry is a cached program and the warnings the passes gave for it,
+// so they're still shown when the passes don't run
+type cacheEntry struct {
+	Program  json.RawMessage `json:"program"`
+	Warnings []string        `json:"warnings"`
+}
+
+// openCache returns the cache in the user's cache directory, or nil when
+// there's nowhere to keep it. settings should describe every option that
+// changes how a source is parsed.
+func openCache(settings string) *programCache {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+
+	// a rebuilt a0 may parse differently, so the executable is part of the key
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			settings += fmt.Sprintf(" %s %d %d", executable, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return &programCache{dir: filepath.Join(base, "a0", "programs"), settings: settings}
+}
+
+func (c *programCache) path(source []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.settings))
+	hash.Write([]byte{0})
+	hash.Write(source)
+	return filepath.Join(c.dir, hex.EncodeToString(hash.Sum(nil))+".json")
+}
+
+// load finds the program cached for source, ok is false on a miss or when the
+// entry can't be read
+func (c *programCache) load(source []byte) (program f.Program, warnings []string, ok bool) {
+	data, err := os.ReadFile(c.path(source))
+	if err != nil {
+		return f.Program{}, nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return f.Program{}, nil, false
+	}
+	node, err := f.UnmarshalAST(entry.Program)
+	if err != nil {
+		return f.Program{}, nil, false
+	}
+	program, ok = node.(f.Program)
+	return program, entry.Warnings, ok
+}
+
+// store caches program for source. The cache only saves time, so failing to
+// write it isn't an error.
+func (c *programCache) store(source []byte, program f.Program, warnings []string) {
+	encoded, err := f.MarshalAST(program)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Program: encoded, Warnings: warnings})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+
+	// written next to its place and renamed in, so a run reading the entry at
+	// the same time never sees half of it
+	temp, err := os.CreateTemp(c.dir, "entry-*")
+	if err != nil {
+		return
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return
+	}
+	if err := os.Rename(temp.Name(), c.path(source)); err != nil {
+		os.Remove(temp.Name())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+// useCacheDir points the user cache directory at a fresh temp dir and returns
+// the folder programs get cached in
+func useCacheDir(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", base)
+	t.Setenv("HOME", base)
+	t.Setenv("LocalAppData", base)
+
+	cache := openCache("")
+	if cache == nil || !strings.HasPrefix(cache.dir, base) {
+		t.Skip("the user cache directory can't be moved on this system")
+	}
+	return cache.dir
+}
+
+// parseCached parses data as the file at path the way run does with args
+func parseCached(t *testing.T, path string, data string, args ...string) f.Program {
+	t.Helper()
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	src := addSourceFlags(flags)
+	parse := addParseFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	programs, _, ok := parsePrograms([]sourceFile{{path: path, reader: strings.NewReader(data)}}, src, parse, false)
+	if !ok {
+		t.Fatalf("parsing %q failed", data)
+	}
+	return programs[0]
+}
+
+// cacheEntries lists the entries in the cache folder
+func cacheEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func marshal(t *testing.T, program f.Program) []byte {
+	t.Helper()
+	data, err := f.MarshalAST(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	dir := useCacheDir(t)
+
+	parseCached(t, "main.a0", "println(1)")
+	if got := len(cacheEntries(t, dir)); got != 1 {
+		t.Fatalf("after the first run the cache has %d entries, want 1", got)
+	}
+
+	steps := []struct {
+		name    string
+		data    string
+		args    []string
+		entries int
+	}{
+		{"same source hits", "println(1)", nil, 1},
+		{"same source in another file hits", "println(1)", nil, 1},
+		{"edited source misses", "println(2)", nil, 2},
+		{"strict keywords miss", "println(1)", []string{"-strict-keywords"}, 3},
+		{"tab width misses", "println(1)", []string{"-tab-width", "8"}, 4},
+		{"no passes misses", "println(1)", []string{"-no-passes"}, 5},
+		{"no cache stores nothing", "println(3)", []string{"-no-cache"}, 5},
+	}
+	for _, step := range steps {
+		parseCached(t, "main.a0", step.data, step.args...)
+		if got := len(cacheEntries(t, dir)); got != step.entries {
+			t.Errorf("%s: the cache has %d entries, want %d", step.name, got, step.entries)
+		}
+	}
+}
+
+func TestCacheHitSkipsParsing(t *testing.T) {
+	dir := useCacheDir(t)
+
+	parseCached(t, "main.a0", "println(1)")
+	entries := cacheEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("the cache has %d entries, want 1", len(entries))
+	}
+
+	// an entry swapped for another program is what a hit gives back
+	other, err := f.MarshalAST(parseCached(t, "", "println(2)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(entries[0], []byte(`{"program":`+string(other)+`,"warnings":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, parseCached(t, "main.a0", "println(1)")); !bytes.Equal(got, other) {
+		t.Errorf("a hit gave\n%s\nwant the cached\n%s", got, other)
+	}
+}
+
+func TestCacheIgnoresBrokenEntries(t *testing.T) {
+	dir := useCacheDir(t)
+
+	want := marshal(t, parseCached(t, "main.a0", "println(1)"))
+	entries := cacheEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("the cache has %d entries, want 1", len(entries))
+	}
+
+	for _, broken := range []string{"", "{", `{"program": {"kind": "Nope"}}`, `{"program": 1}`} {
+		if err := os.WriteFile(entries[0], []byte(broken), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := marshal(t, parseCached(t, "main.a0", "println(1)")); !bytes.Equal(got, want) {
+			t.Errorf("with the entry %q parsing gave\n%s\nwant\n%s", broken, got, want)
+		}
+
+		// and the broken entry is replaced by a good one
+		if got := marshal(t, parseCached(t, "main.a0", "println(1)")); !bytes.Equal(got, want) {
+			t.Errorf("after replacing %q the entry gave\n%s\nwant\n%s", broken, got, want)
+		}
+	}
+}
+
+func TestCacheUnwritable(t *testing.T) {
+	dir := useCacheDir(t)
+
+	// a file where the cache folder should be makes every store fail
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := marshal(t, parseCached(t, "", "println(1)"))
+	for range 2 {
+		if got := marshal(t, parseCached(t, "main.a0", "println(1)")); !bytes.Equal(got, want) {
+			t.Errorf("without a cache parsing gave\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestCacheSkipsInlineSource(t *testing.T) {
+	dir := useCacheDir(t)
+
+	parseCached(t, "", "println(1)")
+	if got := len(cacheEntries(t, dir)); got != 0 {
+		t.Errorf("source given with -e made %d cache entries, want 0", got)
+	}
+}
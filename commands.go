@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+)
+
+//////////////
+// Commands //
+//////////////
+
+func runCommand(fs *flag.FlagSet) func(args []string) int {
+	inline := fs.String("e", "", "Run this source instead of a file")
+	useVM := fs.Bool("vm", false, "Compile programs and run them on the bytecode VM instead of walking the AST")
+	src := addSourceFlags(fs)
+	parse := addParseFlags(fs)
+	limits := addRuntimeFlags(fs)
+	profilePath := fs.String("profile", "", "Write a pprof profile of where the program spent its time to this file")
+	timeCalls := fs.Bool("time", false, "Print how often each function was called and how long the calls took")
+
+	return func(args []string) int {
+		runInline := isFlagSet(fs, "e")
+		if len(args) < 1 && !runInline {
+			fs.Usage()
+			return 2
+		}
+
+		// with -e there's no file, imports are found from the working
+		// directory and every argument goes to the program
+		var sources []sourceFile
+		programArgs := args
+		if runInline {
+			sources = []sourceFile{{reader: strings.NewReader(*inline)}}
+		} else {
+			var paths []string
+			paths, programArgs = splitFiles(args)
+			opened, close, err := openSources(paths)
+			if err != nil {
+				fmt.Println(err)
+				return 1
+			}
+			defer close()
+			sources = opened
+		}
+
+		programs, chunks, ok := parsePrograms(sources, src, parse, *useVM)
+		if !ok {
+			return 1
+		}
+
+		ctx, cancel := limits.deadline()
+		defer cancel()
+		env := limits.newEnv(programArgs, ctx, *src.strictKeywords)
+		if *timeCalls {
+			env.SetTiming(true)
+			defer env.WriteTimings(os.Stderr)
+		}
+		if *profilePath != "" {
+			env.StartProfile(r.DefaultProfileInterval)
+			defer writeProfile(*profilePath, env)
+		}
+
+		// the files share one global scope, each sees what the ones before
+		// it declared
+		report := reporter(sources)
+		for i, source := range sources {
+			env.SetFile(source.path)
+			var err error
+			if chunks[i] != nil {
+				_, err = r.Execute(chunks[i], env)
+			} else {
+				_, err = r.Evaluate(programs[i], env)
+			}
+			if err != nil {
+				var exit *r.ExitError
+				if !errors.As(err, &exit) {
+					report(source, err)
+				}
+				return r.ExitStatus(err)
+			}
+		}
+		return 0
+	}
+}
+
+// writeProfile stops the profile env is taking and saves it to path
+func writeProfile(path string, env *r.Environment) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer file.Close()
+	if err := env.StopProfile(file); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// a0 test and a0 bench look through the given files and folders, the
+// current folder by default, for *_test.a0 files and run each in a global
+// scope of its own
+func testCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	parse := addParseFlags(fs)
+	limits := addRuntimeFlags(fs)
+
+	return func(args []string) int {
+		sources, programs, close, ok := loadTestFiles(args, src, parse)
+		defer close()
+		if !ok {
+			return 1
+		}
+
+		ctx, cancel := limits.deadline()
+		defer cancel()
+		newEnv := func() *r.Environment {
+			return limits.newEnv(nil, ctx, *src.strictKeywords)
+		}
+		if !runTests(sources, programs, newEnv) {
+			return 1
+		}
+		return 0
+	}
+}
+
+func benchCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	parse := addParseFlags(fs)
+	limits := addRuntimeFlags(fs)
+	benchTime := fs.Duration("benchtime", r.DefaultBenchTime, "How long to keep calling each benchmark")
+
+	return func(args []string) int {
+		sources, programs, close, ok := loadTestFiles(args, src, parse)
+		defer close()
+		if !ok {
+			return 1
+		}
+
+		ctx, cancel := limits.deadline()
+		defer cancel()
+		newEnv := func() *r.Environment {
+			return limits.newEnv(nil, ctx, *src.strictKeywords)
+		}
+		if !runBenchmarks(sources, programs, newEnv, *benchTime) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// loadTestFiles finds the test files under paths and parses them
+func loadTestFiles(paths []string, src *sourceFlags, parse *parseFlags) (sources []sourceFile, programs []f.Program, close func(), ok bool) {
+	close = func() {}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	paths, err := moduleFiles(paths, testFileSuffix)
+	if err != nil {
+		fmt.Println(err)
+		return nil, nil, close, false
+	}
+	sources, close, err = openSources(paths)
+	if err != nil {
+		fmt.Println(err)
+		return nil, nil, func() {}, false
+	}
+	programs, _, ok = parsePrograms(sources, src, parse, false)
+	return sources, programs, close, ok
+}
+
+func compileCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	parse := addParseFlags(fs)
+	showBytecode := fs.Bool("bytecode", false, "Print the compiled bytecode instead of writing .a0c files")
+
+	return func(args []string) int {
+		if len(args) < 1 {
+			fs.Usage()
+			return 2
+		}
+		sources, close, err := openSources(args)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer close()
+
+		report := reporter(sources)
+		for _, source := range sources {
+			if isCompiledFile(source.path) {
+				report(source, fmt.Errorf("%s is already compiled", source.path))
+				return 1
+			}
+		}
+		_, chunks, ok := parsePrograms(sources, src, parse, true)
+		if !ok {
+			return 1
+		}
+
+		for i, source := range sources {
+			if *showBytecode {
+				if len(sources) > 1 {
+					fmt.Printf("%s:\n", source.path)
+				}
+				chunks[i].Disassemble(os.Stdout)
+				continue
+			}
+			if err := writeCompiled(compiledPath(source.path), chunks[i]); err != nil {
+				fmt.Println(err)
+				return 1
+			}
+		}
+		return 0
+	}
+}
+
+// compiledPath is where a0 compile writes the compiled form of path
+func compiledPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".a0c"
+}
+
+func writeCompiled(path string, chunk *r.Chunk) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteChunk(file, chunk); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// a0 fmt file.a0 prints the file formatted, or formats standard input when
+// no file is given
+func fmtCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	write := fs.Bool("write", false, "Save the formatted source back to each file instead of printing it")
+	check := fs.Bool("check", false, "List the files that aren't formatted and change nothing")
+
+	return func(args []string) int {
+		sources, close, err := openSources(args)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer close()
+		if len(args) == 0 {
+			sources = []sourceFile{{reader: os.Stdin}}
+		}
+
+		newLexer, _, err := src.lexers()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if !formatSources(sources, newLexer, *write, *check) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// formatSources runs a0 fmt over sources: each one is printed formatted, or
+// with write saved back to its file when that changes it, or with check only
+// named when it isn't formatted. It reports whether all of them could be
+// formatted, and with check whether all of them already were.
+func formatSources(sources []sourceFile, newLexer func([]byte) *f.Lexer, write, check bool) bool {
+	ok := true
+	for _, source := range sources {
+		name := source.path
+		if name == "" {
+			name = "<stdin>"
+		}
+		fail := func(err error) {
+			if len(sources) > 1 {
+				fmt.Fprintf(os.Stderr, "In %s:\n", name)
+			}
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+		}
+
+		data, err := io.ReadAll(source.reader)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		tokenList, err := newLexer(data).Lex()
+		if err != nil {
+			fail(err)
+			continue
+		}
+		formatted, err := f.Format(data, tokenList)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		switch {
+		case check:
+			if !bytes.Equal(data, formatted) {
+				fmt.Println(name)
+				ok = false
+			}
+		case write && source.path != "":
+			if !bytes.Equal(data, formatted) {
+				if err := os.WriteFile(source.path, formatted, 0o644); err != nil {
+					fail(err)
+				}
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+	return ok
+}
+
+// a0 doc lib.a0 or a0 doc src/ writes API docs from the /// comments
+func docCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	asHTML := fs.Bool("html", false, "Write the docs as an HTML page instead of Markdown")
+
+	return func(args []string) int {
+		if len(args) < 1 {
+			fs.Usage()
+			return 2
+		}
+		paths, err := moduleFiles(args, ".a0")
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		sources, close, err := openSources(paths)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer close()
+
+		var modules []moduleDocs
+		ok := parseEach(sources, src, func(source sourceFile, program f.Program) error {
+			if entries := f.DocEntries(program); len(entries) > 0 {
+				modules = append(modules, moduleDocs{Path: source.path, Entries: entries})
+			}
+			return nil
+		})
+		if !ok {
+			return 1
+		}
+
+		if *asHTML {
+			err = docsPage.Execute(os.Stdout, modules)
+		} else {
+			err = writeMarkdownDocs(os.Stdout, modules)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return 0
+	}
+}
+
+// a0 tokens file.a0 prints the tokens, with -json in the layout editors use
+// for highlighting
+func tokensCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the tokens as JSON with their semantic token types, for editors")
+
+	return func(args []string) int {
+		if len(args) < 1 {
+			fs.Usage()
+			return 2
+		}
+		sources, close, err := openSources(args)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer close()
+		newLexer, _, err := src.lexers()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		report := reporter(sources)
+		status := 0
+		for _, source := range sources {
+			data, err := io.ReadAll(source.reader)
+			if err != nil {
+				report(source, err)
+				status = 1
+				continue
+			}
+			tokenList, err := newLexer(data).Lex()
+			if err != nil {
+				report(source, err)
+				status = 1
+				continue
+			}
+			if *asJSON {
+				if err := printSemanticTokens(data, tokenList); err != nil {
+					fmt.Println(err)
+					return 1
+				}
+				continue
+			}
+			fmt.Println("Tokens:")
+			for _, tok := range tokenList {
+				fmt.Println(tok)
+			}
+		}
+		return status
+	}
+}
+
+// printSemanticTokens prints the tokens lexed from source as JSON, each with
+// its type and range, followed by the encoded form a language server sends
+func printSemanticTokens(source []byte, tokenList []f.TokenItem) error {
+	tokens := f.SemanticTokens(source, tokenList)
+	output := struct {
+		Legend struct {
+			TokenTypes     []string `json:"tokenTypes"`
+			TokenModifiers []string `json:"tokenModifiers"`
+		} `json:"legend"`
+		Tokens []f.SemanticToken `json:"tokens"`
+		Data   []int             `json:"data"`
+	}{Tokens: tokens, Data: f.EncodeSemanticTokens(tokens)}
+	output.Legend.TokenTypes = f.SemanticTokenTypes
+	output.Legend.TokenModifiers = []string{}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// a0 ast file.a0 prints the AST as a tree, or with -json or -dot in a form
+// other tools read
+func astCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the AST as JSON, for tools written in other languages")
+	asDOT := fs.Bool("dot", false, "Print the AST as a Graphviz DOT graph")
+
+	return func(args []string) int {
+		if len(args) < 1 {
+			fs.Usage()
+			return 2
+		}
+		sources, close, err := openSources(args)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer close()
+
+		ok := parseEach(sources, src, func(source sourceFile, program f.Program) error {
+			switch {
+			case *asJSON:
+				data, err := f.MarshalAST(program)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			case *asDOT:
+				return f.WriteDOT(os.Stdout, program)
+			default:
+				fmt.Println("AST:")
+				f.PrintAST(os.Stdout, program)
+				return nil
+			}
+		})
+		if !ok {
+			return 1
+		}
+		return 0
+	}
+}
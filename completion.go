@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+/////////////////
+// Completions //
+/////////////////
+
+// a0 completion bash prints a script that makes the shell complete a0's
+// commands and their flags, written from the commands themselves so it
+// can't fall behind them
+func completionCommand(fs *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		if len(args) != 1 {
+			fs.Usage()
+			return 2
+		}
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(os.Stdout)
+		case "zsh":
+			writeZshCompletion(os.Stdout)
+		case "fish":
+			writeFishCompletion(os.Stdout)
+		default:
+			fmt.Fprintf(os.Stderr, "a0 completion: no completions for %q, only bash, zsh and fish\n", args[0])
+			return 2
+		}
+		return 0
+	}
+}
+
+// completionFlag is a flag as the completion scripts need it
+type completionFlag struct {
+	name, usage string
+	takesValue  bool
+}
+
+// commandFlags lists the flags of cmd
+func commandFlags(cmd command) []completionFlag {
+	fs, _ := newFlagSet(cmd)
+	var flags []completionFlag
+	fs.VisitAll(func(fl *flag.Flag) {
+		boolFlag, ok := fl.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:       fl.Name,
+			usage:      fl.Usage,
+			takesValue: !ok || !boolFlag.IsBoolFlag(),
+		})
+	})
+	return flags
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// flags that name files, so the shells offer files for their values
+var fileFlags = map[string]bool{"keywords": true, "profile": true, "history": true}
+
+func writeBashCompletion(w io.Writer) {
+	var out strings.Builder
+	out.WriteString("# bash completion for a0, load it with: source <(a0 completion bash)\n")
+	out.WriteString("_a0() {\n")
+	out.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	out.WriteString("    local command=run\n")
+	out.WriteString("    if [[ $COMP_CWORD -gt 1 ]]; then\n")
+	out.WriteString("        case ${COMP_WORDS[1]} in\n")
+	fmt.Fprintf(&out, "            %s) command=${COMP_WORDS[1]} ;;\n", strings.Join(commandNames(), "|"))
+	out.WriteString("        esac\n")
+	out.WriteString("    fi\n\n")
+
+	// a flag waiting for its value gets files or nothing
+	var valued, files []string
+	for _, cmd := range commands {
+		for _, fl := range commandFlags(cmd) {
+			if fl.takesValue && !slices.Contains(valued, "-"+fl.name) {
+				valued = append(valued, "-"+fl.name)
+				if fileFlags[fl.name] {
+					files = append(files, "-"+fl.name)
+				}
+			}
+		}
+	}
+	out.WriteString("    case $prev in\n")
+	fmt.Fprintf(&out, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(files, "|"))
+	fmt.Fprintf(&out, "        %s) COMPREPLY=(); return ;;\n", strings.Join(valued, "|"))
+	out.WriteString("    esac\n\n")
+
+	out.WriteString("    if [[ $cur == -* ]]; then\n")
+	out.WriteString("        local flags\n")
+	out.WriteString("        case $command in\n")
+	for _, cmd := range commands {
+		var names []string
+		for _, fl := range commandFlags(cmd) {
+			names = append(names, "-"+fl.name)
+		}
+		fmt.Fprintf(&out, "            %s) flags=%q ;;\n", cmd.name, strings.Join(names, " "))
+	}
+	out.WriteString("        esac\n")
+	out.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	out.WriteString("    elif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&out, "        COMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	out.WriteString("    elif [[ $command == help ]]; then\n")
+	fmt.Fprintf(&out, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	out.WriteString("    elif [[ $command == completion ]]; then\n")
+	out.WriteString("        COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\"))\n")
+	out.WriteString("    else\n")
+	out.WriteString("        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	out.WriteString("    fi\n")
+	out.WriteString("}\n")
+	out.WriteString("complete -o filenames -F _a0 a0\n")
+	io.WriteString(w, out.String())
+}
+
+func writeZshCompletion(w io.Writer) {
+	var out strings.Builder
+	out.WriteString("#compdef a0\n")
+	out.WriteString("# zsh completion for a0, load it with: source <(a0 completion zsh)\n")
+	out.WriteString("_a0() {\n")
+	out.WriteString("    local -a commands\n")
+	out.WriteString("    commands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&out, "        %s\n", zshQuote(cmd.name+":"+cmd.summary))
+	}
+	out.WriteString("    )\n")
+	out.WriteString("    if (( CURRENT == 2 )); then\n")
+	out.WriteString("        _describe command commands\n")
+	out.WriteString("        _files\n")
+	out.WriteString("        return\n")
+	out.WriteString("    fi\n\n")
+
+	out.WriteString("    local command=$words[2]\n")
+	out.WriteString("    (( ${commands[(I)$command:*]} )) || command=run\n")
+	out.WriteString("    case $command in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&out, "        %s)\n", cmd.name)
+		out.WriteString("            _arguments \\\n")
+		for _, fl := range commandFlags(cmd) {
+			spec := "-" + fl.name + "[" + zshEscape(fl.usage) + "]"
+			if fl.takesValue {
+				spec += ":" + fl.name + ":"
+				if fileFlags[fl.name] {
+					spec += "_files"
+				}
+			}
+			fmt.Fprintf(&out, "                %s \\\n", zshQuote(spec))
+		}
+		switch cmd.name {
+		case "help":
+			out.WriteString("                '1:command:(" + strings.Join(commandNames(), " ") + ")'\n")
+		case "completion":
+			out.WriteString("                '1:shell:(bash zsh fish)'\n")
+		default:
+			out.WriteString("                '*:file:_files'\n")
+		}
+		out.WriteString("            ;;\n")
+	}
+	out.WriteString("    esac\n")
+	out.WriteString("}\n")
+	out.WriteString("compdef _a0 a0\n")
+	io.WriteString(w, out.String())
+}
+
+func writeFishCompletion(w io.Writer) {
+	var out strings.Builder
+	out.WriteString("# fish completion for a0, load it with: a0 completion fish | source\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&out, "complete -c a0 -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		// a file as the first argument runs it, so those get run's flags
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		if cmd.name == "run" {
+			others := slices.DeleteFunc(commandNames(), func(name string) bool { return name == "run" })
+			condition = "not __fish_seen_subcommand_from " + strings.Join(others, " ")
+		}
+		for _, fl := range commandFlags(cmd) {
+			fmt.Fprintf(&out, "complete -c a0 -n %s -o %s", fishQuote(condition), fl.name)
+			if fl.takesValue {
+				out.WriteString(" -r")
+				if !fileFlags[fl.name] {
+					out.WriteString(" -f")
+				}
+			}
+			fmt.Fprintf(&out, " -d %s\n", fishQuote(fl.usage))
+		}
+	}
+	fmt.Fprintf(&out, "complete -c a0 -n %s -f -a %s\n", fishQuote("__fish_seen_subcommand_from help"), fishQuote(strings.Join(commandNames(), " ")))
+	fmt.Fprintf(&out, "complete -c a0 -n %s -f -a %s\n", fishQuote("__fish_seen_subcommand_from completion"), fishQuote("bash zsh fish"))
+	io.WriteString(w, out.String())
+}
+
+// zshQuote puts s in single quotes for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape keeps a flag description from ending the [...] it's in
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// fishQuote puts s in single quotes for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+//////////////
+// API Docs //
+//////////////
+
+// moduleDocs is what a0 doc lists for one file
+type moduleDocs struct {
+	Path    string
+	Entries []f.DocEntry
+}
+
+// moduleFiles expands the folders among paths into the files inside them and
+// their subfolders whose names end in suffix, so a0 doc and a0 test can take
+// a whole module tree
+func moduleFiles(paths []string, suffix string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				// folders like .git aren't part of the modules
+				if file != path && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// a file named on the command line is read whatever it's called
+			if file == path || strings.HasSuffix(file, suffix) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// writeMarkdownDocs writes the docs of every module as Markdown, a heading a
+// file and a smaller one for each declaration in it
+func writeMarkdownDocs(w io.Writer, modules []moduleDocs) error {
+	var out strings.Builder
+	for i, module := range modules {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "# %s\n", module.Path)
+		for _, entry := range module.Entries {
+			writeMarkdownEntry(&out, entry, "##")
+			for _, method := range entry.Methods {
+				writeMarkdownEntry(&out, method, "###")
+			}
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeMarkdownEntry(out *strings.Builder, entry f.DocEntry, heading string) {
+	fmt.Fprintf(out, "\n%s `%s`\n", heading, entry.Signature())
+	if entry.Doc != "" {
+		fmt.Fprintf(out, "\n%s\n", entry.Doc)
+	}
+}
+
+// docsPage is the HTML a0 doc -html writes, every module in one page
+var docsPage = template.Must(template.New("docs").Funcs(template.FuncMap{"doc": docHTML}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if eq (len .) 1}}{{(index . 0).Path}}{{else}}a0 docs{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+h2, h3 { font-family: monospace; margin-top: 1.5em; }
+.method { margin-left: 2em; }
+code { background: #f3f3f3; padding: 0 0.2em; }
+</style>
+</head>
+<body>
+{{range .}}<section>
+<h1>{{.Path}}</h1>
+{{range .Entries}}{{$class := .Name}}<h2 id="{{.Name}}">{{.Signature}}</h2>
+{{doc .Doc}}{{range .Methods}}<div class="method">
+<h3 id="{{$class}}.{{.Name}}">{{.Signature}}</h3>
+{{doc .Doc}}</div>
+{{end}}{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// codeSpan is text in backticks in a doc comment
+var codeSpan = regexp.MustCompile("`([^`]+)`")
+
+// docHTML turns a doc comment into paragraphs, split at blank lines, with
+// `code` spans kept
+func docHTML(doc string) template.HTML {
+	var out strings.Builder
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		escaped := template.HTMLEscapeString(strings.TrimSpace(paragraph))
+		fmt.Fprintf(&out, "<p>%s</p>\n", codeSpan.ReplaceAllString(escaped, "<code>$1</code>"))
+	}
+	return template.HTML(out.String())
+}
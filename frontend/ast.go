@@ -12,27 +12,46 @@ const (
 	ProgramNode             NodeType = "Program"
 	VarDeclarationNode      NodeType = "VarDeclaration"
 	FunctionDeclarationNode NodeType = "FunctionDeclaration"
+	ClassDeclarationNode    NodeType = "ClassDeclaration"
 
 	// Expressions
 	AssignmentExpressionNode NodeType = "AssignmentExpr"
 	MemberExpressionNode     NodeType = "MemberExpr"
+	SliceExpressionNode      NodeType = "SliceExpr"
+	RangeExpressionNode      NodeType = "RangeExpr"
 	CallExpressionNode       NodeType = "CallExpr"
+	NewExpressionNode        NodeType = "NewExpr"
+	MatchExpressionNode      NodeType = "MatchExpr"
 
 	// Literals
 	ObjectLiteralNode     NodeType = "Object"
+	ArrayLiteralNode      NodeType = "Array"
 	PropertyNode          NodeType = "Property"
 	NumericLiteralNode    NodeType = "NumericLiteral"
 	StringLiteralNode     NodeType = "StringLiteral"
+	CharLiteralNode       NodeType = "CharLiteral"
 	IdentifierNode        NodeType = "Identifier"
 	LogicalExpressionNode NodeType = "LogicalExpr"
 	BinaryExpressionNode  NodeType = "BinaryExpr"
 	UnaryExpressionNode   NodeType = "UnaryExpr"
 
+	// Patterns
+	ObjectPatternNode NodeType = "ObjectPattern"
+	ArrayPatternNode  NodeType = "ArrayPattern"
+
 	// Keywords
-	IfStmtNode     NodeType = "IfStmt"
-	WhileStmtNode  NodeType = "WhileStmt"
-	ForStmtNode    NodeType = "ForStmt"
-	ReturnStmtNode NodeType = "ReturnStmt"
+	IfStmtNode       NodeType = "IfStmt"
+	WhileStmtNode    NodeType = "WhileStmt"
+	ForStmtNode      NodeType = "ForStmt"
+	ForEachStmtNode  NodeType = "ForEachStmt"
+	ReturnStmtNode   NodeType = "ReturnStmt"
+	BreakStmtNode    NodeType = "BreakStmt"
+	ContinueStmtNode NodeType = "ContinueStmt"
+	ImportStmtNode   NodeType = "ImportStmt"
+	TryStmtNode      NodeType = "TryStmt"
+	ThrowStmtNode    NodeType = "ThrowStmt"
+	AssertStmtNode   NodeType = "AssertStmt"
+	BenchStmtNode    NodeType = "BenchStmt"
 )
 
 // Base Types //
@@ -40,6 +59,12 @@ type Stmt interface {
 	NodeType() NodeType
 }
 
+// Positioned is implemented by nodes that record where they start in the
+// source, runtime errors use it to point at the failing code
+type Positioned interface {
+	Position() Position
+}
+
 type Expr interface {
 	Stmt // Expr embeds Stmt so now Expr is also as Stmt
 }
@@ -57,118 +82,391 @@ func (p Program) NodeType() NodeType {
 type VarDeclaration struct {
 	Constant   bool
 	Identifier string
+	Pattern    Expr // ObjectPattern or ArrayPattern when destructuring, Identifier is empty then
 	Value      Expr
+	Pos        Position
+	Comments   []Comment // the comments just above the declaration
+	Doc        string    // the /// doc comment right above it, see DocEntries
 }
 
 func (v VarDeclaration) NodeType() NodeType {
 	return VarDeclarationNode
 }
 
+func (v VarDeclaration) Position() Position {
+	return v.Pos
+}
+
 type FunctionDeclaration struct {
 	Name       string
 	Parameters []string
 	Body       []Stmt
+	Pos        Position
+	Comments   []Comment // the comments just above the declaration
+	Doc        string    // the /// doc comment right above it, see DocEntries
 }
 
 func (f FunctionDeclaration) NodeType() NodeType {
 	return FunctionDeclarationNode
 }
 
+func (f FunctionDeclaration) Position() Position {
+	return f.Pos
+}
+
+type ClassDeclaration struct {
+	Name     string
+	Parent   Expr // class Dog : Animal, nil without a parent
+	Methods  []FunctionDeclaration
+	Pos      Position
+	Comments []Comment // the comments just above the declaration
+	Doc      string    // the /// doc comment right above it, see DocEntries
+}
+
+func (c ClassDeclaration) NodeType() NodeType {
+	return ClassDeclarationNode
+}
+
+func (c ClassDeclaration) Position() Position {
+	return c.Pos
+}
+
 type IfStmt struct {
 	Condition Expr
 	Body      []Stmt
+	Pos       Position
 }
 
 func (i IfStmt) NodeType() NodeType {
 	return IfStmtNode
 }
 
+func (i IfStmt) Position() Position {
+	return i.Pos
+}
+
 type WhileStmt struct {
 	Condition Expr
 	Body      []Stmt
+	Pos       Position
 }
 
 func (w WhileStmt) NodeType() NodeType {
 	return WhileStmtNode
 }
 
+func (w WhileStmt) Position() Position {
+	return w.Pos
+}
+
 type ForStmt struct {
 	Condition Expr
 	Body      []Stmt
+	Pos       Position
 }
 
 func (f ForStmt) NodeType() NodeType {
 	return ForStmtNode
 }
 
+func (f ForStmt) Position() Position {
+	return f.Pos
+}
+
+// for (key, value in iterable) or for (item in iterable)
+type ForEachStmt struct {
+	Key      string
+	Value    string // empty when only one loop variable is given
+	Iterable Expr
+	Body     []Stmt
+	Pos      Position
+}
+
+func (f ForEachStmt) NodeType() NodeType {
+	return ForEachStmtNode
+}
+
+func (f ForEachStmt) Position() Position {
+	return f.Pos
+}
+
 type ReturnStmt struct {
 	Value Expr
+	Pos   Position
 }
 
 func (r ReturnStmt) NodeType() NodeType {
 	return ReturnStmtNode
 }
 
+func (r ReturnStmt) Position() Position {
+	return r.Pos
+}
+
+// import "utils.a0" or import utils, optionally followed by as name
+type ImportStmt struct {
+	Path string
+	Name string // the variable the module is bound to
+	Pos  Position
+}
+
+func (i ImportStmt) NodeType() NodeType {
+	return ImportStmtNode
+}
+
+func (i ImportStmt) Position() Position {
+	return i.Pos
+}
+
+// try { ... } catch (e) { ... } finally { ... }, catch and finally are optional
+// but at least one of them has to be there
+type TryStmt struct {
+	Body        []Stmt
+	CatchName   string // empty when the catch doesn't bind the error
+	CatchBody   []Stmt
+	HasCatch    bool
+	FinallyBody []Stmt
+	Pos         Position
+}
+
+func (t TryStmt) NodeType() NodeType {
+	return TryStmtNode
+}
+
+func (t TryStmt) Position() Position {
+	return t.Pos
+}
+
+type ThrowStmt struct {
+	Value Expr
+	Pos   Position
+}
+
+func (t ThrowStmt) NodeType() NodeType {
+	return ThrowStmtNode
+}
+
+func (t ThrowStmt) Position() Position {
+	return t.Pos
+}
+
+// assert(condition) or assert(condition, message)
+type AssertStmt struct {
+	Condition Expr
+	Message   Expr // nil when no message is given
+	Pos       Position
+}
+
+func (a AssertStmt) NodeType() NodeType {
+	return AssertStmtNode
+}
+
+func (a AssertStmt) Position() Position {
+	return a.Pos
+}
+
+// bench "name" { ... } is a benchmark for a0 bench to run, running the file
+// skips it. Only allowed at the top level.
+type BenchStmt struct {
+	Name string
+	Body []Stmt
+	Pos  Position
+}
+
+func (b BenchStmt) NodeType() NodeType {
+	return BenchStmtNode
+}
+
+func (b BenchStmt) Position() Position {
+	return b.Pos
+}
+
+// break leaves the innermost loop
+type BreakStmt struct {
+	Pos Position
+}
+
+func (b BreakStmt) NodeType() NodeType {
+	return BreakStmtNode
+}
+
+func (b BreakStmt) Position() Position {
+	return b.Pos
+}
+
+// continue skips to the next iteration of the innermost loop
+type ContinueStmt struct {
+	Pos Position
+}
+
+func (c ContinueStmt) NodeType() NodeType {
+	return ContinueStmtNode
+}
+
+func (c ContinueStmt) Position() Position {
+	return c.Pos
+}
+
 // Expressions //
 
 type AssignmentExpr struct {
 	Assignee Expr
 	Value    Expr
+	Pos      Position
 }
 
 func (a AssignmentExpr) NodeType() NodeType {
 	return AssignmentExpressionNode
 }
 
+func (a AssignmentExpr) Position() Position {
+	return a.Pos
+}
+
 type CallExpr struct {
 	Args   []Expr
 	Caller Expr
+	Pos    Position
 }
 
 func (c CallExpr) NodeType() NodeType {
 	return CallExpressionNode
 }
 
+func (c CallExpr) Position() Position {
+	return c.Pos
+}
+
+// new Class(args)
+type NewExpr struct {
+	Class Expr
+	Args  []Expr
+	Pos   Position
+}
+
+func (n NewExpr) NodeType() NodeType {
+	return NewExpressionNode
+}
+
+func (n NewExpr) Position() Position {
+	return n.Pos
+}
+
+// match (subject) { pattern -> body ... }, the first arm whose pattern fits
+// the subject (and whose guard holds) runs
+type MatchExpr struct {
+	Subject Expr
+	Arms    []MatchArm
+	Pos     Position
+}
+
+func (m MatchExpr) NodeType() NodeType {
+	return MatchExpressionNode
+}
+
+func (m MatchExpr) Position() Position {
+	return m.Pos
+}
+
+// Patterns reuse expression nodes: literals compare by value, _ matches
+// anything, other identifiers bind, and object and array literals match shapes
+type MatchArm struct {
+	Pattern Expr
+	Guard   Expr // pattern if guard -> body, nil without a guard
+	Body    []Stmt
+}
+
 type MemberExpr struct {
 	Object   Expr
 	Property Expr
 	Computed bool
+	Pos      Position
 }
 
 func (m MemberExpr) NodeType() NodeType {
 	return MemberExpressionNode
 }
 
+func (m MemberExpr) Position() Position {
+	return m.Pos
+}
+
+// obj[start:end], either bound can be left out
+type SliceExpr struct {
+	Object Expr
+	Start  Expr
+	End    Expr
+	Pos    Position
+}
+
+func (s SliceExpr) NodeType() NodeType {
+	return SliceExpressionNode
+}
+
+func (s SliceExpr) Position() Position {
+	return s.Pos
+}
+
+// start..end
+type RangeExpr struct {
+	Start Expr
+	End   Expr
+	Pos   Position
+}
+
+func (r RangeExpr) NodeType() NodeType {
+	return RangeExpressionNode
+}
+
+func (r RangeExpr) Position() Position {
+	return r.Pos
+}
+
 // Literals //
 type LogicalExpr struct {
 	Left     Expr
 	Right    Expr
 	Operator string
+	Pos      Position
 }
 
 func (l LogicalExpr) NodeType() NodeType {
 	return LogicalExpressionNode
 }
 
+func (l LogicalExpr) Position() Position {
+	return l.Pos
+}
+
 type BinaryExpr struct {
 	Left     Expr
 	Right    Expr
 	Operator string
+	Pos      Position
 }
 
 func (b BinaryExpr) NodeType() NodeType {
 	return BinaryExpressionNode
 }
 
+func (b BinaryExpr) Position() Position {
+	return b.Pos
+}
+
 type UnaryExpr struct {
 	Operant  Expr
 	Operator string
+	Pos      Position
 }
 
 func (b UnaryExpr) NodeType() NodeType {
 	return UnaryExpressionNode
 }
 
+func (b UnaryExpr) Position() Position {
+	return b.Pos
+}
+
 type NumericLiteral struct {
 	Value float64
 }
@@ -185,14 +483,27 @@ func (s StringLiteral) NodeType() NodeType {
 	return StringLiteralNode
 }
 
+type CharLiteral struct {
+	Value rune
+}
+
+func (c CharLiteral) NodeType() NodeType {
+	return CharLiteralNode
+}
+
 type Identifier struct {
 	Symbol string
+	Pos    Position
 }
 
 func (i Identifier) NodeType() NodeType {
 	return IdentifierNode
 }
 
+func (i Identifier) Position() Position {
+	return i.Pos
+}
+
 type Property struct {
 	Key   string
 	Value Expr
@@ -204,8 +515,46 @@ func (p Property) NodeType() NodeType {
 
 type ObjectLiteral struct {
 	Properties []Property
+	Pos        Position
 }
 
 func (o ObjectLiteral) NodeType() NodeType {
 	return ObjectLiteralNode
 }
+
+func (o ObjectLiteral) Position() Position {
+	return o.Pos
+}
+
+type ArrayLiteral struct {
+	Elements []Expr
+	Pos      Position
+}
+
+func (a ArrayLiteral) NodeType() NodeType {
+	return ArrayLiteralNode
+}
+
+func (a ArrayLiteral) Position() Position {
+	return a.Pos
+}
+
+// Patterns //
+
+// {x, y} binds properties to variables of the same name
+type ObjectPattern struct {
+	Names []string
+}
+
+func (o ObjectPattern) NodeType() NodeType {
+	return ObjectPatternNode
+}
+
+// [a, b] binds elements to variables by position
+type ArrayPattern struct {
+	Names []string
+}
+
+func (a ArrayPattern) NodeType() NodeType {
+	return ArrayPatternNode
+}
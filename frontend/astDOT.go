@@ -0,0 +1,130 @@
+package frontend
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/////////////
+// AST DOT //
+/////////////
+
+// WriteDOT writes root as a Graphviz graph, so `dot -Tsvg` can draw it.
+// Every node is a box with its NodeType and its plain fields like the
+// operator or a name, and the edges to its children are labelled with the
+// field they're in, named like in MarshalAST. Non-node structs like match
+// arms get a box of their own with their Go type. Empty fields and
+// positions are left out.
+func WriteDOT(w io.Writer, root Stmt) error {
+	d := &dotWriter{}
+	d.out.WriteString("digraph AST {\n")
+	d.out.WriteString("    node [shape=box, fontname=\"monospace\"];\n")
+	d.out.WriteString("    edge [fontname=\"monospace\", fontsize=10];\n")
+	if _, err := d.node(reflect.ValueOf(&root).Elem()); err != nil {
+		return err
+	}
+	d.out.WriteString("}\n")
+	_, err := io.WriteString(w, d.out.String())
+	return err
+}
+
+type dotWriter struct {
+	out   strings.Builder
+	nodes int
+}
+
+// node writes v as a box, with boxes and edges for everything under it, and
+// returns its id, which is empty when v is nil and nothing was written
+func (d *dotWriter) node(v reflect.Value) (id string, err error) {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("can't draw %s as a node", v.Type())
+	}
+
+	id = fmt.Sprintf("n%d", d.nodes)
+	d.nodes++
+
+	// the box goes first so dot keeps the children in source order
+	label := []string{v.Type().Name()}
+	if node, ok := v.Interface().(Stmt); ok {
+		label[0] = string(node.NodeType())
+	}
+	type child struct {
+		name  string
+		value reflect.Value
+	}
+	var children []child
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if !field.IsExported() || field.Type == positionType || value.IsZero() {
+			continue
+		}
+		name := jsonFieldName(field.Name)
+
+		switch value.Kind() {
+		case reflect.Interface, reflect.Struct:
+			children = append(children, child{name, value})
+
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.String {
+				label = append(label, name+": "+strings.Join(value.Interface().([]string), ", "))
+				continue
+			}
+			for j := range value.Len() {
+				children = append(children, child{fmt.Sprintf("%s[%d]", name, j), value.Index(j)})
+			}
+
+		case reflect.Int32: // CharLiteral is the only rune in the AST
+			label = append(label, name+": "+strconv.QuoteRune(rune(value.Int())))
+
+		case reflect.String:
+			if v.Type() == reflect.TypeFor[StringLiteral]() {
+				label = append(label, name+": "+strconv.Quote(value.String()))
+			} else {
+				label = append(label, name+": "+value.String())
+			}
+
+		case reflect.Float64:
+			label = append(label, name+": "+strconv.FormatFloat(value.Float(), 'g', -1, 64))
+
+		case reflect.Bool:
+			label = append(label, name+": true")
+
+		default:
+			return "", fmt.Errorf("can't draw %s", value.Type())
+		}
+	}
+	fmt.Fprintf(&d.out, "    %s [label=%s];\n", id, dotString(strings.Join(label, "\n")))
+
+	for _, c := range children {
+		to, err := d.node(c.value)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", v.Type().Name(), c.name, err)
+		}
+		if to != "" {
+			fmt.Fprintf(&d.out, "    %s -> %s [label=%s];\n", id, to, dotString(c.name))
+		}
+	}
+	return id, nil
+}
+
+// dotString quotes s for DOT, where a line break is written \l to keep the
+// lines left aligned
+func dotString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	if strings.Contains(s, "\n") {
+		s = strings.ReplaceAll(s, "\n", `\l`) + `\l`
+	}
+	return `"` + s + `"`
+}
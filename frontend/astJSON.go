@@ -0,0 +1,231 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+//////////////
+// AST JSON //
+//////////////
+
+// MarshalAST turns an AST into JSON that tools outside Go can read. Every
+// node is an object with a "type" holding its NodeType, and one key per field
+// of the node named like the Go field with a lowercase first letter, so a
+// BinaryExpr is {"type": "BinaryExpr", "left": ..., "right": ...,
+// "operator": "+", "pos": ...}. Positions are {"line", "column", "offset"},
+// chars are one character strings and missing children or lists are null.
+// Non-node structs like match arms are plain objects without a type.
+func MarshalAST(node Stmt) ([]byte, error) {
+	encoded, err := encodeASTValue(reflect.ValueOf(&node).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalAST reads an AST written by MarshalAST back. Keys it doesn't know
+// are ignored and missing ones are left at their zero value, but every node
+// needs a known "type".
+func UnmarshalAST(data []byte) (Stmt, error) {
+	node, err := decodeASTNode(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading AST: %w", err)
+	}
+	return node, nil
+}
+
+// astNodeTypes is every node UnmarshalAST can create, by NodeType
+var astNodeTypes = func() map[NodeType]reflect.Type {
+	nodes := []Stmt{
+		Program{}, VarDeclaration{}, FunctionDeclaration{}, ClassDeclaration{},
+		IfStmt{}, WhileStmt{}, ForStmt{}, ForEachStmt{}, ReturnStmt{}, BreakStmt{},
+		ContinueStmt{}, ImportStmt{}, TryStmt{}, ThrowStmt{}, AssertStmt{}, BenchStmt{},
+		AssignmentExpr{}, CallExpr{}, NewExpr{}, MatchExpr{}, MemberExpr{},
+		SliceExpr{}, RangeExpr{}, LogicalExpr{}, BinaryExpr{}, UnaryExpr{},
+		NumericLiteral{}, StringLiteral{}, CharLiteral{}, Identifier{},
+		Property{}, ObjectLiteral{}, ArrayLiteral{}, ObjectPattern{}, ArrayPattern{},
+	}
+
+	types := make(map[NodeType]reflect.Type, len(nodes))
+	for _, node := range nodes {
+		types[node.NodeType()] = reflect.TypeOf(node)
+	}
+	return types
+}()
+
+var (
+	stmtType     = reflect.TypeFor[Stmt]()
+	positionType = reflect.TypeFor[Position]()
+)
+
+type positionJSON struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+func encodeASTValue(v reflect.Value) (any, error) {
+	if v.Type() == positionType {
+		pos := v.Interface().(Position)
+		return positionJSON{Line: pos.line, Column: pos.column, Offset: pos.offset}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeASTValue(v.Elem())
+
+	case reflect.Struct:
+		fields := map[string]any{}
+		if v.Type().Implements(stmtType) {
+			fields["type"] = v.Interface().(Stmt).NodeType()
+		}
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			value, err := encodeASTValue(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", v.Type().Name(), field.Name, err)
+			}
+			fields[jsonFieldName(field.Name)] = value
+		}
+		return fields, nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		elements := make([]any, v.Len())
+		for i := range v.Len() {
+			element, err := encodeASTValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+
+	case reflect.Int32: // CharLiteral is the only rune in the AST
+		return string(rune(v.Int())), nil
+
+	case reflect.String, reflect.Bool, reflect.Float64:
+		return v.Interface(), nil
+
+	default:
+		return nil, fmt.Errorf("can't write %s as JSON", v.Type())
+	}
+}
+
+func decodeASTNode(data []byte) (Stmt, error) {
+	var header struct {
+		Type NodeType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	nodeType, known := astNodeTypes[header.Type]
+	if !known {
+		return nil, fmt.Errorf("unknown node type %q", header.Type)
+	}
+
+	node, err := decodeASTValue(data, nodeType)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", header.Type, err)
+	}
+	return node.Interface().(Stmt), nil
+}
+
+func decodeASTValue(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+
+	if t == positionType {
+		var pos positionJSON
+		if err := json.Unmarshal(data, &pos); err != nil {
+			return value, err
+		}
+		value.Set(reflect.ValueOf(Position{line: pos.Line, column: pos.Column, offset: pos.Offset}))
+		return value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if string(data) == "null" {
+			return value, nil
+		}
+		node, err := decodeASTNode(data)
+		if err != nil {
+			return value, err
+		}
+		if !reflect.TypeOf(node).AssignableTo(t) {
+			return value, fmt.Errorf("%s can't be used as a %s", node.NodeType(), t.Name())
+		}
+		value.Set(reflect.ValueOf(node))
+
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return value, err
+		}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			raw, present := fields[jsonFieldName(field.Name)]
+			if !field.IsExported() || !present {
+				continue
+			}
+			fieldValue, err := decodeASTValue(raw, field.Type)
+			if err != nil {
+				return value, fmt.Errorf("%s: %w", jsonFieldName(field.Name), err)
+			}
+			value.Field(i).Set(fieldValue)
+		}
+
+	case reflect.Slice:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return value, err
+		}
+		if elements == nil {
+			return value, nil
+		}
+		value.Set(reflect.MakeSlice(t, len(elements), len(elements)))
+		for i, element := range elements {
+			elementValue, err := decodeASTValue(element, t.Elem())
+			if err != nil {
+				return value, fmt.Errorf("[%d]: %w", i, err)
+			}
+			value.Index(i).Set(elementValue)
+		}
+
+	case reflect.Int32:
+		var char string
+		if err := json.Unmarshal(data, &char); err != nil {
+			return value, err
+		}
+		if utf8.RuneCountInString(char) != 1 {
+			return value, fmt.Errorf("expected a single character but got %q", char)
+		}
+		r, _ := utf8.DecodeRuneInString(char)
+		value.SetInt(int64(r))
+
+	default:
+		if err := json.Unmarshal(data, value.Addr().Interface()); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
+
+// jsonFieldName is the key a Go field is written under, Pos becomes pos
+func jsonFieldName(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
@@ -0,0 +1,116 @@
+package frontend_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// everyNode is a program with every kind of node in it
+const everyNode = `import "lib.a0" as lib
+/// adds things
+fun add(a, b) { return a + b }
+class Point : Base {
+    fun init(x) { self.x = x }
+}
+var [first, second] = [1, 2.5]
+const {name} = { name: "n", other }
+if (first < 2 and not false) { println(first) }
+while (true) { break }
+for (3) { continue }
+for (k, v in { a: 'c' }) {}
+try { throw error("x") } catch (e) {} finally {}
+assert(1 == 1, "math")
+bench "b" { xs[1:2] }
+val m = match (first) { [a, _] -> a, _ -> { -1 } }
+new Point(1..3).x = add(1, 2)
+`
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	program, err := testutil.Parse(everyNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.MarshalAST(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := f.UnmarshalAST(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, program) {
+		t.Errorf("the program read back differs\n--- read back ---\n%#v\n--- parsed ---\n%#v", back, program)
+	}
+
+	again, err := f.MarshalAST(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("marshaling again gave different JSON\n%s\n%s", again, data)
+	}
+}
+
+func TestASTJSONShape(t *testing.T) {
+	expr, err := f.ParseExpressionString("1 + x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.MarshalAST(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["type"] != "BinaryExpr" || got["operator"] != "+" {
+		t.Errorf("got %s, want a BinaryExpr with operator +", data)
+	}
+	left, _ := got["left"].(map[string]any)
+	if left["type"] != "NumericLiteral" || left["value"] != 1.0 {
+		t.Errorf("got left %v, want the number 1", left)
+	}
+	pos, _ := got["pos"].(map[string]any)
+	if pos["line"] != 1.0 || pos["column"] != 3.0 {
+		t.Errorf("got pos %v, want line 1 column 3", pos)
+	}
+}
+
+func TestUnmarshalASTIgnoresUnknownKeys(t *testing.T) {
+	node, err := f.UnmarshalAST([]byte(`{"type": "Identifier", "symbol": "x", "comment": "added by a tool"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := node.(f.Identifier); !ok || id.Symbol != "x" {
+		t.Errorf("got %#v, want the identifier x", node)
+	}
+}
+
+func TestUnmarshalASTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not JSON", `{"type":`, "reading AST"},
+		{"no type", `{"symbol": "x"}`, "reading AST"},
+		{"unknown type", `{"type": "GotoStmt"}`, "GotoStmt"},
+		{"wrong child", `{"type": "ReturnStmt", "value": 3}`, "reading AST"},
+		{"wrong field type", `{"type": "Identifier", "symbol": 3}`, "reading AST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.UnmarshalAST([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package frontend
+
+import (
+	"fmt"
+	"io"
+)
+
+//////////////
+// AST Tree //
+//////////////
+
+// handles statement nodes
+func printStmt(w io.Writer, node Stmt, indent string, isLast bool) {
+	// tree symbols
+	branch, nextIndent := "├── ", indent+"│   "
+	if isLast {
+		branch, nextIndent = "└── ", indent+"    "
+	}
+
+	switch n := node.(type) {
+	case Program:
+		fmt.Fprintln(w, indent+branch+"Program")
+		for i, stmt := range n.Body {
+			printStmt(w, stmt, nextIndent, i == len(n.Body)-1)
+		}
+
+	case VarDeclaration:
+		fmt.Fprintf(w, "%s%sVarDeclaration: Name: %s | Constant: %t\n",
+			indent, branch,
+			n.Identifier,
+			n.Constant,
+		)
+		if n.Value != nil {
+			printExpr(w, n.Value, nextIndent, true)
+		}
+
+	case AssignmentExpr:
+		fmt.Fprintf(w, "%s%sAssignmentExpr\n", indent, branch)
+		printExpr(w, n.Assignee, nextIndent, false)
+		printExpr(w, n.Value, nextIndent, true)
+
+	case FunctionDeclaration:
+		fmt.Fprintf(w, "%s%sFunctionDeclaration\n", indent, branch)
+
+		// Name
+		fmt.Fprintf(w, "%s%sName: %s\n",
+			nextIndent, "└── ", n.Name,
+		)
+
+		// Parameters
+		fmt.Fprintf(w, "%s├── Parameters\n", nextIndent)
+		for i, param := range n.Parameters {
+			pBranch := "│   ├── "
+			if i == len(n.Parameters)-1 {
+				pBranch = "│   └── "
+			}
+			fmt.Fprintf(w, "%s%sIdentifier (%s)\n",
+				nextIndent, pBranch, param,
+			)
+		}
+
+		// Body
+		bodyIndent := nextIndent + "    "
+		fmt.Fprintf(w, "%s└── Body\n", nextIndent)
+		for i, stmt := range n.Body {
+			printStmt(w, stmt, bodyIndent, i == len(n.Body)-1)
+		}
+
+	case CallExpr:
+		// Treat bare CallExpr as a statement
+		fmt.Fprintf(w, "%s%sCallExpr\n", indent, branch)
+		printExpr(w, n.Caller, nextIndent, false)
+		for i, arg := range n.Args {
+			printExpr(w, arg, nextIndent, i == len(n.Args)-1)
+		}
+
+	case ObjectLiteral:
+		fmt.Fprintf(w, "%s%sObjectLiteral\n", indent, branch)
+		for i, prop := range n.Properties {
+			propBranch := "├── "
+			if i == len(n.Properties)-1 {
+				propBranch = "└── "
+			}
+			fmt.Fprintf(w, "%s%sProperty: Key: %s\n",
+				nextIndent, propBranch, prop.Key,
+			)
+			// property value is an Expr
+			printExpr(w, prop.Value, nextIndent+"│   ", i == len(n.Properties)-1)
+		}
+
+	default:
+		// an expression used as a statement, like x + 1 typed into the REPL
+		printExpr(w, n, indent, isLast)
+	}
+}
+
+// handles expression nodes
+func printExpr(w io.Writer, node Expr, indent string, isLast bool) {
+	branch, nextIndent := "├── ", indent+"│   "
+	if isLast {
+		branch, nextIndent = "└── ", indent+"    "
+	}
+
+	switch n := node.(type) {
+	case Identifier:
+		fmt.Fprintf(w, "%s%sIdentifier (%s)\n", indent, branch, n.Symbol)
+
+	case NumericLiteral:
+		fmt.Fprintf(w, "%s%sNumericLiteral (%f)\n", indent, branch, n.Value)
+
+	case BinaryExpr:
+		fmt.Fprintf(w, "%s%sBinaryExpr (Operator: %s)\n", indent, branch, n.Operator)
+		printExpr(w, n.Left, nextIndent, false)
+		printExpr(w, n.Right, nextIndent, true)
+
+	case LogicalExpr:
+		fmt.Fprintf(w, "%s%sLogicalExpr (Operator: %s)\n", indent, branch, n.Operator)
+		printExpr(w, n.Left, nextIndent, false)
+		printExpr(w, n.Right, nextIndent, true)
+
+	case UnaryExpr:
+		fmt.Fprintf(w, "%s%sUnaryExpr (Operator: %s)\n", indent, branch, n.Operator)
+		printExpr(w, n.Operant, nextIndent, true)
+
+	case CallExpr:
+		fmt.Fprintf(w, "%s%sCallExpr\n", indent, branch)
+		printExpr(w, n.Caller, nextIndent, false)
+		for i, arg := range n.Args {
+			printExpr(w, arg, nextIndent, i == len(n.Args)-1)
+		}
+
+	default:
+		fmt.Fprintf(w, "%s%sUnknown expr node of type %T\n", indent, branch, n)
+	}
+}
+
+// PrintAST writes root as an indented tree for reading while debugging, the
+// nodes it doesn't know show up with their Go type
+func PrintAST(w io.Writer, root Stmt) {
+	printStmt(w, root, "", true)
+}
@@ -0,0 +1,127 @@
+package frontend
+
+import (
+	"errors"
+	"strings"
+)
+
+/////////////////
+// Diagnostics //
+/////////////////
+
+// Severity is how bad a diagnostic is, numbered the way the language server
+// protocol numbers them
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "information"
+	case SeverityHint:
+		return "hint"
+	}
+	return "unknown"
+}
+
+// Range is the part of the source a diagnostic is about. End is the same as
+// Start when only where the problem starts is known.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// PointRange is the range of the single place pos
+func PointRange(pos Position) Range {
+	return Range{Start: pos, End: pos}
+}
+
+// Semantic is r with zero based lines and UTF-16 characters the way the
+// language server protocol counts them, source being the text r is in
+func (r Range) Semantic(source []byte) SemanticRange {
+	lines := lineStarts(source)
+	return SemanticRange{
+		Start: lspPosition(source, lines, r.Start.Offset()),
+		End:   lspPosition(source, lines, r.End.Offset()),
+	}
+}
+
+// Diagnostic is a problem found in a program, with the parts an editor shows
+// kept apart instead of formatted into one line
+type Diagnostic struct {
+	// Code names the kind of problem, like "parse-error" or "type-error", and
+	// stays the same between releases so hosts can act on it
+	Code     string
+	Message  string // what went wrong, without the position and kind in front
+	Range    Range  // the zero Range when the position isn't known
+	Severity Severity
+	// Related are other places that had a part in it, like the calls a
+	// runtime error happened inside of
+	Related []RelatedInformation
+}
+
+// RelatedInformation is a place a diagnostic mentions, with what happened
+// there
+type RelatedInformation struct {
+	Message string
+	Range   Range
+}
+
+// Diagnoser is an error that can describe itself as a Diagnostic. The errors
+// from lexing, parsing and running programs all are.
+type Diagnoser interface {
+	error
+	Diagnostic() Diagnostic
+}
+
+// Diagnostics gives the diagnostics err carries, one for each error when it
+// holds several like ParsingErrors does. An error that isn't a Diagnoser,
+// like one from reading a file, becomes a diagnostic with no range.
+func Diagnostics(err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	if diagnoser, ok := err.(Diagnoser); ok {
+		return []Diagnostic{diagnoser.Diagnostic()}
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var diagnostics []Diagnostic
+		for _, inner := range joined.Unwrap() {
+			diagnostics = append(diagnostics, Diagnostics(inner)...)
+		}
+		return diagnostics
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		return Diagnostics(inner)
+	}
+	return []Diagnostic{{Code: "error", Message: err.Error(), Severity: SeverityError}}
+}
+
+func (e *ParsingError) Diagnostic() Diagnostic {
+	return Diagnostic{
+		Code:     "parse-error",
+		Message:  strings.TrimPrefix(e.Message, "Parsing Error: "),
+		Range:    PointRange(e.Pos),
+		Severity: SeverityError,
+	}
+}
+
+// Diagnostic makes w a diagnostic, though it isn't an error
+func (w Warning) Diagnostic() Diagnostic {
+	return Diagnostic{
+		Code:     "warning",
+		Message:  w.Message,
+		Range:    PointRange(w.Pos),
+		Severity: SeverityWarning,
+	}
+}
@@ -0,0 +1,94 @@
+package frontend
+
+import "strings"
+
+//////////
+// Docs //
+//////////
+
+// DocEntry is a declaration for a0 doc to list, with the /// comment written
+// right above it
+type DocEntry struct {
+	Kind       string // function, class, method, variable or constant
+	Name       string
+	Parameters []string // of a function or method
+	Parent     string   // the class a class extends, empty without one
+	Doc        string
+	Pos        Position
+	Methods    []DocEntry // of a class
+}
+
+// Signature is how the entry is declared, like fun greet(name) or
+// class Dog : Animal
+func (d DocEntry) Signature() string {
+	switch d.Kind {
+	case "function", "method":
+		return "fun " + d.Name + "(" + strings.Join(d.Parameters, ", ") + ")"
+	case "class":
+		if d.Parent != "" {
+			return "class " + d.Name + " : " + d.Parent
+		}
+		return "class " + d.Name
+	case "constant":
+		return "const " + d.Name
+	default:
+		return "var " + d.Name
+	}
+}
+
+// DocEntries lists the declarations at the top level of program, which is
+// what importing it as a module gives access to. Functions and classes are
+// always listed, variables only when they have a doc comment since most of
+// them are just the program's working state.
+func DocEntries(program Program) []DocEntry {
+	var entries []DocEntry
+	for _, stmt := range program.Body {
+		switch stmt := stmt.(type) {
+		case FunctionDeclaration:
+			entries = append(entries, functionDoc(stmt, "function"))
+		case ClassDeclaration:
+			entry := DocEntry{Kind: "class", Name: stmt.Name, Doc: stmt.Doc, Pos: stmt.Pos}
+			if stmt.Parent != nil {
+				entry.Parent = className(stmt.Parent)
+			}
+			for _, method := range stmt.Methods {
+				entry.Methods = append(entry.Methods, functionDoc(method, "method"))
+			}
+			entries = append(entries, entry)
+		case VarDeclaration:
+			if stmt.Doc == "" || stmt.Identifier == "" {
+				continue
+			}
+			kind := "variable"
+			if stmt.Constant {
+				kind = "constant"
+			}
+			entries = append(entries, DocEntry{Kind: kind, Name: stmt.Identifier, Doc: stmt.Doc, Pos: stmt.Pos})
+		}
+	}
+	return entries
+}
+
+func functionDoc(function FunctionDeclaration, kind string) DocEntry {
+	return DocEntry{
+		Kind:       kind,
+		Name:       function.Name,
+		Parameters: function.Parameters,
+		Doc:        function.Doc,
+		Pos:        function.Pos,
+	}
+}
+
+// className spells out a parent class, which is a name or a member of a
+// module like shapes.Shape
+func className(expr Expr) string {
+	switch expr := expr.(type) {
+	case Identifier:
+		return expr.Symbol
+	case MemberExpr:
+		if property, ok := expr.Property.(Identifier); ok && !expr.Computed {
+			return className(expr.Object) + "." + property.Symbol
+		}
+	}
+	return "?"
+}
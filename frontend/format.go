@@ -0,0 +1,812 @@
+package frontend
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+///////////////
+// Formatter //
+///////////////
+
+// precedences above the binary operators, for printing parentheses
+const (
+	precPostfix = precUnary + 1 // calls, members, indexes and slices
+	precPrimary = precUnary + 2 // names, literals and anything in brackets
+)
+
+// indentUnit is one level of indentation in formatted source
+const indentUnit = "    "
+
+// Format lays out source the way a0 fmt does: four spaces of indentation,
+// one statement a line, spaces around binary operators and every keyword
+// spelled the canonical way. Comments are kept, and so is one blank line
+// wherever there were blank lines between statements. Lists, objects and
+// parameter lists written across several lines keep one item a line with
+// their comments, a comment in the middle of an expression that gets printed
+// on one line moves above its statement. tokens must be what Lex returned
+// for source.
+func Format(source []byte, tokens []TokenItem) ([]byte, error) {
+	parser := NewParser(tokens)
+	program, err := parser.ProduceAst()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &formatter{source: source, tokens: tokens, ends: parser.ends}
+	for _, token := range tokens {
+		p.comments = append(p.comments, token.leading...)
+		p.comments = append(p.comments, token.trailing...)
+	}
+	sort.SliceStable(p.comments, func(i, j int) bool {
+		return p.comments[i].Pos.offset < p.comments[j].Pos.offset
+	})
+
+	p.statements(program.Body, 0)
+	p.leading(tokens[len(tokens)-1].pos.offset + 1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.out, nil
+}
+
+// formatter prints a program back out while walking the tokens it was parsed
+// from alongside, the tokens say where statements start and end, which
+// lines things were on and what the comments are next to
+type formatter struct {
+	source   []byte
+	tokens   []TokenItem
+	ends     map[int]int // the token after each statement, by the token it starts at
+	comments []Comment   // the ones not printed yet, in source order
+	literal  int         // where to look for the token of the next literal
+	out      []byte
+	indent   int
+	last     int // the source line of what was printed last in the current block, 0 at its start
+	err      error
+}
+
+func (f *formatter) fail(format string, args ...any) {
+	if f.err == nil {
+		f.err = fmt.Errorf("cannot format: "+format, args...)
+	}
+}
+
+// write adds text to the current line, indenting it first when the line is
+// empty so far
+func (f *formatter) write(text string) {
+	if len(f.out) > 0 && f.out[len(f.out)-1] == '\n' {
+		f.out = append(f.out, strings.Repeat(indentUnit, f.indent)...)
+	}
+	f.out = append(f.out, text...)
+}
+
+func (f *formatter) newline() {
+	f.out = append(f.out, '\n')
+}
+
+// blankLine keeps a blank line in front of something on source line line
+// when there was one after what came before it
+func (f *formatter) blankLine(line int) {
+	if f.last != 0 && line > f.last+1 {
+		f.newline()
+	}
+}
+
+// leading prints the comments before offset, each on its own line
+func (f *formatter) leading(offset int) {
+	for len(f.comments) > 0 && f.comments[0].Pos.offset < offset {
+		comment := f.comments[0]
+		f.comments = f.comments[1:]
+		f.blankLine(comment.Pos.line)
+		f.write(commentText(comment))
+		f.newline()
+		f.last = comment.Pos.line
+	}
+}
+
+func commentText(comment Comment) string {
+	return strings.TrimRight(comment.Text, " \t")
+}
+
+// hasComments reports whether a comment is left before offset
+func (f *formatter) hasComments(offset int) bool {
+	return len(f.comments) > 0 && f.comments[0].Pos.offset < offset
+}
+
+// item prints one line of a block, a statement or a literal element or match
+// arm, that runs from token start up to token end. next is where the one
+// after it starts, past any ; or , ending this one. The comments before it go
+// above it, a comment on its last line goes after it, and any left inside it
+// are moved above it.
+func (f *formatter) item(start, end, next int, print func()) {
+	first := f.tokens[start].pos
+	f.leading(first.offset)
+	f.blankLine(first.line)
+
+	mark := len(f.out)
+	print()
+
+	lastLine := f.tokens[next-1].pos.line
+	var inside []byte
+	for len(f.comments) > 0 && f.comments[0].Pos.offset < f.tokens[next].pos.offset {
+		comment := f.comments[0]
+		if comment.Pos.offset < f.tokens[end-1].pos.offset {
+			inside = append(inside, strings.Repeat(indentUnit, f.indent)+commentText(comment)+"\n"...)
+		} else if comment.Pos.line == lastLine {
+			f.write(" " + commentText(comment))
+		} else {
+			break
+		}
+		f.comments = f.comments[1:]
+	}
+	f.out = slices.Insert(f.out, mark, inside...)
+
+	f.newline()
+	f.last = lastLine
+}
+
+// openLines starts a bracket whose contents go on lines of their own, the
+// comment after the bracket stays on its line
+func (f *formatter) openLines(bracket string, open int) {
+	f.write(bracket)
+	if len(f.comments) > 0 {
+		comment := f.comments[0]
+		if comment.Pos.line == f.tokens[open].pos.line && comment.Pos.offset < f.tokens[open+1].pos.offset {
+			f.write(" " + commentText(comment))
+			f.comments = f.comments[1:]
+		}
+	}
+	f.newline()
+	f.indent++
+	f.last = 0
+}
+
+// closeLines ends what openLines started with the bracket at token close,
+// after the comments before it
+func (f *formatter) closeLines(bracket string, close int) {
+	f.leading(f.tokens[close].pos.offset)
+	f.indent--
+	f.write(bracket)
+}
+
+////////////
+// Tokens //
+////////////
+
+// skip moves past any tokens of type tokenType starting at token i
+func (f *formatter) skip(i int, tokenType Token) int {
+	for i < len(f.tokens) && f.tokens[i].tokenType == tokenType {
+		i++
+	}
+	return i
+}
+
+func opens(token Token) bool {
+	return token == OPENPAREN || token == OPENCURLY || token == OPENBRACKET
+}
+
+func closes(token Token) bool {
+	return token == CLOSEPAREN || token == CLOSECURLY || token == CLOSEBRACKET
+}
+
+// matching finds the bracket closing the one at token open
+func (f *formatter) matching(open int) int {
+	depth := 0
+	for i := open; i < len(f.tokens); i++ {
+		switch {
+		case opens(f.tokens[i].tokenType):
+			depth++
+		case closes(f.tokens[i].tokenType):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	f.fail("no bracket closes the one at %s", f.tokens[open].pos)
+	return len(f.tokens) - 1
+}
+
+// scan finds the first token of type tokenType from start on that isn't
+// inside brackets, or the bracket closing the ones start is inside
+func (f *formatter) scan(start int, tokenType Token) int {
+	i := start
+	for i < len(f.tokens) && f.tokens[i].tokenType != tokenType && !closes(f.tokens[i].tokenType) {
+		if opens(f.tokens[i].tokenType) {
+			i = f.matching(i)
+		}
+		i++
+	}
+	return min(i, len(f.tokens)-1)
+}
+
+// element finds where an element of a list starting at token start ends,
+// and where the next one starts past its comma
+func (f *formatter) element(start int) (end, next int) {
+	end = f.scan(start, COMMA)
+	if f.tokens[end].tokenType == COMMA {
+		return end, end + 1
+	}
+	return end, end
+}
+
+// tokenAt finds the token starting at pos
+func (f *formatter) tokenAt(pos Position) int {
+	i := sort.Search(len(f.tokens), func(i int) bool { return f.tokens[i].pos.offset >= pos.offset })
+	if i == len(f.tokens) || f.tokens[i].pos.offset != pos.offset {
+		f.fail("no token at %s", pos)
+		return 0
+	}
+	return i
+}
+
+// literalToken finds the token the next literal was written as, literals are
+// printed in the order they're in the source. ok is false when the token
+// found doesn't hold value, then the literal is printed from its value.
+func (f *formatter) literalToken(value string, tokenTypes ...Token) (text string, ok bool) {
+	for i := f.literal; i < len(f.tokens); i++ {
+		token := f.tokens[i]
+		if slices.Contains(tokenTypes, token.tokenType) {
+			f.literal = i + 1
+			return string(f.source[token.pos.offset:token.end.offset]), token.value == value
+		}
+	}
+	return "", false
+}
+
+////////////////
+// Statements //
+////////////////
+
+// statements prints the statements of a block, the first one starts at token
+// start
+func (f *formatter) statements(body []Stmt, start int) {
+	for _, stmt := range body {
+		start = f.skip(start, SEMICOLON)
+		end, ok := f.ends[start]
+		if !ok {
+			f.fail("lost track of the statement at %s", f.tokens[start].pos)
+			return
+		}
+		next := f.skip(end, SEMICOLON)
+		f.literal = start
+		f.item(start, end, next, func() { f.stmt(stmt, start) })
+		start = next
+	}
+}
+
+// block prints a block of statements in braces, open is the token of its {
+func (f *formatter) block(body []Stmt, open int) {
+	close := f.matching(open)
+	if len(body) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("{}")
+		return
+	}
+	f.openLines("{", open)
+	f.statements(body, open+1)
+	f.closeLines("}", close)
+}
+
+// stmt prints a statement that starts at token start
+func (f *formatter) stmt(node Stmt, start int) {
+	switch node := node.(type) {
+	case VarDeclaration:
+		if node.Constant {
+			f.write("const ")
+		} else {
+			f.write("var ")
+		}
+		if node.Pattern != nil {
+			f.expr(node.Pattern, precLowest)
+		} else {
+			f.write(node.Identifier)
+		}
+		if node.Value != nil {
+			f.write(" = ")
+			f.expr(node.Value, precLowest)
+		}
+	case FunctionDeclaration:
+		f.function(node, start)
+	case ClassDeclaration:
+		f.class(node, start)
+	case IfStmt:
+		f.write("if (")
+		f.expr(node.Condition, precLowest)
+		f.write(") ")
+		f.block(node.Body, f.matching(start+1)+1)
+	case WhileStmt:
+		f.write("while (")
+		f.expr(node.Condition, precLowest)
+		f.write(") ")
+		f.block(node.Body, f.matching(start+1)+1)
+	case ForStmt:
+		f.write("for (")
+		f.expr(node.Condition, precLowest)
+		f.write(") ")
+		f.block(node.Body, f.matching(start+1)+1)
+	case ForEachStmt:
+		f.write("for (" + node.Key)
+		if node.Value != "" {
+			f.write(", " + node.Value)
+		}
+		f.write(" in ")
+		f.expr(node.Iterable, precLowest)
+		f.write(") ")
+		f.block(node.Body, f.matching(start+1)+1)
+	case ReturnStmt:
+		f.write("return")
+		if node.Value != nil {
+			f.write(" ")
+			f.expr(node.Value, precLowest)
+		}
+	case BreakStmt:
+		f.write("break")
+	case ContinueStmt:
+		f.write("continue")
+	case ImportStmt:
+		path := f.tokens[start+1]
+		f.write("import " + string(f.source[path.pos.offset:path.end.offset]))
+		if alias := f.tokens[start+2]; alias.tokenType == IDENT && alias.value == "as" && alias.pos.line == path.pos.line {
+			f.write(" as " + node.Name)
+		}
+	case TryStmt:
+		f.try(node, start)
+	case ThrowStmt:
+		f.write("throw ")
+		f.expr(node.Value, precLowest)
+	case AssertStmt:
+		f.write("assert(")
+		f.expr(node.Condition, precLowest)
+		if node.Message != nil {
+			f.write(", ")
+			f.expr(node.Message, precLowest)
+		}
+		f.write(")")
+	case BenchStmt:
+		name := f.tokens[start+1]
+		f.write("bench " + string(f.source[name.pos.offset:name.end.offset]) + " ")
+		f.block(node.Body, start+2)
+	case Expr:
+		mark := len(f.out)
+		f.expr(node, precLowest)
+
+		// a line starting with - or + carries on the expression the line
+		// before ended with, parentheses keep the statement on its own
+		text := bytes.TrimLeft(f.out[mark:], " ")
+		if len(text) > 0 && (text[0] == '-' || text[0] == '+') {
+			f.out = slices.Insert(f.out, len(f.out)-len(text), '(')
+			f.write(")")
+		}
+	}
+}
+
+func (f *formatter) function(node FunctionDeclaration, start int) {
+	open := start + 2
+	close := f.matching(open)
+	f.write("fun " + node.Name)
+	f.parameters(node.Parameters, open, close)
+	f.write(" ")
+	f.block(node.Body, close+1)
+}
+
+// parameters prints a parameter list on one line, or a parameter a line with
+// the comments next to them when it was written across several
+func (f *formatter) parameters(names []string, open, close int) {
+	if f.tokens[open].pos.line == f.tokens[close].pos.line || len(names) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("(" + strings.Join(names, ", ") + ")")
+		return
+	}
+
+	f.openLines("(", open)
+	start := open + 1
+	for i, name := range names {
+		end, next := f.element(start)
+		f.item(start, end, next, func() {
+			f.write(name)
+			if i < len(names)-1 {
+				f.write(",")
+			}
+		})
+		start = next
+	}
+	f.closeLines(")", close)
+}
+
+func (f *formatter) class(node ClassDeclaration, start int) {
+	f.write("class " + node.Name)
+	if node.Parent != nil {
+		f.write(" : ")
+		f.expr(node.Parent, precPostfix)
+	}
+	f.write(" ")
+
+	open := start
+	for f.tokens[open].tokenType != OPENCURLY {
+		open++
+	}
+	close := f.matching(open)
+	if len(node.Methods) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("{}")
+		return
+	}
+
+	f.openLines("{", open)
+	methodStart := open + 1
+	for _, method := range node.Methods {
+		end, ok := f.ends[methodStart]
+		if !ok {
+			f.fail("lost track of the method at %s", f.tokens[methodStart].pos)
+			return
+		}
+		f.item(methodStart, end, end, func() { f.function(method, methodStart) })
+		methodStart = end
+	}
+	f.closeLines("}", close)
+}
+
+func (f *formatter) try(node TryStmt, start int) {
+	f.write("try ")
+	f.block(node.Body, start+1)
+	next := f.matching(start+1) + 1
+
+	if node.HasCatch {
+		f.write(" catch ")
+		next++
+		if node.CatchName != "" {
+			f.write("(" + node.CatchName + ") ")
+			next += 3
+		}
+		f.block(node.CatchBody, next)
+		next = f.matching(next) + 1
+	}
+
+	if f.tokens[next].tokenType == FINALLY {
+		f.write(" finally ")
+		f.block(node.FinallyBody, next+1)
+	}
+}
+
+/////////////////
+// Expressions //
+/////////////////
+
+// precedence is how tightly an expression holds together when printed, a
+// part that binds looser than where it goes needs parentheses
+func precedence(node Expr) int {
+	switch node := node.(type) {
+	case AssignmentExpr:
+		return precLowest
+	case LogicalExpr:
+		return operatorPrecedence(node.Operator)
+	case BinaryExpr:
+		return operatorPrecedence(node.Operator)
+	case RangeExpr:
+		return precRange
+	case UnaryExpr:
+		return precUnary
+	case NumericLiteral:
+		if math.Signbit(node.Value) {
+			return precUnary
+		}
+		return precPrimary
+	case CallExpr, MemberExpr, SliceExpr:
+		return precPostfix
+	default:
+		return precPrimary
+	}
+}
+
+func operatorPrecedence(operator string) int {
+	switch operator {
+	case "==", "!=":
+		return precEquality
+	case "<", ">", "<=", ">=":
+		return precRelational
+	case "+", "-":
+		return precAdditive
+	case "*", "/", "%":
+		return precMultiplicative
+	default:
+		return precLogical // and, or and their aliases
+	}
+}
+
+// expr prints an expression, in parentheses when it binds looser than min
+func (f *formatter) expr(node Expr, min int) {
+	if precedence(node) < min {
+		f.write("(")
+		f.expr(node, precLowest)
+		f.write(")")
+		return
+	}
+
+	switch node := node.(type) {
+	case AssignmentExpr:
+		f.expr(node.Assignee, precLowest+1)
+		f.write(" = ")
+		f.expr(node.Value, precLowest)
+	case LogicalExpr:
+		f.binary(node.Left, node.Right, canonicalOperator(node.Operator))
+	case BinaryExpr:
+		f.binary(node.Left, node.Right, node.Operator)
+	case RangeExpr:
+		f.expr(node.Start, precRange+1)
+		f.write("..")
+		f.expr(node.End, precRange+1)
+	case UnaryExpr:
+		f.write(node.Operator)
+		f.expr(node.Operant, precUnary)
+	case NumericLiteral:
+		f.number(node.Value)
+	case StringLiteral:
+		text, ok := f.literalToken(node.Value, STRING)
+		if !ok {
+			text = quoteString(node.Value)
+		}
+		f.write(text)
+	case CharLiteral:
+		text, ok := f.literalToken(string(node.Value), CHAR)
+		if !ok {
+			text = "'" + strings.Trim(quoteString(string(node.Value)), `"`) + "'"
+		}
+		f.write(text)
+	case Identifier:
+		f.write(node.Symbol)
+	case CallExpr:
+		f.expr(node.Caller, precPostfix)
+		f.arguments(node.Args)
+	case NewExpr:
+		f.write("new ")
+		f.expr(node.Class, precPostfix)
+		f.arguments(node.Args)
+	case MemberExpr:
+		// 5.x would read as the number 5. followed by x
+		if _, number := node.Object.(NumericLiteral); number && !node.Computed {
+			f.expr(node.Object, precPrimary+1)
+		} else {
+			f.expr(node.Object, precPostfix)
+		}
+		if node.Computed {
+			f.write("[")
+			f.expr(node.Property, precLowest)
+			f.write("]")
+		} else {
+			f.write(".")
+			f.expr(node.Property, precPrimary)
+		}
+	case SliceExpr:
+		f.expr(node.Object, precPostfix)
+		f.write("[")
+		if node.Start != nil {
+			f.expr(node.Start, precLowest)
+		}
+		f.write(":")
+		if node.End != nil {
+			f.expr(node.End, precLowest)
+		}
+		f.write("]")
+	case ObjectLiteral:
+		f.object(node)
+	case ArrayLiteral:
+		f.array(node)
+	case ObjectPattern:
+		f.write("{" + strings.Join(node.Names, ", ") + "}")
+	case ArrayPattern:
+		f.write("[" + strings.Join(node.Names, ", ") + "]")
+	case MatchExpr:
+		f.match(node)
+	default:
+		f.fail("unknown expression %s", node.NodeType())
+	}
+}
+
+// binary prints left operator right, grouping to the left like the parser
+func (f *formatter) binary(left, right Expr, operator string) {
+	precedence := operatorPrecedence(operator)
+	f.expr(left, precedence)
+	f.write(" " + operator + " ")
+	f.expr(right, precedence+1)
+}
+
+// canonicalOperator spells the playful aliases of and and or the usual way
+func canonicalOperator(operator string) string {
+	if keyword, playful := playfulAliases[operator]; playful {
+		return keyword
+	}
+	return operator
+}
+
+// number prints a number the way it was written, like 1.50 or 2e3, unless
+// that can't be found
+func (f *formatter) number(value float64) {
+	sign := ""
+	if math.Signbit(value) {
+		sign = "-"
+	}
+
+	for i := f.literal; i < len(f.tokens); i++ {
+		token := f.tokens[i]
+		if token.tokenType == INT || token.tokenType == FLOAT {
+			f.literal = i + 1
+			if written, err := strconv.ParseFloat(token.value, 64); err == nil && written == math.Abs(value) {
+				f.write(sign + token.value)
+				return
+			}
+			break
+		}
+	}
+
+	if math.Abs(value) < 1e21 {
+		f.write(strconv.FormatFloat(value, 'f', -1, 64))
+	} else {
+		f.write(strconv.FormatFloat(value, 'g', -1, 64))
+	}
+}
+
+// quoteString writes s as a string literal using the escapes the lexer knows
+func quoteString(s string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\n':
+			quoted.WriteString(`\n`)
+		case '\t':
+			quoted.WriteString(`\t`)
+		case '\r':
+			quoted.WriteString(`\r`)
+		case 0:
+			quoted.WriteString(`\0`)
+		case '\\', '"', '\'':
+			quoted.WriteRune('\\')
+			quoted.WriteRune(r)
+		default:
+			quoted.WriteRune(r)
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+func (f *formatter) arguments(args []Expr) {
+	f.write("(")
+	for i, arg := range args {
+		if i > 0 {
+			f.write(", ")
+		}
+		f.expr(arg, precLowest)
+	}
+	f.write(")")
+}
+
+// object prints an object literal on one line, or a property a line when it
+// was written across several
+func (f *formatter) object(node ObjectLiteral) {
+	open := f.tokenAt(node.Pos)
+	close := f.matching(open)
+	property := func(property Property) {
+		f.write(property.Key)
+		if property.Value != nil {
+			f.write(": ")
+			f.expr(property.Value, precLowest)
+		}
+	}
+
+	if f.tokens[open].pos.line == f.tokens[close].pos.line || len(node.Properties) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("{")
+		for i, p := range node.Properties {
+			if i > 0 {
+				f.write(", ")
+			}
+			property(p)
+		}
+		f.write("}")
+		return
+	}
+
+	f.openLines("{", open)
+	start := open + 1
+	for i, p := range node.Properties {
+		end, next := f.element(start)
+		f.item(start, end, next, func() {
+			property(p)
+			if i < len(node.Properties)-1 {
+				f.write(",")
+			}
+		})
+		start = next
+	}
+	f.closeLines("}", close)
+}
+
+// array prints a list literal on one line, or an element a line when it was
+// written across several
+func (f *formatter) array(node ArrayLiteral) {
+	open := f.tokenAt(node.Pos)
+	close := f.matching(open)
+
+	if f.tokens[open].pos.line == f.tokens[close].pos.line || len(node.Elements) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("[")
+		for i, element := range node.Elements {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.expr(element, precLowest)
+		}
+		f.write("]")
+		return
+	}
+
+	f.openLines("[", open)
+	start := open + 1
+	for i, element := range node.Elements {
+		end, next := f.element(start)
+		f.item(start, end, next, func() {
+			f.expr(element, precLowest)
+			if i < len(node.Elements)-1 {
+				f.write(",")
+			}
+		})
+		start = next
+	}
+	f.closeLines("]", close)
+}
+
+// match prints a match expression with an arm a line, arms with a single
+// expression end in a comma
+func (f *formatter) match(node MatchExpr) {
+	f.write("match (")
+	f.expr(node.Subject, precLowest)
+	f.write(") ")
+
+	open := f.matching(f.tokenAt(node.Pos)+1) + 1
+	close := f.matching(open)
+	if len(node.Arms) == 0 && !f.hasComments(f.tokens[close].pos.offset) {
+		f.write("{}")
+		return
+	}
+
+	f.openLines("{", open)
+	start := open + 1
+	for _, arm := range node.Arms {
+		arrow := f.scan(start, ARROW)
+		body := arrow + 1
+		var end, next int
+		if f.tokens[body].tokenType == OPENCURLY {
+			end = f.matching(body) + 1
+			next = f.skip(end, COMMA)
+		} else {
+			end, next = f.element(body)
+		}
+
+		f.item(start, end, next, func() {
+			// patterns are parsed as single values, only a negative number
+			// goes without parentheses
+			min := precPrimary
+			if unary, ok := arm.Pattern.(UnaryExpr); ok && unary.Operator == "-" {
+				min = precUnary
+			} else if number, ok := arm.Pattern.(NumericLiteral); ok && math.Signbit(number.Value) {
+				min = precUnary
+			}
+			f.expr(arm.Pattern, min)
+			if arm.Guard != nil {
+				f.write(" if ")
+				f.expr(arm.Guard, precLowest)
+			}
+			f.write(" -> ")
+			if f.tokens[body].tokenType == OPENCURLY {
+				f.block(arm.Body, body)
+			} else {
+				f.expr(arm.Body[0].(Expr), precLowest)
+				f.write(",")
+			}
+		})
+		start = next
+	}
+	f.closeLines("}", close)
+}
@@ -0,0 +1,86 @@
+package frontend_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// format runs the formatter over src
+func format(t *testing.T, src string) string {
+	t.Helper()
+	tokens, err := testutil.Lex(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := f.Format([]byte(src), tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(formatted)
+}
+
+// commentsIn lists the comments of src in order
+func commentsIn(t *testing.T, src string) []string {
+	t.Helper()
+	tokens, err := testutil.Lex(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments []string
+	for _, token := range tokens {
+		for _, comment := range append(token.LeadingComments(), token.TrailingComments()...) {
+			comments = append(comments, strings.TrimSpace(comment.Text))
+		}
+	}
+	return comments
+}
+
+// TestFormatGolden formats every file in testdata/format, checks the result
+// against its golden file, that formatting it again changes nothing and that
+// no comment went missing
+func TestFormatGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/format/*.a0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted := format(t, string(src))
+			testutil.AssertGolden(t, file+".golden", formatted)
+
+			if again := format(t, formatted); again != formatted {
+				t.Errorf("formatting twice changed it\n--- once ---\n%s\n--- twice ---\n%s", formatted, again)
+			}
+
+			want := commentsIn(t, string(src))
+			got := commentsIn(t, formatted)
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got comments %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatParameterComments(t *testing.T) {
+	src := "fun f(\n a, // the a\n b\n) {\n    return a\n}\n"
+	want := "fun f(\n    a, // the a\n    b\n) {\n    return a\n}\n"
+	if got := format(t, src); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatParametersOnOneLine(t *testing.T) {
+	src := "fun f(a,b)   {}\n"
+	if got, want := format(t, src), "fun f(a, b) {}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
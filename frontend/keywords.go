@@ -0,0 +1,148 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//////////////
+// Keywords //
+//////////////
+
+// KeywordTable maps every spelling the lexer treats as a keyword to the token
+// it produces. Anything not in the table lexes as an identifier.
+type KeywordTable map[string]Token
+
+var defaultKeywords = KeywordTable{
+	// Functions
+	"func":  FUN,
+	"fun":   FUN,
+	"fn":    FUN,
+	"funky": FUN,
+	"def":   FUN,
+
+	// Control flow
+	"if":       IF,
+	"❓":        IF,
+	"for":      FOR,
+	"in":       IN,
+	"while":    WHILE,
+	"loop":     WHILE,
+	"forever":  WHILE,
+	"return":   RETURN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
+
+	// Errors
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"assert":  ASSERT,
+
+	// Classes
+	"class": CLASS,
+	"new":   NEW,
+
+	// Modules
+	"import": IMPORT,
+
+	// Declarations
+	"var":    VAR,
+	"val":    VAR,
+	"define": VAR,
+	"let":    VAR,
+	"const":  CONST,
+
+	// Logic
+	"and":     AND,
+	"plus":    AND,
+	"or":      OR,
+	"perhaps": OR,
+	"not":     NOT,
+}
+
+// playfulAliases are the joke spellings that strict keyword mode turns off,
+// each with the keyword to write instead
+var playfulAliases = map[string]string{
+	"funky":   "fun",
+	"❓":       "if",
+	"loop":    "while",
+	"forever": "while",
+	"define":  "var",
+	"plus":    "and",
+	"perhaps": "or",
+}
+
+// DefaultKeywords returns a copy of the built-in keyword table that can be
+// changed without affecting other lexers
+func DefaultKeywords() KeywordTable {
+	table := make(KeywordTable, len(defaultKeywords))
+	for word, token := range defaultKeywords {
+		table[word] = token
+	}
+	return table
+}
+
+// StrictKeywords returns a copy of the built-in keyword table with only the
+// canonical spellings, none of the playful aliases
+func StrictKeywords() KeywordTable {
+	table := DefaultKeywords()
+	for alias := range playfulAliases {
+		table.Remove(alias)
+	}
+	return table
+}
+
+// Alias makes alias lex the same way as an existing keyword, for example
+// Alias("si", "if") for a Spanish keyword set
+func (k KeywordTable) Alias(alias, keyword string) error {
+	token, exists := k[keyword]
+	if !exists {
+		return fmt.Errorf("cannot alias %q to unknown keyword %q", alias, keyword)
+	}
+	k[alias] = token
+	return nil
+}
+
+// Remove stops word from being a keyword so it can be used as an identifier
+func (k KeywordTable) Remove(word string) {
+	delete(k, word)
+}
+
+// Load applies a keyword config to the table. Each line is either
+// `alias = keyword` to add an alias or `-word` to remove one, blank lines and
+// lines starting with # are skipped.
+func (k KeywordTable) Load(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if word, found := strings.CutPrefix(line, "-"); found {
+			k.Remove(strings.TrimSpace(word))
+			continue
+		}
+
+		alias, keyword, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("keyword config line %d: expected `alias = keyword` or `-word`", lineNumber)
+		}
+
+		err := k.Alias(strings.TrimSpace(alias), strings.TrimSpace(keyword))
+		if err != nil {
+			return fmt.Errorf("keyword config line %d: %w", lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
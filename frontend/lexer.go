@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"strings"
 	"unicode"
@@ -19,6 +20,8 @@ const (
 	ILLEGAL
 	IDENT
 	RETURN
+	BREAK
+	CONTINUE
 	INT
 	FLOAT
 	STRING
@@ -38,22 +41,35 @@ const (
 	MUL
 	DIV
 	MOD
-	NOT   // !, not
-	COLON // :
-	COMMA // ,
-	DOT   // .
-	DE    // ==
-	NE    // !=
-	GT    // >
-	LT    // <
-	GTE   // >=
-	LTE   // <=
+	NOT       // !, not
+	COLON     // :
+	COMMA     // ,
+	SEMICOLON // ;
+	DOT       // .
+	RANGE     // ..
+	ARROW     // ->
+	DE        // ==
+	NE        // !=
+	GT        // >
+	LT        // <
+	GTE       // >=
+	LTE       // <=
 
 	// Reserved Words (Key Words)
 	IF
 	FOR
 	WHILE
 	FUN
+	IN
+	IMPORT
+	TRY
+	CATCH
+	FINALLY
+	THROW
+	ASSERT
+	CLASS
+	NEW
+	MATCH
 	AND // and, &&
 	OR  // or, ||
 
@@ -63,14 +79,16 @@ const (
 
 var TokensList = []string{
 	// Data Types
-	EOF:     "EOF",
-	ILLEGAL: "ILLEGAL",
-	IDENT:   "IDENT",
-	RETURN:  "RETURN",
-	INT:     "INT",
-	FLOAT:   "FLOAT",
-	STRING:  "STRING",
-	CHAR:    "CHAR",
+	EOF:      "EOF",
+	ILLEGAL:  "ILLEGAL",
+	IDENT:    "IDENT",
+	RETURN:   "RETURN",
+	BREAK:    "BREAK",
+	CONTINUE: "CONTINUE",
+	INT:      "INT",
+	FLOAT:    "FLOAT",
+	STRING:   "STRING",
+	CHAR:     "CHAR",
 
 	// Reserved Characters
 	VAR:          "VAR",
@@ -86,33 +104,123 @@ var TokensList = []string{
 	MUL:          "MUL",
 	DIV:          "DIV",
 	MOD:          "MOD",
-	NOT:          "NOT",   // !
-	COLON:        "COLON", // :
-	COMMA:        "COMMA", // ,
-	DOT:          "DOT",   // .
-	DE:           "DE",    // ==
-	NE:           "NE",    // !=
-	GT:           "GT",    // >
-	LT:           "LT",    // <
-	GTE:          "GTE",   // >=
-	LTE:          "LTE",   // <=
+	NOT:          "NOT",       // !
+	COLON:        "COLON",     // :
+	COMMA:        "COMMA",     // ,
+	SEMICOLON:    "SEMICOLON", // ;
+	DOT:          "DOT",       // .
+	RANGE:        "RANGE",     // ..
+	ARROW:        "ARROW",     // ->
+	DE:           "DE",        // ==
+	NE:           "NE",        // !=
+	GT:           "GT",        // >
+	LT:           "LT",        // <
+	GTE:          "GTE",       // >=
+	LTE:          "LTE",       // <=
 
 	// Reserved Words (Key Words)
-	IF:    "IF",
-	FOR:   "FOR",
-	WHILE: "WHILE",
-	FUN:   "FUN",
-	AND:   "AND", // and, &&
-	OR:    "OR",  // or, ||
+	IF:      "IF",
+	FOR:     "FOR",
+	WHILE:   "WHILE",
+	FUN:     "FUN",
+	IN:      "IN",
+	IMPORT:  "IMPORT",
+	TRY:     "TRY",
+	CATCH:   "CATCH",
+	FINALLY: "FINALLY",
+	THROW:   "THROW",
+	ASSERT:  "ASSERT",
+	CLASS:   "CLASS",
+	NEW:     "NEW",
+	MATCH:   "MATCH",
+	AND:     "AND", // and, &&
+	OR:      "OR",  // or, ||
 
 	// Assignment
 	EQUALS: "EQUALS", // =
 }
 
+func (t Token) String() string {
+	if t < 0 || int(t) >= len(TokensList) || TokensList[t] == "" {
+		return fmt.Sprintf("Token(%d)", int(t))
+	}
+	return TokensList[t]
+}
+
+// GoString is the constant t is, like frontend.IDENT
+func (t Token) GoString() string {
+	return "frontend." + t.String()
+}
+
 type TokenItem struct {
 	pos       Position
+	end       Position // just past the last character of the token
 	tokenType Token
 	value     string
+	leading   []Comment // comments on the lines before the token
+	trailing  []Comment // a comment after the token on the same line
+}
+
+// Comment is a // comment, kept as trivia on the tokens around it rather than
+// thrown away so tools can put the source back together
+type Comment struct {
+	Text string // the whole comment, including the //
+	Pos  Position
+	End  Position
+}
+
+// LeadingComments are the comments between the previous token and this one
+// that aren't on the previous token's line
+func (t TokenItem) LeadingComments() []Comment {
+	return t.leading
+}
+
+// TrailingComments are the comments after the token on the same line
+func (t TokenItem) TrailingComments() []Comment {
+	return t.trailing
+}
+
+// Type is the kind of token, IDENT, ADD, IF and so on
+func (t TokenItem) Type() Token {
+	return t.tokenType
+}
+
+// Value is the text of the token as written, or the decoded contents for
+// strings and chars
+func (t TokenItem) Value() string {
+	return t.value
+}
+
+// Pos is where the token starts
+func (t TokenItem) Pos() Position {
+	return t.pos
+}
+
+// End is just past the last character of the token
+func (t TokenItem) End() Position {
+	return t.end
+}
+
+// Span is where the token starts and where it ends, the source text of the
+// token is source[start.Offset():end.Offset()]
+func (t TokenItem) Span() (start, end Position) {
+	return t.pos, t.end
+}
+
+func (t TokenItem) String() string {
+	return fmt.Sprintf("%s %q at %s", t.tokenType, t.value, t.pos)
+}
+
+// GoString is the Go code that makes t again, comments aside. The fields are
+// unexported, so it's a call to NewTokenItem rather than a composite literal.
+func (t TokenItem) GoString() string {
+	return fmt.Sprintf("frontend.NewTokenItem(%#v, %q, %#v, %#v)", t.tokenType, t.value, t.pos, t.end)
+}
+
+// NewTokenItem builds a token, for tools and tests that make tokens without
+// lexing source
+func NewTokenItem(tokenType Token, value string, pos, end Position) TokenItem {
+	return TokenItem{tokenType: tokenType, value: value, pos: pos, end: end}
 }
 
 ////////////
@@ -121,50 +229,171 @@ type TokenItem struct {
 type Position struct {
 	line   int
 	column int
+	offset int // in bytes from the start of the source
+}
+
+func (p Position) Line() int {
+	return p.line
+}
+
+func (p Position) Column() int {
+	return p.column
+}
+
+func (p Position) Offset() int {
+	return p.offset
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("(%d, %d)", p.line, p.column)
+}
+
+// GoString is the call to NewPosition that makes p again
+func (p Position) GoString() string {
+	return fmt.Sprintf("frontend.NewPosition(%d, %d, %d)", p.line, p.column, p.offset)
+}
+
+// NewPosition builds a position, for code that stores positions and reads
+// them back like compiled programs
+func NewPosition(line, column, offset int) Position {
+	return Position{line: line, column: column, offset: offset}
 }
 
 type Lexer struct {
-	pos    Position
-	reader *bufio.Reader
+	strict   bool // reject the playful keyword aliases, see UseStrictKeywords
+	pos      Position
+	prevPos  Position // pos before the last rune was read, for goBack
+	offset   int      // bytes read so far
+	lastSize int      // bytes the last rune took up, for goBack
+	skipLF   bool     // the last rune was the \r of a \r\n
+	tabWidth int
+	reader   *bufio.Reader
+	keywords KeywordTable
 }
 
+// DefaultTabWidth is how many columns apart tab stops are unless the lexer
+// is told otherwise with SetTabWidth
+const DefaultTabWidth = 4
+
 func NewLexer(reader io.Reader) *Lexer {
 	return &Lexer{
-		pos:    Position{line: 1, column: 0},
-		reader: bufio.NewReader(reader),
+		pos:      Position{line: 1, column: 0},
+		tabWidth: DefaultTabWidth,
+		reader:   bufio.NewReader(reader),
+		keywords: defaultKeywords,
+	}
+}
+
+// SetTabWidth sets how many columns apart tab stops are, so positions match
+// what an editor shows. 1 or less makes a tab count as a single column.
+func (l *Lexer) SetTabWidth(width int) {
+	l.tabWidth = max(width, 1)
+}
+
+// UseKeywords swaps the keyword table the lexer recognizes, see DefaultKeywords
+func (l *Lexer) UseKeywords(keywords KeywordTable) {
+	l.keywords = keywords
+}
+
+// UseStrictKeywords drops the playful aliases like funky and perhaps from the
+// keyword table, and makes using one an error that names the keyword to use
+func (l *Lexer) UseStrictKeywords() {
+	keywords := make(KeywordTable, len(l.keywords))
+	for word, token := range l.keywords {
+		if _, playful := playfulAliases[word]; !playful {
+			keywords[word] = token
+		}
+	}
+	l.keywords = keywords
+	l.strict = true
+}
+
+// checkStrict fails for a playful alias when strict keywords are on
+func (l *Lexer) checkStrict(word string, pos Position) error {
+	keyword, playful := playfulAliases[word]
+	if !l.strict || !playful {
+		return nil
+	}
+	return &ParsingError{
+		Message: fmt.Sprintf("%q is an alias that strict keyword mode doesn't allow, write %q instead", word, keyword),
+		Pos:     pos,
 	}
 }
 
 func (l *Lexer) Lex() ([]TokenItem, error) {
+	if err := l.skipBOM(); err != nil {
+		return nil, err
+	}
+
 	tokenList := []TokenItem{}
+	ended := 0              // tokens before this one have their end set
+	comments := []Comment{} // comments waiting for the next token
 	for {
-		r, _, err := l.reader.ReadRune()
+		// every token is read in one go, so the last one ends where the
+		// lexer is now
+		for ; ended < len(tokenList); ended++ {
+			tokenList[ended].end = Position{line: l.pos.line, column: l.pos.column + 1, offset: l.offset}
+			tokenList[ended].leading = comments
+			comments = []Comment{}
+		}
+
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
-				EOFPos := Position{line: l.pos.line, column: l.pos.column}
-				tokenList = append(tokenList, TokenItem{EOFPos, EOF, ""})
+				EOFPos := Position{line: l.pos.line, column: l.pos.column, offset: l.offset}
+				tokenList = append(tokenList, TokenItem{pos: EOFPos, end: EOFPos, tokenType: EOF, value: "", leading: comments})
 				return tokenList, nil
 			}
 			// if it finds an error while reading that is not EOF
 			return nil, err
 		}
 
-		l.pos.column++
-
 		switch r {
 		case '\n':
-			l.resetPosition()
 			continue
 		case '+':
-			tokenList = append(tokenList, TokenItem{l.pos, ADD, "+"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: ADD, value: "+"})
 		case '-':
-			tokenList = append(tokenList, TokenItem{l.pos, SUB, "-"})
+			if next, _ := l.reader.Peek(1); len(next) == 1 && next[0] == '>' {
+				arrowPos := l.pos
+				l.readRune()
+				tokenList = append(tokenList, TokenItem{pos: arrowPos, tokenType: ARROW, value: "->"})
+				continue
+			}
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: SUB, value: "-"})
 		case '*':
-			tokenList = append(tokenList, TokenItem{l.pos, MUL, "*"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: MUL, value: "*"})
 		case '/':
-			tokenList = append(tokenList, TokenItem{l.pos, DIV, "/"})
+			if next, _ := l.reader.Peek(1); len(next) == 1 && next[0] == '/' {
+				comment, err := l.lexComment('/')
+				if err != nil {
+					return nil, err
+				}
+
+				// a comment after code on the same line belongs to that code
+				if last := len(tokenList) - 1; last >= 0 && tokenList[last].pos.line == comment.Pos.line {
+					tokenList[last].trailing = append(tokenList[last].trailing, comment)
+				} else {
+					comments = append(comments, comment)
+				}
+				continue
+			}
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: DIV, value: "/"})
 		case '%':
-			tokenList = append(tokenList, TokenItem{l.pos, MOD, "%"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: MOD, value: "%"})
+		case '#':
+			// a #! line and the #a0 version pragma can come before the code,
+			// they're kept as comments and the parser reads the pragma
+			next, _ := l.reader.Peek(2)
+			if len(tokenList) > 0 || !(string(next) == "a0" || len(next) > 0 && next[0] == '!' && l.pos.line == 1) {
+				tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: ILLEGAL, value: "#"})
+				continue
+			}
+			comment, err := l.lexComment('#')
+			if err != nil {
+				return nil, err
+			}
+			comments = append(comments, comment)
 		case '=':
 			equalPos := l.pos
 
@@ -178,19 +407,19 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{equalPos, equalType, lit})
+			tokenList = append(tokenList, TokenItem{pos: equalPos, tokenType: equalType, value: lit})
 		case '(':
-			tokenList = append(tokenList, TokenItem{l.pos, OPENPAREN, "("})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: OPENPAREN, value: "("})
 		case ')':
-			tokenList = append(tokenList, TokenItem{l.pos, CLOSEPAREN, ")"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: CLOSEPAREN, value: ")"})
 		case '{':
-			tokenList = append(tokenList, TokenItem{l.pos, OPENCURLY, "{"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: OPENCURLY, value: "{"})
 		case '}':
-			tokenList = append(tokenList, TokenItem{l.pos, CLOSECURLY, "}"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: CLOSECURLY, value: "}"})
 		case '[':
-			tokenList = append(tokenList, TokenItem{l.pos, OPENBRACKET, "["})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: OPENBRACKET, value: "["})
 		case ']':
-			tokenList = append(tokenList, TokenItem{l.pos, CLOSEBRACKET, "]"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: CLOSEBRACKET, value: "]"})
 		case '!':
 			notPos := l.pos
 
@@ -204,13 +433,21 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{notPos, notType, lit})
+			tokenList = append(tokenList, TokenItem{pos: notPos, tokenType: notType, value: lit})
 		case ':':
-			tokenList = append(tokenList, TokenItem{l.pos, COLON, ":"})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: COLON, value: ":"})
 		case ',':
-			tokenList = append(tokenList, TokenItem{l.pos, COMMA, ","})
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: COMMA, value: ","})
+		case ';':
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: SEMICOLON, value: ";"})
 		case '.':
-			tokenList = append(tokenList, TokenItem{l.pos, DOT, "."})
+			if next, _ := l.reader.Peek(1); len(next) == 1 && next[0] == '.' {
+				rangePos := l.pos
+				l.readRune()
+				tokenList = append(tokenList, TokenItem{pos: rangePos, tokenType: RANGE, value: ".."})
+				continue
+			}
+			tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: DOT, value: "."})
 		case '&':
 			andPos := l.pos
 
@@ -224,7 +461,7 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{andPos, andType, lit})
+			tokenList = append(tokenList, TokenItem{pos: andPos, tokenType: andType, value: lit})
 		case '|':
 			orPos := l.pos
 
@@ -238,7 +475,7 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{orPos, orType, lit})
+			tokenList = append(tokenList, TokenItem{pos: orPos, tokenType: orType, value: lit})
 		case '<':
 			ltPos := l.pos
 
@@ -252,7 +489,7 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{ltPos, ltType, lit})
+			tokenList = append(tokenList, TokenItem{pos: ltPos, tokenType: ltType, value: lit})
 		case '>':
 			gtPos := l.pos
 
@@ -266,11 +503,11 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				return nil, err
 			}
 
-			tokenList = append(tokenList, TokenItem{gtPos, gtType, lit})
+			tokenList = append(tokenList, TokenItem{pos: gtPos, tokenType: gtType, value: lit})
 		default:
 			if unicode.IsSpace(r) {
 				continue
-			} else if unicode.IsDigit(r) {
+			} else if isDigit(r) {
 				intPos := l.pos
 
 				err := l.goBack()
@@ -283,8 +520,8 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 					return nil, err
 				}
 
-				tokenList = append(tokenList, TokenItem{intPos, varType, lit})
-			} else if unicode.IsLetter(r) {
+				tokenList = append(tokenList, TokenItem{pos: intPos, tokenType: varType, value: lit})
+			} else if isIdentStart(r) {
 				letterPos := l.pos
 
 				err := l.goBack()
@@ -296,31 +533,24 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 				if err != nil {
 					return nil, err
 				}
+				if err := l.checkStrict(lit, letterPos); err != nil {
+					return nil, err
+				}
+
+				if keyword, exists := l.keywords[lit]; exists {
+					tokenList = append(tokenList, TokenItem{pos: letterPos, tokenType: keyword, value: lit})
+				} else {
+					tokenList = append(tokenList, TokenItem{pos: letterPos, tokenType: IDENT, value: lit})
+				}
+			} else if r == '\'' {
+				charPos := l.pos
 
-				switch lit {
-				case "func", "fun", "fn", "funky", "def":
-					tokenList = append(tokenList, TokenItem{letterPos, FUN, lit})
-				case "if", "❓":
-					tokenList = append(tokenList, TokenItem{letterPos, IF, lit})
-				case "for":
-					tokenList = append(tokenList, TokenItem{letterPos, FOR, lit})
-				case "while", "loop", "forever":
-					tokenList = append(tokenList, TokenItem{letterPos, WHILE, lit})
-				case "var", "val", "define", "let":
-					tokenList = append(tokenList, TokenItem{letterPos, VAR, lit})
-				case "const":
-					tokenList = append(tokenList, TokenItem{letterPos, CONST, lit})
-				case "and", "plus":
-					tokenList = append(tokenList, TokenItem{letterPos, AND, lit})
-				case "or", "perhaps":
-					tokenList = append(tokenList, TokenItem{letterPos, OR, lit})
-				case "not":
-					tokenList = append(tokenList, TokenItem{letterPos, NOT, lit})
-				case "return":
-					tokenList = append(tokenList, TokenItem{letterPos, RETURN, lit})
-				default:
-					tokenList = append(tokenList, TokenItem{letterPos, IDENT, lit})
+				lit, charType, err := l.lexChar()
+				if err != nil {
+					return nil, err
 				}
+
+				tokenList = append(tokenList, TokenItem{pos: charPos, tokenType: charType, value: lit})
 			} else if r == '"' {
 				stringPos := l.pos
 
@@ -334,24 +564,119 @@ func (l *Lexer) Lex() ([]TokenItem, error) {
 					return nil, err
 				}
 
-				tokenList = append(tokenList, TokenItem{stringPos, varType, lit})
+				tokenList = append(tokenList, TokenItem{pos: stringPos, tokenType: varType, value: lit})
+			} else if keyword, exists := l.keywords[string(r)]; exists {
+				// symbol keywords like ❓ aren't letters so they never reach lexIdent
+				tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: keyword, value: string(r)})
+			} else if err := l.checkStrict(string(r), l.pos); err != nil {
+				return nil, err
 			} else {
-				tokenList = append(tokenList, TokenItem{l.pos, ILLEGAL, string(r)})
+				tokenList = append(tokenList, TokenItem{pos: l.pos, tokenType: ILLEGAL, value: string(r)})
 			}
 		}
 	}
 }
 
-func (l *Lexer) resetPosition() {
-	l.pos.line++
-	l.pos.column = 0
+// lexComment reads a // comment up to the end of the line, called after the
+// first /. The line break is left for Lex.
+func (l *Lexer) lexComment(start rune) (Comment, error) {
+	comment := Comment{Pos: l.pos}
+	text := strings.Builder{}
+	text.WriteRune(start)
+
+	for {
+		next, err := l.reader.Peek(1)
+		if err != nil && err != io.EOF {
+			return Comment{}, err
+		}
+		if len(next) == 0 || next[0] == '\n' || next[0] == '\r' {
+			break
+		}
+
+		r, _, err := l.readRune()
+		if err != nil {
+			return Comment{}, err
+		}
+		text.WriteRune(r)
+	}
+
+	comment.Text = text.String()
+	comment.End = Position{line: l.pos.line, column: l.pos.column + 1, offset: l.offset}
+	return comment, nil
+}
+
+// readRune reads the next rune and moves pos onto it. Line endings come out
+// as a single '\n' whether they were written \n, \r\n or \r, and after one
+// pos is at the start of the next line. Tabs move the column on to the next
+// tab stop.
+func (l *Lexer) readRune() (rune, int, error) {
+	r, size, err := l.reader.ReadRune()
+	if err != nil {
+		return r, size, err
+	}
+
+	// the \r of a \r\n was already the line break, so the \n is skipped
+	if l.skipLF {
+		l.skipLF = false
+		if r == '\n' {
+			l.offset += size
+			r, size, err = l.reader.ReadRune()
+			if err != nil {
+				return r, size, err
+			}
+		}
+	}
+
+	l.prevPos = l.pos
+	l.pos.offset = l.offset
+	l.offset += size
+	l.lastSize = size
+
+	// peeking here would stop goBack from working, so a \n right after is
+	// skipped by the next read instead
+	if r == '\r' {
+		l.skipLF = true
+		r = '\n'
+	}
+
+	switch r {
+	case '\n':
+		l.pos.line++
+		l.pos.column = 0
+	case '\t':
+		l.pos.column += l.tabWidth - l.pos.column%l.tabWidth
+	default:
+		l.pos.column++
+	}
+	return r, size, nil
 }
 
+// goBack unreads the last rune, only one rune can be unread at a time
 func (l *Lexer) goBack() error {
-	l.pos.column--
 	if err := l.reader.UnreadRune(); err != nil {
 		return err
 	}
+	l.pos = l.prevPos
+	l.offset -= l.lastSize
+	l.lastSize = 0
+	l.skipLF = false
+	return nil
+}
+
+// skipBOM drops the byte order mark some editors put at the start of UTF-8
+// files, offsets still count its bytes so they match the file
+func (l *Lexer) skipBOM() error {
+	r, size, err := l.reader.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if r != '\uFEFF' {
+		return l.reader.UnreadRune()
+	}
+	l.offset += size
 	return nil
 }
 
@@ -359,8 +684,39 @@ func (l *Lexer) lexNum() (string, Token, error) {
 	var literal string
 	varType := INT
 	dotCount := 0
+	hasExponent := false
 	for {
-		r, _, err := l.reader.ReadRune()
+		// 1..10 is a range, not a malformed float
+		if next, _ := l.reader.Peek(2); string(next) == ".." {
+			return literal, varType, nil
+		}
+
+		// exponents like 1.5e-3 or 2E6, the e only counts if digits follow it
+		if !hasExponent && l.atExponent() {
+			hasExponent = true
+			if varType != ILLEGAL {
+				varType = FLOAT
+			}
+
+			// the e and its optional sign
+			for range 2 {
+				r, _, err := l.readRune()
+				if err != nil {
+					return "", ILLEGAL, err
+				}
+				if isDigit(r) {
+					err := l.goBack()
+					if err != nil {
+						return "", ILLEGAL, err
+					}
+					break
+				}
+				literal += string(r)
+			}
+			continue
+		}
+
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				return literal, varType, nil
@@ -368,11 +724,10 @@ func (l *Lexer) lexNum() (string, Token, error) {
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-		if unicode.IsDigit(r) {
+		if isDigit(r) {
 			literal += string(r)
 		} else if r == '.' {
-			if dotCount == 0 {
+			if dotCount == 0 && !hasExponent {
 				varType = FLOAT
 			} else {
 				varType = ILLEGAL
@@ -390,10 +745,42 @@ func (l *Lexer) lexNum() (string, Token, error) {
 	}
 }
 
+// atExponent reports whether the upcoming input is an exponent like e5, e+5 or E-5
+func (l *Lexer) atExponent() bool {
+	next, _ := l.reader.Peek(3)
+	if len(next) < 2 || (next[0] != 'e' && next[0] != 'E') {
+		return false
+	}
+	if next[1] == '+' || next[1] == '-' {
+		return len(next) == 3 && next[2] >= '0' && next[2] <= '9'
+	}
+	return next[1] >= '0' && next[1] <= '9'
+}
+
+// Identifiers start with a letter from any script or an _, and go on with
+// letters, digits, _, combining marks and the zero width joiners some scripts
+// need. Emoji and other symbols can't be used. Names aren't normalized, so é
+// typed as one character and as e plus a combining accent are different
+// names. Number literals only use the ASCII digits.
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Nl, r) || r == '_'
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) ||
+		unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) ||
+		r == '\u200c' || r == '\u200d'
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
 func (l *Lexer) lexIdent() (string, error) {
 	var literal string
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				return literal, nil
@@ -401,10 +788,7 @@ func (l *Lexer) lexIdent() (string, error) {
 			return "", err
 		}
 
-		l.pos.column++
-		if unicode.IsLetter(r) ||
-			unicode.IsDigit(r) ||
-			r == '_' {
+		if isIdentPart(r) {
 			literal += string(r)
 		} else {
 			err := l.goBack()
@@ -421,11 +805,10 @@ func (l *Lexer) lexString() (string, Token, error) {
 	var literal string
 
 	// Skip the opening quote
-	r, _, err := l.reader.ReadRune()
+	r, _, err := l.readRune()
 	if err != nil {
 		return "", ILLEGAL, err
 	}
-	l.pos.column++
 
 	if r != '"' {
 		return "", ILLEGAL, nil
@@ -433,7 +816,7 @@ func (l *Lexer) lexString() (string, Token, error) {
 
 	// Read until closing quote
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				// Unterminated string
@@ -442,13 +825,24 @@ func (l *Lexer) lexString() (string, Token, error) {
 			return literal, ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		if r == '"' {
 			// Found closing quote, we're done
 			break
 		}
 
+		if r == '\\' {
+			escaped, _, err := l.readRune()
+			if err != nil {
+				return literal, ILLEGAL, nil
+			}
+
+			unescaped, ok := unescape(escaped)
+			if !ok {
+				return literal + string(r) + string(escaped), ILLEGAL, nil
+			}
+			r = unescaped
+		}
+
 		literal += string(r)
 	}
 
@@ -457,6 +851,66 @@ func (l *Lexer) lexString() (string, Token, error) {
 	return literal, STRING, nil
 }
 
+// lexChar reads a char literal like 'a' or '\n', called after the opening quote
+func (l *Lexer) lexChar() (string, Token, error) {
+	var literal string
+	for {
+		r, _, err := l.readRune()
+		if err != nil {
+			if err == io.EOF {
+				// Unterminated char
+				return literal, ILLEGAL, nil
+			}
+			return literal, ILLEGAL, err
+		}
+
+		if r == '\'' {
+			break
+		}
+
+		if r == '\\' {
+			escaped, _, err := l.readRune()
+			if err != nil {
+				return literal, ILLEGAL, nil
+			}
+
+			unescaped, ok := unescape(escaped)
+			if !ok {
+				return literal + string(r) + string(escaped), ILLEGAL, nil
+			}
+			r = unescaped
+		}
+
+		literal += string(r)
+	}
+
+	// exactly one character between the quotes
+	if len([]rune(literal)) != 1 {
+		return literal, ILLEGAL, nil
+	}
+
+	return literal, CHAR, nil
+}
+
+// unescape gives the character an escape sequence like \n stands for, shared
+// by string and char literals
+func unescape(escaped rune) (rune, bool) {
+	switch escaped {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '0':
+		return 0, true
+	case '\\', '\'', '"':
+		return escaped, true
+	default:
+		return 0, false
+	}
+}
+
 func (l *Lexer) lexEquals() (string, Token, error) {
 	var equalType Token
 	equalCount := 0
@@ -464,7 +918,7 @@ func (l *Lexer) lexEquals() (string, Token, error) {
 
 readLoop:
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break readLoop
@@ -472,8 +926,6 @@ readLoop:
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		switch r {
 		case '=':
 			lit.WriteRune(r)
@@ -506,7 +958,7 @@ func (l *Lexer) lexNot() (string, Token, error) {
 
 readLoop:
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break readLoop
@@ -514,8 +966,6 @@ readLoop:
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		switch r {
 		case '!':
 			lit.WriteRune(r)
@@ -548,7 +998,7 @@ func (l *Lexer) lexAnd() (string, Token, error) {
 	andCount := 0
 	var lit strings.Builder
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -556,8 +1006,6 @@ func (l *Lexer) lexAnd() (string, Token, error) {
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		if r == '&' {
 			lit.WriteRune(r)
 			andCount++
@@ -584,7 +1032,7 @@ func (l *Lexer) lexOr() (string, Token, error) {
 	orCount := 0
 	var lit strings.Builder
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -592,8 +1040,6 @@ func (l *Lexer) lexOr() (string, Token, error) {
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		if r == '|' {
 			lit.WriteRune(r)
 			orCount++
@@ -623,7 +1069,7 @@ func (l *Lexer) lexLessThan() (string, Token, error) {
 
 readLoop:
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break readLoop
@@ -631,8 +1077,6 @@ readLoop:
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		switch r {
 		case '<':
 			lit.WriteRune(r)
@@ -668,7 +1112,7 @@ func (l *Lexer) lexGreaterThan() (string, Token, error) {
 
 readLoop:
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break readLoop
@@ -676,8 +1120,6 @@ readLoop:
 			return "", ILLEGAL, err
 		}
 
-		l.pos.column++
-
 		switch r {
 		case '>':
 			lit.WriteRune(r)
@@ -0,0 +1,238 @@
+package frontend_test
+
+import (
+	"fmt"
+	"go/parser"
+	"slices"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestTokenGoString(t *testing.T) {
+	tokens, err := testutil.Lex(`val greeting = "hi\n"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range tokens {
+		code := fmt.Sprintf("%#v", token)
+		if _, err := parser.ParseExpr(code); err != nil {
+			t.Errorf("%s isn't Go: %v", code, err)
+		}
+	}
+
+	token := f.NewTokenItem(f.STRING, "hi\n", f.NewPosition(1, 16, 15), f.NewPosition(1, 22, 21))
+	want := `frontend.NewTokenItem(frontend.STRING, "hi\n", frontend.NewPosition(1, 16, 15), frontend.NewPosition(1, 22, 21))`
+	if got := fmt.Sprintf("%#v", token); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got := fmt.Sprintf("%#v", tokens[3]); got != want {
+		t.Errorf("lexed token is %s, want %s", got, want)
+	}
+}
+
+// lexWith lexes src with a lexer set up by setup, which can be nil
+func lexWith(t *testing.T, src string, setup func(l *f.Lexer)) []f.TokenItem {
+	t.Helper()
+	lexer := f.NewLexer(strings.NewReader(src))
+	if setup != nil {
+		setup(lexer)
+	}
+	tokens, err := lexer.Lex()
+	if err != nil {
+		t.Fatalf("lexing %q: %v", src, err)
+	}
+	return tokens
+}
+
+// tokenList writes tokens as type "value" line:column, one a line, leaving
+// out the EOF
+func tokenList(tokens []f.TokenItem) string {
+	var lines []string
+	for _, token := range tokens {
+		if token.Type() == f.EOF {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s %q %d:%d", token.Type(), token.Value(), token.Pos().Line(), token.Pos().Column()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLexNumbers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"42", `INT "42" 1:1`},
+		{"3.25", `FLOAT "3.25" 1:1`},
+		{"1.5e-3", `FLOAT "1.5e-3" 1:1`},
+		{"2E6", `FLOAT "2E6" 1:1`},
+		{"6e+2", `FLOAT "6e+2" 1:1`},
+		{"1e", "INT \"1\" 1:1\nIDENT \"e\" 1:2"},
+		{"1e+x", "INT \"1\" 1:1\nIDENT \"e\" 1:2\nADD \"+\" 1:3\nIDENT \"x\" 1:4"},
+		{"1..3", "INT \"1\" 1:1\nRANGE \"..\" 1:2\nINT \"3\" 1:4"},
+		{"1.2.3", `ILLEGAL "1.2.3" 1:1`},
+		{"1e5.5", `ILLEGAL "1e5.5" 1:1`},
+	}
+	for _, tt := range tests {
+		if got := tokenList(lexWith(t, tt.src, nil)); got != tt.want {
+			t.Errorf("%q lexed as\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+
+	for src, want := range map[string]float64{"1.5e-3": 0.0015, "2E6": 2e6, "6e+2": 600, "7": 7} {
+		if got := f.TokenToFloat(lexWith(t, src, nil)[0]); got != want {
+			t.Errorf("%q is %v, want %v", src, got, want)
+		}
+	}
+}
+
+// a token's span slices its exact text out of the source, multi-byte
+// characters and all
+func TestTokenSpans(t *testing.T) {
+	src := "val café = \"né\" // note\nx1+'é'"
+	tests := []struct {
+		text      string
+		start     string
+		endColumn int
+	}{
+		{"val", "1:1", 4},
+		{"café", "1:5", 9},
+		{"=", "1:10", 11},
+		{`"né"`, "1:12", 16},
+		{"x1", "2:1", 3},
+		{"+", "2:3", 4},
+		{"'é'", "2:4", 7},
+	}
+	tokens := lexWith(t, src, nil)
+	if len(tokens) != len(tests)+1 {
+		t.Fatalf("got %d tokens, want %d and the EOF", len(tokens), len(tests))
+	}
+	for i, tt := range tests {
+		start, end := tokens[i].Span()
+		if text := src[start.Offset():end.Offset()]; text != tt.text {
+			t.Errorf("token %d spans %q, want %q", i, text, tt.text)
+		}
+		if got := fmt.Sprintf("%d:%d", start.Line(), start.Column()); got != tt.start {
+			t.Errorf("%s starts at %s, want %s", tt.text, got, tt.start)
+		}
+		if end.Column() != tt.endColumn {
+			t.Errorf("%s ends at column %d, want %d", tt.text, end.Column(), tt.endColumn)
+		}
+	}
+}
+
+func TestLexCommentTrivia(t *testing.T) {
+	tokens := lexWith(t, "// first\n// second\nval x = 1 // after\n\n// at the end\n", nil)
+	comments := func(list []f.Comment) []string {
+		var texts []string
+		for _, comment := range list {
+			texts = append(texts, comment.Text)
+		}
+		return texts
+	}
+
+	if got := comments(tokens[0].LeadingComments()); !slices.Equal(got, []string{"// first", "// second"}) {
+		t.Errorf("val has leading comments %q", got)
+	}
+	if got := comments(tokens[3].TrailingComments()); !slices.Equal(got, []string{"// after"}) {
+		t.Errorf("1 has trailing comments %q", got)
+	}
+	eof := tokens[len(tokens)-1]
+	if got := comments(eof.LeadingComments()); !slices.Equal(got, []string{"// at the end"}) {
+		t.Errorf("the EOF has leading comments %q", got)
+	}
+}
+
+func TestStrictKeywords(t *testing.T) {
+	// the aliases lex as their keywords by default
+	got := tokenList(lexWith(t, "funky f() { forever (a perhaps b) {} }", nil))
+	for _, want := range []string{`FUN "funky"`, `WHILE "forever"`, `OR "perhaps"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got\n%s\nwant %s in it", got, want)
+		}
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"funky f() {}", `"funky" is an alias that strict keyword mode doesn't allow, write "fun" instead`},
+		{"a plus b", `write "and" instead`},
+		{"❓ (x) {}", `write "if" instead`},
+	}
+	for _, tt := range tests {
+		lexer := f.NewLexer(strings.NewReader(tt.src))
+		lexer.UseStrictKeywords()
+		_, err := lexer.Lex()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got %v, want %s", tt.src, err, tt.want)
+		}
+	}
+
+	strict := lexWith(t, "fun f() { while (a or b) {} }", func(l *f.Lexer) { l.UseStrictKeywords() })
+	if strict[0].Type() != f.FUN {
+		t.Errorf("strict mode lexed fun as %s", strict[0].Type())
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"café", `IDENT "café" 1:1`},
+		// columns count characters as written, a combining accent is one
+		{"caf\u00e9 = 1", "IDENT \"caf\u00e9\" 1:1\nEQUALS \"=\" 1:6\nINT \"1\" 1:8"},
+		{"cafe\u0301 = 1", "IDENT \"cafe\u0301\" 1:1\nEQUALS \"=\" 1:7\nINT \"1\" 1:9"},
+		{"名前 x", "IDENT \"名前\" 1:1\nIDENT \"x\" 1:4"},
+		{"_x1 x٣", "IDENT \"_x1\" 1:1\nIDENT \"x٣\" 1:5"},
+		{"Ⅻ", `IDENT "Ⅻ" 1:1`},
+		{"x🙂", "IDENT \"x\" 1:1\nILLEGAL \"🙂\" 1:2"},
+		{"٣", `ILLEGAL "٣" 1:1`},
+	}
+	for _, tt := range tests {
+		if got := tokenList(lexWith(t, tt.src, nil)); got != tt.want {
+			t.Errorf("%q lexed as\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLineEndingsBOMAndTabs(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		setup func(l *f.Lexer)
+		want  string
+	}{
+		{"CRLF", "a\r\nb\r\n\r\nc", nil, "IDENT \"a\" 1:1\nIDENT \"b\" 2:1\nIDENT \"c\" 4:1"},
+		{"CR", "a\rb", nil, "IDENT \"a\" 1:1\nIDENT \"b\" 2:1"},
+		{"CRLF in a comment", "// c\r\nb", nil, `IDENT "b" 2:1`},
+		{"BOM", "\uFEFFval x", nil, "VAR \"val\" 1:1\nIDENT \"x\" 1:5"},
+		{"tabs", "\tx\t\ty", nil, "IDENT \"x\" 1:5\nIDENT \"y\" 1:13"},
+		{"tab after text", "ab\tc", nil, "IDENT \"ab\" 1:1\nIDENT \"c\" 1:5"},
+		{"tab width 8", "\tx", func(l *f.Lexer) { l.SetTabWidth(8) }, `IDENT "x" 1:9`},
+		{"tab width 1", "\t\tx", func(l *f.Lexer) { l.SetTabWidth(0) }, `IDENT "x" 1:3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenList(lexWith(t, tt.src, tt.setup)); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// offsets count the bytes of the BOM and of both halves of a \r\n, so
+	// they still index the file
+	src := "\uFEFFa\r\nbc"
+	tokens := lexWith(t, src, nil)
+	for _, token := range tokens[:2] {
+		start, end := token.Span()
+		if text := src[start.Offset():end.Offset()]; text != token.Value() {
+			t.Errorf("%s spans %q", token.Value(), text)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package frontend
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 ///////////////////
@@ -18,6 +23,26 @@ func (e *ParsingError) Error() string {
 	return fmt.Sprintf("Parse Error at (%d, %d): %s", e.Pos.line, e.Pos.column, e.Message)
 }
 
+// ParsingErrors is every error found in one pass over a file, in the order
+// they appear
+type ParsingErrors []*ParsingError
+
+func (e ParsingErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e ParsingErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
 ////////////
 // Parser //
 ////////////
@@ -26,6 +51,10 @@ type Parser struct {
 	tokens       []TokenItem
 	tokenIndex   int
 	currentToken TokenItem
+	errors       ParsingErrors // errors recovered from so far
+	warnings     []Warning     // found along the way, see Warnings
+	ends         map[int]int   // the token after each statement, by the token it starts at, for Format
+	blockDepth   int           // how many blocks the current token is in, 0 at the top level
 }
 
 func TokenToFloat(token TokenItem) float64 {
@@ -38,6 +67,7 @@ func NewParser(tokens []TokenItem) *Parser {
 	p := Parser{
 		tokens:     tokens,
 		tokenIndex: -1,
+		ends:       make(map[int]int),
 	}
 	p.advance()
 	return &p
@@ -60,23 +90,203 @@ func (p *Parser) expect(expectedType Token, errMsg string) (TokenItem, error) {
 	return token, nil
 }
 
+// ProduceAst parses the whole program. A statement that fails to parse is
+// skipped so the rest of the file still gets checked, and the error returned
+// is then a ParsingErrors listing everything that went wrong.
 func (p *Parser) ProduceAst() (Program, error) {
 	program := Program{}
-
-	for {
-		stmt, err := p.parseStmt()
-		if err != nil {
-			return Program{}, err
+	p.checkPragma()
+
+	for p.currentToken.tokenType != EOF {
+		// there's no block to close at the top level
+		if p.currentToken.tokenType == CLOSECURLY {
+			p.errors = append(p.errors, &ParsingError{
+				Message: "Unexpected } with no block to close",
+				Pos:     p.eat().pos,
+			})
+			continue
 		}
-		program.Body = append(program.Body, stmt)
-		if p.currentToken.tokenType == EOF {
-			break
+
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			program.Body = append(program.Body, stmt)
 		}
 	}
 
+	if len(p.errors) > 0 {
+		return program, p.errors
+	}
 	return program, nil
 }
 
+// Warnings are the problems ProduceAst found that don't stop the program,
+// like a version pragma newer than this build
+func (p *Parser) Warnings() []Warning {
+	return p.warnings
+}
+
+// ParseExpression parses the tokens as one expression, for things like watch
+// expressions that aren't a whole program. Nothing but an optional ; may
+// follow the expression.
+func (p *Parser) ParseExpression() (Expr, error) {
+	expr, err := p.parseExpr()
+	if err != nil {
+		var parseErr *ParsingError
+		if errors.As(err, &parseErr) {
+			p.pointBeforeEOF(parseErr)
+		}
+		return nil, err
+	}
+
+	if p.currentToken.tokenType == SEMICOLON {
+		p.eat()
+	}
+	if p.currentToken.tokenType != EOF {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("Unexpected %s after the end of the expression", describeToken(p.currentToken)),
+			Pos:     p.currentToken.pos,
+		}
+	}
+	return expr, nil
+}
+
+// ParseExpressionString lexes and parses source as a single expression
+func ParseExpressionString(source string) (Expr, error) {
+	tokens, err := NewLexer(strings.NewReader(source)).Lex()
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(tokens).ParseExpression()
+}
+
+// parseStmtOrRecover parses a statement, or records why it couldn't and
+// skips to where the next statement probably starts
+func (p *Parser) parseStmtOrRecover() (Stmt, bool) {
+	// empty statements, like the second ; in a;;b
+	for p.currentToken.tokenType == SEMICOLON {
+		p.eat()
+	}
+	if p.currentToken.tokenType == EOF || p.currentToken.tokenType == CLOSECURLY {
+		return nil, false
+	}
+
+	start := p.tokenIndex
+	stmt, err := p.parseStmt()
+	if err == nil {
+		p.ends[start] = p.tokenIndex
+		p.endStatement()
+		return stmt, true
+	}
+
+	var parseErr *ParsingError
+	if !errors.As(err, &parseErr) {
+		parseErr = &ParsingError{Message: err.Error(), Pos: p.currentToken.pos}
+	}
+	p.pointBeforeEOF(parseErr)
+	p.errors = append(p.errors, parseErr)
+	p.synchronize(start)
+	return nil, false
+}
+
+// endStatement checks the statement just parsed is followed by a terminator:
+// a ;, a new line, the } closing its block or the end of the file. Anything
+// else means two statements ran together, like var x = 1 var y = 2.
+func (p *Parser) endStatement() {
+	switch p.currentToken.tokenType {
+	case SEMICOLON:
+		p.eat()
+		return
+	case EOF, CLOSECURLY:
+		return
+	}
+	if p.onNewLine() {
+		return
+	}
+
+	p.errors = append(p.errors, &ParsingError{
+		Message: fmt.Sprintf("Unexpected %s after the end of a statement, put it on a new line or separate them with ;", describeToken(p.currentToken)),
+		Pos:     p.currentToken.pos,
+	})
+	// a statement keyword is most likely the start of the next statement,
+	// anything else is the rest of a mistyped one
+	if !startsStatement(p.currentToken.tokenType) {
+		p.synchronize(p.tokenIndex)
+	}
+}
+
+// pointBeforeEOF moves an error at the end of the file to where the last
+// token ends. The end of the file comes after any blank lines and comments
+// left, often at column 0 of a line past the code, while what's missing
+// belongs right after the code that's there.
+func (p *Parser) pointBeforeEOF(err *ParsingError) {
+	if len(p.tokens) < 2 {
+		return
+	}
+	eof := p.tokens[len(p.tokens)-1]
+	if err.Pos == eof.pos {
+		err.Pos = p.tokens[len(p.tokens)-2].end
+	}
+}
+
+// describeToken is how a token is named in error messages
+func describeToken(token TokenItem) string {
+	if token.tokenType == EOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q (%s)", token.value, token.tokenType)
+}
+
+// synchronize skips the rest of a broken statement that started at token
+// start. It stops at the first new line or statement keyword after any
+// braces the statement opened are closed, or in front of a "}" closing the
+// block the statement is in.
+func (p *Parser) synchronize(start int) {
+	depth := 0
+	for _, token := range p.tokens[start:min(p.tokenIndex, len(p.tokens))] {
+		switch token.tokenType {
+		case OPENCURLY:
+			depth++
+		case CLOSECURLY:
+			depth = max(depth-1, 0)
+		}
+	}
+
+	for p.currentToken.tokenType != EOF {
+		if p.tokenIndex > start && depth == 0 && (p.onNewLine() || startsStatement(p.currentToken.tokenType)) {
+			return
+		}
+
+		switch p.currentToken.tokenType {
+		case OPENCURLY:
+			depth++
+		case CLOSECURLY:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		p.eat()
+	}
+}
+
+// startsStatement is true for the keywords that can only begin a statement
+func startsStatement(tokenType Token) bool {
+	switch tokenType {
+	case VAR, CONST, FUN, CLASS, IF, WHILE, FOR, RETURN, BREAK, CONTINUE, IMPORT, TRY, THROW, ASSERT:
+		return true
+	default:
+		return false
+	}
+}
+
+// looks at the token offset places ahead of the current one without eating it
+func (p *Parser) peek(offset int) TokenItem {
+	index := p.tokenIndex + offset
+	if index < len(p.tokens) {
+		return p.tokens[index]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
 func (p *Parser) advance() {
 	p.tokenIndex++
 	if p.tokenIndex < len(p.tokens) {
@@ -90,6 +300,8 @@ func (p *Parser) parseStmt() (Stmt, error) {
 		return p.parseVarDeclaration()
 	case FUN:
 		return p.parseFunctionDeclaration()
+	case CLASS:
+		return p.parseClassDeclaration()
 	case IF:
 		return p.parseIfStmt()
 	case WHILE:
@@ -98,7 +310,22 @@ func (p *Parser) parseStmt() (Stmt, error) {
 		return p.parseForStmt()
 	case RETURN:
 		return p.parseReturnStmt()
+	case BREAK:
+		return BreakStmt{Pos: p.eat().pos}, nil
+	case CONTINUE:
+		return ContinueStmt{Pos: p.eat().pos}, nil
+	case IMPORT:
+		return p.parseImportStmt()
+	case TRY:
+		return p.parseTryStmt()
+	case THROW:
+		return p.parseThrowStmt()
+	case ASSERT:
+		return p.parseAssertStmt()
 	default:
+		if p.atBenchStmt() {
+			return p.parseBenchStmt()
+		}
 		return p.parseExpr()
 	}
 }
@@ -108,74 +335,151 @@ func (p *Parser) parseExpr() (Expr, error) {
 	return p.parseAssignmentExpr()
 }
 
-func (p *Parser) parseAdditive() (Expr, error) {
-	left, err := p.parseMulti()
+// Operator Precedence //
+// Binary operators are parsed by precedence climbing over infixOperators, so
+// adding one is a matter of adding its entry. Higher precedences bind tighter.
+const (
+	precLowest         = iota
+	precLogical        // and, or
+	precEquality       // == !=
+	precRelational     // < > <= >=
+	precRange          // ..
+	precAdditive       // + -
+	precMultiplicative // * / %
+	precUnary          // prefix - + !
+)
+
+type infixOperator struct {
+	precedence int
+	rightAssoc bool // a op b op c groups as a op (b op c)
+	nonAssoc   bool // a op b op c is an error rather than grouping either way
+	build      func(left, right Expr, operator TokenItem) Expr
+}
+
+func binaryOperator(precedence int) infixOperator {
+	return infixOperator{precedence: precedence, build: func(left, right Expr, operator TokenItem) Expr {
+		return BinaryExpr{Left: left, Right: right, Operator: operator.value, Pos: operator.pos}
+	}}
+}
+
+func logicalOperator(precedence int) infixOperator {
+	return infixOperator{precedence: precedence, build: func(left, right Expr, operator TokenItem) Expr {
+		return LogicalExpr{Left: left, Right: right, Operator: operator.value, Pos: operator.pos}
+	}}
+}
+
+var infixOperators = map[Token]infixOperator{
+	AND: logicalOperator(precLogical),
+	OR:  logicalOperator(precLogical),
+
+	DE: logicalOperator(precEquality),
+	NE: logicalOperator(precEquality),
+
+	LT:  logicalOperator(precRelational),
+	GT:  logicalOperator(precRelational),
+	LTE: logicalOperator(precRelational),
+	GTE: logicalOperator(precRelational),
+
+	// binds looser than arithmetic so 0..n-1 works
+	RANGE: {precedence: precRange, nonAssoc: true, build: func(left, right Expr, operator TokenItem) Expr {
+		return RangeExpr{Start: left, End: right, Pos: operator.pos}
+	}},
+
+	ADD: binaryOperator(precAdditive),
+	SUB: binaryOperator(precAdditive),
+
+	MUL: binaryOperator(precMultiplicative),
+	DIV: binaryOperator(precMultiplicative),
+	MOD: binaryOperator(precMultiplicative),
+}
+
+// prefixOperators are the tokens that start a unary expression, with the
+// operator each one stands for
+var prefixOperators = map[Token]string{
+	SUB: "-",
+	ADD: "+",
+	NOT: "!",
+}
+
+// parseBinaryExpr parses operators that bind tighter than minPrecedence,
+// parseBinaryExpr(precLowest) takes all of them
+func (p *Parser) parseBinaryExpr(minPrecedence int) (Expr, error) {
+	left, err := p.parseUnary()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == ADD || p.currentToken.tokenType == SUB {
-		operator := p.eat().value
-		right, err := p.parseMulti()
+	for {
+		operator, ok := infixOperators[p.currentToken.tokenType]
+		if !ok || operator.precedence <= minPrecedence {
+			return left, nil
+		}
+		operatorToken := p.eat()
+
+		rightPrecedence := operator.precedence
+		if operator.rightAssoc {
+			rightPrecedence--
+		}
+		right, err := p.parseBinaryExpr(rightPrecedence)
 		if err != nil {
 			return nil, err
 		}
-		left = BinaryExpr{
-			Left:     left,
-			Right:    right,
-			Operator: operator,
+		left = operator.build(left, right, operatorToken)
+
+		if next, ok := infixOperators[p.currentToken.tokenType]; ok && operator.nonAssoc && next.precedence == operator.precedence {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("%s can't be chained, group it with parentheses", operatorToken.value),
+				Pos:     p.currentToken.pos,
+			}
 		}
 	}
-	return left, nil
 }
 
-func (p *Parser) parseMulti() (Expr, error) {
-	left, err := p.parseCallMemberExpr()
+// parseUnary parses prefix -, + and !, which bind tighter than * and / but
+// looser than calls and members, so -a.b is -(a.b) and -2 * 3 is (-2) * 3
+func (p *Parser) parseUnary() (Expr, error) {
+	operator, ok := prefixOperators[p.currentToken.tokenType]
+	if !ok {
+		return p.parseCallMemberExpr()
+	}
+
+	operatorToken := p.eat()
+	operand, err := p.parseUnary()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == MUL || p.currentToken.tokenType == DIV || p.currentToken.tokenType == MOD {
-		operator := p.eat().value
-		right, err := p.parseCallMemberExpr()
-		if err != nil {
-			return nil, err
-		}
-		left = BinaryExpr{
-			Left:     left,
-			Right:    right,
-			Operator: operator,
+	// -5 is a negative literal rather than an operation on 5
+	if number, ok := operand.(NumericLiteral); ok && operator != "!" {
+		if operator == "-" {
+			number.Value = -number.Value
 		}
+		return number, nil
 	}
-	return left, nil
+
+	return UnaryExpr{
+		Operator: operator,
+		Operant:  operand,
+		Pos:      operatorToken.pos,
+	}, nil
 }
 
 func (p *Parser) parsePrimary() (Expr, error) {
 	tokenType := p.currentToken.tokenType
 
-	if tokenType == NOT {
-		p.eat()
-		expr, err := p.parsePrimary()
-		if err != nil {
-			return nil, err
-		}
-
-		return UnaryExpr{
-			Operator: "!",
-			Operant:  expr,
-		}, nil
-	}
-
 	switch tokenType {
 	case IDENT:
 		token := p.eat()
-		return Identifier{Symbol: token.value}, nil
+		return Identifier{Symbol: token.value, Pos: token.pos}, nil
 	case INT, FLOAT:
 		token := p.eat()
 		return NumericLiteral{Value: TokenToFloat(token)}, nil
 	case STRING:
 		token := p.eat()
 		return StringLiteral{Value: token.value}, nil
+	case CHAR:
+		token := p.eat()
+		return CharLiteral{Value: []rune(token.value)[0]}, nil
 	case OPENPAREN:
 		p.eat() // Skip '('
 		value, err := p.parseExpr()
@@ -191,7 +495,13 @@ func (p *Parser) parsePrimary() (Expr, error) {
 		return value, nil
 	case OPENCURLY:
 		return p.parseObjectExpr()
-	case EOF, CLOSEPAREN, CLOSECURLY, COMMA:
+	case OPENBRACKET:
+		return p.parseArrayExpr()
+	case NEW:
+		return p.parseNewExpr()
+	case MATCH:
+		return p.parseMatchExpr()
+	case EOF, CLOSEPAREN, CLOSECURLY, COMMA, SEMICOLON:
 		return nil, &ParsingError{
 			Message: "Expected an expression or value but found none",
 			Pos:     p.currentToken.pos,
@@ -203,7 +513,7 @@ func (p *Parser) parsePrimary() (Expr, error) {
 		}
 	default:
 		return nil, &ParsingError{
-			Message: fmt.Sprintf("Unrecognized Primary Token (Type: %s, Value: %s)", TokensList[p.currentToken.tokenType], p.currentToken.value),
+			Message: fmt.Sprintf("Unexpected %s where a value was expected", describeToken(p.currentToken)),
 			Pos:     p.currentToken.pos,
 		}
 	}
@@ -212,7 +522,12 @@ func (p *Parser) parsePrimary() (Expr, error) {
 // Parsing Variable Declarations
 func (p *Parser) parseVarDeclaration() (Stmt, error) {
 	isConstant := p.currentToken.tokenType == CONST
-	p.eat()
+	declToken := p.eat()
+
+	// var {x, y} = point and var [a, b] = pair
+	if p.currentToken.tokenType == OPENCURLY || p.currentToken.tokenType == OPENBRACKET {
+		return p.parseDestructuringDeclaration(isConstant, declToken.pos)
+	}
 
 	identifier, err := p.expect(IDENT, "Expected identifier name after var | const keyword")
 	if err != nil {
@@ -230,6 +545,9 @@ func (p *Parser) parseVarDeclaration() (Stmt, error) {
 			Constant:   isConstant,
 			Identifier: identifier.value,
 			Value:      nil,
+			Pos:        declToken.pos,
+			Comments:   declToken.leading,
+			Doc:        docComment(declToken),
 		}, nil
 	}
 
@@ -243,17 +561,96 @@ func (p *Parser) parseVarDeclaration() (Stmt, error) {
 		Constant:   isConstant,
 		Identifier: identifier.value,
 		Value:      value,
+		Pos:        declToken.pos,
+		Comments:   declToken.leading,
+		Doc:        docComment(declToken),
+	}, nil
+}
+
+func (p *Parser) parseDestructuringDeclaration(isConstant bool, declPos Position) (Stmt, error) {
+	patternPos := p.currentToken.pos
+	target, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := toPattern(target, patternPos)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.expect(EQUALS, "Destructuring declaration must be initialized")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return VarDeclaration{
+		Constant: isConstant,
+		Pattern:  pattern,
+		Value:    value,
+		Pos:      declPos,
 	}, nil
 }
 
+// toPattern turns an object or array literal made only of plain names into a
+// destructuring pattern
+func toPattern(expr Expr, pos Position) (Expr, error) {
+	switch e := expr.(type) {
+	case ObjectLiteral:
+		names := []string{}
+		for _, property := range e.Properties {
+			if property.Value != nil {
+				return nil, &ParsingError{
+					Message: fmt.Sprintf("Object pattern only takes property names, found value for \"%s\"", property.Key),
+					Pos:     pos,
+				}
+			}
+			names = append(names, property.Key)
+		}
+		return ObjectPattern{Names: names}, nil
+	case ArrayLiteral:
+		names := []string{}
+		for _, element := range e.Elements {
+			ident, ok := element.(Identifier)
+			if !ok {
+				return nil, &ParsingError{
+					Message: "Array pattern only takes variable names",
+					Pos:     pos,
+				}
+			}
+			names = append(names, ident.Symbol)
+		}
+		return ArrayPattern{Names: names}, nil
+	default:
+		return nil, &ParsingError{
+			Message: "Expected an object or array pattern",
+			Pos:     pos,
+		}
+	}
+}
+
 func (p *Parser) parseAssignmentExpr() (Expr, error) {
-	expr, err := p.parseLogicalExpr()
+	assigneePos := p.currentToken.pos
+	expr, err := p.parseBinaryExpr(precLowest)
 	if err != nil {
 		return nil, err
 	}
 
 	if p.currentToken.tokenType == EQUALS {
-		p.eat() // consume the '=' token
+		equalsToken := p.eat() // consume the '=' token
+
+		// [a, b] = pair and {x, y} = point assign into existing variables
+		if expr.NodeType() == ArrayLiteralNode || expr.NodeType() == ObjectLiteralNode {
+			expr, err = toPattern(expr, assigneePos)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		value, err := p.parseAssignmentExpr()
 		if err != nil {
@@ -263,6 +660,7 @@ func (p *Parser) parseAssignmentExpr() (Expr, error) {
 		return AssignmentExpr{
 			Assignee: expr,
 			Value:    value,
+			Pos:      equalsToken.pos,
 		}, nil
 	}
 
@@ -272,9 +670,9 @@ func (p *Parser) parseAssignmentExpr() (Expr, error) {
 // Parsing Objects
 func (p *Parser) parseObjectExpr() (Expr, error) {
 	if p.currentToken.tokenType != OPENCURLY {
-		return p.parseAdditive()
+		return p.parseBinaryExpr(precRange)
 	}
-	p.eat() // Skip the open brace
+	openToken := p.eat() // Skip the open brace
 	properties := []Property{}
 
 	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
@@ -329,246 +727,438 @@ func (p *Parser) parseObjectExpr() (Expr, error) {
 		return nil, err
 	}
 
-	return ObjectLiteral{Properties: properties}, nil
+	return ObjectLiteral{Properties: properties, Pos: openToken.pos}, nil
 }
 
-// Parsing Member Calls
-func (p *Parser) parseCallMemberExpr() (Expr, error) {
-	member, err := p.parseMemberExpr()
+// Parsing new expressions, the argument list can be left out
+func (p *Parser) parseNewExpr() (Expr, error) {
+	newToken, err := p.expect(NEW, "Expected 'new' keyword")
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := p.parseMemberExpr()
 	if err != nil {
 		return nil, err
 	}
 
+	args := []Expr{}
 	if p.currentToken.tokenType == OPENPAREN {
-		return p.parseCallExpr(member)
+		args, err = p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return member, nil
+	return NewExpr{Class: class, Args: args, Pos: newToken.pos}, nil
 }
 
-// Parsing Calls
-func (p *Parser) parseCallExpr(caller Expr) (Expr, error) {
-	arguments, err := p.parseArguments()
+// Parsing match expressions
+func (p *Parser) parseMatchExpr() (Expr, error) {
+	matchToken, err := p.expect(MATCH, "Expected 'match' keyword")
 	if err != nil {
 		return nil, err
 	}
 
-	callExpr := CallExpr{Caller: caller, Args: arguments}
-
-	if p.currentToken.tokenType == OPENPAREN {
-		return p.parseCallExpr(callExpr)
+	_, err = p.expect(OPENPAREN, "Expected '(' after 'match'")
+	if err != nil {
+		return nil, err
 	}
 
-	return callExpr, nil
-}
-
-func (p *Parser) parseArguments() ([]Expr, error) {
-	args := []Expr{}
+	subject, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := p.expect(OPENPAREN, "Expected \"(\"")
+	_, err = p.expect(CLOSEPAREN, "Expected ')' after match subject")
 	if err != nil {
 		return nil, err
 	}
 
-	if p.currentToken.tokenType == CLOSEPAREN {
-		p.eat()
-		return args, nil
+	_, err = p.expect(OPENCURLY, "Expected '{' to begin match arms")
+	if err != nil {
+		return nil, err
 	}
 
-	for {
-		arg, err := p.parseExpr()
+	arms := []MatchArm{}
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
+		var pattern Expr
+		if p.currentToken.tokenType == SUB {
+			pattern, err = p.parseUnary() // negative number patterns
+		} else {
+			pattern, err = p.parsePrimary()
+		}
 		if err != nil {
 			return nil, err
 		}
-		args = append(args, arg)
 
-		if p.currentToken.tokenType != COMMA {
-			break
+		arm := MatchArm{Pattern: pattern}
+		if p.currentToken.tokenType == IF {
+			p.eat() // Skip if
+			arm.Guard, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
 		}
-		p.eat() // Skip comma
+
+		_, err = p.expect(ARROW, "Expected '->' after match pattern")
+		if err != nil {
+			return nil, err
+		}
+
+		// either a block or a single expression, a comma has to follow an
+		// expression so the next pattern isn't read as part of it
+		if p.currentToken.tokenType == OPENCURLY {
+			arm.Body, err = p.parseBlock("match arm")
+			if err != nil {
+				return nil, err
+			}
+			if p.currentToken.tokenType == COMMA {
+				p.eat()
+			}
+		} else {
+			body, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			arm.Body = []Stmt{body}
+
+			if p.currentToken.tokenType != CLOSECURLY {
+				_, err = p.expect(COMMA, "Expected ',' after match arm")
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		arms = append(arms, arm)
 	}
 
-	_, err = p.expect(CLOSEPAREN, "Expected \")\"")
+	_, err = p.expect(CLOSECURLY, "Expected '}' to close match arms")
 	if err != nil {
 		return nil, err
 	}
 
-	return args, nil
+	return MatchExpr{Subject: subject, Arms: arms, Pos: matchToken.pos}, nil
 }
 
-func (p *Parser) parseMemberExpr() (Expr, error) {
-	object, err := p.parsePrimary()
+// Parsing Arrays
+func (p *Parser) parseArrayExpr() (Expr, error) {
+	openToken, err := p.expect(OPENBRACKET, "Expected \"[\"")
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == DOT || p.currentToken.tokenType == OPENBRACKET {
-		operator := p.eat()
-		var property Expr
-		var computed bool
-
-		// Non-computed values (dot values obj.expr)
-		if operator.tokenType == DOT {
-			computed = false
-			property, err = p.parsePrimary()
-			if err != nil {
-				return nil, err
-			}
+	elements := []Expr{}
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSEBRACKET {
+		element, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
 
-			if property.NodeType() != IdentifierNode {
-				return nil, &ParsingError{
-					Pos:     p.currentToken.pos,
-					Message: "Cannot use dot operator without having an identifier after it",
-				}
-			}
-		} else { // this allows chaining
-			computed = true
-			property, err = p.parseExpr()
+		// Expect comma or closing bracket
+		if p.currentToken.tokenType != CLOSEBRACKET {
+			_, err = p.expect(COMMA, "Expected comma or closing bracket after element")
 			if err != nil {
 				return nil, err
 			}
-			p.expect(CLOSEBRACKET, "Expected \"]\"")
-		}
-
-		object = MemberExpr{
-			Object:   object,
-			Property: property,
-			Computed: computed,
 		}
 	}
 
-	return object, nil
-}
-
-// Parsing Function Declarations
-func (p *Parser) parseFunctionDeclaration() (Stmt, error) {
-	p.eat() // Skip the fun keyword
-
-	name, err := p.expect(IDENT, "Expected function name after keyword \"fun\"")
+	_, err = p.expect(CLOSEBRACKET, "Array literal missing closing bracket")
 	if err != nil {
 		return nil, err
 	}
 
-	args, err := p.parseArguments()
+	return ArrayLiteral{Elements: elements, Pos: openToken.pos}, nil
+}
+
+// Parsing Member Calls
+// parseCallMemberExpr parses a primary expression followed by any mix of
+// member accesses, indexes, slices and calls, like a.b().c[0](x)
+func (p *Parser) parseCallMemberExpr() (Expr, error) {
+	expr, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
 
-	params := []string{}
-	for _, arg := range args {
-		if arg.NodeType() != IdentifierNode {
-			return nil, &ParsingError{
-				Message: "Expected parameter inside function declaration",
-				Pos:     name.pos,
-			}
+	for {
+		switch {
+		case p.currentToken.tokenType == DOT:
+			expr, err = p.parseMemberSuffix(expr)
+		case p.currentToken.tokenType == OPENBRACKET && !p.onNewLine():
+			expr, err = p.parseMemberSuffix(expr)
+		case p.currentToken.tokenType == OPENPAREN && !p.onNewLine():
+			expr, err = p.parseCallExpr(expr)
+		default:
+			return expr, nil
+		}
+		if err != nil {
+			return nil, err
 		}
-		params = append(params, arg.(Identifier).Symbol)
 	}
+}
 
-	_, err = p.expect(OPENCURLY, "Expected \"{\"")
+// onNewLine reports whether the current token starts a new line. A "(" or
+// "[" there begins a new statement rather than calling or indexing whatever
+// ended the line before it.
+func (p *Parser) onNewLine() bool {
+	if p.tokenIndex <= 0 || p.tokenIndex >= len(p.tokens) {
+		return false
+	}
+	return p.currentToken.pos.line > p.tokens[p.tokenIndex-1].pos.line
+}
+
+// Parsing Calls
+func (p *Parser) parseCallExpr(caller Expr) (Expr, error) {
+	openPos := p.currentToken.pos
+	arguments, err := p.parseArguments()
 	if err != nil {
 		return nil, err
 	}
 
-	body := []Stmt{}
-	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
-		statement, err := p.parseStmt()
+	return CallExpr{Caller: caller, Args: arguments, Pos: openPos}, nil
+}
+
+func (p *Parser) parseArguments() ([]Expr, error) {
+	args := []Expr{}
+
+	_, err := p.expect(OPENPAREN, "Expected \"(\"")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken.tokenType == CLOSEPAREN {
+		p.eat()
+		return args, nil
+	}
+
+	for {
+		arg, err := p.parseExpr()
 		if err != nil {
 			return nil, err
 		}
+		args = append(args, arg)
 
-		body = append(body, statement)
+		if p.currentToken.tokenType != COMMA {
+			break
+		}
+		p.eat() // Skip comma
 	}
 
-	_, err = p.expect(CLOSECURLY, "Expected \"}\"")
+	_, err = p.expect(CLOSEPAREN, "Expected \")\"")
 	if err != nil {
 		return nil, err
 	}
 
-	return FunctionDeclaration{
-		Name:       name.value,
-		Parameters: params,
-		Body:       body,
-	}, nil
+	return args, nil
 }
 
-func (p *Parser) parseLogicalExpr() (Expr, error) {
-	left, err := p.parseEqualityExpr()
+// parseMemberExpr parses member accesses without calls, for places like
+// new Foo.Bar(...) where the call belongs to something else
+func (p *Parser) parseMemberExpr() (Expr, error) {
+	object, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == AND || p.currentToken.tokenType == OR {
-		operator := p.eat().value
+	for p.currentToken.tokenType == DOT || p.currentToken.tokenType == OPENBRACKET {
+		object, err = p.parseMemberSuffix(object)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return object, nil
+}
+
+// parseMemberSuffix parses one .name, [index] or [start:end] applied to object
+func (p *Parser) parseMemberSuffix(object Expr) (Expr, error) {
+	operator := p.eat()
 
-		right, err := p.parseEqualityExpr()
+	// Non-computed values (dot values obj.expr)
+	if operator.tokenType == DOT {
+		property, err := p.parsePrimary()
 		if err != nil {
 			return nil, err
 		}
 
-		left = LogicalExpr{
-			Left:     left,
-			Right:    right,
-			Operator: operator,
+		if property.NodeType() != IdentifierNode {
+			return nil, &ParsingError{
+				Pos:     p.currentToken.pos,
+				Message: "Cannot use dot operator without having an identifier after it",
+			}
+		}
+
+		return MemberExpr{
+			Object:   object,
+			Property: property,
+			Computed: false,
+			Pos:      operator.pos,
+		}, nil
+	}
+
+	// slices like obj[start:end] where both bounds are optional
+	var start Expr
+	var err error
+	if p.currentToken.tokenType != COLON {
+		start, err = p.parseExpr()
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return left, nil
+	if p.currentToken.tokenType == COLON {
+		p.eat() // Skip colon
+		var end Expr
+		if p.currentToken.tokenType != CLOSEBRACKET {
+			end, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = p.expect(CLOSEBRACKET, "Expected \"]\"")
+		if err != nil {
+			return nil, err
+		}
+
+		return SliceExpr{
+			Object: object,
+			Start:  start,
+			End:    end,
+			Pos:    operator.pos,
+		}, nil
+	}
+
+	_, err = p.expect(CLOSEBRACKET, "Expected \"]\"")
+	if err != nil {
+		return nil, err
+	}
+
+	return MemberExpr{
+		Object:   object,
+		Property: start,
+		Computed: true,
+		Pos:      operator.pos,
+	}, nil
 }
 
-func (p *Parser) parseEqualityExpr() (Expr, error) {
-	left, err := p.parseRelationalExpr()
+// Parsing Function Declarations
+func (p *Parser) parseFunctionDeclaration() (Stmt, error) {
+	funToken := p.eat() // Skip the fun keyword
+
+	name, err := p.expect(IDENT, "Expected function name after keyword \"fun\"")
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == DE || p.currentToken.tokenType == NE {
-		operator := p.eat().value
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
 
-		right, err := p.parseRelationalExpr()
-		if err != nil {
-			return nil, err
+	params := []string{}
+	for _, arg := range args {
+		if arg.NodeType() != IdentifierNode {
+			return nil, &ParsingError{
+				Message: "Expected parameter inside function declaration",
+				Pos:     name.pos,
+			}
 		}
+		params = append(params, arg.(Identifier).Symbol)
+	}
 
-		left = LogicalExpr{
-			Left:     left,
-			Right:    right,
-			Operator: operator,
+	_, err = p.expect(OPENCURLY, "Expected \"{\"")
+	if err != nil {
+		return nil, err
+	}
+
+	body := []Stmt{}
+	p.blockDepth++
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
 		}
 	}
+	p.blockDepth--
 
-	return left, nil
+	_, err = p.expect(CLOSECURLY, "Expected \"}\"")
+	if err != nil {
+		return nil, err
+	}
+
+	return FunctionDeclaration{
+		Name:       name.value,
+		Parameters: params,
+		Body:       body,
+		Pos:        funToken.pos,
+		Comments:   funToken.leading,
+		Doc:        docComment(funToken),
+	}, nil
 }
 
-func (p *Parser) parseRelationalExpr() (Stmt, error) {
-	left, err := p.parseAdditive()
+// Parsing Class Declarations
+func (p *Parser) parseClassDeclaration() (Stmt, error) {
+	classToken := p.eat() // Skip the class keyword
+
+	name, err := p.expect(IDENT, "Expected class name after keyword \"class\"")
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.tokenType == LT || p.currentToken.tokenType == GT ||
-		p.currentToken.tokenType == LTE || p.currentToken.tokenType == GTE {
+	// class Dog : Animal
+	var parent Expr
+	if p.currentToken.tokenType == COLON {
+		p.eat() // Skip colon
+		parent, err = p.parseMemberExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		operator := p.eat().value
+	_, err = p.expect(OPENCURLY, "Expected \"{\" to begin class body")
+	if err != nil {
+		return nil, err
+	}
 
-		right, err := p.parseAdditive()
+	methods := []FunctionDeclaration{}
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
+		if p.currentToken.tokenType != FUN {
+			return nil, &ParsingError{
+				Message: "Class bodies can only contain method declarations",
+				Pos:     p.currentToken.pos,
+			}
+		}
+
+		start := p.tokenIndex
+		method, err := p.parseFunctionDeclaration()
 		if err != nil {
 			return nil, err
 		}
+		p.ends[start] = p.tokenIndex
+		methods = append(methods, method.(FunctionDeclaration))
+	}
 
-		left = LogicalExpr{
-			Left:     left,
-			Right:    right,
-			Operator: operator,
-		}
+	_, err = p.expect(CLOSECURLY, "Expected \"}\" to close class body")
+	if err != nil {
+		return nil, err
 	}
 
-	return left, nil
+	return ClassDeclaration{
+		Name:     name.value,
+		Parent:   parent,
+		Methods:  methods,
+		Pos:      classToken.pos,
+		Comments: classToken.leading,
+		Doc:      docComment(classToken),
+	}, nil
 }
 
 // Parsing if statements
 func (p *Parser) parseIfStmt() (Stmt, error) {
-	_, err := p.expect(IF, "Expected 'if' keyword")
+	ifToken, err := p.expect(IF, "Expected 'if' keyword")
 	if err != nil {
 		return nil, err
 	}
@@ -595,11 +1185,9 @@ func (p *Parser) parseIfStmt() (Stmt, error) {
 
 	body := []Stmt{}
 	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
-		stmt, err := p.parseStmt()
-		if err != nil {
-			return nil, err
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
 		}
-		body = append(body, stmt)
 	}
 
 	_, err = p.expect(CLOSECURLY, "Expected '}' to close if statement body")
@@ -610,12 +1198,13 @@ func (p *Parser) parseIfStmt() (Stmt, error) {
 	return IfStmt{
 		Condition: condition,
 		Body:      body,
+		Pos:       ifToken.pos,
 	}, nil
 }
 
 // Parsing while loops
 func (p *Parser) parseWhileStmt() (Stmt, error) {
-	_, err := p.expect(WHILE, "Expected 'while' keyword")
+	whileToken, err := p.expect(WHILE, "Expected 'while' keyword")
 	if err != nil {
 		return nil, err
 	}
@@ -642,11 +1231,9 @@ func (p *Parser) parseWhileStmt() (Stmt, error) {
 
 	body := []Stmt{}
 	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
-		stmt, err := p.parseStmt()
-		if err != nil {
-			return nil, err
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
 		}
-		body = append(body, stmt)
 	}
 
 	_, err = p.expect(CLOSECURLY, "Expected '}' to close while loop body")
@@ -657,12 +1244,13 @@ func (p *Parser) parseWhileStmt() (Stmt, error) {
 	return WhileStmt{
 		Condition: condition,
 		Body:      body,
+		Pos:       whileToken.pos,
 	}, nil
 }
 
 // Parsing for loops
 func (p *Parser) parseForStmt() (Stmt, error) {
-	_, err := p.expect(FOR, "Expected 'for' keyword")
+	forToken, err := p.expect(FOR, "Expected 'for' keyword")
 	if err != nil {
 		return nil, err
 	}
@@ -672,6 +1260,12 @@ func (p *Parser) parseForStmt() (Stmt, error) {
 		return nil, err
 	}
 
+	// for (item in ...) and for (key, value in ...)
+	if p.currentToken.tokenType == IDENT &&
+		(p.peek(1).tokenType == IN || (p.peek(1).tokenType == COMMA && p.peek(3).tokenType == IN)) {
+		return p.parseForEachStmt(forToken.pos)
+	}
+
 	condition, err := p.parseExpr()
 	if err != nil {
 		return nil, err
@@ -689,11 +1283,9 @@ func (p *Parser) parseForStmt() (Stmt, error) {
 
 	body := []Stmt{}
 	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
-		stmt, err := p.parseStmt()
-		if err != nil {
-			return nil, err
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
 		}
-		body = append(body, stmt)
 	}
 
 	_, err = p.expect(CLOSECURLY, "Expected '}' to close while loop body")
@@ -704,19 +1296,78 @@ func (p *Parser) parseForStmt() (Stmt, error) {
 	return ForStmt{
 		Condition: condition,
 		Body:      body,
+		Pos:       forToken.pos,
+	}, nil
+}
+
+// Parsing foreach loops, called after the opening parenthesis
+func (p *Parser) parseForEachStmt(forPos Position) (Stmt, error) {
+	key, err := p.expect(IDENT, "Expected loop variable name")
+	if err != nil {
+		return nil, err
+	}
+
+	value := ""
+	if p.currentToken.tokenType == COMMA {
+		p.eat() // Skip comma
+		valueToken, err := p.expect(IDENT, "Expected second loop variable name after ','")
+		if err != nil {
+			return nil, err
+		}
+		value = valueToken.value
+	}
+
+	_, err = p.expect(IN, "Expected 'in' after loop variables")
+	if err != nil {
+		return nil, err
+	}
+
+	iterable, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.expect(CLOSEPAREN, "Expected ')' after foreach iterable")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.expect(OPENCURLY, "Expected '{' to begin foreach loop body")
+	if err != nil {
+		return nil, err
+	}
+
+	body := []Stmt{}
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
+		}
+	}
+
+	_, err = p.expect(CLOSECURLY, "Expected '}' to close foreach loop body")
+	if err != nil {
+		return nil, err
+	}
+
+	return ForEachStmt{
+		Key:      key.value,
+		Value:    value,
+		Iterable: iterable,
+		Body:     body,
+		Pos:      forPos,
 	}, nil
 }
 
 // Parsing Return Statements
 func (p *Parser) parseReturnStmt() (Stmt, error) {
-	_, err := p.expect(RETURN, "Expected 'return' keyword")
+	returnToken, err := p.expect(RETURN, "Expected 'return' keyword")
 	if err != nil {
 		return nil, err
 	}
 
 	// If next token is close curly or EOF, no return value
 	if p.currentToken.tokenType == CLOSECURLY || p.currentToken.tokenType == EOF {
-		return ReturnStmt{Value: nil}, nil
+		return ReturnStmt{Value: nil, Pos: returnToken.pos}, nil
 	}
 
 	// Otherwise parse expression for return value
@@ -725,5 +1376,232 @@ func (p *Parser) parseReturnStmt() (Stmt, error) {
 		return nil, err
 	}
 
-	return ReturnStmt{Value: expr}, nil
+	return ReturnStmt{Value: expr, Pos: returnToken.pos}, nil
+}
+
+// Parsing Imports
+func (p *Parser) parseImportStmt() (Stmt, error) {
+	importToken, err := p.expect(IMPORT, "Expected 'import' keyword")
+	if err != nil {
+		return nil, err
+	}
+
+	// import "path/to/file.a0" or import name
+	pathToken := p.eat()
+	if pathToken.tokenType != STRING && pathToken.tokenType != IDENT {
+		return nil, &ParsingError{
+			Message: "Expected a module name or path after 'import'",
+			Pos:     pathToken.pos,
+		}
+	}
+	path := pathToken.value
+
+	// bound to the file name without its extension unless renamed with as
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if p.currentToken.tokenType == IDENT && p.currentToken.value == "as" {
+		p.eat() // Skip as
+		alias, err := p.expect(IDENT, "Expected a name after 'as'")
+		if err != nil {
+			return nil, err
+		}
+		name = alias.value
+	}
+
+	if !isIdentifier(name) {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("Cannot bind module %q to a variable, name it with 'as'", path),
+			Pos:     pathToken.pos,
+		}
+	}
+
+	return ImportStmt{Path: path, Name: name, Pos: importToken.pos}, nil
+}
+
+// docComment is the block of /// comments ending on the line right above
+// token, with the slashes and the space after them taken off every line
+func docComment(token TokenItem) string {
+	var lines []string
+	line := token.pos.line
+	for i := len(token.leading) - 1; i >= 0; i-- {
+		comment := token.leading[i]
+		text, doc := strings.CutPrefix(comment.Text, "///")
+		if !doc || strings.HasPrefix(text, "/") || comment.Pos.line != line-1 {
+			break
+		}
+		lines = append(lines, strings.TrimRight(strings.TrimPrefix(text, " "), " \t"))
+		line = comment.Pos.line
+	}
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n")
+}
+
+// isIdentifier reports whether name would lex as a single identifier
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// parseBlock parses { statements } where what names the block in error messages
+func (p *Parser) parseBlock(what string) ([]Stmt, error) {
+	_, err := p.expect(OPENCURLY, fmt.Sprintf("Expected '{' to begin %s", what))
+	if err != nil {
+		return nil, err
+	}
+
+	body := []Stmt{}
+	for p.currentToken.tokenType != EOF && p.currentToken.tokenType != CLOSECURLY {
+		if stmt, ok := p.parseStmtOrRecover(); ok {
+			body = append(body, stmt)
+		}
+	}
+
+	_, err = p.expect(CLOSECURLY, fmt.Sprintf("Expected '}' to close %s", what))
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// Parsing try statements
+func (p *Parser) parseTryStmt() (Stmt, error) {
+	tryToken, err := p.expect(TRY, "Expected 'try' keyword")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock("try body")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := TryStmt{Body: body, Pos: tryToken.pos}
+
+	if p.currentToken.tokenType == CATCH {
+		p.eat()
+		stmt.HasCatch = true
+
+		// catch (e) binds the error, a bare catch just swallows it
+		if p.currentToken.tokenType == OPENPAREN {
+			p.eat()
+			name, err := p.expect(IDENT, "Expected error variable name in catch")
+			if err != nil {
+				return nil, err
+			}
+			stmt.CatchName = name.value
+
+			_, err = p.expect(CLOSEPAREN, "Expected ')' after catch variable")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stmt.CatchBody, err = p.parseBlock("catch body")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.currentToken.tokenType == FINALLY {
+		p.eat()
+		stmt.FinallyBody, err = p.parseBlock("finally body")
+		if err != nil {
+			return nil, err
+		}
+	} else if !stmt.HasCatch {
+		return nil, &ParsingError{
+			Message: "Expected 'catch' or 'finally' after try body",
+			Pos:     tryToken.pos,
+		}
+	}
+
+	return stmt, nil
+}
+
+// Parsing throw statements
+func (p *Parser) parseThrowStmt() (Stmt, error) {
+	throwToken, err := p.expect(THROW, "Expected 'throw' keyword")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return ThrowStmt{Value: value, Pos: throwToken.pos}, nil
+}
+
+// bench isn't a keyword, so it stays free as a name. It only starts a bench
+// block when a name string and a { follow it.
+func (p *Parser) atBenchStmt() bool {
+	return p.currentToken.tokenType == IDENT && p.currentToken.value == "bench" &&
+		p.peek(1).tokenType == STRING && p.peek(2).tokenType == OPENCURLY
+}
+
+// Parsing bench blocks, bench "name" { ... }
+func (p *Parser) parseBenchStmt() (Stmt, error) {
+	benchToken := p.eat()
+	if p.blockDepth > 0 {
+		return nil, &ParsingError{
+			Message: "Parsing Error: bench blocks can only be at the top level of a file",
+			Pos:     benchToken.pos,
+		}
+	}
+	name := p.eat()
+
+	body, err := p.parseBlock("the bench block")
+	if err != nil {
+		return nil, err
+	}
+	return BenchStmt{Name: name.value, Body: body, Pos: benchToken.pos}, nil
+}
+
+// Parsing assert statements, assert(cond, "message") or assert cond, "message"
+func (p *Parser) parseAssertStmt() (Stmt, error) {
+	assertToken, err := p.expect(ASSERT, "Expected 'assert' keyword")
+	if err != nil {
+		return nil, err
+	}
+
+	var args []Expr
+	if p.currentToken.tokenType == OPENPAREN {
+		args, err = p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		condition, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, condition)
+
+		if p.currentToken.tokenType == COMMA {
+			p.eat() // Skip comma
+			message, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, message)
+		}
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		return nil, &ParsingError{
+			Message: "assert takes a condition and an optional message",
+			Pos:     assertToken.pos,
+		}
+	}
+
+	stmt := AssertStmt{Condition: args[0], Pos: assertToken.pos}
+	if len(args) == 2 {
+		stmt.Message = args[1]
+	}
+	return stmt, nil
 }
@@ -0,0 +1,213 @@
+package frontend_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestParseBenchStmt(t *testing.T) {
+	program, err := testutil.Parse("bench \"sort\" {\n    xs.sort()\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bench, ok := program.Body[0].(f.BenchStmt)
+	if !ok {
+		t.Fatalf("got %T, want a BenchStmt", program.Body[0])
+	}
+	if bench.Name != "sort" || len(bench.Body) != 1 || bench.Pos.Line() != 1 {
+		t.Errorf("got %+v", bench)
+	}
+}
+
+func TestBenchIsStillAName(t *testing.T) {
+	program, err := testutil.Parse(`val bench = 1; bench + 1; bench("x")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range program.Body {
+		if _, ok := stmt.(f.BenchStmt); ok {
+			t.Errorf("parsed %#v as a bench block", stmt)
+		}
+	}
+}
+
+func TestBenchStmtOnlyAtTopLevel(t *testing.T) {
+	_, err := testutil.Parse(`fun f() { bench "x" { } }`)
+	if err == nil || !strings.Contains(err.Error(), "top level") {
+		t.Errorf("got %v, want a top level error", err)
+	}
+}
+
+// parseErrors parses src and lists its errors as line:column message
+func parseErrors(t *testing.T, src string) []string {
+	t.Helper()
+	_, err := testutil.Parse(src)
+	var errs f.ParsingErrors
+	if err != nil && !errors.As(err, &errs) {
+		t.Fatalf("got %v, want ParsingErrors", err)
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, fmt.Sprintf("%d:%d %s", e.Pos.Line(), e.Pos.Column(), e.Message))
+	}
+	return got
+}
+
+func TestParseRecoversFromErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"one bad line among good ones", "val = 1\nval y = 2\nval = 3", []string{
+			`1:5 Parsing Error: Expected identifier name after var | const keyword`,
+			`3:5 Parsing Error: Expected identifier name after var | const keyword`,
+		}},
+		{"inside blocks", "fun f() {\n    val = 1\n    return 2\n}\nfun g() {\n    1 +\n}", []string{
+			`2:9 Parsing Error: Expected identifier name after var | const keyword`,
+			`7:1 Expected an expression or value but found none`,
+		}},
+		{"a stray brace", "val x = 1\n}\nval y = 2", []string{
+			`2:1 Unexpected } with no block to close`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseErrors(t, tt.src)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// a program keeps the statements around the broken ones
+func TestParseKeepsGoodStatements(t *testing.T) {
+	program, err := testutil.Parse("val a = 1\nval = 2\nval b = 3")
+	if err == nil {
+		t.Fatal("parsing didn't fail")
+	}
+	if len(program.Body) != 2 {
+		t.Errorf("got %d statements, want the 2 good ones", len(program.Body))
+	}
+}
+
+// errors at the end of the file point just past the last token, not at the
+// lines and comments after it
+func TestParseErrorAtEOF(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"val x = (1 +", "1:13"},
+		{"val x = (1 +\n\n// trailing comment\n", "1:13"},
+		{"fun f() {\n    val y = 1\n\n", "2:14"},
+		{"val xs = [1, 2", "1:15"},
+	}
+	for _, tt := range tests {
+		got := parseErrors(t, tt.src)
+		if len(got) == 0 || !strings.HasPrefix(got[0], tt.want+" ") {
+			t.Errorf("%q: got %q, want an error at %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestStatementTerminators(t *testing.T) {
+	if got := parseErrors(t, "var x = 1; var y = 2\nx;;y\n{ x }; y"); got != nil {
+		t.Errorf("got %q, want no errors", got)
+	}
+
+	got := parseErrors(t, "var x = 1 var y = 2\nvar z = 3 4")
+	want := []string{
+		`1:11 Unexpected "var" (VAR) after the end of a statement, put it on a new line or separate them with ;`,
+		`2:11 Unexpected "4" (INT) after the end of a statement, put it on a new line or separate them with ;`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// sexpr writes an expression with every operator in parentheses
+func sexpr(expr f.Expr) string {
+	switch e := expr.(type) {
+	case f.BinaryExpr:
+		return "(" + e.Operator + " " + sexpr(e.Left) + " " + sexpr(e.Right) + ")"
+	case f.LogicalExpr:
+		return "(" + e.Operator + " " + sexpr(e.Left) + " " + sexpr(e.Right) + ")"
+	case f.UnaryExpr:
+		return "(" + e.Operator + " " + sexpr(e.Operant) + ")"
+	case f.RangeExpr:
+		return "(.. " + sexpr(e.Start) + " " + sexpr(e.End) + ")"
+	case f.AssignmentExpr:
+		return "(= " + sexpr(e.Assignee) + " " + sexpr(e.Value) + ")"
+	case f.MemberExpr:
+		return "(. " + sexpr(e.Object) + " " + sexpr(e.Property) + ")"
+	case f.CallExpr:
+		args := []string{sexpr(e.Caller)}
+		for _, arg := range e.Args {
+			args = append(args, sexpr(arg))
+		}
+		return "(call " + strings.Join(args, " ") + ")"
+	case f.NumericLiteral:
+		return fmt.Sprint(e.Value)
+	case f.Identifier:
+		return e.Symbol
+	}
+	return fmt.Sprintf("<%T>", expr)
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"1 - 2 - 3", "(- (- 1 2) 3)"},
+		{"2 * 3 % 4 / 5", "(/ (% (* 2 3) 4) 5)"},
+		{"-a * b", "(* (- a) b)"},
+		{"!a == b", "(== (! a) b)"},
+		{"a < b == c > d", "(== (< a b) (> c d))"},
+		{"a and b or c", "(or (and a b) c)"},
+		{"a == 1 and b", "(and (== a 1) b)"},
+		{"1..n + 1", "(.. 1 (+ n 1))"},
+		{"x = y = 1 + 2", "(= x (= y (+ 1 2)))"},
+		{"f(1)(2) * a.b", "(* (call (call f 1) 2) (. a b))"},
+		{"(1 + 2) * 3", "(* (+ 1 2) 3)"},
+	}
+	for _, tt := range tests {
+		expr, err := f.ParseExpressionString(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if got := sexpr(expr); got != tt.want {
+			t.Errorf("%q parsed as %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	if _, err := f.ParseExpressionString("xs[0] + 1;"); err != nil {
+		t.Errorf("an expression with a trailing ; failed: %v", err)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 +", "Parse Error at (1, 4)"},
+		{"1 2", `Parse Error at (1, 3): Unexpected "2" (INT) after the end of the expression`},
+		{"a; b", `Parse Error at (1, 4): Unexpected "b" (IDENT) after the end of the expression`},
+	}
+	for _, tt := range tests {
+		_, err := f.ParseExpressionString(tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: got %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
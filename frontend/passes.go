@@ -0,0 +1,282 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+)
+
+////////////
+// Passes //
+////////////
+
+// Pass is one rewrite of a whole program, run after parsing and before the
+// program is evaluated. A pass must not change what the program does, only
+// how much work it takes.
+type Pass struct {
+	Name string
+	Run  func(program Program) (Program, error)
+	// Check, when set, is called with the program before Run rewrites it, to
+	// tell the user about code the pass is going to take out
+	Check func(program Program) []Warning
+}
+
+// Warning is something a pass noticed about a program that doesn't stop it
+// from running, like code that can never run
+type Warning struct {
+	Message string
+	Pos     Position
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("Warning at (%d, %d): %s", w.Pos.Line(), w.Pos.Column(), w.Message)
+}
+
+// PassManager runs passes over a program in the order they were registered
+type PassManager struct {
+	passes   []Pass
+	warnings []Warning
+}
+
+func NewPassManager(passes ...Pass) *PassManager {
+	return &PassManager{passes: passes}
+}
+
+// DefaultPasses are the passes the a0 command runs
+func DefaultPasses() []Pass {
+	return []Pass{ConstantFolding, DeadBranches, UnreachableCode}
+}
+
+// Register adds a pass that runs after the ones already registered
+func (m *PassManager) Register(pass Pass) {
+	m.passes = append(m.passes, pass)
+}
+
+// Run feeds the program through every pass, stopping at the first error
+func (m *PassManager) Run(program Program) (Program, error) {
+	for _, pass := range m.passes {
+		if pass.Check != nil {
+			m.warnings = append(m.warnings, pass.Check(program)...)
+		}
+
+		var err error
+		program, err = pass.Run(program)
+		if err != nil {
+			return Program{}, fmt.Errorf("%s pass: %w", pass.Name, err)
+		}
+	}
+	return program, nil
+}
+
+// Warnings are the warnings from every program Run has been given so far
+func (m *PassManager) Warnings() []Warning {
+	return m.warnings
+}
+
+// rewrite runs exit on every node of program, bottom up
+func rewrite(program Program, exit func(node Stmt) Stmt) Program {
+	return Walk(program, VisitorFuncs{ExitFunc: exit}).(Program)
+}
+
+// ConstantFolding replaces arithmetic on number literals with its result, so
+// 60 * 60 * 24 is computed once instead of every time it runs. Division and
+// modulo by zero are left for the interpreter to report.
+var ConstantFolding = Pass{
+	Name: "constant folding",
+	Run: func(program Program) (Program, error) {
+		return rewrite(program, func(node Stmt) Stmt {
+			binary, ok := node.(BinaryExpr)
+			if !ok {
+				return node
+			}
+			left, leftOk := binary.Left.(NumericLiteral)
+			right, rightOk := binary.Right.(NumericLiteral)
+			if !leftOk || !rightOk {
+				return node
+			}
+
+			switch binary.Operator {
+			case "+":
+				return NumericLiteral{Value: left.Value + right.Value}
+			case "-":
+				return NumericLiteral{Value: left.Value - right.Value}
+			case "*":
+				return NumericLiteral{Value: left.Value * right.Value}
+			case "/":
+				if right.Value != 0 {
+					return NumericLiteral{Value: left.Value / right.Value}
+				}
+			case "%":
+				if right.Value != 0 {
+					return NumericLiteral{Value: math.Mod(left.Value, right.Value)}
+				}
+			}
+			return node
+		}), nil
+	},
+}
+
+// DeadBranches removes if and while statements whose condition is a literal
+// that's always falsy, like if (false) { ... } left in to switch code off
+var DeadBranches = Pass{
+	Name: "dead branches",
+	Run: func(program Program) (Program, error) {
+		return removeDeadBranches(program, func(Warning) {}), nil
+	},
+	Check: func(program Program) []Warning {
+		var warnings []Warning
+		removeDeadBranches(program, func(warning Warning) {
+			warnings = append(warnings, warning)
+		})
+		return warnings
+	},
+}
+
+func removeDeadBranches(program Program, warn func(Warning)) Program {
+	// false and nada are only constants as long as nothing shadows them
+	shadowed := declaredNames(program)
+
+	alwaysFalse := func(condition Expr) bool {
+		switch c := condition.(type) {
+		case NumericLiteral:
+			return c.Value == 0
+		case StringLiteral:
+			return c.Value == ""
+		case Identifier:
+			return (c.Symbol == "false" || c.Symbol == "nada") && !shadowed[c.Symbol]
+		}
+		return false
+	}
+
+	return rewrite(program, func(node Stmt) Stmt {
+		switch n := node.(type) {
+		case IfStmt:
+			if alwaysFalse(n.Condition) {
+				warn(Warning{Message: "the condition is always false, this if never runs", Pos: n.Pos})
+				return nil
+			}
+		case WhileStmt:
+			if alwaysFalse(n.Condition) {
+				warn(Warning{Message: "the condition is always false, this while never runs", Pos: n.Pos})
+				return nil
+			}
+		}
+		return node
+	})
+}
+
+// UnreachableCode removes the statements of a block that come after a
+// return, throw, break or continue, which can never run
+var UnreachableCode = Pass{
+	Name: "unreachable code",
+	Run: func(program Program) (Program, error) {
+		return removeUnreachable(program, func(Warning) {}), nil
+	},
+	Check: func(program Program) []Warning {
+		var warnings []Warning
+		removeUnreachable(program, func(warning Warning) {
+			warnings = append(warnings, warning)
+		})
+		return warnings
+	},
+}
+
+func removeUnreachable(program Program, warn func(Warning)) Program {
+	// cut ends body at the statement that leaves it, warning once about
+	// everything after it
+	cut := func(body []Stmt) []Stmt {
+		for i, stmt := range body[:max(len(body)-1, 0)] {
+			var leaves string
+			switch stmt.(type) {
+			case ReturnStmt:
+				leaves = "return"
+			case ThrowStmt:
+				leaves = "throw"
+			case BreakStmt:
+				leaves = "break"
+			case ContinueStmt:
+				leaves = "continue"
+			default:
+				continue
+			}
+
+			pos := stmt.(Positioned).Position()
+			if next, ok := body[i+1].(Positioned); ok {
+				pos = next.Position()
+			}
+			warn(Warning{Message: fmt.Sprintf("unreachable code after %s", leaves), Pos: pos})
+			return body[:i+1]
+		}
+		return body
+	}
+
+	return Walk(program, VisitorFuncs{ExitFunc: func(node Stmt) Stmt {
+		switch n := node.(type) {
+		case Program:
+			n.Body = cut(n.Body)
+			return n
+		case FunctionDeclaration:
+			n.Body = cut(n.Body)
+			return n
+		case IfStmt:
+			n.Body = cut(n.Body)
+			return n
+		case WhileStmt:
+			n.Body = cut(n.Body)
+			return n
+		case ForStmt:
+			n.Body = cut(n.Body)
+			return n
+		case ForEachStmt:
+			n.Body = cut(n.Body)
+			return n
+		case BenchStmt:
+			n.Body = cut(n.Body)
+			return n
+		case TryStmt:
+			n.Body = cut(n.Body)
+			n.CatchBody = cut(n.CatchBody)
+			n.FinallyBody = cut(n.FinallyBody)
+			return n
+		case MatchExpr:
+			for i := range n.Arms {
+				n.Arms[i].Body = cut(n.Arms[i].Body)
+			}
+			return n
+		}
+		return node
+	}}).(Program)
+}
+
+// declaredNames is every name the program declares anywhere, in any scope
+func declaredNames(program Program) map[string]bool {
+	names := map[string]bool{}
+	declare := func(declared ...string) {
+		for _, name := range declared {
+			names[name] = true
+		}
+	}
+
+	Inspect(program, func(node Stmt) bool {
+		switch n := node.(type) {
+		case VarDeclaration:
+			declare(n.Identifier)
+		case ObjectPattern:
+			declare(n.Names...)
+		case ArrayPattern:
+			declare(n.Names...)
+		case FunctionDeclaration:
+			declare(n.Name)
+			declare(n.Parameters...)
+		case ClassDeclaration:
+			declare(n.Name)
+		case ForEachStmt:
+			declare(n.Key, n.Value)
+		case TryStmt:
+			declare(n.CatchName)
+		case ImportStmt:
+			declare(n.Name)
+		}
+		return true
+	})
+	return names
+}
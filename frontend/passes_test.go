@@ -0,0 +1,175 @@
+package frontend_test
+
+import (
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// runPass parses src and runs pass alone over it, giving back the rewritten
+// program and the warnings the pass had about it
+func runPass(t *testing.T, pass f.Pass, src string) (f.Program, []f.Warning) {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatalf("unexpected error parsing %q: %v", src, err)
+	}
+	manager := f.NewPassManager(pass)
+	program, err = manager.Run(program)
+	if err != nil {
+		t.Fatalf("unexpected error running %s: %v", pass.Name, err)
+	}
+	return program, manager.Warnings()
+}
+
+// declaredValue is the value of the declaration the program starts with
+func declaredValue(t *testing.T, program f.Program) f.Expr {
+	t.Helper()
+	if len(program.Body) == 0 {
+		t.Fatal("the program is empty")
+	}
+	decl, ok := program.Body[0].(f.VarDeclaration)
+	if !ok {
+		t.Fatalf("got %T, want a VarDeclaration", program.Body[0])
+	}
+	return decl.Value
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"val x = 1 + 2", 3},
+		{"val x = 60 * 60 * 24", 86400},
+		{"val x = 10 - 4 / 2", 8},
+		{"val x = 7 % 4", 3},
+		{"val x = (1 + 2) * (3 + 4)", 21},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, warnings := runPass(t, f.ConstantFolding, tt.src)
+			got, ok := declaredValue(t, program).(f.NumericLiteral)
+			if !ok || got.Value != tt.want {
+				t.Errorf("got %#v, want NumericLiteral %v", declaredValue(t, program), tt.want)
+			}
+			if len(warnings) != 0 {
+				t.Errorf("unexpected warnings %v", warnings)
+			}
+		})
+	}
+}
+
+func TestConstantFoldingLeavesTheRest(t *testing.T) {
+	tests := []string{
+		"val x = 1 / 0",
+		"val x = 1 % 0",
+		"val x = y + 1",
+		`val x = "a" + 1`,
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			program, _ := runPass(t, f.ConstantFolding, src)
+			if _, ok := declaredValue(t, program).(f.BinaryExpr); !ok {
+				t.Errorf("got %#v, want the BinaryExpr left alone", declaredValue(t, program))
+			}
+		})
+	}
+}
+
+func TestDeadBranches(t *testing.T) {
+	tests := []struct {
+		src      string
+		kept     int // statements left in the program
+		warnings int
+	}{
+		{"if (false) { println(1) }", 0, 1},
+		{"while (nada) { println(1) }", 0, 1},
+		{`if (0) { println(1) }; if ("") { println(2) }`, 0, 2},
+		{"if (true) { println(1) }", 1, 0},
+		{"if (x) { println(1) }", 1, 0},
+		// false is a variable here, so its value isn't known
+		{"val false = 1; if (false) { println(1) }", 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, warnings := runPass(t, f.DeadBranches, tt.src)
+			if len(program.Body) != tt.kept {
+				t.Errorf("got %d statements, want %d: %#v", len(program.Body), tt.kept, program.Body)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("got %d warnings, want %d: %v", len(warnings), tt.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestDeadBranchesWarningPosition(t *testing.T) {
+	_, warnings := runPass(t, f.DeadBranches, "println(1)\nif (false) {\n}")
+	if len(warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(warnings))
+	}
+	if line := warnings[0].Pos.Line(); line != 2 {
+		t.Errorf("warning on line %d, want 2", line)
+	}
+}
+
+func TestUnreachableCode(t *testing.T) {
+	tests := []struct {
+		src      string
+		kept     int // statements left in the function
+		warnings int
+		leaves   string
+	}{
+		{"fun a() { return 1\nprintln(2)\nprintln(3) }", 1, 1, "return"},
+		{`fun a() { throw error("x")` + "\nprintln(2) }", 1, 1, "throw"},
+		{"fun a() { println(1)\nreturn 1 }", 2, 0, ""},
+		{"fun a() { }", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, warnings := runPass(t, f.UnreachableCode, tt.src)
+			fn, ok := program.Body[0].(f.FunctionDeclaration)
+			if !ok {
+				t.Fatalf("got %T, want a FunctionDeclaration", program.Body[0])
+			}
+			if len(fn.Body) != tt.kept {
+				t.Errorf("got %d statements, want %d: %#v", len(fn.Body), tt.kept, fn.Body)
+			}
+			if len(warnings) != tt.warnings {
+				t.Fatalf("got %d warnings, want %d: %v", len(warnings), tt.warnings, warnings)
+			}
+			if tt.warnings > 0 {
+				want := "unreachable code after " + tt.leaves
+				if warnings[0].Message != want {
+					t.Errorf("got warning %q, want %q", warnings[0].Message, want)
+				}
+				if line := warnings[0].Pos.Line(); line != 2 {
+					t.Errorf("warning on line %d, want 2, the first statement cut", line)
+				}
+			}
+		})
+	}
+}
+
+func TestUnreachableCodeInLoops(t *testing.T) {
+	src := `
+while (true) {
+    break
+    println(1)
+}
+for (x in [1]) {
+    continue
+    println(2)
+}`
+	program, warnings := runPass(t, f.UnreachableCode, src)
+	while := program.Body[0].(f.WhileStmt)
+	forEach := program.Body[1].(f.ForEachStmt)
+	if len(while.Body) != 1 || len(forEach.Body) != 1 {
+		t.Errorf("got %d and %d statements, want 1 and 1", len(while.Body), len(forEach.Body))
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got %d warnings, want 2: %v", len(warnings), warnings)
+	}
+}
@@ -0,0 +1,165 @@
+package frontend
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/////////////////////
+// Semantic Tokens //
+/////////////////////
+
+// SemanticTokenTypes is the legend of the token types SemanticTokens uses,
+// named like the standard types of the language server protocol. Encoded
+// tokens refer to a type by its index in here.
+var SemanticTokenTypes = []string{"keyword", "variable", "number", "string", "operator", "comment"}
+
+// SemanticToken is a token or comment of a source file the way an editor
+// wants it: its type from SemanticTokenTypes, empty for punctuation, and the
+// range it covers with zero based lines and UTF-16 characters like the
+// language server protocol counts them
+type SemanticToken struct {
+	Kind  string        `json:"kind"` // the lexer's name for it, like IDENT or WHILE, or COMMENT
+	Type  string        `json:"type"`
+	Text  string        `json:"text"` // the source text, quotes and all
+	Range SemanticRange `json:"range"`
+}
+
+type SemanticRange struct {
+	Start SemanticPosition `json:"start"`
+	End   SemanticPosition `json:"end"`
+}
+
+type SemanticPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// SemanticTokens classifies the tokens Lex returned for source, along with
+// the comments attached to them, in source order
+func SemanticTokens(source []byte, tokens []TokenItem) []SemanticToken {
+	lines := lineStarts(source)
+	rangeOf := func(start, end Position) SemanticRange {
+		return SemanticRange{
+			Start: lspPosition(source, lines, start.Offset()),
+			End:   lspPosition(source, lines, end.Offset()),
+		}
+	}
+
+	var result []SemanticToken
+	addComments := func(comments []Comment) {
+		for _, comment := range comments {
+			result = append(result, SemanticToken{
+				Kind:  "COMMENT",
+				Type:  "comment",
+				Text:  comment.Text,
+				Range: rangeOf(comment.Pos, comment.End),
+			})
+		}
+	}
+
+	for _, token := range tokens {
+		addComments(token.LeadingComments())
+		if token.Type() != EOF {
+			text := string(source[token.Pos().Offset():token.End().Offset()])
+			result = append(result, SemanticToken{
+				Kind:  token.Type().String(),
+				Type:  semanticType(token.Type(), text),
+				Text:  text,
+				Range: rangeOf(token.Pos(), token.End()),
+			})
+		}
+		addComments(token.TrailingComments())
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].Range.Start, result[j].Range.Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	return result
+}
+
+func semanticType(token Token, text string) string {
+	switch token {
+	case IDENT:
+		return "variable"
+	case INT, FLOAT:
+		return "number"
+	case STRING, CHAR:
+		return "string"
+	case ADD, SUB, MUL, DIV, MOD, RANGE, ARROW, DE, NE, GT, LT, GTE, LTE, EQUALS:
+		return "operator"
+	case NOT, AND, OR:
+		// written as a word they're keywords, as !, && and || operators
+		if first, _ := utf8.DecodeRuneInString(text); unicode.IsLetter(first) {
+			return "keyword"
+		}
+		return "operator"
+	case RETURN, BREAK, CONTINUE, VAR, CONST, IF, FOR, WHILE, FUN, IN, IMPORT,
+		TRY, CATCH, FINALLY, THROW, ASSERT, CLASS, NEW, MATCH:
+		return "keyword"
+	default:
+		return ""
+	}
+}
+
+// EncodeSemanticTokens packs tokens into the flat list of integers the data
+// of a textDocument/semanticTokens response holds: five per token, its line
+// relative to the token before, its start relative to that token's start
+// when they share a line, its length, its type's index in
+// SemanticTokenTypes and no modifiers. Punctuation and tokens spanning
+// several lines are left out, the protocol can't always show those.
+func EncodeSemanticTokens(tokens []SemanticToken) []int {
+	types := make(map[string]int, len(SemanticTokenTypes))
+	for i, name := range SemanticTokenTypes {
+		types[name] = i
+	}
+
+	var data []int
+	var line, character int
+	for _, token := range tokens {
+		index, typed := types[token.Type]
+		start, end := token.Range.Start, token.Range.End
+		if !typed || start.Line != end.Line {
+			continue
+		}
+
+		if start.Line != line {
+			character = 0
+		}
+		data = append(data, start.Line-line, start.Character-character, end.Character-start.Character, index, 0)
+		line, character = start.Line, start.Character
+	}
+	return data
+}
+
+// lineStarts is the offset every line of source starts at, ending lines at
+// \n, \r\n or \r like the lexer does
+func lineStarts(source []byte) []int {
+	starts := []int{0}
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case '\r':
+			if i+1 < len(source) && source[i+1] == '\n' {
+				i++
+			}
+			starts = append(starts, i+1)
+		case '\n':
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lspPosition turns a byte offset into a zero based line and the number of
+// UTF-16 code units before it on that line
+func lspPosition(source []byte, lines []int, offset int) SemanticPosition {
+	line := sort.Search(len(lines), func(i int) bool { return lines[i] > offset }) - 1
+	character := 0
+	for _, r := range string(source[lines[line]:offset]) {
+		character += utf16.RuneLen(r)
+	}
+	return SemanticPosition{Line: line, Character: character}
+}
@@ -0,0 +1,84 @@
+package frontend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//////////////
+// Versions //
+//////////////
+
+// LanguageMajor and LanguageMinor are the version of the a0 language this
+// build reads. A script names the version it was written for with a pragma
+// before any of its code:
+//
+//	#a0 1.x
+//
+// A different major version is refused, a newer minor one gets a warning
+// since the script may use something this build doesn't have yet.
+const (
+	LanguageMajor = 1
+	LanguageMinor = 0
+)
+
+// LanguageVersion is LanguageMajor and LanguageMinor written like 1.0
+var LanguageVersion = fmt.Sprintf("%d.%d", LanguageMajor, LanguageMinor)
+
+// pragmaPrefix starts the version pragma
+const pragmaPrefix = "#a0"
+
+// parseVersion reads the version in a pragma, like 1, 1.x or 1.2. minor is
+// -1 when any minor version will do.
+func parseVersion(text string) (major, minor int, err error) {
+	majorText, minorText, hasMinor := strings.Cut(text, ".")
+	major, err = strconv.Atoi(majorText)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("invalid version %q", text)
+	}
+	if !hasMinor || minorText == "x" {
+		return major, -1, nil
+	}
+	minor, err = strconv.Atoi(minorText)
+	if err != nil || minor < 0 {
+		return 0, 0, fmt.Errorf("invalid version %q", text)
+	}
+	return major, minor, nil
+}
+
+// checkPragma looks for the version pragma among the comments before the
+// first token and checks it against this build, a mismatch that can't run is
+// an error and one that might is a warning
+func (p *Parser) checkPragma() {
+	if len(p.tokens) == 0 {
+		return
+	}
+	for _, comment := range p.tokens[0].leading {
+		rest, ok := strings.CutPrefix(comment.Text, pragmaPrefix)
+		if !ok {
+			continue
+		}
+
+		text := strings.TrimSpace(rest)
+		major, minor, err := parseVersion(text)
+		switch {
+		case err != nil || rest == "" || rest[0] != ' ':
+			p.errors = append(p.errors, &ParsingError{
+				Message: fmt.Sprintf("Invalid version pragma %q, write it like #a0 %d.x or #a0 %s", comment.Text, LanguageMajor, LanguageVersion),
+				Pos:     comment.Pos,
+			})
+		case major != LanguageMajor:
+			p.errors = append(p.errors, &ParsingError{
+				Message: fmt.Sprintf("This script is written for a0 %s but this is a0 %s, which can't run it", text, LanguageVersion),
+				Pos:     comment.Pos,
+			})
+		case minor > LanguageMinor:
+			p.warnings = append(p.warnings, Warning{
+				Message: fmt.Sprintf("this script is written for a0 %s but this is a0 %s, it may use something that's missing here", text, LanguageVersion),
+				Pos:     comment.Pos,
+			})
+		}
+		return
+	}
+}
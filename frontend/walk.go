@@ -0,0 +1,228 @@
+package frontend
+
+//////////
+// Walk //
+//////////
+
+// Visitor is called around every node Walk reaches
+type Visitor interface {
+	// Enter is called before the node's children are walked, returning false
+	// skips them (Exit is still called)
+	Enter(node Stmt) bool
+	// Exit is called after the children and its result takes the node's place
+	// in the tree. Return node to keep it, another node to replace it or nil
+	// to drop it from the list it's in (or leave an empty child behind).
+	Exit(node Stmt) Stmt
+}
+
+// VisitorFuncs builds a Visitor out of plain functions, either can be nil
+type VisitorFuncs struct {
+	EnterFunc func(node Stmt) bool
+	ExitFunc  func(node Stmt) Stmt
+}
+
+func (v VisitorFuncs) Enter(node Stmt) bool {
+	if v.EnterFunc == nil {
+		return true
+	}
+	return v.EnterFunc(node)
+}
+
+func (v VisitorFuncs) Exit(node Stmt) Stmt {
+	if v.ExitFunc == nil {
+		return node
+	}
+	return v.ExitFunc(node)
+}
+
+// Inspect calls visit on every node depth first, in source order, returning
+// false from visit skips the node's children
+func Inspect(node Stmt, visit func(node Stmt) bool) {
+	Walk(node, VisitorFuncs{EnterFunc: visit})
+}
+
+// Walk visits node and everything under it depth first, in source order, and
+// returns the tree with the visitor's replacements applied. Nodes are values,
+// so the tree passed in is never changed. Methods of a class and properties of
+// an object can only be replaced by nodes of the same kind, anything else
+// leaves the original in place.
+func Walk(node Stmt, visitor Visitor) Stmt {
+	if node == nil {
+		return nil
+	}
+	if !visitor.Enter(node) {
+		return visitor.Exit(node)
+	}
+
+	switch n := node.(type) {
+	// Statements
+	case Program:
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case VarDeclaration:
+		n.Pattern = walkExpr(n.Pattern, visitor)
+		n.Value = walkExpr(n.Value, visitor)
+		node = n
+	case FunctionDeclaration:
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case ClassDeclaration:
+		n.Parent = walkExpr(n.Parent, visitor)
+		methods := make([]FunctionDeclaration, 0, len(n.Methods))
+		for _, method := range n.Methods {
+			switch walked := Walk(method, visitor).(type) {
+			case nil:
+			case FunctionDeclaration:
+				methods = append(methods, walked)
+			default:
+				methods = append(methods, method)
+			}
+		}
+		n.Methods = methods
+		node = n
+	case IfStmt:
+		n.Condition = walkExpr(n.Condition, visitor)
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case WhileStmt:
+		n.Condition = walkExpr(n.Condition, visitor)
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case ForStmt:
+		n.Condition = walkExpr(n.Condition, visitor)
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case ForEachStmt:
+		n.Iterable = walkExpr(n.Iterable, visitor)
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+	case ReturnStmt:
+		n.Value = walkExpr(n.Value, visitor)
+		node = n
+	case TryStmt:
+		n.Body = walkStmts(n.Body, visitor)
+		n.CatchBody = walkStmts(n.CatchBody, visitor)
+		n.FinallyBody = walkStmts(n.FinallyBody, visitor)
+		node = n
+	case ThrowStmt:
+		n.Value = walkExpr(n.Value, visitor)
+		node = n
+	case AssertStmt:
+		n.Condition = walkExpr(n.Condition, visitor)
+		n.Message = walkExpr(n.Message, visitor)
+		node = n
+	case BenchStmt:
+		n.Body = walkStmts(n.Body, visitor)
+		node = n
+
+	// Expressions
+	case AssignmentExpr:
+		n.Assignee = walkExpr(n.Assignee, visitor)
+		n.Value = walkExpr(n.Value, visitor)
+		node = n
+	case CallExpr:
+		n.Caller = walkExpr(n.Caller, visitor)
+		n.Args = walkExprs(n.Args, visitor)
+		node = n
+	case NewExpr:
+		n.Class = walkExpr(n.Class, visitor)
+		n.Args = walkExprs(n.Args, visitor)
+		node = n
+	case MatchExpr:
+		n.Subject = walkExpr(n.Subject, visitor)
+		arms := make([]MatchArm, len(n.Arms))
+		for i, arm := range n.Arms {
+			arm.Pattern = walkExpr(arm.Pattern, visitor)
+			arm.Guard = walkExpr(arm.Guard, visitor)
+			arm.Body = walkStmts(arm.Body, visitor)
+			arms[i] = arm
+		}
+		n.Arms = arms
+		node = n
+	case MemberExpr:
+		n.Object = walkExpr(n.Object, visitor)
+		n.Property = walkExpr(n.Property, visitor)
+		node = n
+	case SliceExpr:
+		n.Object = walkExpr(n.Object, visitor)
+		n.Start = walkExpr(n.Start, visitor)
+		n.End = walkExpr(n.End, visitor)
+		node = n
+	case RangeExpr:
+		n.Start = walkExpr(n.Start, visitor)
+		n.End = walkExpr(n.End, visitor)
+		node = n
+	case LogicalExpr:
+		n.Left = walkExpr(n.Left, visitor)
+		n.Right = walkExpr(n.Right, visitor)
+		node = n
+	case BinaryExpr:
+		n.Left = walkExpr(n.Left, visitor)
+		n.Right = walkExpr(n.Right, visitor)
+		node = n
+	case UnaryExpr:
+		n.Operant = walkExpr(n.Operant, visitor)
+		node = n
+
+	// Literals
+	case Property:
+		n.Value = walkExpr(n.Value, visitor)
+		node = n
+	case ObjectLiteral:
+		properties := make([]Property, 0, len(n.Properties))
+		for _, property := range n.Properties {
+			switch walked := Walk(property, visitor).(type) {
+			case nil:
+			case Property:
+				properties = append(properties, walked)
+			default:
+				properties = append(properties, property)
+			}
+		}
+		n.Properties = properties
+		node = n
+	case ArrayLiteral:
+		n.Elements = walkExprs(n.Elements, visitor)
+		node = n
+
+	default:
+		// BreakStmt, ContinueStmt, ImportStmt, the other literals,
+		// identifiers and patterns have no children
+	}
+
+	return visitor.Exit(node)
+}
+
+func walkExpr(expr Expr, visitor Visitor) Expr {
+	walked := Walk(expr, visitor)
+	if walked == nil {
+		return nil
+	}
+	return walked.(Expr)
+}
+
+func walkStmts(stmts []Stmt, visitor Visitor) []Stmt {
+	if stmts == nil {
+		return nil
+	}
+	walked := make([]Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = Walk(stmt, visitor); stmt != nil {
+			walked = append(walked, stmt)
+		}
+	}
+	return walked
+}
+
+func walkExprs(exprs []Expr, visitor Visitor) []Expr {
+	if exprs == nil {
+		return nil
+	}
+	walked := make([]Expr, 0, len(exprs))
+	for _, expr := range exprs {
+		if expr = walkExpr(expr, visitor); expr != nil {
+			walked = append(walked, expr)
+		}
+	}
+	return walked
+}
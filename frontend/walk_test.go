@@ -0,0 +1,109 @@
+package frontend_test
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// describe names a node for the visit order tests
+func describe(node f.Stmt) string {
+	switch n := node.(type) {
+	case f.Identifier:
+		return n.Symbol
+	case f.NumericLiteral:
+		return strconv.FormatFloat(n.Value, 'g', -1, 64)
+	case f.BinaryExpr:
+		return n.Operator
+	}
+	return string(node.NodeType())
+}
+
+func parseProgram(t *testing.T, src string) f.Program {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestWalkOrder(t *testing.T) {
+	program := parseProgram(t, "var x = 1 + 2 * y\nif (x) { f(x, 3) }")
+
+	var entered, exited []string
+	f.Walk(program, f.VisitorFuncs{
+		EnterFunc: func(node f.Stmt) bool {
+			entered = append(entered, describe(node))
+			return true
+		},
+		ExitFunc: func(node f.Stmt) f.Stmt {
+			exited = append(exited, describe(node))
+			return node
+		},
+	})
+
+	wantEntered := []string{"Program", "VarDeclaration", "+", "1", "*", "2", "y", "IfStmt", "x", "CallExpr", "f", "x", "3"}
+	wantExited := []string{"1", "2", "y", "*", "+", "VarDeclaration", "x", "f", "x", "3", "CallExpr", "IfStmt", "Program"}
+	if !slices.Equal(entered, wantEntered) {
+		t.Errorf("entered %q, want %q", entered, wantEntered)
+	}
+	if !slices.Equal(exited, wantExited) {
+		t.Errorf("exited %q, want %q", exited, wantExited)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parseProgram(t, "fun f() { return inner }\nouter")
+
+	var seen []string
+	f.Inspect(program, func(node f.Stmt) bool {
+		seen = append(seen, describe(node))
+		_, isFunction := node.(f.FunctionDeclaration)
+		return !isFunction
+	})
+	if want := []string{"Program", "FunctionDeclaration", "outer"}; !slices.Equal(seen, want) {
+		t.Errorf("saw %q, want %q", seen, want)
+	}
+}
+
+func TestWalkReplaces(t *testing.T) {
+	program := parseProgram(t, "var x = 1 + 1\nprintln(x)\nclass C { fun m() { return 1 } }")
+	original := parseProgram(t, "var x = 1 + 1\nprintln(x)\nclass C { fun m() { return 1 } }")
+
+	walked := f.Walk(program, f.VisitorFuncs{ExitFunc: func(node f.Stmt) f.Stmt {
+		switch n := node.(type) {
+		case f.NumericLiteral:
+			n.Value = 2
+			return n
+		case f.CallExpr:
+			// dropping a statement takes it out of its block
+			return nil
+		case f.FunctionDeclaration:
+			// a method can't become something else, it stays as it was
+			return f.Identifier{Symbol: "not a method"}
+		}
+		return node
+	}}).(f.Program)
+
+	if len(walked.Body) != 2 {
+		t.Fatalf("got %d statements, want the call dropped", len(walked.Body))
+	}
+	sum := walked.Body[0].(f.VarDeclaration).Value.(f.BinaryExpr)
+	if sum.Left.(f.NumericLiteral).Value != 2 || sum.Right.(f.NumericLiteral).Value != 2 {
+		t.Errorf("got %#v, want both ones replaced", sum)
+	}
+	class := walked.Body[1].(f.ClassDeclaration)
+	if len(class.Methods) != 1 || class.Methods[0].Name != "m" {
+		t.Errorf("got methods %#v, want m kept", class.Methods)
+	}
+
+	// the tree walked is left alone
+	if !reflect.DeepEqual(program, original) {
+		t.Error("Walk changed the tree it was given")
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+/////////////////
+// Line Editor //
+/////////////////
+
+// lineReader gives the REPL its input a line at a time
+type lineReader interface {
+	// ReadLine shows prompt and reads one line, without its newline. It
+	// fails with io.EOF once the input has ended and errInterrupted when the
+	// person typing pressed Ctrl-C.
+	ReadLine(prompt string) (string, error)
+}
+
+var errInterrupted = errors.New("interrupted")
+
+// scanLines reads plain lines, for input that isn't a terminal or a terminal
+// the line editor can't drive
+type scanLines struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	prompts bool // show the prompts, only worth it when a person is typing
+}
+
+func (l *scanLines) ReadLine(prompt string) (string, error) {
+	if l.prompts {
+		fmt.Fprint(l.out, prompt)
+	}
+	if !l.in.Scan() {
+		if err := l.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return l.in.Text(), nil
+}
+
+// lineInput reads the lines of a lineReader as a stream of text, without
+// prompts
+type lineInput struct {
+	lines   lineReader
+	pending []byte
+}
+
+func (l *lineInput) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		line, err := l.lines.ReadLine("")
+		if errors.Is(err, errInterrupted) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		l.pending = []byte(line + "\n")
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
+
+// maxHistory is how many lines of history are kept
+const maxHistory = 1000
+
+// lineEditor reads lines from a terminal with the usual editing keys, a
+// history walked with the arrow keys and Tab completion
+type lineEditor struct {
+	in  *os.File
+	key *bufio.Reader
+	out io.Writer
+
+	history []string
+	// historyPath is the file history is kept in between sessions, empty to
+	// keep it only for this one
+	historyPath string
+
+	// complete gives the words that could finish line at pos, and where the
+	// word they replace starts
+	complete func(line []rune, pos int) (start int, words []string)
+}
+
+func newLineEditor(in *os.File, out io.Writer, historyPath string) *lineEditor {
+	e := &lineEditor{
+		in:          in,
+		key:         bufio.NewReader(in),
+		out:         out,
+		historyPath: historyPath,
+	}
+	e.loadHistory()
+	return e
+}
+
+// canEdit reports whether the line editor can drive the terminal in
+func canEdit(in *os.File) bool {
+	restore, err := makeRaw(in.Fd())
+	if err != nil {
+		return false
+	}
+	restore()
+	return true
+}
+
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.in.Fd())
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var line []rune
+	pos := 0
+	// browsing is the history entry shown, len(history) for the line being
+	// typed, which draft keeps while browsing
+	browsing := len(e.history)
+	var draft []rune
+
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(line))
+		if back := len(line) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	show := func(entry []rune) {
+		line = append([]rune(nil), entry...)
+		pos = len(line)
+		redraw()
+	}
+
+	redraw()
+	for {
+		r, _, err := e.key.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			e.addHistory(string(line))
+			return string(line), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D ends the input on an empty line, deletes otherwise
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(line)
+		case 2: // Ctrl-B
+			pos = max(pos-1, 0)
+		case 6: // Ctrl-F
+			pos = min(pos+1, len(line))
+		case 11: // Ctrl-K
+			line = line[:pos]
+		case 21: // Ctrl-U
+			line = line[pos:]
+			pos = 0
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case 16, 14: // Ctrl-P and Ctrl-N
+			browsing, draft = e.browse(r == 16, browsing, draft, line, show)
+			continue
+		case '\t':
+			line, pos = e.completeAt(line, pos)
+		case 27: // Escape starts the arrow and other special keys
+			switch e.escape() {
+			case "A":
+				browsing, draft = e.browse(true, browsing, draft, line, show)
+				continue
+			case "B":
+				browsing, draft = e.browse(false, browsing, draft, line, show)
+				continue
+			case "C":
+				pos = min(pos+1, len(line))
+			case "D":
+				pos = max(pos-1, 0)
+			case "H", "1~", "7~":
+				pos = 0
+			case "F", "4~", "8~":
+				pos = len(line)
+			case "3~": // Delete
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsControl(r) {
+				continue
+			}
+			line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
+
+// escape reads the rest of an escape sequence, giving back what follows the
+// [ or O, like A for the up arrow or 3~ for Delete
+func (e *lineEditor) escape() string {
+	r, _, err := e.key.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+	var seq strings.Builder
+	for {
+		r, _, err := e.key.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq.WriteRune(r)
+		if r < '0' || r > '9' {
+			return seq.String()
+		}
+	}
+}
+
+// browse moves one entry back through the history, or forward with back
+// unset, keeping the line being typed in draft to come back to
+func (e *lineEditor) browse(back bool, browsing int, draft, line []rune, show func([]rune)) (int, []rune) {
+	switch {
+	case back && browsing > 0:
+		if browsing == len(e.history) {
+			draft = append([]rune(nil), line...)
+		}
+		browsing--
+		show([]rune(e.history[browsing]))
+	case !back && browsing < len(e.history):
+		browsing++
+		if browsing == len(e.history) {
+			show(draft)
+		} else {
+			show([]rune(e.history[browsing]))
+		}
+	}
+	return browsing, draft
+}
+
+// completeAt finishes the word before pos when only one word fits, or as
+// far as all the words that fit agree, and lists them when that's nowhere
+func (e *lineEditor) completeAt(line []rune, pos int) ([]rune, int) {
+	if e.complete == nil {
+		return line, pos
+	}
+	start, words := e.complete(line, pos)
+	if len(words) == 0 {
+		return line, pos
+	}
+
+	typed := string(line[start:pos])
+	fill := words[0]
+	for _, word := range words[1:] {
+		fill = commonPrefix(fill, word)
+	}
+	if fill == typed && len(words) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(words, "  "))
+		return line, pos
+	}
+
+	rest := append([]rune(fill), line[pos:]...)
+	line = append(line[:start:start], rest...)
+	return line, start + len([]rune(fill))
+}
+
+func commonPrefix(a, b string) string {
+	ar, br := []rune(a), []rune(b)
+	n := 0
+	for n < len(ar) && n < len(br) && ar[n] == br[n] {
+		n++
+	}
+	return string(ar[:n])
+}
+
+// loadHistory reads the history kept by earlier sessions, a missing file is
+// a first session
+func (e *lineEditor) loadHistory() {
+	if e.historyPath == "" {
+		return
+	}
+	data, err := os.ReadFile(e.historyPath)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		// the file only ever grows, so it's cut back here
+		os.WriteFile(e.historyPath, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	}
+	for _, line := range lines {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+}
+
+// addHistory remembers line, and saves it straight away so a session that
+// doesn't end cleanly still keeps it. Blank lines and repeats are left out.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+
+	if e.historyPath == "" {
+		return
+	}
+	file, err := os.OpenFile(e.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	f "github.com/Mstr0A/a0-lang/frontend"
 	r "github.com/Mstr0A/a0-lang/runtime"
@@ -13,200 +19,490 @@ import (
 // Main Function //
 ///////////////////
 
-// handles statement nodes
-func printStmt(node f.Stmt, indent string, isLast bool) {
-	// tree symbols
-	branch, nextIndent := "├── ", indent+"│   "
-	if isLast {
-		branch, nextIndent = "└── ", indent+"    "
-	}
-
-	switch n := node.(type) {
-	case f.Program:
-		fmt.Println(indent + branch + "Program")
-		for i, stmt := range n.Body {
-			printStmt(stmt, nextIndent, i == len(n.Body)-1)
-		}
-
-	case f.VarDeclaration:
-		fmt.Printf("%s%sVarDeclaration: Name: %s | Constant: %t\n",
-			indent, branch,
-			n.Identifier,
-			n.Constant,
-		)
-		if n.Value != nil {
-			printExpr(n.Value, nextIndent, true)
-		}
-
-	case f.AssignmentExpr:
-		fmt.Printf("%s%sAssignmentExpr\n", indent, branch)
-		printExpr(n.Assignee, nextIndent, false)
-		printExpr(n.Value, nextIndent, true)
-
-	case f.FunctionDeclaration:
-		fmt.Printf("%s%sFunctionDeclaration\n", indent, branch)
-
-		// Name
-		fmt.Printf("%s%sName: %s\n",
-			nextIndent, "└── ", n.Name,
-		)
-
-		// Parameters
-		fmt.Printf("%s├── Parameters\n", nextIndent)
-		for i, param := range n.Parameters {
-			pBranch := "│   ├── "
-			if i == len(n.Parameters)-1 {
-				pBranch = "│   └── "
-			}
-			fmt.Printf("%s%sIdentifier (%s)\n",
-				nextIndent, pBranch, param,
-			)
-		}
+// command is one of the things a0 does, picked by the first argument
+type command struct {
+	name    string
+	args    string // what goes after the flags, for the usage line
+	summary string
+	// setup adds the command's flags to fs and returns what runs it with the
+	// arguments left after them, giving back the exit code
+	setup func(fs *flag.FlagSet) func(args []string) int
+}
 
-		// Body
-		bodyIndent := nextIndent + "    "
-		fmt.Printf("%s└── Body\n", nextIndent)
-		for i, stmt := range n.Body {
-			printStmt(stmt, bodyIndent, i == len(n.Body)-1)
-		}
+// commands are listed by a0 help in this order. They're filled in by init
+// because help and completion read the list themselves.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "<file> [more files...] [--] [args...]\n       a0 run [flags] -e <source> [args...]", "Run a0 programs, the files share one global scope", runCommand},
+		{"repl", "", "Read and run a0 one entry at a time", replCommand},
+		{"test", "[files or folders...]", "Run the test_ functions of *_test.a0 files", testCommand},
+		{"bench", "[files or folders...]", "Time the bench blocks of *_test.a0 files", benchCommand},
+		{"compile", "<file> [more files...]", "Compile files to .a0c bytecode next to them", compileCommand},
+		{"fmt", "[files...]", "Format source files, or standard input", fmtCommand},
+		{"doc", "<file or folder> [more...]", "Write API docs from /// comments", docCommand},
+		{"tokens", "<file> [more files...]", "Print the tokens of source files", tokensCommand},
+		{"ast", "<file> [more files...]", "Print the AST of source files", astCommand},
+		{"completion", "bash | zsh | fish", "Print a shell completion script", completionCommand},
+		{"version", "", "Print the language version and what a0 was built from", versionCommand},
+		{"help", "[command]", "Show the commands or the flags of one", helpCommand},
+	}
+}
 
-	case f.CallExpr:
-		// Treat bare CallExpr as a statement
-		fmt.Printf("%s%sCallExpr\n", indent, branch)
-		printExpr(n.Caller, nextIndent, false)
-		for i, arg := range n.Args {
-			printExpr(arg, nextIndent, i == len(n.Args)-1)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
+	}
+	return command{}, false
+}
 
-	case f.ObjectLiteral:
-		fmt.Printf("%s%sObjectLiteral\n", indent, branch)
-		for i, prop := range n.Properties {
-			propBranch := "├── "
-			if i == len(n.Properties)-1 {
-				propBranch = "└── "
-			}
-			fmt.Printf("%s%sProperty: Key: %s\n",
-				nextIndent, propBranch, prop.Key,
-			)
-			// property value is an Expr
-			printExpr(prop.Value, nextIndent+"│   ", i == len(n.Properties)-1)
+// newFlagSet gives cmd a flag set of its own, with usage text listing its
+// flags for -h and mistakes
+func newFlagSet(cmd command) (*flag.FlagSet, func(args []string) int) {
+	fs := flag.NewFlagSet("a0 "+cmd.name, flag.ExitOnError)
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: a0 %s", cmd.name)
+		if hasFlags(fs) {
+			fmt.Fprint(out, " [flags]")
+		}
+		if cmd.args != "" {
+			fmt.Fprint(out, " "+cmd.args)
+		}
+		fmt.Fprintf(out, "\n\n%s.\n", cmd.summary)
+		if hasFlags(fs) {
+			fmt.Fprint(out, "\nFlags:\n")
+			fs.PrintDefaults()
 		}
+	}
+	return fs, run
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
 
+func main() {
+	// a0 file.a0 and a0 -e source are short for a0 run, and a0 on its own
+	// starts the REPL
+	args := os.Args[1:]
+	cmd, _ := findCommand("run")
+	switch {
+	case len(args) == 0:
+		cmd, _ = findCommand("repl")
+	case args[0] == "-h" || args[0] == "-help" || args[0] == "--help":
+		printHelp(os.Stdout)
+		return
 	default:
-		fmt.Printf("%s%sUnknown stmt node of type %T\n", indent, branch, n)
+		if found, ok := findCommand(args[0]); ok {
+			cmd, args = found, args[1:]
+		}
 	}
+
+	fs, run := newFlagSet(cmd)
+	fs.Parse(args)
+	os.Exit(run(fs.Args()))
 }
 
-// handles expression nodes
-func printExpr(node f.Expr, indent string, isLast bool) {
-	branch, nextIndent := "├── ", indent+"│   "
-	if isLast {
-		branch, nextIndent = "└── ", indent+"    "
+// printHelp lists every command with what it does
+func printHelp(w io.Writer) {
+	fmt.Fprintln(w, "a0 runs a0 programs and the tools around them.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: a0 <command> [flags] [arguments]")
+	fmt.Fprintln(w, "       a0 [flags] <file> [args...]   (same as a0 run)")
+	fmt.Fprintln(w, "       a0                            (same as a0 repl)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "a0 help <command>" for the flags of a command.`)
+}
 
-	switch n := node.(type) {
-	case f.Identifier:
-		fmt.Printf("%s%sIdentifier (%s)\n", indent, branch, n.Symbol)
-
-	case f.NumericLiteral:
-		fmt.Printf("%s%sNumericLiteral (%f)\n", indent, branch, n.Value)
+func helpCommand(fs *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		if len(args) == 0 {
+			printHelp(os.Stdout)
+			return 0
+		}
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "a0 help: unknown command %q\n", args[0])
+			return 2
+		}
+		fs, _ := newFlagSet(cmd)
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return 0
+	}
+}
 
-	case f.BinaryExpr:
-		fmt.Printf("%s%sBinaryExpr (Operator: %s)\n", indent, branch, n.Operator)
-		printExpr(n.Left, nextIndent, false)
-		printExpr(n.Right, nextIndent, true)
+///////////
+// Flags //
+///////////
 
-	case f.LogicalExpr:
-		fmt.Printf("%s%sLogicalExpr (Operator: %s)\n", indent, branch, n.Operator)
-		printExpr(n.Left, nextIndent, false)
-		printExpr(n.Right, nextIndent, true)
+// sourceFlags are how every command that reads a0 source lexes it
+type sourceFlags struct {
+	keywordsPath   *string
+	tabWidth       *int
+	strictKeywords *bool
+}
 
-	case f.UnaryExpr:
-		fmt.Printf("%s%sUnaryExpr (Operator: %s)\n", indent, branch, n.Operator)
-		printExpr(n.Operant, nextIndent, true)
+func addSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		keywordsPath:   fs.String("keywords", "", "Load keyword aliases from a config file"),
+		tabWidth:       fs.Int("tab-width", f.DefaultTabWidth, "Columns between tab stops, for the positions in error messages"),
+		strictKeywords: fs.Bool("strict-keywords", false, "Only accept the canonical keywords, not aliases like funky or perhaps"),
+	}
+}
 
-	case f.CallExpr:
-		fmt.Printf("%s%sCallExpr\n", indent, branch)
-		printExpr(n.Caller, nextIndent, false)
-		for i, arg := range n.Args {
-			printExpr(arg, nextIndent, i == len(n.Args)-1)
+// lexers loads the keyword aliases and returns a function making lexers set
+// up the way the flags ask, along with the keywords for the cache key
+func (s *sourceFlags) lexers() (func(data []byte) *f.Lexer, f.KeywordTable, error) {
+	var keywords f.KeywordTable
+	if *s.keywordsPath != "" {
+		var err error
+		if keywords, err = loadKeywords(*s.keywordsPath); err != nil {
+			return nil, nil, err
 		}
+	}
+	newLexer := func(data []byte) *f.Lexer {
+		lexer := f.NewLexer(bytes.NewReader(data))
+		lexer.SetTabWidth(*s.tabWidth)
+		if keywords != nil {
+			lexer.UseKeywords(keywords)
+		}
+		if *s.strictKeywords {
+			lexer.UseStrictKeywords()
+		}
+		return lexer
+	}
+	return newLexer, keywords, nil
+}
 
-	default:
-		fmt.Printf("%s%sUnknown expr node of type %T\n", indent, branch, n)
+// the default keywords with the aliases from a config file applied on top
+func loadKeywords(path string) (f.KeywordTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
+
+	keywords := f.DefaultKeywords()
+	if err := keywords.Load(file); err != nil {
+		return nil, err
+	}
+	return keywords, nil
 }
 
-func printAST(root f.Stmt) {
-	printStmt(root, "", true)
+// parseFlags are how the commands that run programs prepare them
+type parseFlags struct {
+	noCache  *bool
+	noPasses *bool
 }
 
-func main() {
-	///////////
-	// Flags //
-	///////////
+func addParseFlags(fs *flag.FlagSet) *parseFlags {
+	return &parseFlags{
+		noCache:  fs.Bool("no-cache", false, "Parse every file again instead of reusing the cached result of an earlier run"),
+		noPasses: fs.Bool("no-passes", false, "Run the program as parsed, without constant folding or dead code removal"),
+	}
+}
 
-	showTokens := flag.Bool("tokens", false, "Print the token list")
-	showAst := flag.Bool("ast", false, "Print the AST")
-	flag.Parse()
+// runtimeFlags are the limits and settings of the environment programs run in
+type runtimeFlags struct {
+	fs         *flag.FlagSet
+	seed       *int64
+	maxSteps   *int
+	timeout    *time.Duration
+	maxMemory  *int64
+	maxDepth   *int
+	warnShadow *bool
+	policy     r.Policy
+}
 
-	if len(flag.Args()) < 1 {
-		fmt.Println("Usage: yourlang [options] <file>")
-		flag.PrintDefaults()
-		os.Exit(1)
+func addRuntimeFlags(fs *flag.FlagSet) *runtimeFlags {
+	o := &runtimeFlags{
+		fs:         fs,
+		seed:       fs.Int64("seed", 0, "Seed the random module so runs are reproducible"),
+		maxSteps:   fs.Int("max-steps", 0, "Stop the program with an error after this many evaluation steps, 0 for no limit"),
+		timeout:    fs.Duration("timeout", 0, "Stop the program with an error once it has run this long, like 5s or 2m"),
+		maxMemory:  fs.Int64("max-memory", 0, "Stop the program with an error once the values it makes add up to more than this many megabytes, 0 for no limit"),
+		maxDepth:   fs.Int("max-depth", r.DefaultMaxDepth, "Maximum depth of nested calls, 0 for no limit"),
+		warnShadow: fs.Bool("warn-shadow", false, "Warn when a declaration shadows a global or a parameter"),
 	}
+	fs.Func("allow", "Only give programs these natives, modules and groups like @env, comma separated", policyList(&o.policy.Allow))
+	fs.Func("deny", "Keep these natives, modules and groups like @filesystem from programs, comma separated", policyList(&o.policy.Deny))
+	return o
+}
 
-	//////////
-	// File //
-	//////////
+// policyList reads a comma separated list of policy entries into list
+func policyList(list *[]string) func(string) error {
+	return func(value string) error {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				*list = append(*list, entry)
+			}
+		}
+		return r.Policy{Allow: *list}.Check()
+	}
+}
 
-	filePath := flag.Args()[0]
-	file, err := os.Open(filePath)
-	if err != nil {
-		panic(err)
+// deadline is the context -timeout stops programs with, nil without one
+func (o *runtimeFlags) deadline() (context.Context, context.CancelFunc) {
+	if *o.timeout <= 0 {
+		return nil, func() {}
 	}
-	defer file.Close()
+	return context.WithTimeout(context.Background(), *o.timeout)
+}
 
-	///////////
-	// Lexer //
-	///////////
+// newEnv makes a global scope set up the way the flags ask, programs in it
+// see args in os.args and stop once ctx is done
+func (o *runtimeFlags) newEnv(args []string, ctx context.Context, strictKeywords bool) *r.Environment {
+	env := r.NewEnvironment(nil)
+	env.SetArgs(args)
+	if isFlagSet(o.fs, "seed") {
+		env.SeedRandom(uint64(*o.seed))
+	}
+	env.SetMaxDepth(*o.maxDepth)
+	env.SetMaxSteps(*o.maxSteps)
+	env.SetMaxMemory(*o.maxMemory << 20)
+	if ctx != nil {
+		env.SetContext(ctx)
+	}
+	env.SetStrictKeywords(strictKeywords)
+	if *o.warnShadow {
+		env.SetShadowWarnings(os.Stderr)
+	}
+	if len(o.policy.Allow) > 0 || len(o.policy.Deny) > 0 {
+		// the flags already checked the groups
+		env.SetPolicy(o.policy)
+	}
+	return env
+}
 
-	lexer := f.NewLexer(file)
-	tokenList, err := lexer.Lex()
-	if err != nil {
-		fmt.Println(err)
-		return
+// isFlagSet reports whether a flag was given on the command line, so zero
+// values can still be passed explicitly
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+///////////
+// Files //
+///////////
+
+// sourceFile is one of the files making up the program, path is empty for
+// source given with -e or read from standard input
+type sourceFile struct {
+	path   string
+	reader io.Reader
+}
+
+// openSources opens every path, close closes the ones that were opened
+func openSources(paths []string) (sources []sourceFile, close func(), err error) {
+	var files []*os.File
+	close = func() {
+		for _, file := range files {
+			file.Close()
+		}
 	}
-	if *showTokens {
-		fmt.Println("Tokens:")
-		for _, tok := range tokenList {
-			fmt.Println(tok)
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			close()
+			return nil, nil, err
 		}
+		files = append(files, file)
+		sources = append(sources, sourceFile{path: path, reader: file})
 	}
+	return sources, close, nil
+}
 
-	//////////////////////////
-	// Parser & Interpreter //
-	//////////////////////////
+// reporter prints the errors of sources, only saying which file they're
+// from when there's a choice
+func reporter(sources []sourceFile) func(source sourceFile, err error) {
+	return func(source sourceFile, err error) {
+		if len(sources) > 1 {
+			fmt.Printf("In %s:\n", source.path)
+		}
+		fmt.Println(err)
+	}
+}
 
-	parser := f.NewParser(tokenList)
-	program, err := parser.ProduceAst()
+// splitFiles separates the source files at the start of args from the
+// arguments meant for the program. The first argument is always a file, the
+// ones after it are too as long as they end in .a0 or .a0c, and -- ends the
+// files early so an argument ending in .a0 can still reach the program.
+func splitFiles(args []string) (files []string, programArgs []string) {
+	files = []string{args[0]}
+	rest := args[1:]
+	for len(rest) > 0 && (filepath.Ext(rest[0]) == ".a0" || isCompiledFile(rest[0])) {
+		files = append(files, rest[0])
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == "--" {
+		rest = rest[1:]
+	}
+	return files, rest
+}
+
+// isCompiledFile reports whether path is a program compiled by a0 compile
+func isCompiledFile(path string) bool {
+	return filepath.Ext(path) == ".a0c"
+}
+
+////////////////////
+// Lexer & Parser //
+////////////////////
+
+// parsePrograms gets every source ready to run: lexed, parsed and through
+// the passes, or taken from the cache when it was before, and with compile
+// turned into bytecode too. Compiled files are read as they are. Every file
+// is parsed before any of them runs, so a syntax error in a later file
+// doesn't leave the program half done, ok is false when any of them had one.
+func parsePrograms(sources []sourceFile, src *sourceFlags, parse *parseFlags, compile bool) (programs []f.Program, chunks []*r.Chunk, ok bool) {
+	report := reporter(sources)
+	newLexer, keywords, err := src.lexers()
 	if err != nil {
 		fmt.Println(err)
-		return
+		return nil, nil, false
 	}
-	if *showAst {
-		fmt.Println("AST:")
-		printAST(program)
+
+	var cache *programCache
+	if !*parse.noCache {
+		cache = openCache(fmt.Sprintf("tab-width=%d strict=%t no-passes=%t keywords=%v", *src.tabWidth, *src.strictKeywords, *parse.noPasses, keywords))
 	}
 
-	if *showAst || *showTokens {
-		return
+	programs = make([]f.Program, len(sources))
+	chunks = make([]*r.Chunk, len(sources))
+	ok = true
+	for i, source := range sources {
+		if isCompiledFile(source.path) {
+			chunk, err := r.ReadChunk(source.reader)
+			if err != nil {
+				report(source, err)
+				ok = false
+				continue
+			}
+			chunks[i] = chunk
+			continue
+		}
+
+		data, err := io.ReadAll(source.reader)
+		if err != nil {
+			report(source, err)
+			ok = false
+			continue
+		}
+
+		// source given with -e isn't worth keeping
+		var warnings []string
+		cached := false
+		if cache != nil && source.path != "" {
+			programs[i], warnings, cached = cache.load(data)
+		}
+		if !cached {
+			tokenList, err := newLexer(data).Lex()
+			if err != nil {
+				report(source, err)
+				ok = false
+				continue
+			}
+			parser := f.NewParser(tokenList)
+			programs[i], err = parser.ProduceAst()
+			if err != nil {
+				report(source, err)
+				ok = false
+				continue
+			}
+			for _, warning := range parser.Warnings() {
+				warnings = append(warnings, warning.String())
+			}
+
+			if !*parse.noPasses {
+				passes := f.NewPassManager(f.DefaultPasses()...)
+				programs[i], err = passes.Run(programs[i])
+				if err != nil {
+					report(source, err)
+					ok = false
+					continue
+				}
+				for _, warning := range passes.Warnings() {
+					warnings = append(warnings, warning.String())
+				}
+			}
+
+			if cache != nil && source.path != "" {
+				cache.store(data, programs[i], warnings)
+			}
+		}
+		if len(warnings) > 0 {
+			if len(sources) > 1 {
+				fmt.Fprintf(os.Stderr, "In %s:\n", source.path)
+			}
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+		}
+
+		if compile {
+			chunks[i], err = r.Compile(programs[i])
+			if err != nil {
+				report(source, err)
+				ok = false
+			}
+		}
 	}
+	return programs, chunks, ok
+}
 
-	env := r.NewEnvironment(nil)
-	_, err = r.Evaluate(program, env)
+// parseEach lexes and parses every source as written, without the passes or
+// the cache, and hands the results to use. It reports whether all of them
+// parsed and use returned no error, the errors are printed.
+func parseEach(sources []sourceFile, src *sourceFlags, use func(source sourceFile, program f.Program) error) bool {
+	report := reporter(sources)
+	newLexer, _, err := src.lexers()
 	if err != nil {
 		fmt.Println(err)
-		return
+		return false
+	}
+
+	ok := true
+	for _, source := range sources {
+		data, err := io.ReadAll(source.reader)
+		if err != nil {
+			report(source, err)
+			ok = false
+			continue
+		}
+		tokenList, err := newLexer(data).Lex()
+		if err != nil {
+			report(source, err)
+			ok = false
+			continue
+		}
+		program, err := f.NewParser(tokenList).ProduceAst()
+		if err != nil {
+			report(source, err)
+			ok = false
+			continue
+		}
+		if err := use(source, program); err != nil {
+			report(source, err)
+			ok = false
+		}
 	}
+	return ok
 }
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// the example program shipped with the repo keeps running the same way
+func TestExampleProgram(t *testing.T) {
+	testutil.RunGolden(t, "main.a0.example")
+}
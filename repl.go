@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+)
+
+//////////
+// REPL //
+//////////
+
+// a0 repl reads entries from standard input and runs them one at a time in
+// the same global scope, showing the value of each expression. Entries
+// starting with : are commands to the REPL itself, see replCommands.
+func replCommand(fs *flag.FlagSet) func(args []string) int {
+	src := addSourceFlags(fs)
+	parse := addParseFlags(fs)
+	limits := addRuntimeFlags(fs)
+	historyPath := fs.String("history", defaultHistoryPath(), "Keep the history of the REPL in this file between sessions, empty to keep none")
+
+	return func(args []string) int {
+		newLexer, keywords, err := src.lexers()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if keywords == nil {
+			keywords = f.DefaultKeywords()
+		}
+		if *src.strictKeywords {
+			keywords = f.StrictKeywords()
+		}
+
+		s := &repl{
+			out: os.Stdout,
+			newEnv: func() *r.Environment {
+				return limits.newEnv(args, nil, *src.strictKeywords)
+			},
+			newLexer: newLexer,
+			keywords: slices.Sorted(maps.Keys(keywords)),
+			src:      src,
+			parse:    parse,
+			timeout:  *limits.timeout,
+		}
+		if info, err := os.Stdin.Stat(); err == nil {
+			s.interactive = info.Mode()&os.ModeCharDevice != 0
+		}
+
+		// a person at a terminal gets the line editor, anything else is read
+		// as plain lines. Those are read ahead, so input() in the programs
+		// has to take its lines from the REPL too.
+		if s.interactive && canEdit(os.Stdin) {
+			editor := newLineEditor(os.Stdin, os.Stdout, *historyPath)
+			editor.complete = s.complete
+			s.lines = editor
+		} else {
+			s.lines = &scanLines{in: bufio.NewScanner(os.Stdin), out: os.Stdout, prompts: s.interactive}
+			s.input = &lineInput{lines: s.lines}
+		}
+		s.reset()
+		return s.run()
+	}
+}
+
+// repl is one session of a0 repl
+type repl struct {
+	lines    lineReader
+	input    io.Reader // what input() reads when it can't be standard input
+	out      io.Writer
+	env      *r.Environment
+	newEnv   func() *r.Environment
+	builtins map[string]bool // globals env starts out with, :env leaves them out
+	newLexer func(data []byte) *f.Lexer
+	keywords []string // offered by Tab completion along with the globals
+	// src and parse are how :load reads files
+	src   *sourceFlags
+	parse *parseFlags
+	// timeout is how long each entry may run, the session itself has no limit
+	timeout time.Duration
+	// interactive is set when a person is typing, prompts are only shown then
+	interactive bool
+}
+
+// run reads and runs entries until the input ends or one calls exit,
+// giving back the exit code
+func (s *repl) run() int {
+	if s.interactive {
+		fmt.Fprintln(s.out, "a0 repl, :help for commands, Ctrl-D to leave")
+	}
+	for {
+		entry, ok := s.read()
+		if !ok {
+			return 0
+		}
+		var code int
+		var exited bool
+		if strings.HasPrefix(strings.TrimSpace(entry), ":") {
+			code, exited = s.command(entry)
+		} else {
+			code, exited = s.eval(entry)
+		}
+		if exited {
+			return code
+		}
+	}
+}
+
+// reset starts over in a new global scope
+func (s *repl) reset() {
+	s.env = s.newEnv()
+	if s.input != nil {
+		s.env.SetInput(s.input)
+	}
+	s.builtins = make(map[string]bool)
+	for _, name := range s.env.Names() {
+		s.builtins[name] = true
+	}
+}
+
+// read reads one entry, which goes on over more lines while a bracket is
+// left open. ok is false once the input has ended. Ctrl-C drops the entry
+// and starts a new one.
+func (s *repl) read() (entry string, ok bool) {
+	var lines strings.Builder
+	prompt := "> "
+	for {
+		line, err := s.lines.ReadLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			lines.Reset()
+			prompt = "> "
+			continue
+		}
+		if err != nil {
+			break
+		}
+		lines.WriteString(line)
+		lines.WriteString("\n")
+		if !s.unfinished(lines.String()) {
+			return lines.String(), true
+		}
+		prompt = "... "
+	}
+	if s.interactive {
+		fmt.Fprintln(s.out)
+	}
+	return lines.String(), lines.Len() > 0
+}
+
+// unfinished reports whether source stops with brackets still open. Source
+// that doesn't lex is finished, running it reports the error.
+func (s *repl) unfinished(source string) bool {
+	tokens, err := s.newLexer([]byte(source)).Lex()
+	if err != nil {
+		return false
+	}
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type() {
+		case f.OPENCURLY, f.OPENPAREN, f.OPENBRACKET:
+			depth++
+		case f.CLOSECURLY, f.CLOSEPAREN, f.CLOSEBRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// eval runs one entry and shows its value or its error. exited is set when
+// it called exit, with the code it asked for.
+func (s *repl) eval(entry string) (code int, exited bool) {
+	program, ok := s.parseEntry(entry)
+	if !ok || len(program.Body) == 0 {
+		return 0, false
+	}
+	value, err := s.evaluate(program, nil)
+	if err != nil {
+		return s.fail(err)
+	}
+
+	// declarations and statements are quiet, only expressions show a value
+	switch program.Body[len(program.Body)-1].(type) {
+	case f.VarDeclaration, f.FunctionDeclaration, f.ClassDeclaration, f.ImportStmt, f.AssignmentExpr,
+		f.IfStmt, f.WhileStmt, f.ForStmt, f.ForEachStmt, f.TryStmt, f.BenchStmt:
+		return 0, false
+	}
+	if value != nil {
+		if _, isNada := value.(r.NadaVal); !isNada {
+			fmt.Fprintln(s.out, showValue(value))
+		}
+	}
+	return 0, false
+}
+
+// parseEntry lexes and parses source typed into the REPL, printing what's
+// wrong with it when it can't
+func (s *repl) parseEntry(source string) (program f.Program, ok bool) {
+	tokens, err := s.newLexer([]byte(source)).Lex()
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return program, false
+	}
+	program, err = f.NewParser(tokens).ProduceAst()
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return program, false
+	}
+	return program, true
+}
+
+// evaluate runs program, or chunk when there is one, within the timeout
+func (s *repl) evaluate(program f.Program, chunk *r.Chunk) (r.RuntimeVal, error) {
+	if s.timeout > 0 {
+		// the environment lets go of the deadline before it's cancelled, so
+		// the next entry doesn't start out stopped
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		s.env.SetContext(ctx)
+		defer func() {
+			s.env.SetContext(context.Background())
+			cancel()
+		}()
+	}
+	if chunk != nil {
+		return r.Execute(chunk, s.env)
+	}
+	return r.Evaluate(program, s.env)
+}
+
+// fail shows err, unless it's the program calling exit which ends the session
+func (s *repl) fail(err error) (code int, exited bool) {
+	var exit *r.ExitError
+	if errors.As(err, &exit) {
+		return exit.Code, true
+	}
+	fmt.Fprintln(s.out, err)
+	return 0, false
+}
+
+///////////////////
+// REPL Commands //
+///////////////////
+
+// replCommands are what the REPL does with entries starting with :, in the
+// order :help lists them
+var replCommands = []struct {
+	name, args, summary string
+}{
+	{":load", "file.a0", "run a file in the session, its declarations stay around"},
+	{":save", "file", "write the variables declared so far to a file"},
+	{":restore", "file", "bring back the variables a :save wrote"},
+	{":env", "", "list the variables declared so far"},
+	{":type", "expr", "show the type of an expression's value"},
+	{":ast", "source", "show the syntax tree of some source without running it"},
+	{":reset", "", "forget everything and start over"},
+	{":help", "", "list these commands"},
+}
+
+// command runs one of the replCommands
+func (s *repl) command(entry string) (code int, exited bool) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(entry), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":load":
+		if arg == "" {
+			fmt.Fprintln(s.out, "usage: :load file.a0")
+			return 0, false
+		}
+		return s.load(arg)
+	case ":save", ":restore":
+		if arg == "" {
+			fmt.Fprintf(s.out, "usage: %s file\n", name)
+			return 0, false
+		}
+		if err := s.saveState(name == ":save", arg); err != nil {
+			fmt.Fprintln(s.out, err)
+		}
+	case ":env":
+		s.showEnv()
+	case ":type":
+		program, ok := s.parseEntry(arg)
+		if !ok {
+			return 0, false
+		}
+		if len(program.Body) == 0 {
+			fmt.Fprintln(s.out, "usage: :type expr")
+			return 0, false
+		}
+		value, err := s.evaluate(program, nil)
+		if err != nil {
+			return s.fail(err)
+		}
+		fmt.Fprintln(s.out, r.TypeName(value))
+	case ":ast":
+		program, ok := s.parseEntry(arg)
+		if ok {
+			f.PrintAST(s.out, program)
+		}
+	case ":reset":
+		s.reset()
+	case ":help":
+		for _, cmd := range replCommands {
+			usage := strings.TrimSpace(cmd.name + " " + cmd.args)
+			fmt.Fprintf(s.out, "  %-14s %s\n", usage, cmd.summary)
+		}
+	default:
+		fmt.Fprintf(s.out, "Unknown command %s, :help lists them\n", name)
+	}
+	return 0, false
+}
+
+// load runs the file at path in the session's global scope, read the same
+// way a0 run reads it, with imports inside it found relative to it
+func (s *repl) load(path string) (code int, exited bool) {
+	sources, close, err := openSources([]string{path})
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return 0, false
+	}
+	defer close()
+	programs, chunks, ok := parsePrograms(sources, s.src, s.parse, false)
+	if !ok {
+		return 0, false
+	}
+
+	s.env.SetFile(path)
+	defer s.env.SetFile("")
+	if _, err := s.evaluate(programs[0], chunks[0]); err != nil {
+		return s.fail(err)
+	}
+	return 0, false
+}
+
+// saveState writes the session's variables to the file at path, or reads
+// them back from it when save is unset
+func (s *repl) saveState(save bool, path string) error {
+	if !save {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return s.env.LoadState(file)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.env.SaveState(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// showEnv lists the globals declared in the session, the builtins left out
+func (s *repl) showEnv() {
+	for _, name := range s.env.Names() {
+		if s.builtins[name] {
+			continue
+		}
+		value, err := s.env.LookupVar(name)
+		if err != nil {
+			continue
+		}
+		keyword := "var"
+		if s.env.IsConstant(name) {
+			keyword = "const"
+		}
+		fmt.Fprintf(s.out, "%s %s = %s\n", keyword, name, showValue(value))
+	}
+}
+
+// defaultHistoryPath is ~/.a0_history, or nowhere without a home folder
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".a0_history")
+}
+
+// complete gives what Tab can finish the word before pos in line with: the
+// globals and keywords, the properties of an object after a dot, or the
+// REPL's own commands after a :
+func (s *repl) complete(line []rune, pos int) (start int, words []string) {
+	start = wordStart(line, pos)
+	prefix := string(line[start:pos])
+
+	var options []string
+	switch {
+	case start == 1 && line[0] == ':':
+		for _, cmd := range replCommands {
+			options = append(options, strings.TrimPrefix(cmd.name, ":"))
+		}
+	case start > 0 && line[start-1] == '.':
+		name := string(line[wordStart(line, start-1) : start-1])
+		value, err := s.env.LookupVar(name)
+		if err != nil {
+			return start, nil
+		}
+		object, isObject := value.(r.ObjectVal)
+		if !isObject {
+			return start, nil
+		}
+		options = slices.Sorted(maps.Keys(object.Properties))
+	case prefix == "":
+		return start, nil
+	default:
+		options = append(s.env.Names(), s.keywords...)
+		slices.Sort(options)
+		options = slices.Compact(options)
+	}
+
+	for _, option := range options {
+		if strings.HasPrefix(option, prefix) {
+			words = append(words, option)
+		}
+	}
+	return start, words
+}
+
+// wordStart finds where the name ending at pos in line starts
+func wordStart(line []rune, pos int) int {
+	start := pos
+	for start > 0 {
+		c := line[start-1]
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) && !unicode.Is(unicode.Mn, c) {
+			break
+		}
+		start--
+	}
+	return start
+}
+
+// showValue is how the REPL shows a value, strings and chars quoted so they
+// can be told apart from numbers and names
+func showValue(value r.RuntimeVal) string {
+	switch v := value.(type) {
+	case r.StringVal:
+		return strconv.Quote(v.Value)
+	case r.CharVal:
+		return strconv.QuoteRune(v.Value)
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+////////////////
+// Benchmarks //
+////////////////
+
+// BenchPrefix starts the name of the functions RunBenchmarks runs besides the
+// bench blocks
+const BenchPrefix = "bench_"
+
+// DefaultBenchTime is how long RunBenchmarks keeps calling each benchmark
+// when it isn't told otherwise
+const DefaultBenchTime = time.Second
+
+// BenchResult is what one benchmark function measured. Runs is how many
+// times it was called in the final round, the per call figures are averages
+// over those calls. When a call fails Message says why and Line and Column
+// where, and nothing is measured.
+type BenchResult struct {
+	Name        string
+	Runs        int
+	PerOp       time.Duration
+	BytesPerOp  uint64
+	AllocsPerOp uint64
+	Message     string
+	Line        int
+	Column      int
+}
+
+func (b BenchResult) Failed() bool {
+	return b.Message != ""
+}
+
+// maxBenchRuns keeps a benchmark that does nothing from being called forever
+const maxBenchRuns = 1_000_000_000
+
+// RunBenchmarks runs program in env and then each of its bench "name" { ... }
+// blocks and top level functions named bench_something, in the order they're
+// declared. A block runs like the body of a function declared where it is,
+// its name is the one given in the string. Like Go's
+// benchmarks every one is called once, then in bigger and bigger rounds
+// until a round takes at least benchTime, and that round is the one
+// measured. err is set when the program itself fails or a benchmark hits
+// os.exit or a limit, which ends the run.
+func RunBenchmarks(program f.Program, env *Environment, benchTime time.Duration) ([]BenchResult, error) {
+	if _, err := Evaluate(program, env); err != nil {
+		return nil, err
+	}
+
+	var results []BenchResult
+	for _, stmt := range program.Body {
+		var name string
+		var fn RuntimeVal
+		switch s := stmt.(type) {
+		case f.BenchStmt:
+			name = s.Name
+			fn = UserFunctionValue{Name: s.Name, DeclarationEnv: env, Body: s.Body}
+		case f.FunctionDeclaration:
+			if !strings.HasPrefix(s.Name, BenchPrefix) {
+				continue
+			}
+			var err error
+			name = s.Name
+			if fn, err = env.LookupVar(name); err != nil {
+				return results, err
+			}
+		default:
+			continue
+		}
+
+		var err error
+		result := BenchResult{Name: name}
+		runs := 1
+		for {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			for range runs {
+				if _, err = CallFunction(fn, nil, env); err != nil {
+					break
+				}
+			}
+			elapsed := time.Since(start)
+			runtime.ReadMemStats(&after)
+
+			if err != nil {
+				if uncatchable(err) {
+					return results, err
+				}
+				failure := toErrorVal(err)
+				result.Message, result.Line, result.Column = failure.Message, failure.Line, failure.Column
+				break
+			}
+			if elapsed >= benchTime || runs >= maxBenchRuns {
+				result.Runs = runs
+				result.PerOp = elapsed / time.Duration(runs)
+				result.BytesPerOp = (after.TotalAlloc - before.TotalAlloc) / uint64(runs)
+				result.AllocsPerOp = (after.Mallocs - before.Mallocs) / uint64(runs)
+				break
+			}
+			runs = nextBenchRuns(runs, elapsed, benchTime)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// nextBenchRuns guesses how many calls the next round needs to take
+// benchTime from how long runs calls took, aiming a bit over so it's
+// usually the last round. It grows at least by one and at most 100 times.
+func nextBenchRuns(runs int, elapsed, benchTime time.Duration) int {
+	next := runs * 100
+	if elapsed > 0 {
+		next = int(float64(runs) * float64(benchTime) / float64(elapsed) * 1.2)
+	}
+	return min(max(next, runs+1), runs*100, maxBenchRuns)
+}
@@ -0,0 +1,63 @@
+package runtime_test
+
+import (
+	"testing"
+	"time"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestRunBenchmarks(t *testing.T) {
+	program, err := testutil.Parse(`
+var runs = 0
+bench "count" {
+    runs = runs + 1
+}
+fun bench_old() {}
+bench "fails" {
+    throw error("nope")
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := r.NewEnvironment(nil)
+	results, err := r.RunBenchmarks(program, env, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, name := range []string{"count", "bench_old", "fails"} {
+		if results[i].Name != name {
+			t.Errorf("result %d is %s, want %s", i, results[i].Name, name)
+		}
+	}
+	if results[0].Failed() || results[0].Runs == 0 {
+		t.Errorf("count: %+v", results[0])
+	}
+	if !results[2].Failed() || results[2].Message != "nope" {
+		t.Errorf("fails: %+v", results[2])
+	}
+
+	// the block ran in the global scope it's declared in
+	runs, err := env.LookupVar("runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs.(r.NumberVal).Value < float64(results[0].Runs) {
+		t.Errorf("runs is %v, want at least %d", runs, results[0].Runs)
+	}
+}
+
+func TestBenchBlocksSkippedWhenRun(t *testing.T) {
+	testutil.AssertOutput(t, `
+bench "loud" {
+    println("benchmarking")
+}
+println("ran")
+`, "ran\n")
+}
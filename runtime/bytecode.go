@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+//////////////
+// Bytecode //
+//////////////
+
+// Opcode is what an instruction does, the comment on each one says what it
+// takes off the stack and what it leaves on it
+type Opcode byte
+
+const (
+	OpConstant       Opcode = iota // -> constants[operand]
+	OpNada                         // -> nada
+	OpPop                          // value ->
+	OpSwap                         // a, b -> b, a
+	OpGetVar                       // -> the variable named by constants[operand]
+	OpSetVar                       // value -> value, assigned to the variable named by constants[operand]
+	OpDeclareVar                   // value -> value, declared as the variable named by constants[operand]
+	OpDeclareConst                 // value -> value, declared as the constant named by constants[operand]
+	OpGetProperty                  // object -> object.name, name is constants[operand]
+	OpGetIndex                     // object, key -> object[key]
+	OpSetProperty                  // object, value -> value, after object.name = value
+	OpSetIndex                     // object, key, value -> value, after object[key] = value
+	OpGetMethod                    // receiver -> receiver.name bound to the receiver
+	OpGetMethodIndex               // receiver, key -> receiver[key] bound to the receiver
+	OpCall                         // operand arguments, function -> result
+	OpTailCall                     // operand arguments, function -> , returns what the call returns
+	OpNew                          // class, operand arguments -> instance
+	OpArray                        // operand elements -> list
+	OpObject                       // operand key and value pairs -> object
+	OpRange                        // start, end -> range
+	OpAdd                          // a, b -> a + b
+	OpSubtract                     // a, b -> a - b
+	OpMultiply                     // a, b -> a * b
+	OpDivide                       // a, b -> a / b
+	OpModulo                       // a, b -> a % b
+	OpEqual                        // a, b -> a == b
+	OpNotEqual                     // a, b -> a != b
+	OpLess                         // a, b -> a < b
+	OpLessEqual                    // a, b -> a <= b
+	OpGreater                      // a, b -> a > b
+	OpGreaterEqual                 // a, b -> a >= b
+	OpAnd                          // a, b -> a and b
+	OpOr                           // a, b -> a or b
+	OpNegate                       // a -> -a
+	OpNot                          // a -> !a
+	OpJump                         // jumps to the instruction at operand
+	OpJumpIfFalse                  // condition -> , jumps to operand when the condition is falsy
+	OpForCount                     // count -> how many times a for loop runs
+	OpForNext                      // remaining, result -> remaining - 1, result or jumps to operand when none remain
+	OpPushScope                    // opens a new scope inside the current one
+	OpPopScope                     // goes back to the scope around the current one
+	OpThrow                        // value -> , throws it
+	OpFunction                     // -> a function running the body constants[operand] in the current scope
+	OpEval                         // -> the result of evaluating the node constants[operand]
+	OpReturn                       // value -> , ends the chunk with value
+)
+
+var opcodeNames = [...]string{
+	OpConstant:       "CONSTANT",
+	OpNada:           "NADA",
+	OpPop:            "POP",
+	OpSwap:           "SWAP",
+	OpGetVar:         "GET_VAR",
+	OpSetVar:         "SET_VAR",
+	OpDeclareVar:     "DECLARE_VAR",
+	OpDeclareConst:   "DECLARE_CONST",
+	OpGetProperty:    "GET_PROPERTY",
+	OpGetIndex:       "GET_INDEX",
+	OpSetProperty:    "SET_PROPERTY",
+	OpSetIndex:       "SET_INDEX",
+	OpGetMethod:      "GET_METHOD",
+	OpGetMethodIndex: "GET_METHOD_INDEX",
+	OpCall:           "CALL",
+	OpTailCall:       "TAIL_CALL",
+	OpNew:            "NEW",
+	OpArray:          "ARRAY",
+	OpObject:         "OBJECT",
+	OpRange:          "RANGE",
+	OpAdd:            "ADD",
+	OpSubtract:       "SUBTRACT",
+	OpMultiply:       "MULTIPLY",
+	OpDivide:         "DIVIDE",
+	OpModulo:         "MODULO",
+	OpEqual:          "EQUAL",
+	OpNotEqual:       "NOT_EQUAL",
+	OpLess:           "LESS",
+	OpLessEqual:      "LESS_EQUAL",
+	OpGreater:        "GREATER",
+	OpGreaterEqual:   "GREATER_EQUAL",
+	OpAnd:            "AND",
+	OpOr:             "OR",
+	OpNegate:         "NEGATE",
+	OpNot:            "NOT",
+	OpJump:           "JUMP",
+	OpJumpIfFalse:    "JUMP_IF_FALSE",
+	OpForCount:       "FOR_COUNT",
+	OpForNext:        "FOR_NEXT",
+	OpPushScope:      "PUSH_SCOPE",
+	OpPopScope:       "POP_SCOPE",
+	OpThrow:          "THROW",
+	OpFunction:       "FUNCTION",
+	OpEval:           "EVAL",
+	OpReturn:         "RETURN",
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) && opcodeNames[op] != "" {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("Opcode(%d)", op)
+}
+
+// Instruction is one step of a chunk. Operand is a constant index, a jump
+// target or a count depending on the opcode, Pos is the source position
+// errors raised by the instruction point at.
+type Instruction struct {
+	Op      Opcode
+	Operand int
+	Pos     f.Position
+}
+
+// Chunk is a compiled program or function body. Constants holds the
+// NumberVal, StringVal and CharVal literals and the variable names the code
+// refers to, the *FunctionProto of every function declared in it, and the
+// f.Stmt nodes OpEval hands to the tree-walking evaluator for what the
+// compiler doesn't lower itself.
+type Chunk struct {
+	Code      []Instruction
+	Constants []any
+}
+
+// FunctionProto is a compiled function declaration, OpFunction turns it into
+// a UserFunctionValue closing over the scope it's declared in
+type FunctionProto struct {
+	Name       string
+	Parameters []string
+	Code       *Chunk
+}
+
+// Disassemble writes a readable listing of the chunk, one instruction a line
+func (c *Chunk) Disassemble(w io.Writer) error {
+	for i, instruction := range c.Code {
+		line := fmt.Sprintf("%04d %-16s", i, instruction.Op)
+		switch instruction.Op {
+		case OpConstant, OpGetVar, OpSetVar, OpDeclareVar, OpDeclareConst,
+			OpGetProperty, OpSetProperty, OpGetMethod, OpFunction, OpEval:
+			line += fmt.Sprintf(" %d (%s)", instruction.Operand, describeConstant(c.Constants[instruction.Operand]))
+		case OpCall, OpTailCall, OpNew, OpArray, OpObject:
+			line += fmt.Sprintf(" %d", instruction.Operand)
+		case OpJump, OpJumpIfFalse, OpForNext:
+			line += fmt.Sprintf(" -> %04d", instruction.Operand)
+		}
+		if instruction.Pos.Line() != 0 {
+			line += fmt.Sprintf("  ; %s", instruction.Pos)
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+
+	// function bodies are listed after the code declaring them
+	for _, constant := range c.Constants {
+		if proto, ok := constant.(*FunctionProto); ok {
+			if _, err := fmt.Fprintf(w, "\n%s(%s):\n", proto.Name, strings.Join(proto.Parameters, ", ")); err != nil {
+				return err
+			}
+			if err := proto.Code.Disassemble(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func describeConstant(constant any) string {
+	switch c := constant.(type) {
+	case StringVal:
+		return strconv.Quote(c.Value)
+	case CharVal:
+		return strconv.QuoteRune(c.Value)
+	case NumberVal:
+		return c.String()
+	case *FunctionProto:
+		return "fun " + c.Name
+	case f.Stmt:
+		return string(c.NodeType())
+	default:
+		return fmt.Sprintf("%v", c)
+	}
+}
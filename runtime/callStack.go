@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+////////////////
+// Call Stack //
+////////////////
+
+// Frame is one active call on the a0 call stack
+type Frame struct {
+	Function string
+	Pos      f.Position // where the function was called from
+}
+
+// TraceError is a runtime error that escaped from inside function calls,
+// along with the call stack at the point it happened, innermost call first
+type TraceError struct {
+	Err   error
+	Stack []Frame
+}
+
+func (e *TraceError) Error() string {
+	var builder strings.Builder
+	builder.WriteString(e.Err.Error())
+	builder.WriteString("\nStack trace:")
+	for i, frame := range e.Stack {
+		// deep recursion would print thousands of identical lines, keep
+		// both ends of the stack and count the rest
+		if len(e.Stack) > 2*traceEdge && i >= traceEdge && i < len(e.Stack)-traceEdge {
+			if i == traceEdge {
+				fmt.Fprintf(&builder, "\n    ... %d more calls ...", len(e.Stack)-2*traceEdge)
+			}
+			continue
+		}
+		if frame.Pos.Line() == 0 {
+			fmt.Fprintf(&builder, "\n    at %s, called by the host", frame.Function)
+			continue
+		}
+		fmt.Fprintf(&builder, "\n    at %s, called at (%d, %d)", frame.Function, frame.Pos.Line(), frame.Pos.Column())
+	}
+	return builder.String()
+}
+
+// traceEdge is how many frames are shown from each end of a long trace
+const traceEdge = 10
+
+func (e *TraceError) Unwrap() error {
+	return e.Err
+}
+
+// call runs start as a call to name made at pos. An error coming out of it
+// gets the stack attached, unless a deeper call already did that.
+func (s *session) call(name string, pos f.Position, start func() step) step {
+	if err := s.pushFrame(name, pos); err != nil {
+		return finish(nil, err)
+	}
+
+	s.startTiming(name)
+	return enterCatch(start, func(result RuntimeVal, err error) step {
+		s.stopTiming(name)
+		if err != nil {
+			err = s.trace(err)
+		}
+
+		s.stack = s.stack[:len(s.stack)-1]
+		return finish(result, err)
+	})
+}
+
+// pushFrame puts a call to name made at pos on the call stack, unless that
+// would nest calls deeper than maxDepth
+func (s *session) pushFrame(name string, pos f.Position) error {
+	if s.maxDepth > 0 && len(s.stack) >= s.maxDepth {
+		errorMessage := fmt.Sprintf("Maximum recursion depth exceeded (%d calls) calling %s", s.maxDepth, name)
+		return &InterpretingError{Message: errorMessage}
+	}
+
+	s.stack = append(s.stack, Frame{Function: name, Pos: pos})
+	return nil
+}
+
+// trace attaches the call stack as it is now to err, unless a deeper call
+// already did or the program is exiting
+func (s *session) trace(err error) error {
+	var trace *TraceError
+	var exit *ExitError
+	if errors.As(err, &trace) || errors.As(err, &exit) {
+		return err
+	}
+
+	stack := make([]Frame, len(s.stack))
+	for i, frame := range s.stack {
+		stack[len(s.stack)-1-i] = frame
+	}
+	return &TraceError{Err: err, Stack: stack}
+}
+
+// calleeName is how a called value shows up in stack traces. Values that
+// can't be called only get a frame long enough to fail, and printing them
+// there would read as a function named 5.
+func calleeName(fn RuntimeVal) string {
+	switch v := fn.(type) {
+	case UserFunctionValue:
+		return v.Name
+	case NativeFunctionValue:
+		return v.Name
+	case *ClassVal:
+		return v.Name
+	default:
+		return "<value>"
+	}
+}
@@ -0,0 +1,61 @@
+package runtime_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// traceOf runs src evaluated and compiled and returns both stack traces,
+// which have to agree
+func traceOf(t *testing.T, src string) string {
+	t.Helper()
+	_, evalErr := testutil.Eval(src)
+
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := r.Compile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := r.NewEnvironment(nil)
+	env.SetOutput(io.Discard, io.Discard)
+	_, vmErr := r.Execute(chunk, env)
+
+	var trace *r.TraceError
+	if !errors.As(evalErr, &trace) {
+		t.Fatalf("%q: got %v, want a stack trace", src, evalErr)
+	}
+	if vmErr == nil || vmErr.Error() != evalErr.Error() {
+		t.Errorf("%q: evaluated\n%v\nbut compiled\n%v", src, evalErr, vmErr)
+	}
+	return evalErr.Error()
+}
+
+func TestStackTraceNamesFunctions(t *testing.T) {
+	got := traceOf(t, `fun inner() { throw "boom" }
+fun outer() { inner()
+    return 1 }
+outer()`)
+	for _, want := range []string{"at inner, called at (2, 20)", "at outer, called at (4, 6)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace %q is missing %q", got, want)
+		}
+	}
+}
+
+func TestStackTraceHidesCalledValues(t *testing.T) {
+	got := traceOf(t, `val o = { m: 5 }
+fun f() { o.m()
+    return 1 }
+f()`)
+	if !strings.Contains(got, "at <value>, called at (2, 14)") || strings.Contains(got, "at 5") {
+		t.Errorf("got %q, want the non-function shown as <value>", got)
+	}
+}
@@ -0,0 +1,378 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+////////////////
+// .a0c Files //
+////////////////
+
+// A compiled file starts with chunkMagic and the format version, followed by
+// the constant pool and the instructions. Numbers are unsigned varints unless
+// said otherwise:
+//
+//	constants: count, then per constant a kind byte and
+//	  number: 8 bytes, the float64 bits little endian
+//	  string: length, bytes
+//	  char:   the rune
+//	  node:   length, the node as JSON from f.MarshalAST
+//	  function: the name's length and bytes, the parameter count and each
+//	    parameter the same way, then the body's constants and code
+//	code: count, then per instruction the opcode byte, operand, line, column
+//	  and offset
+const chunkMagic = "\x00a0c"
+
+// ChunkVersion is the version of the .a0c format this build writes and reads.
+// It goes up whenever the opcodes or the layout change, files written with
+// another version have to be compiled again.
+const ChunkVersion = 2
+
+const (
+	constantNumber byte = iota + 1
+	constantString
+	constantChar
+	constantNode
+	constantFunction
+)
+
+// WriteChunk writes chunk to w in the .a0c format
+func WriteChunk(w io.Writer, chunk *Chunk) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(chunkMagic)
+	writeUvarint(out, ChunkVersion)
+	if err := writeChunkBody(out, chunk); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// writeChunkBody writes the constants and code of chunk, the part a function
+// constant repeats for the function's body
+func writeChunkBody(out *bufio.Writer, chunk *Chunk) error {
+	writeUvarint(out, uint64(len(chunk.Constants)))
+	for _, constant := range chunk.Constants {
+		switch c := constant.(type) {
+		case NumberVal:
+			out.WriteByte(constantNumber)
+			binary.Write(out, binary.LittleEndian, math.Float64bits(c.Value))
+		case StringVal:
+			out.WriteByte(constantString)
+			writeString(out, c.Value)
+		case CharVal:
+			out.WriteByte(constantChar)
+			writeUvarint(out, uint64(c.Value))
+		case f.Stmt:
+			data, err := f.MarshalAST(c)
+			if err != nil {
+				return err
+			}
+			out.WriteByte(constantNode)
+			writeUvarint(out, uint64(len(data)))
+			out.Write(data)
+		case *FunctionProto:
+			out.WriteByte(constantFunction)
+			writeString(out, c.Name)
+			writeUvarint(out, uint64(len(c.Parameters)))
+			for _, parameter := range c.Parameters {
+				writeString(out, parameter)
+			}
+			if err := writeChunkBody(out, c.Code); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("can't write constant %v of type %T", c, c)
+		}
+	}
+
+	writeUvarint(out, uint64(len(chunk.Code)))
+	for _, instruction := range chunk.Code {
+		out.WriteByte(byte(instruction.Op))
+		writeUvarint(out, uint64(instruction.Operand))
+		writeUvarint(out, uint64(instruction.Pos.Line()))
+		writeUvarint(out, uint64(instruction.Pos.Column()))
+		writeUvarint(out, uint64(instruction.Pos.Offset()))
+	}
+	return nil
+}
+
+func writeUvarint(w *bufio.Writer, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func writeString(w *bufio.Writer, s string) {
+	writeUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+// ReadChunk reads a chunk written by WriteChunk
+func ReadChunk(r io.Reader) (*Chunk, error) {
+	chunk, err := readChunk(bufio.NewReader(r))
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading compiled program: %w", err)
+	}
+	return chunk, nil
+}
+
+func readChunk(in *bufio.Reader) (*Chunk, error) {
+	magic := make([]byte, len(chunkMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || string(magic) != chunkMagic {
+		return nil, errors.New("not a compiled a0 file")
+	}
+
+	version, err := binary.ReadUvarint(in)
+	if err != nil {
+		return nil, err
+	}
+	if version != ChunkVersion {
+		return nil, fmt.Errorf("compiled for format version %d but this a0 reads version %d, compile it again", version, ChunkVersion)
+	}
+
+	chunk, err := readChunkBody(in)
+	if err != nil {
+		return nil, err
+	}
+	return chunk, validateChunk(chunk)
+}
+
+func readChunkBody(in *bufio.Reader) (*Chunk, error) {
+	chunk := &Chunk{}
+
+	count, err := binary.ReadUvarint(in)
+	if err != nil {
+		return nil, err
+	}
+	for range count {
+		constant, err := readConstant(in)
+		if err != nil {
+			return nil, err
+		}
+		chunk.Constants = append(chunk.Constants, constant)
+	}
+
+	count, err = binary.ReadUvarint(in)
+	if err != nil {
+		return nil, err
+	}
+	for range count {
+		op, err := in.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		var fields [4]uint64 // operand, line, column, offset
+		for i := range fields {
+			if fields[i], err = binary.ReadUvarint(in); err != nil {
+				return nil, err
+			}
+		}
+		chunk.Code = append(chunk.Code, Instruction{
+			Op:      Opcode(op),
+			Operand: int(fields[0]),
+			Pos:     f.NewPosition(int(fields[1]), int(fields[2]), int(fields[3])),
+		})
+	}
+	return chunk, nil
+}
+
+func readConstant(in *bufio.Reader) (any, error) {
+	kind, err := in.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case constantNumber:
+		var bits uint64
+		if err := binary.Read(in, binary.LittleEndian, &bits); err != nil {
+			return nil, err
+		}
+		return NumberVal{Value: math.Float64frombits(bits)}, nil
+	case constantString:
+		s, err := readString(in)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal{Value: s}, nil
+	case constantNode:
+		data, err := readString(in)
+		if err != nil {
+			return nil, err
+		}
+		return f.UnmarshalAST([]byte(data))
+	case constantChar:
+		r, err := binary.ReadUvarint(in)
+		if err != nil {
+			return nil, err
+		}
+		return CharVal{Value: rune(r)}, nil
+	case constantFunction:
+		proto := &FunctionProto{}
+		if proto.Name, err = readString(in); err != nil {
+			return nil, err
+		}
+		count, err := binary.ReadUvarint(in)
+		if err != nil {
+			return nil, err
+		}
+		for range count {
+			parameter, err := readString(in)
+			if err != nil {
+				return nil, err
+			}
+			proto.Parameters = append(proto.Parameters, parameter)
+		}
+		if proto.Code, err = readChunkBody(in); err != nil {
+			return nil, err
+		}
+		return proto, nil
+	default:
+		return nil, fmt.Errorf("unknown constant kind %d", kind)
+	}
+}
+
+// readString reads a length and that many bytes. The length comes from the
+// file, so it's capped, and the bytes are copied as they arrive instead of
+// into a buffer made that long up front, a damaged length then fails at the
+// end of the file without allocating it all
+func readString(in *bufio.Reader) (string, error) {
+	length, err := binary.ReadUvarint(in)
+	if err != nil {
+		return "", err
+	}
+	if length > maxStringLength {
+		return "", fmt.Errorf("a string of %d bytes is longer than the %d a0 allows", length, maxStringLength)
+	}
+
+	var data strings.Builder
+	if _, err := io.CopyN(&data, in, int64(length)); err != nil {
+		return "", err
+	}
+	return data.String(), nil
+}
+
+// validateChunk checks that every operand refers to something that exists
+// and that no instruction takes more off the stack than is on it, so a
+// damaged file fails here instead of crashing Execute
+func validateChunk(chunk *Chunk) error {
+	for _, constant := range chunk.Constants {
+		if proto, ok := constant.(*FunctionProto); ok {
+			if err := validateChunk(proto.Code); err != nil {
+				return fmt.Errorf("function %s: %w", proto.Name, err)
+			}
+		}
+	}
+
+	for i, instruction := range chunk.Code {
+		if int(instruction.Op) >= len(opcodeNames) {
+			return fmt.Errorf("instruction %d: unknown opcode %d", i, instruction.Op)
+		}
+
+		var valid bool
+		switch instruction.Op {
+		case OpConstant:
+			_, valid = constantAt(chunk, instruction.Operand).(RuntimeVal)
+		case OpGetVar, OpSetVar, OpDeclareVar, OpDeclareConst, OpGetProperty, OpSetProperty, OpGetMethod:
+			_, valid = constantAt(chunk, instruction.Operand).(StringVal)
+		case OpFunction:
+			_, valid = constantAt(chunk, instruction.Operand).(*FunctionProto)
+		case OpEval:
+			_, valid = constantAt(chunk, instruction.Operand).(f.Stmt)
+		case OpJump, OpJumpIfFalse, OpForNext:
+			valid = instruction.Operand >= 0 && instruction.Operand <= len(chunk.Code)
+		default:
+			valid = instruction.Operand >= 0 && instruction.Operand <= maxChunkOperand
+		}
+		if !valid {
+			return fmt.Errorf("instruction %d: %s has a bad operand %d", i, instruction.Op, instruction.Operand)
+		}
+	}
+	return validateStack(chunk)
+}
+
+// maxChunkOperand bounds the counts CALL, ARRAY and the like take, far more
+// than any program has, so working out how much they pop can't overflow
+const maxChunkOperand = 1 << 24
+
+// chunkState is how many values are on the stack and how many scopes are open
+// when an instruction starts
+type chunkState struct {
+	stack  int
+	scopes int
+}
+
+// validateStack follows every path through the code the way run would,
+// counting the values each instruction pops and pushes. An instruction
+// popping more than is there, a scope closed that was never opened, or two
+// paths arriving at one instruction with different counts make the chunk bad.
+func validateStack(chunk *Chunk) error {
+	states := make([]*chunkState, len(chunk.Code)+1)
+	states[0] = &chunkState{}
+	pending := []int{0}
+
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if i == len(chunk.Code) {
+			continue // running off the end returns nada
+		}
+
+		instruction := chunk.Code[i]
+		state := *states[i]
+		pops, pushes := stackUse(instruction.Op, instruction.Operand)
+		if state.stack < pops {
+			return fmt.Errorf("instruction %d: %s takes %d values but the stack only has %d", i, instruction.Op, pops, state.stack)
+		}
+		state.stack += pushes - pops
+
+		switch instruction.Op {
+		case OpPushScope:
+			state.scopes++
+		case OpPopScope:
+			if state.scopes == 0 {
+				return fmt.Errorf("instruction %d: %s without a scope to close", i, instruction.Op)
+			}
+			state.scopes--
+		}
+
+		var next []int
+		switch instruction.Op {
+		case OpReturn, OpThrow, OpTailCall:
+		case OpJump:
+			next = []int{instruction.Operand}
+		case OpJumpIfFalse, OpForNext:
+			next = []int{i + 1, instruction.Operand}
+		default:
+			next = []int{i + 1}
+		}
+		for _, target := range next {
+			switch {
+			case states[target] == nil:
+				states[target] = &state
+				pending = append(pending, target)
+			case *states[target] != state:
+				return fmt.Errorf("instruction %d: reached with %d values on the stack and %d scopes open, and also with %d and %d",
+					target, states[target].stack, states[target].scopes, state.stack, state.scopes)
+			}
+		}
+	}
+	return nil
+}
+
+func constantAt(chunk *Chunk, index int) any {
+	if index < 0 || index >= len(chunk.Constants) {
+		return nil
+	}
+	return chunk.Constants[index]
+}
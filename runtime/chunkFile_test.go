@@ -0,0 +1,152 @@
+package runtime_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// chunkSources are compiled and written out to seed the corruption tests
+var chunkSources = []string{
+	"println(1 + 2)",
+	`val o = { a: [1, "two", 'c'] }
+o.a[0] = o.a.length()
+println(o)`,
+	`fun fact(n, acc) {
+    if (n <= 1) { return acc }
+    return fact(n - 1, acc * n)
+}
+var total = 0
+for (x in 0..5) {
+    if (x == 3) { continue }
+    total = total + fact(x, 1)
+}
+while (total > 10) { total = total - 10 }
+println(total)`,
+}
+
+// compileToBytes compiles src and writes it out in the .a0c format
+func compileToBytes(t testing.TB, src string) []byte {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := r.Compile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := r.WriteChunk(&out, chunk); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// chunkBytes writes out a chunk built by hand, with no constants
+func chunkBytes(code ...r.Instruction) []byte {
+	var out bytes.Buffer
+	if err := r.WriteChunk(&out, &r.Chunk{Code: code}); err != nil {
+		panic(err)
+	}
+	return out.Bytes()
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	for _, src := range chunkSources {
+		chunk, err := r.ReadChunk(bytes.NewReader(compileToBytes(t, src)))
+		if err != nil {
+			t.Fatalf("reading %q back: %v", src, err)
+		}
+
+		env := r.NewEnvironment(nil)
+		var fromChunk, fromSource bytes.Buffer
+		env.SetOutput(&fromChunk, io.Discard)
+		if _, err := r.Execute(chunk, env); err != nil {
+			t.Fatal(err)
+		}
+		env = r.NewEnvironment(nil)
+		env.SetOutput(&fromSource, io.Discard)
+		if _, err := testutil.EvalIn(src, env); err != nil {
+			t.Fatal(err)
+		}
+		if fromChunk.String() != fromSource.String() {
+			t.Errorf("%q printed %q compiled but %q evaluated", src, fromChunk.String(), fromSource.String())
+		}
+	}
+}
+
+func TestReadChunkRejectsDamage(t *testing.T) {
+	header := []byte("\x00a0c")
+	header = binary.AppendUvarint(header, r.ChunkVersion)
+
+	// one string constant claiming to be far longer than the file
+	hugeString := append([]byte{}, header...)
+	hugeString = binary.AppendUvarint(hugeString, 1)
+	hugeString = append(hugeString, 2) // a string constant
+	hugeString = binary.AppendUvarint(hugeString, 1<<40)
+	shortString := append([]byte{}, header...)
+	shortString = binary.AppendUvarint(shortString, 1)
+	shortString = append(shortString, 2)
+	shortString = binary.AppendUvarint(shortString, 1000)
+	shortString = append(shortString, "abc"...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		contains string
+	}{
+		{"not a chunk", []byte("println(1)"), "not a compiled a0 file"},
+		{"truncated", compileToBytes(t, chunkSources[2])[:40], "unexpected EOF"},
+		{"huge string", hugeString, "longer than"},
+		{"short string", shortString, "unexpected EOF"},
+		{"pop on an empty stack", chunkBytes(r.Instruction{Op: r.OpPop}), "stack only has 0"},
+		{"call without a function", chunkBytes(r.Instruction{Op: r.OpNada}, r.Instruction{Op: r.OpCall, Operand: 1}), "takes 2 values"},
+		{"swap one value", chunkBytes(r.Instruction{Op: r.OpNada}, r.Instruction{Op: r.OpSwap}), "takes 2 values"},
+		{"for next with nothing", chunkBytes(r.Instruction{Op: r.OpForNext, Operand: 1}), "takes 2 values"},
+		{"jump backwards past the start", chunkBytes(r.Instruction{Op: r.OpJump, Operand: -1}), "bad operand"},
+		{"huge array", chunkBytes(r.Instruction{Op: r.OpArray, Operand: 1 << 40}), "bad operand"},
+		{"scope closed twice", chunkBytes(r.Instruction{Op: r.OpPushScope}, r.Instruction{Op: r.OpPopScope}, r.Instruction{Op: r.OpPopScope}), "without a scope"},
+		{"paths disagree", chunkBytes(
+			r.Instruction{Op: r.OpNada},
+			r.Instruction{Op: r.OpJumpIfFalse, Operand: 3},
+			r.Instruction{Op: r.OpNada},
+			r.Instruction{Op: r.OpNada},
+		), "reached with"},
+		{"unknown opcode", chunkBytes(r.Instruction{Op: 200}), "unknown opcode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.ReadChunk(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// FuzzReadChunk feeds ReadChunk damaged files. It must fail with an error
+// rather than panic, and a chunk it accepts must run without panicking.
+func FuzzReadChunk(f *testing.F) {
+	for _, src := range chunkSources {
+		f.Add(compileToBytes(f, src))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		chunk, err := r.ReadChunk(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		env := r.NewEnvironment(nil)
+		env.SetOutput(io.Discard, io.Discard)
+		env.SetMaxSteps(10000)
+		r.Execute(chunk, env)
+	})
+}
@@ -0,0 +1,96 @@
+package runtime_test
+
+import (
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestClosureCounter(t *testing.T) {
+	testutil.AssertOutput(t, `
+fun makeCounter() {
+    var count = 0
+    fun inc() {
+        count = count + 1
+        return count
+    }
+    return inc
+}
+
+val a = makeCounter()
+val b = makeCounter()
+println(a(), a(), a())
+println(b())
+`, "1 2 3\n1\n")
+}
+
+func TestClosureAccumulator(t *testing.T) {
+	got := testutil.MustEval(t, `
+fun makeAccumulator(start) {
+    var total = start
+    fun add(n) {
+        total = total + n
+        return total
+    }
+    return add
+}
+
+val acc = makeAccumulator(10)
+acc(5)
+acc(-2)
+acc(7)
+`)
+	testutil.AssertValue(t, got, r.NumberVal{Value: 20})
+}
+
+func TestClosureNestedCapture(t *testing.T) {
+	testutil.AssertOutput(t, `
+fun outer(a) {
+    fun middle(b) {
+        fun inner(c) {
+            return a * 100 + b * 10 + c
+        }
+        return inner
+    }
+    return middle
+}
+
+val m = outer(1)
+val i = m(2)
+println(i(3))
+println(m(4)(5))
+`, "123\n145\n")
+}
+
+func TestClosureCapturesByReference(t *testing.T) {
+	testutil.AssertOutput(t, `
+fun pair() {
+    var shared = 0
+    fun set(n) {
+        shared = n
+    }
+    fun get() {
+        return shared
+    }
+    return [set, get]
+}
+
+val fns = pair()
+val set = fns[0]
+val get = fns[1]
+println(get())
+set(42)
+println(get())
+`, "0\n42\n")
+
+	// a variable changed after the closure is made is seen by the closure
+	testutil.AssertOutput(t, `
+var x = 1
+fun readX() {
+    return x
+}
+x = 2
+println(readX())
+`, "2\n")
+}
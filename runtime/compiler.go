@@ -0,0 +1,590 @@
+package runtime
+
+import (
+	"fmt"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+//////////////
+// Compiler //
+//////////////
+
+// Compile lowers a parsed program into a chunk of bytecode for Execute.
+// Expressions, variables, functions, if statements and while and for loops
+// become instructions of their own. Everything else (classes, try, match and
+// the rest) is kept as a node in the constant pool that OpEval runs through
+// Evaluate, so any program compiles and runs the same way.
+func Compile(program f.Program) (*Chunk, error) {
+	c := &compiler{chunk: &Chunk{}, constants: map[any]int{}}
+
+	if len(program.Body) == 0 {
+		c.emit(OpNada, 0, f.Position{})
+	}
+	for i, stmt := range program.Body {
+		if i > 0 {
+			c.emit(OpPop, 0, f.Position{})
+		}
+		if err := c.compileStmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	c.emit(OpReturn, 0, f.Position{})
+
+	return c.chunk, nil
+}
+
+type compiler struct {
+	chunk     *Chunk
+	constants map[any]int // where each literal and name already is in the pool
+	depth     int         // values on the stack at this point of the code
+	scopes    int         // scopes opened at this point of the code
+	loops     []*loopContext
+	function  bool // compiling a function body, where return f(x) is a tail call
+}
+
+// loopContext is what break and continue need to leave the loop they're in
+type loopContext struct {
+	depth  int   // stack depth at the start of the body
+	scopes int   // scopes open around the body
+	next   int   // where the next iteration starts
+	breaks []int // jumps waiting for the end of the loop
+}
+
+// stackEffect is how many values an instruction adds to the stack, negative
+// when it takes more than it leaves
+func stackEffect(op Opcode, operand int) int {
+	pops, pushes := stackUse(op, operand)
+	return pushes - pops
+}
+
+// stackUse is how many values an instruction takes off the stack and how
+// many it puts back, as the comments on the opcodes say
+func stackUse(op Opcode, operand int) (pops, pushes int) {
+	switch op {
+	case OpConstant, OpNada, OpGetVar, OpFunction, OpEval:
+		return 0, 1
+	case OpPop, OpJumpIfFalse, OpThrow, OpReturn:
+		return 1, 0
+	case OpSwap, OpForNext:
+		return 2, 2
+	case OpSetVar, OpDeclareVar, OpDeclareConst, OpGetProperty, OpGetMethod, OpNegate, OpNot, OpForCount:
+		return 1, 1
+	case OpGetIndex, OpSetProperty, OpGetMethodIndex, OpRange,
+		OpAdd, OpSubtract, OpMultiply, OpDivide, OpModulo,
+		OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpAnd, OpOr:
+		return 2, 1
+	case OpSetIndex:
+		return 3, 1
+	case OpCall, OpNew:
+		return operand + 1, 1
+	case OpTailCall:
+		return operand + 1, 0
+	case OpArray:
+		return operand, 1
+	case OpObject:
+		return 2 * operand, 1
+	default:
+		return 0, 0
+	}
+}
+
+func (c *compiler) emit(op Opcode, operand int, pos f.Position) int {
+	c.chunk.Code = append(c.chunk.Code, Instruction{Op: op, Operand: operand, Pos: pos})
+	c.depth += stackEffect(op, operand)
+	return len(c.chunk.Code) - 1
+}
+
+// patch points the jump at index to the next instruction emitted
+func (c *compiler) patch(index int) {
+	c.chunk.Code[index].Operand = len(c.chunk.Code)
+}
+
+// constant is the index of value in the pool, adding it if it isn't there yet
+func (c *compiler) constant(value any) int {
+	if _, isNode := value.(f.Stmt); !isNode {
+		if index, exists := c.constants[value]; exists {
+			return index
+		}
+	}
+
+	c.chunk.Constants = append(c.chunk.Constants, value)
+	index := len(c.chunk.Constants) - 1
+	if _, isNode := value.(f.Stmt); !isNode {
+		c.constants[value] = index
+	}
+	return index
+}
+
+func (c *compiler) name(name string) int {
+	return c.constant(StringVal{Value: name})
+}
+
+// fallback leaves node to the tree-walking evaluator
+func (c *compiler) fallback(node f.Stmt) error {
+	c.emit(OpEval, c.constant(node), f.Position{})
+	return nil
+}
+
+// Statements //
+// every statement leaves exactly one value on the stack, its result
+
+func (c *compiler) compileStmt(stmt f.Stmt) error {
+	if !compiles(stmt) {
+		return c.fallback(stmt)
+	}
+
+	// a break or continue outside any loop is an error Evaluate reports
+	switch stmt.(type) {
+	case f.BreakStmt, f.ContinueStmt:
+		if len(c.loops) == 0 {
+			return c.fallback(stmt)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case f.VarDeclaration:
+		if s.Value == nil {
+			c.emit(OpNada, 0, f.Position{})
+		} else if err := c.compileExpr(s.Value); err != nil {
+			return err
+		}
+		op := OpDeclareVar
+		if s.Constant {
+			op = OpDeclareConst
+		}
+		c.emit(op, c.name(s.Identifier), s.Pos)
+		return nil
+
+	case f.IfStmt:
+		if err := c.compileExpr(s.Condition); err != nil {
+			return err
+		}
+		skip := c.emit(OpJumpIfFalse, 0, s.Pos)
+		if err := c.compileScopedBlock(s.Body); err != nil {
+			return err
+		}
+		end := c.emit(OpJump, 0, s.Pos)
+		c.patch(skip)
+		c.depth--
+		c.emit(OpNada, 0, f.Position{})
+		c.patch(end)
+		return nil
+
+	case f.WhileStmt:
+		c.emit(OpNada, 0, f.Position{})
+		start := len(c.chunk.Code)
+		if err := c.compileExpr(s.Condition); err != nil {
+			return err
+		}
+		exit := c.emit(OpJumpIfFalse, 0, s.Pos)
+		c.emit(OpPop, 0, f.Position{})
+		if err := c.compileLoopBody(s.Body, start); err != nil {
+			return err
+		}
+		c.emit(OpJump, start, s.Pos)
+		c.patch(exit)
+		c.endLoop()
+		return nil
+
+	case f.ForStmt:
+		if err := c.compileExpr(s.Condition); err != nil {
+			return err
+		}
+		c.emit(OpForCount, 0, s.Pos)
+		c.emit(OpNada, 0, f.Position{})
+		start := c.emit(OpForNext, 0, s.Pos)
+		c.emit(OpPop, 0, f.Position{})
+		if err := c.compileLoopBody(s.Body, start); err != nil {
+			return err
+		}
+		c.emit(OpJump, start, s.Pos)
+		c.patch(start)
+		c.endLoop()
+		c.emit(OpSwap, 0, f.Position{})
+		c.emit(OpPop, 0, f.Position{})
+		return nil
+
+	case f.BreakStmt:
+		loop := c.loops[len(c.loops)-1]
+		depth := c.depth
+		c.unwind(loop)
+		loop.breaks = append(loop.breaks, c.emit(OpJump, 0, s.Pos))
+		c.depth = depth + 1
+		return nil
+
+	case f.ContinueStmt:
+		loop := c.loops[len(c.loops)-1]
+		depth := c.depth
+		c.unwind(loop)
+		c.emit(OpJump, loop.next, s.Pos)
+		c.depth = depth + 1
+		return nil
+
+	case f.FunctionDeclaration:
+		body := &compiler{chunk: &Chunk{}, constants: map[any]int{}, function: true}
+		if err := body.compileBlock(s.Body); err != nil {
+			return err
+		}
+		// falling off the end of a function returns nada
+		body.emit(OpPop, 0, f.Position{})
+		body.emit(OpNada, 0, f.Position{})
+		body.emit(OpReturn, 0, f.Position{})
+
+		proto := &FunctionProto{Name: s.Name, Parameters: s.Parameters, Code: body.chunk}
+		c.emit(OpFunction, c.constant(proto), s.Pos)
+		c.emit(OpDeclareConst, c.name(s.Name), s.Pos)
+		return nil
+
+	case f.ReturnStmt:
+		depth := c.depth
+		// the call in return f(x) takes over the frame of the function
+		if call, ok := s.Value.(f.CallExpr); ok && c.function && compiles(call) {
+			if err := c.compileCall(call, OpTailCall); err != nil {
+				return err
+			}
+			c.depth = depth + 1
+			return nil
+		}
+		if s.Value == nil {
+			c.emit(OpNada, 0, f.Position{})
+		} else if err := c.compileExpr(s.Value); err != nil {
+			return err
+		}
+		c.emit(OpReturn, 0, s.Pos)
+		c.depth = depth + 1
+		return nil
+
+	case f.ThrowStmt:
+		depth := c.depth
+		if err := c.compileExpr(s.Value); err != nil {
+			return err
+		}
+		c.emit(OpThrow, 0, s.Pos)
+		c.depth = depth + 1
+		return nil
+
+	default:
+		return c.compileExpr(stmt)
+	}
+}
+
+// compileBlock runs body in the current scope, leaving the value of its last
+// statement or nada when it's empty
+func (c *compiler) compileBlock(body []f.Stmt) error {
+	if len(body) == 0 {
+		c.emit(OpNada, 0, f.Position{})
+	}
+	for i, stmt := range body {
+		if i > 0 {
+			c.emit(OpPop, 0, f.Position{})
+		}
+		if err := c.compileStmt(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileScopedBlock(body []f.Stmt) error {
+	c.emit(OpPushScope, 0, f.Position{})
+	c.scopes++
+	if err := c.compileBlock(body); err != nil {
+		return err
+	}
+	c.emit(OpPopScope, 0, f.Position{})
+	c.scopes--
+	return nil
+}
+
+// compileLoopBody compiles the body of a loop whose next iteration starts at
+// next. The loop's result was just taken off the stack, the body leaves the
+// new one.
+func (c *compiler) compileLoopBody(body []f.Stmt, next int) error {
+	c.loops = append(c.loops, &loopContext{depth: c.depth, scopes: c.scopes, next: next})
+	return c.compileScopedBlock(body)
+}
+
+// endLoop points every break of the innermost loop at the next instruction
+func (c *compiler) endLoop() {
+	loop := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+	for _, jump := range loop.breaks {
+		c.patch(jump)
+	}
+}
+
+// unwind drops what the body of loop has put on the stack and the scopes it
+// opened, then leaves nada as the loop's result for break and continue
+func (c *compiler) unwind(loop *loopContext) {
+	for c.depth > loop.depth {
+		c.emit(OpPop, 0, f.Position{})
+	}
+	for scopes := c.scopes; scopes > loop.scopes; scopes-- {
+		c.emit(OpPopScope, 0, f.Position{})
+	}
+	c.emit(OpNada, 0, f.Position{})
+}
+
+// Expressions //
+
+var binaryOpcodes = map[string]Opcode{
+	"+": OpAdd,
+	"-": OpSubtract,
+	"*": OpMultiply,
+	"/": OpDivide,
+	"%": OpModulo,
+}
+
+var logicalOpcodes = map[string]Opcode{
+	"and": OpAnd,
+	"or":  OpOr,
+	"==":  OpEqual,
+	"!=":  OpNotEqual,
+	"<":   OpLess,
+	"<=":  OpLessEqual,
+	">":   OpGreater,
+	">=":  OpGreaterEqual,
+}
+
+var unaryOpcodes = map[string]Opcode{
+	"-": OpNegate,
+	"!": OpNot,
+}
+
+func (c *compiler) compileExpr(expr f.Expr) error {
+	if !compiles(expr) {
+		return c.fallback(expr)
+	}
+
+	switch e := expr.(type) {
+	case f.NumericLiteral:
+		c.emit(OpConstant, c.constant(NumberVal{Value: e.Value}), f.Position{})
+	case f.StringLiteral:
+		c.emit(OpConstant, c.constant(StringVal{Value: e.Value}), f.Position{})
+	case f.CharLiteral:
+		c.emit(OpConstant, c.constant(CharVal{Value: e.Value}), f.Position{})
+	case f.Identifier:
+		c.emit(OpGetVar, c.name(e.Symbol), e.Pos)
+
+	case f.BinaryExpr:
+		if err := c.compileExprs(e.Left, e.Right); err != nil {
+			return err
+		}
+		c.emit(binaryOpcodes[e.Operator], 0, e.Pos)
+	case f.LogicalExpr:
+		if err := c.compileExprs(e.Left, e.Right); err != nil {
+			return err
+		}
+		c.emit(logicalOpcodes[e.Operator], 0, e.Pos)
+	case f.UnaryExpr:
+		if err := c.compileExpr(e.Operant); err != nil {
+			return err
+		}
+		c.emit(unaryOpcodes[e.Operator], 0, e.Pos)
+	case f.RangeExpr:
+		if err := c.compileExprs(e.Start, e.End); err != nil {
+			return err
+		}
+		c.emit(OpRange, 0, e.Pos)
+
+	case f.ArrayLiteral:
+		if err := c.compileExprs(e.Elements...); err != nil {
+			return err
+		}
+		c.emit(OpArray, len(e.Elements), e.Pos)
+	case f.ObjectLiteral:
+		for _, property := range e.Properties {
+			c.emit(OpConstant, c.name(property.Key), f.Position{})
+			// {x} is short for {x: x}
+			if property.Value == nil {
+				c.emit(OpGetVar, c.name(property.Key), e.Pos)
+			} else if err := c.compileExpr(property.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(OpObject, len(e.Properties), e.Pos)
+
+	case f.MemberExpr:
+		if err := c.compileExpr(e.Object); err != nil {
+			return err
+		}
+		if !e.Computed {
+			c.emit(OpGetProperty, c.name(e.Property.(f.Identifier).Symbol), e.Pos)
+			return nil
+		}
+		if err := c.compileExpr(e.Property); err != nil {
+			return err
+		}
+		c.emit(OpGetIndex, 0, e.Pos)
+
+	case f.AssignmentExpr:
+		return c.compileAssignment(e)
+
+	case f.CallExpr:
+		return c.compileCall(e, OpCall)
+
+	case f.NewExpr:
+		if err := c.compileExprs(append([]f.Expr{e.Class}, e.Args...)...); err != nil {
+			return err
+		}
+		c.emit(OpNew, len(e.Args), e.Pos)
+
+	default:
+		errorMessage := fmt.Sprintf("Cannot compile %s", expr.NodeType())
+		return &InterpretingError{Message: errorMessage}
+	}
+	return nil
+}
+
+func (c *compiler) compileExprs(exprs ...f.Expr) error {
+	for _, expr := range exprs {
+		if err := c.compileExpr(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileCall compiles call as an OpCall or an OpTailCall
+func (c *compiler) compileCall(call f.CallExpr, op Opcode) error {
+	// arguments are evaluated before the function, like Evaluate does
+	if err := c.compileExprs(call.Args...); err != nil {
+		return err
+	}
+	if member, ok := call.Caller.(f.MemberExpr); ok {
+		if err := c.compileExpr(member.Object); err != nil {
+			return err
+		}
+		if !member.Computed {
+			c.emit(OpGetMethod, c.name(member.Property.(f.Identifier).Symbol), call.Pos)
+		} else {
+			if err := c.compileExpr(member.Property); err != nil {
+				return err
+			}
+			c.emit(OpGetMethodIndex, 0, call.Pos)
+		}
+	} else if err := c.compileExpr(call.Caller); err != nil {
+		return err
+	}
+	c.emit(op, len(call.Args), call.Pos)
+	return nil
+}
+
+func (c *compiler) compileAssignment(assignment f.AssignmentExpr) error {
+	switch assignee := assignment.Assignee.(type) {
+	case f.Identifier:
+		if err := c.compileExpr(assignment.Value); err != nil {
+			return err
+		}
+		c.emit(OpSetVar, c.name(assignee.Symbol), assignment.Pos)
+
+	case f.MemberExpr:
+		if err := c.compileExpr(assignee.Object); err != nil {
+			return err
+		}
+		if !assignee.Computed {
+			if err := c.compileExpr(assignment.Value); err != nil {
+				return err
+			}
+			c.emit(OpSetProperty, c.name(assignee.Property.(f.Identifier).Symbol), assignment.Pos)
+			return nil
+		}
+		if err := c.compileExprs(assignee.Property, assignment.Value); err != nil {
+			return err
+		}
+		c.emit(OpSetIndex, 0, assignment.Pos)
+	}
+	return nil
+}
+
+// compiles reports whether the compiler lowers node itself rather than
+// leaving it to OpEval. Loops are only lowered when nothing left to OpEval
+// in their body can break out of them or continue them, those signals only
+// reach loops that Evaluate runs.
+func compiles(node f.Stmt) bool {
+	switch n := node.(type) {
+	case f.VarDeclaration:
+		return n.Pattern == nil
+	case f.FunctionDeclaration, f.IfStmt, f.ReturnStmt, f.ThrowStmt, f.BreakStmt, f.ContinueStmt:
+		return true
+	case f.WhileStmt:
+		return !evalBreaksOut(n.Body)
+	case f.ForStmt:
+		return !evalBreaksOut(n.Body)
+
+	case f.NumericLiteral, f.StringLiteral, f.CharLiteral, f.Identifier,
+		f.RangeExpr, f.ArrayLiteral, f.ObjectLiteral, f.NewExpr:
+		return true
+	case f.BinaryExpr:
+		_, known := binaryOpcodes[n.Operator]
+		return known
+	case f.LogicalExpr:
+		_, known := logicalOpcodes[n.Operator]
+		return known
+	case f.UnaryExpr:
+		_, known := unaryOpcodes[n.Operator]
+		return known
+	case f.MemberExpr:
+		return isPropertyName(n)
+	case f.CallExpr:
+		member, isMember := n.Caller.(f.MemberExpr)
+		return !isMember || isPropertyName(member)
+	case f.AssignmentExpr:
+		switch assignee := n.Assignee.(type) {
+		case f.Identifier:
+			return true
+		case f.MemberExpr:
+			return isPropertyName(assignee)
+		}
+		return false
+
+	default:
+		return false
+	}
+}
+
+// isPropertyName is true for obj[key] and for obj.name with a plain name
+func isPropertyName(member f.MemberExpr) bool {
+	_, isIdent := member.Property.(f.Identifier)
+	return member.Computed || isIdent
+}
+
+// evalBreaksOut reports whether a loop body has a break or continue for the
+// loop inside code that will be left to OpEval
+func evalBreaksOut(body []f.Stmt) bool {
+	found := false
+	for _, stmt := range body {
+		f.Inspect(stmt, func(node f.Stmt) bool {
+			if found {
+				return false
+			}
+			// a function's body is compiled on its own, no loop around it
+			if _, isFunction := node.(f.FunctionDeclaration); isFunction {
+				return false
+			}
+			if !compiles(node) {
+				found = hasLoopSignal(node)
+				return false
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// hasLoopSignal reports whether node has a break or continue that isn't
+// inside a loop or function of its own
+func hasLoopSignal(node f.Stmt) bool {
+	found := false
+	f.Inspect(node, func(inner f.Stmt) bool {
+		switch inner.(type) {
+		case f.BreakStmt, f.ContinueStmt:
+			found = true
+		case f.WhileStmt, f.ForStmt, f.ForEachStmt, f.FunctionDeclaration, f.ClassDeclaration:
+			return false
+		}
+		return !found
+	})
+	return found
+}
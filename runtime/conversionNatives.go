@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+////////////////////////
+// Conversion Natives //
+////////////////////////
+
+// setupConversionNatives declares toNumber, toString and toBool
+func setupConversionNatives(env *Environment) {
+	// toNumber("42") is 42. Text that isn't a number gives nada rather than
+	// an error so input can be checked with a plain comparison.
+	env.DeclareVar("toNumber", NativeFunctionValue{
+		Name: "toNumber",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if err := conversionArgs("toNumber", args); err != nil {
+				return nil, err
+			}
+
+			switch v := args[0].(type) {
+			case NumberVal:
+				return v, nil
+			case BoolVal:
+				if v.Value {
+					return NumberVal{Value: 1}, nil
+				}
+				return NumberVal{Value: 0}, nil
+			case StringVal:
+				return parseNumber(v.Value), nil
+			case CharVal:
+				return parseNumber(string(v.Value)), nil
+			default:
+				return NadaVal{}, nil
+			}
+		},
+	}, true)
+
+	// toString(x) is x as print would show it
+	env.DeclareVar("toString", NativeFunctionValue{
+		Name: "toString",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if err := conversionArgs("toString", args); err != nil {
+				return nil, err
+			}
+			return StringVal{Value: args[0].String()}, nil
+		},
+	}, true)
+
+	// toBool(x) is whether x counts as true in an if
+	env.DeclareVar("toBool", NativeFunctionValue{
+		Name: "toBool",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if err := conversionArgs("toBool", args); err != nil {
+				return nil, err
+			}
+			return BoolVal{Value: isTruthy(args[0])}, nil
+		},
+	}, true)
+}
+
+func conversionArgs(name string, args []RuntimeVal) error {
+	if len(args) != 1 {
+		errorMessage := fmt.Sprintf("%s expects 1 argument but got %d", name, len(args))
+		return &InterpretingError{Message: errorMessage}
+	}
+	return nil
+}
+
+// parseNumber reads decimal text like "42", " 3.5 " or "1e3", anything else
+// (including inf and nan) is nada
+func parseNumber(text string) RuntimeVal {
+	num, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsInf(num, 0) || math.IsNaN(num) {
+		return NadaVal{}
+	}
+	return NumberVal{Value: num}
+}
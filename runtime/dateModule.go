@@ -0,0 +1,305 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"time"
+	_ "time/tzdata" // time zones work even where the system has no zoneinfo
+)
+
+/////////////////
+// Date Module //
+/////////////////
+
+// milliseconds in each unit d.diff can report in
+var dateUnits = map[string]float64{
+	"ms":      1,
+	"seconds": 1000,
+	"minutes": 60 * 1000,
+	"hours":   60 * 60 * 1000,
+	"days":    24 * 60 * 60 * 1000,
+}
+
+// the furthest from 1970 a date from fromMillis can be, the same 100 million
+// days either way JavaScript allows
+const maxDateMillis = 8.64e15
+
+// the largest a part given to make can be, big enough for any real date while
+// keeping the sum time.Date works out far from overflowing
+const maxDatePart = 1e9
+
+// dateModule builds the global date object. Functions that take a zone
+// accept names like "UTC", "Local" or "Europe/Berlin" and default to local
+// time.
+func dateModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "date"}
+
+	// now(zone)
+	module.Properties["now"] = NativeFunctionValue{
+		Name: "date.now",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			loc, err := zoneArg("date.now", args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return DateVal{Time: time.Now().In(loc)}, nil
+		},
+	}
+
+	// fromMillis(ms, zone) is the date for a unix millisecond timestamp
+	module.Properties["fromMillis"] = NativeFunctionValue{
+		Name: "date.fromMillis",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 {
+				return nil, &InterpretingError{Message: "date.fromMillis expects (ms, zone)"}
+			}
+			ms, ok := args[0].(NumberVal)
+			if !ok {
+				return nil, &TypeError{Operation: "date.fromMillis", Expected: "a number", Got: TypeOf(args[0])}
+			}
+			if !(math.Abs(ms.Value) <= maxDateMillis) {
+				errorMessage := fmt.Sprintf("date.fromMillis expects from %v to %v milliseconds but got %v", -maxDateMillis, maxDateMillis, ms.Value)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			loc, err := zoneArg("date.fromMillis", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return DateVal{Time: time.UnixMilli(int64(ms.Value)).In(loc)}, nil
+		},
+	}
+
+	// make(year, month, day, hour, minute, second, zone), everything after
+	// day is optional and the zone can come right after the last number
+	module.Properties["make"] = NativeFunctionValue{
+		Name: "date.make",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			parts := args
+			loc := time.Local
+			if len(args) > 0 {
+				if _, isNumber := args[len(args)-1].(NumberVal); !isNumber {
+					var err error
+					loc, err = zoneArg("date.make", args, len(args)-1)
+					if err != nil {
+						return nil, err
+					}
+					parts = args[:len(args)-1]
+				}
+			}
+
+			nums, ok := numberArgs(parts)
+			if !ok || len(nums) < 3 || len(nums) > 6 {
+				return nil, &InterpretingError{Message: "date.make expects (year, month, day, hour, minute, second, zone)"}
+			}
+			for _, num := range nums {
+				if !(math.Abs(num) <= maxDatePart) || num != math.Trunc(num) {
+					errorMessage := fmt.Sprintf("date.make expects whole numbers from %v to %v but got %v", -maxDatePart, maxDatePart, num)
+					return nil, &InterpretingError{Message: errorMessage}
+				}
+			}
+			for len(nums) < 6 {
+				nums = append(nums, 0)
+			}
+
+			made := time.Date(int(nums[0]), time.Month(nums[1]), int(nums[2]), int(nums[3]), int(nums[4]), int(nums[5]), 0, loc)
+			return DateVal{Time: made}, nil
+		},
+	}
+
+	// parse(text, layout, zone) reads a date, by default trying iso, datetime
+	// and date layouts in turn. The zone is used when the text has no offset.
+	module.Properties["parse"] = NativeFunctionValue{
+		Name: "date.parse",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			text, ok := stringArg(args, 0)
+			if !ok || len(args) > 3 {
+				return nil, &InterpretingError{Message: "date.parse expects (text, layout, zone)"}
+			}
+
+			layouts := []string{time.RFC3339, time.DateTime, time.DateOnly}
+			if len(args) >= 2 {
+				layout, ok := stringArg(args, 1)
+				if !ok {
+					return nil, &TypeError{Operation: "date.parse", Expected: "a layout string", Got: TypeOf(args[1])}
+				}
+				layouts = []string{layoutFor(layout)}
+			}
+
+			loc, err := zoneArg("date.parse", args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, layout := range layouts {
+				parsed, err := time.ParseInLocation(layout, text, loc)
+				if err == nil {
+					return DateVal{Time: parsed}, nil
+				}
+			}
+			errorMessage := fmt.Sprintf("date.parse: cannot read %q as a date", text)
+			return nil, &InterpretingError{Message: errorMessage}
+		},
+	}
+
+	return module
+}
+
+// zoneArg reads an optional time zone name at args[i]
+func zoneArg(name string, args []RuntimeVal, i int) (*time.Location, error) {
+	if i >= len(args) {
+		return time.Local, nil
+	}
+
+	zone, ok := stringArg(args, i)
+	if !ok {
+		return nil, &TypeError{Operation: name, Expected: "a time zone name", Got: TypeOf(args[i])}
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		errorMessage := fmt.Sprintf("%s: unknown time zone %q", name, zone)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+	return loc, nil
+}
+
+// layoutFor turns a named layout like "iso" into a Go layout, anything else
+// is taken as a Go layout already
+func layoutFor(layout string) string {
+	if named, exists := timeLayouts[layout]; exists {
+		return named
+	}
+	return layout
+}
+
+//////////////////
+// Date Methods //
+//////////////////
+
+// dateMethod is a native method on dates, d is the receiver. Dates are
+// values, methods like addDays return a new date.
+type dateMethod func(d time.Time, args []RuntimeVal) (RuntimeVal, error)
+
+var dateMethods = map[string]dateMethod{
+	"addYears":   dateAdder(func(d time.Time, n float64) time.Time { return d.AddDate(int(n), 0, 0) }),
+	"addMonths":  dateAdder(func(d time.Time, n float64) time.Time { return d.AddDate(0, int(n), 0) }),
+	"addDays":    dateAdder(func(d time.Time, n float64) time.Time { return d.AddDate(0, 0, int(n)) }),
+	"addHours":   dateAdder(func(d time.Time, n float64) time.Time { return addSeconds(d, n*60*60) }),
+	"addMinutes": dateAdder(func(d time.Time, n float64) time.Time { return addSeconds(d, n*60) }),
+	"addSeconds": dateAdder(addSeconds),
+
+	// d.diff(other, unit) is d - other in ms, seconds, minutes, hours or days
+	"diff": func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) == 0 || len(args) > 2 {
+			return nil, &InterpretingError{Message: "date.diff expects (other, unit)"}
+		}
+		other, ok := args[0].(DateVal)
+		if !ok {
+			return nil, &TypeError{Operation: "date.diff", Expected: "a date", Got: TypeOf(args[0])}
+		}
+
+		unit := "ms"
+		if len(args) == 2 {
+			unit, ok = stringArg(args, 1)
+			if !ok {
+				return nil, &TypeError{Operation: "date.diff", Expected: "a unit name", Got: TypeOf(args[1])}
+			}
+		}
+		perUnit, exists := dateUnits[unit]
+		if !exists {
+			errorMessage := fmt.Sprintf("date.diff: unknown unit %q, use ms, seconds, minutes, hours or days", unit)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+
+		ms := float64(d.Sub(other.Time)) / float64(time.Millisecond)
+		return NumberVal{Value: ms / perUnit}, nil
+	},
+
+	// d.format(layout) defaults to iso
+	"format": func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		layout := time.RFC3339
+		if len(args) > 0 {
+			text, ok := stringArg(args, 0)
+			if !ok {
+				return nil, &TypeError{Operation: "date.format", Expected: "a layout string", Got: TypeOf(args[0])}
+			}
+			layout = layoutFor(text)
+		}
+		return StringVal{Value: d.Format(layout)}, nil
+	},
+
+	// d.inZone(zone) is the same instant shown in another time zone
+	"inZone": func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, &InterpretingError{Message: "date.inZone expects a time zone name"}
+		}
+		loc, err := zoneArg("date.inZone", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return DateVal{Time: d.In(loc)}, nil
+	},
+	"zone": func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		return StringVal{Value: d.Location().String()}, nil
+	},
+
+	"year":   datePart(func(d time.Time) int { return d.Year() }),
+	"month":  datePart(func(d time.Time) int { return int(d.Month()) }),
+	"day":    datePart(func(d time.Time) int { return d.Day() }),
+	"hour":   datePart(func(d time.Time) int { return d.Hour() }),
+	"minute": datePart(func(d time.Time) int { return d.Minute() }),
+	"second": datePart(func(d time.Time) int { return d.Second() }),
+	// 0 is Sunday
+	"weekday": datePart(func(d time.Time) int { return int(d.Weekday()) }),
+	"millis": func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		return NumberVal{Value: float64(d.UnixMilli())}, nil
+	},
+}
+
+// dateAdder builds an addX(n) method
+func dateAdder(add func(d time.Time, n float64) time.Time) dateMethod {
+	return func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		nums, ok := numberArgs(args)
+		if !ok || len(nums) != 1 {
+			return nil, &InterpretingError{Message: "date add methods expect a number"}
+		}
+		if !(math.Abs(nums[0]) <= maxDatePart) {
+			errorMessage := fmt.Sprintf("date add methods expect from %v to %v but got %v", -maxDatePart, maxDatePart, nums[0])
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		return DateVal{Time: add(d, nums[0])}, nil
+	}
+}
+
+// addSeconds moves d by secs, in whole seconds and nanoseconds apart since a
+// time.Duration can't hold more than about 292 years
+func addSeconds(d time.Time, secs float64) time.Time {
+	whole := math.Trunc(secs)
+	nanos := math.Round((secs - whole) * float64(time.Second))
+	return time.Unix(d.Unix()+int64(whole), int64(d.Nanosecond())+int64(nanos)).In(d.Location())
+}
+
+// datePart builds a method reading one calendar field
+func datePart(part func(d time.Time) int) dateMethod {
+	return func(d time.Time, args []RuntimeVal) (RuntimeVal, error) {
+		return NumberVal{Value: float64(part(d))}, nil
+	}
+}
+
+// bindDateMethod turns a date method into a function value that already knows
+// its receiver, like bindStringMethod
+func bindDateMethod(name string, date DateVal) (NativeFunctionValue, bool) {
+	method, found := dateMethods[name]
+	if !found {
+		return NativeFunctionValue{}, false
+	}
+
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return method(date.Time, args)
+		},
+		receiver: date,
+	}, true
+}
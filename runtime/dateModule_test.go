@@ -0,0 +1,93 @@
+package runtime_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestDateArithmetic(t *testing.T) {
+	tests := []struct {
+		src  string
+		want r.RuntimeVal
+	}{
+		{`date.make(2024, 1, 31, "UTC").format()`, r.StringVal{Value: "2024-01-31T00:00:00Z"}},
+		{`date.make(2024, 1, 31, 10, 30, 0, "UTC").addMonths(1).format()`, r.StringVal{Value: "2024-03-02T10:30:00Z"}},
+		{`date.make(2024, 13, 1, "UTC").format("date")`, r.StringVal{Value: "2025-01-01"}},
+		{`date.make(2024, 2, 28, "UTC").addDays(1).day()`, r.NumberVal{Value: 29}},
+		{`date.make(2024, 1, 1, "UTC").addYears(-1).year()`, r.NumberVal{Value: 2023}},
+		{`date.make(2024, 1, 1, "UTC").addHours(1.5).format()`, r.StringVal{Value: "2024-01-01T01:30:00Z"}},
+		{`date.make(2024, 1, 1, "UTC").addSeconds(-0.25).millis()`, r.NumberVal{Value: 1704067199750}},
+		{`date.make(2024, 1, 1, "UTC").addHours(1e9).year()`, r.NumberVal{Value: 116103}},
+		{`date.make(2024, 1, 2, "UTC").diff(date.make(2024, 1, 1, "UTC"), "hours")`, r.NumberVal{Value: 24}},
+		{`date.make(2024, 1, 1, "UTC").diff(date.make(2024, 1, 2, "UTC"), "days")`, r.NumberVal{Value: -1}},
+		{`date.fromMillis(0, "UTC").format()`, r.StringVal{Value: "1970-01-01T00:00:00Z"}},
+		{`date.fromMillis(8.64e15, "UTC").year()`, r.NumberVal{Value: 275760}},
+		{`date.parse("2024-05-06", "date", "UTC").weekday()`, r.NumberVal{Value: 1}},
+		{`date.parse("2024-05-06 07:08:09", "datetime", "UTC").hour()`, r.NumberVal{Value: 7}},
+		{`date.parse("2024-05-06T07:08:09+02:00").inZone("UTC").hour()`, r.NumberVal{Value: 5}},
+		{`date.make(2024, 1, 1, "UTC").inZone("Asia/Tokyo").zone()`, r.StringVal{Value: "Asia/Tokyo"}},
+		{`date.make(2024, 1, 1, "UTC") < date.make(2024, 1, 2, "UTC")`, r.BoolVal{Value: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), tt.want)
+		})
+	}
+}
+
+func TestDateArgumentErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		typeErr  bool // a TypeError rather than an InterpretingError
+		contains string
+	}{
+		{"date.make(2024, 1)", false, "date.make expects (year, month, day"},
+		{`date.make(2024, "1", 1)`, false, "date.make expects (year, month, day"},
+		{"date.make(1e300, 1, 1)", false, "date.make expects whole numbers"},
+		{"date.make(-1e300, 1, 1)", false, "date.make expects whole numbers"},
+		{"date.make(2024, 1.5, 1)", false, "but got 1.5"},
+		{"date.make(2024, 1, math.sqrt(-1))", false, "but got NaN"},
+		{"date.make(2024, math.pow(10, 400), 1)", false, "but got +Inf"},
+		{`date.make(2024, 1, 1, "Mars/Olympus")`, false, `unknown time zone "Mars/Olympus"`},
+		{"date.make(2024, 1, 1, true)", true, ""},
+		{"date.fromMillis()", false, "date.fromMillis expects (ms, zone)"},
+		{`date.fromMillis("0")`, true, ""},
+		{"date.fromMillis(1e300)", false, "date.fromMillis expects from"},
+		{"date.fromMillis(math.sqrt(-1))", false, "date.fromMillis expects from"},
+		{"date.parse()", false, "date.parse expects (text, layout, zone)"},
+		{`date.parse("soon")`, false, `cannot read "soon" as a date`},
+		{`date.parse("2024-01-01", 5)`, true, ""},
+		{"date.now(1)", true, ""},
+		{"date.now().addDays()", false, "date add methods expect a number"},
+		{`date.now().addDays("1")`, false, "date add methods expect a number"},
+		{"date.now().addDays(1e300)", false, "date add methods expect from"},
+		{"date.now().addSeconds(math.sqrt(-1))", false, "but got NaN"},
+		{"date.now().diff()", false, "date.diff expects (other, unit)"},
+		{"date.now().diff(1)", true, ""},
+		{`date.now().diff(date.now(), "weeks")`, false, `unknown unit "weeks"`},
+		{"date.now().format(1)", true, ""},
+		{"date.now().inZone()", false, "date.inZone expects a time zone name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var typeErr *r.TypeError
+			var interpErr *r.InterpretingError
+			switch {
+			case err == nil:
+				t.Fatal("got no error")
+			case tt.typeErr && !errors.As(err, &typeErr):
+				t.Fatalf("got %v, want a type error", err)
+			case !tt.typeErr && !errors.As(err, &interpErr):
+				t.Fatalf("got %v, want a runtime error", err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package runtime
+
+import (
+	"fmt"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+/////////////////
+// Diagnostics //
+/////////////////
+
+// The runtime's errors describe themselves as diagnostics too, see
+// f.Diagnostics
+
+func (e *InterpretingError) Diagnostic() f.Diagnostic {
+	return f.Diagnostic{
+		Code:     "runtime-error",
+		Message:  e.Message,
+		Range:    f.PointRange(e.Pos),
+		Severity: f.SeverityError,
+	}
+}
+
+func (e *TypeError) Diagnostic() f.Diagnostic {
+	return f.Diagnostic{
+		Code:     "type-error",
+		Message:  e.message(),
+		Range:    f.PointRange(e.Pos),
+		Severity: f.SeverityError,
+	}
+}
+
+func (e *LimitError) Diagnostic() f.Diagnostic {
+	return f.Diagnostic{
+		Code:     "limit-exceeded",
+		Message:  e.Message,
+		Range:    f.PointRange(e.Pos),
+		Severity: f.SeverityError,
+	}
+}
+
+func (e *ThrowError) Diagnostic() f.Diagnostic {
+	// errors made by the host only have a line and column
+	pos := e.Thrown.pos
+	if pos.Line() != e.Thrown.Line || pos.Column() != e.Thrown.Column {
+		pos = f.NewPosition(e.Thrown.Line, e.Thrown.Column, 0)
+	}
+	return f.Diagnostic{
+		Code:     "uncaught-error",
+		Message:  e.Thrown.Message,
+		Range:    f.PointRange(pos),
+		Severity: f.SeverityError,
+	}
+}
+
+// Diagnostic describes the exit, which is only worth showing when the code
+// isn't 0
+func (e *ExitError) Diagnostic() f.Diagnostic {
+	severity := f.SeverityError
+	if e.Code == 0 {
+		severity = f.SeverityInformation
+	}
+	return f.Diagnostic{
+		Code:     "exit",
+		Message:  e.Error(),
+		Severity: severity,
+	}
+}
+
+// Diagnostic is the diagnostic of the error inside the calls, with each call
+// it happened inside of as related information, innermost first
+func (e *TraceError) Diagnostic() f.Diagnostic {
+	diagnostic := f.Diagnostic{Code: "error", Message: e.Err.Error(), Severity: f.SeverityError}
+	if inner := f.Diagnostics(e.Err); len(inner) > 0 {
+		diagnostic = inner[0]
+	}
+	for _, frame := range e.Stack {
+		if frame.Pos.Line() == 0 {
+			continue
+		}
+		diagnostic.Related = append(diagnostic.Related, f.RelatedInformation{
+			Message: fmt.Sprintf("in %s, called here", frame.Function),
+			Range:   f.PointRange(frame.Pos),
+		})
+	}
+	return diagnostic
+}
@@ -0,0 +1,110 @@
+package runtime_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		code     string
+		message  string
+		line     int
+		column   int
+		severity f.Severity
+	}{
+		{"lexing", "val s = \"open", "parse-error", "", 1, 9, f.SeverityError},
+		{"parsing", "val x = (1 + ", "parse-error", "", 1, 13, f.SeverityError},
+		{"runtime", "val x = 1\nnope", "runtime-error", "nope", 2, 1, f.SeverityError},
+		{"type", "val x = 1\ndate.fromMillis(\"a\")", "type-error", "a number", 2, 16, f.SeverityError},
+		{"throw", "\n  throw \"bad\"", "uncaught-error", "bad", 2, 3, f.SeverityError},
+		{"exit 0", "exit(0)", "exit", "", 0, 0, f.SeverityInformation},
+		{"exit 1", "exit(1)", "exit", "", 0, 0, f.SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			diagnostics := f.Diagnostics(err)
+			if len(diagnostics) == 0 {
+				t.Fatalf("got no diagnostics from %v", err)
+			}
+			d := diagnostics[0]
+			if d.Code != tt.code || d.Severity != tt.severity {
+				t.Errorf("got %s %s, want %s %s", d.Severity, d.Code, tt.severity, tt.code)
+			}
+			if !strings.Contains(d.Message, tt.message) || strings.HasPrefix(d.Message, "Parsing Error") {
+				t.Errorf("got message %q, want it to mention %q", d.Message, tt.message)
+			}
+			if start := d.Range.Start; start.Line() != tt.line || start.Column() != tt.column {
+				t.Errorf("got %d:%d, want %d:%d", start.Line(), start.Column(), tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsOfEveryParseError(t *testing.T) {
+	_, err := testutil.Parse("val = 1\nval y = 2\nval = 3")
+	diagnostics := f.Diagnostics(err)
+	if len(diagnostics) != 2 {
+		t.Fatalf("got %d diagnostics from %v, want one for each bad line", len(diagnostics), err)
+	}
+	for i, line := range []int{1, 3} {
+		if got := diagnostics[i].Range.Start.Line(); got != line {
+			t.Errorf("diagnostic %d is on line %d, want %d", i, got, line)
+		}
+	}
+}
+
+func TestDiagnosticsOfLimits(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	env.SetMaxSteps(50)
+	_, err := testutil.EvalIn("while (true) {}", env)
+	diagnostics := f.Diagnostics(err)
+	if len(diagnostics) != 1 || diagnostics[0].Code != "limit-exceeded" {
+		t.Errorf("got %+v, want limit-exceeded", diagnostics)
+	}
+}
+
+// the calls an error happened in are related information, innermost first
+func TestDiagnosticsOfStackTraces(t *testing.T) {
+	src := `fun inner() { return nope }
+fun outer() { return inner() + 1 }
+outer()`
+	_, err := testutil.Eval(src)
+	var trace *r.TraceError
+	if !errors.As(err, &trace) {
+		t.Fatalf("got %v, want a stack trace", err)
+	}
+	d := f.Diagnostics(err)[0]
+	if d.Code != "runtime-error" || d.Range.Start.Line() != 1 {
+		t.Errorf("got %s on line %d, want the runtime error on line 1", d.Code, d.Range.Start.Line())
+	}
+	var related []string
+	for _, info := range d.Related {
+		related = append(related, fmt.Sprintf("%s %d", info.Message, info.Range.Start.Line()))
+	}
+	want := "in inner, called here 2, in outer, called here 3"
+	if got := strings.Join(related, ", "); got != want {
+		t.Errorf("got related %q, want %q", got, want)
+	}
+}
+
+// errors that aren't diagnosers, like the host's own, still get one
+func TestDiagnosticsOfOtherErrors(t *testing.T) {
+	err := fmt.Errorf("loading: %w", errors.New("no such file"))
+	diagnostics := f.Diagnostics(err)
+	if len(diagnostics) != 1 || diagnostics[0].Code != "error" || diagnostics[0].Message != "no such file" {
+		t.Errorf("got %+v, want the wrapped error", diagnostics)
+	}
+	if f.Diagnostics(nil) != nil {
+		t.Error("nil has diagnostics")
+	}
+}
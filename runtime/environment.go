@@ -2,7 +2,13 @@ package runtime
 
 import (
 	"fmt"
-	"strings"
+	"maps"
+	"math"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
 )
 
 func setupGlobalScope(env *Environment) {
@@ -12,44 +18,234 @@ func setupGlobalScope(env *Environment) {
 	env.DeclareVar("false", BoolVal{Value: false}, true)
 
 	// Defining native global functions
-	env.DeclareVar("print", NativeFunctionValue{
-		Name: "print",
-		Call: func(args []RuntimeVal, env *Environment) RuntimeVal {
-			var builder strings.Builder
+	setupPrintNatives(env)
+	setupInputNatives(env)
+	env.DeclareVar("exit", exitFunction("exit"), true)
+
+	// error(message) or error(message, value) builds an error to throw
+	env.DeclareVar("error", NativeFunctionValue{
+		Name: "error",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 || len(args) > 2 {
+				errorMessage := fmt.Sprintf("error expects 1 or 2 arguments but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			var payload RuntimeVal = NadaVal{}
+			if len(args) == 2 {
+				payload = args[1]
+			}
+			return ErrorVal{Message: args[0].String(), Value: payload}, nil
+		},
+	}, true)
+
+	// len works on strings (in characters), lists and objects (in properties)
+	env.DeclareVar("len", NativeFunctionValue{
+		Name: "len",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				errorMessage := fmt.Sprintf("len expects 1 argument but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			switch v := args[0].(type) {
+			case StringVal:
+				return NumberVal{Value: float64(utf8.RuneCountInString(v.Value))}, nil
+			case *ListVal:
+				return NumberVal{Value: float64(len(v.Elements))}, nil
+			case ObjectVal:
+				return NumberVal{Value: float64(len(v.Properties))}, nil
+			default:
+				return nil, &TypeError{Operation: "len", Expected: "a string, list or object", Got: TypeOf(v)}
+			}
+		},
+	}, true)
+
+	// type(x) is the name of the type of x, like "Number" or "List"
+	env.DeclareVar("type", NativeFunctionValue{
+		Name: "type",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				errorMessage := fmt.Sprintf("type expects 1 argument but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return StringVal{Value: TypeName(args[0])}, nil
+		},
+	}, true)
+
+	// ord('a') is 97, also takes one character strings
+	env.DeclareVar("ord", NativeFunctionValue{
+		Name: "ord",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			char, err := charArg("ord", args)
+			if err != nil {
+				return nil, err
+			}
+			return NumberVal{Value: float64(char)}, nil
+		},
+	}, true)
+
+	// chr(97) is 'a', the character with that code point. Like ord it also
+	// takes a char or a one character string, which it gives back as a char
+	env.DeclareVar("chr", NativeFunctionValue{
+		Name: "chr",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				errorMessage := fmt.Sprintf("chr expects 1 argument but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			num, ok := args[0].(NumberVal)
+			if !ok {
+				char, err := charArg("chr", args)
+				if err != nil {
+					return nil, err
+				}
+				return CharVal{Value: char}, nil
+			}
+			if num.Value < 0 || num.Value > unicode.MaxRune || num.Value != math.Trunc(num.Value) {
+				errorMessage := fmt.Sprintf("chr expects a code point from 0 to %d but got %s", unicode.MaxRune, num)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return CharVal{Value: rune(num.Value)}, nil
+		},
+	}, true)
+
+	// range(end), range(start, end) or range(start, end, step)
+	env.DeclareVar("range", NativeFunctionValue{
+		Name: "range",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 || len(args) > 3 {
+				errorMessage := fmt.Sprintf("range expects 1 to 3 arguments but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			bounds := make([]float64, len(args))
 			for i, arg := range args {
-				if i > 0 {
-					builder.WriteString("")
+				num, ok := arg.(NumberVal)
+				if !ok {
+					return nil, &TypeError{Operation: "range", Expected: "numbers", Got: TypeOf(arg)}
 				}
-				builder.WriteString(arg.String())
+				bounds[i] = num.Value
+			}
+
+			switch len(bounds) {
+			case 1:
+				return RangeVal{Start: 0, End: bounds[0], Step: 1}, nil
+			case 2:
+				return RangeVal{Start: bounds[0], End: bounds[1], Step: 1}, nil
+			default:
+				return RangeVal{Start: bounds[0], End: bounds[1], Step: bounds[2]}, nil
 			}
-			fmt.Println(builder.String())
-			return NadaVal{}
 		},
 	}, true)
+
+	setupObjectNatives(env)
+	setupConversionNatives(env)
+	setupIDNatives(env)
+	setupTestNatives(env)
+
+	// Native modules, frozen so programs can't swap out their functions
+	modules := map[string]ObjectVal{
+		"math":   mathModule(),
+		"random": randomModule(),
+		"time":   timeModule(),
+		"date":   dateModule(),
+		"os":     osModule(),
+		"json":   jsonModule(),
+		"path":   pathModule(),
+		"fs":     fsModule(),
+	}
+	for name, module := range modules {
+		env.session.freeze(module)
+		env.DeclareVar(name, module, true)
+	}
+	setupRegisteredModules(env)
+}
+
+// charArg reads the one argument of a native as a char, or a string that is
+// one character long
+func charArg(name string, args []RuntimeVal) (rune, error) {
+	if len(args) != 1 {
+		errorMessage := fmt.Sprintf("%s expects 1 argument but got %d", name, len(args))
+		return 0, &InterpretingError{Message: errorMessage}
+	}
+
+	switch v := args[0].(type) {
+	case CharVal:
+		return v.Value, nil
+	case StringVal:
+		runes := []rune(v.Value)
+		if len(runes) != 1 {
+			errorMessage := fmt.Sprintf("%s expects a single character but got %q", name, v.Value)
+			return 0, &InterpretingError{Message: errorMessage}
+		}
+		return runes[0], nil
+	}
+	return 0, &TypeError{Operation: name, Expected: "a char", Got: TypeOf(args[0])}
 }
 
 type Environment struct {
-	global    bool
-	parent    *Environment
-	variables map[string]RuntimeVal
-	constants map[string]struct{}
+	global     bool
+	parent     *Environment
+	variables  map[string]RuntimeVal
+	constants  map[string]struct{}
+	parameters map[string]struct{} // names declared as function parameters
+	session    *session            // shared by every environment of a program
+	file       string              // source file of the module this scope belongs to, if any
 }
 
 func NewEnvironment(parentEnv *Environment) *Environment {
 	e := &Environment{
-		global:    parentEnv == nil,
-		parent:    parentEnv,
-		variables: make(map[string]RuntimeVal),
-		constants: make(map[string]struct{}),
+		global:     parentEnv == nil,
+		parent:     parentEnv,
+		variables:  make(map[string]RuntimeVal),
+		constants:  make(map[string]struct{}),
+		parameters: make(map[string]struct{}),
 	}
 
 	if e.global {
+		e.session = newSession()
 		setupGlobalScope(e)
+	} else {
+		e.session = parentEnv.session
 	}
 
 	return e
 }
 
+// copyGlobal makes a new global scope, with a session of its own, holding
+// what the global scope env holds. env isn't changed so many copies can be
+// made from it at once, and the objects frozen in it stay frozen.
+func (env *Environment) copyGlobal() *Environment {
+	s := newSession()
+	maps.Copy(s.frozen, env.session.frozen)
+	s.policies = env.session.policies
+	return &Environment{
+		global:     true,
+		variables:  maps.Clone(env.variables),
+		constants:  maps.Clone(env.constants),
+		parameters: make(map[string]struct{}),
+		session:    s,
+	}
+}
+
+// SetFile records the source file code in this environment comes from, so
+// imports inside it resolve relative to that file
+func (env *Environment) SetFile(path string) {
+	env.file = path
+}
+
+// File is the source file of the closest enclosing module, empty if unknown
+func (env *Environment) File() string {
+	for scope := env; scope != nil; scope = scope.parent {
+		if scope.file != "" {
+			return scope.file
+		}
+	}
+	return ""
+}
+
 func (env *Environment) setVar(name string, value RuntimeVal) {
 	env.variables[name] = value
 }
@@ -60,6 +256,11 @@ func (env *Environment) DeclareVar(varName string, value RuntimeVal, constant bo
 		errorMessage := fmt.Sprintf("Variable %v already defined, cannot redeclare", varName)
 		return nil, &InterpretingError{Message: errorMessage}
 	}
+	if hooks := env.session.hooks; hooks != nil && hooks.OnDeclare != nil {
+		if err := hookError(hooks.OnDeclare(varName, value, constant)); err != nil {
+			return nil, err
+		}
+	}
 	env.setVar(varName, value)
 
 	if constant {
@@ -69,6 +270,36 @@ func (env *Environment) DeclareVar(varName string, value RuntimeVal, constant bo
 	return value, nil
 }
 
+// Redeclaring a name in the same scope is an error, but a declaration in a
+// child scope (a function, block or loop body) may shadow one from an outer
+// scope on purpose. warnShadowing flags the likely accidents, hiding a global
+// or a parameter, when the host asked for shadowing warnings.
+func (env *Environment) warnShadowing(varName string, pos f.Position) {
+	w := env.session.warn
+	if w == nil || env.global || env.parent == nil {
+		return
+	}
+	if _, exists := env.variables[varName]; exists {
+		return
+	}
+
+	owner, err := env.parent.resolve(varName)
+	if err != nil {
+		return
+	}
+
+	var shadowed string
+	if owner.global {
+		shadowed = "global"
+	} else if _, isParam := owner.parameters[varName]; isParam {
+		shadowed = "parameter"
+	} else {
+		return
+	}
+
+	fmt.Fprintf(w, "Warning at (%d, %d): %s shadows the %s %s\n", pos.Line(), pos.Column(), varName, shadowed, varName)
+}
+
 func (env *Environment) AssignVal(varName string, value RuntimeVal) (RuntimeVal, error) {
 	resolvedEnv, err := env.resolve(varName)
 	if err != nil {
@@ -79,6 +310,11 @@ func (env *Environment) AssignVal(varName string, value RuntimeVal) (RuntimeVal,
 		errorMessage := fmt.Sprintf("Cannot assign to constant variable: %v", varName)
 		return nil, &InterpretingError{Message: errorMessage}
 	}
+	if hooks := env.session.hooks; hooks != nil && hooks.OnAssign != nil {
+		if err := hookError(hooks.OnAssign(varName, value)); err != nil {
+			return nil, err
+		}
+	}
 
 	resolvedEnv.setVar(varName, value)
 	return value, nil
@@ -89,9 +325,26 @@ func (env *Environment) LookupVar(varName string) (RuntimeVal, error) {
 	if err != nil {
 		return nil, err
 	}
+	if hooks := env.session.hooks; hooks != nil && hooks.OnLookup != nil {
+		if err := hookError(hooks.OnLookup(varName)); err != nil {
+			return nil, err
+		}
+	}
 	return resolvedEnv.variables[varName], nil
 }
 
+// Names lists the variables declared in this scope, not the ones around it,
+// sorted
+func (env *Environment) Names() []string {
+	return slices.Sorted(maps.Keys(env.variables))
+}
+
+// IsConstant reports whether varName was declared in this scope as a constant
+func (env *Environment) IsConstant(varName string) bool {
+	_, exists := env.constants[varName]
+	return exists
+}
+
 func (env *Environment) resolve(varName string) (*Environment, error) {
 	_, exists := env.variables[varName]
 	if exists {
@@ -99,7 +352,7 @@ func (env *Environment) resolve(varName string) (*Environment, error) {
 	}
 	if env.parent == nil {
 		errorMessage := fmt.Sprintf("Variable %v does not exist", varName)
-		panic(errorMessage)
+		return nil, &InterpretingError{Message: errorMessage}
 	}
 	return env.parent.resolve(varName)
 }
@@ -1,25 +1,30 @@
 package runtime
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 
 	f "github.com/Mstr0A/a0-lang/frontend"
 )
 
 // Logical expression eval //
-func evalLogicalExpr(logicOp f.LogicalExpr, env *Environment) (RuntimeVal, error) {
-	leftSide, err := Evaluate(logicOp.Left, env)
-	if err != nil {
-		return nil, err
-	}
-
-	rightSide, err := Evaluate(logicOp.Right, env)
-	if err != nil {
-		return nil, err
-	}
+func evalLogicalExpr(logicOp f.LogicalExpr, env *Environment) step {
+	return evalThen(logicOp.Left, env, func(leftSide RuntimeVal) step {
+		return evalThen(logicOp.Right, env, func(rightSide RuntimeVal) step {
+			return finish(applyLogical(leftSide, rightSide, logicOp.Operator))
+		})
+	})
+}
 
-	switch logicOp.Operator {
+// applyLogical works out a logical or comparison operator, both sides are
+// always evaluated first
+func applyLogical(leftSide, rightSide RuntimeVal, operator string) (RuntimeVal, error) {
+	switch operator {
 	case "and":
 		return BoolVal{isTruthy(leftSide) && isTruthy(rightSide)}, nil
 	case "or":
@@ -27,22 +32,23 @@ func evalLogicalExpr(logicOp f.LogicalExpr, env *Environment) (RuntimeVal, error
 	case "not":
 		return BoolVal{!isTruthy(leftSide)}, nil
 	case "==":
-		return BoolVal{deepEqual(leftSide, rightSide)}, nil
+		return BoolVal{Equal(leftSide, rightSide)}, nil
 	case "!=":
-		return BoolVal{!deepEqual(leftSide, rightSide)}, nil
-	case "<":
-		return BoolVal{lessThan(leftSide, rightSide)}, nil
-	case "<=":
-		return BoolVal{lessEqual(leftSide, rightSide)}, nil
-	case ">":
-		return BoolVal{greaterThan(leftSide, rightSide)}, nil
-	case ">=":
-		return BoolVal{greaterEqual(leftSide, rightSide)}, nil
+		return BoolVal{!Equal(leftSide, rightSide)}, nil
+	case "<", "<=", ">", ">=":
+		order, ok, err := compareValues(leftSide, rightSide)
+		if err != nil {
+			return nil, err
+		}
+		return BoolVal{ok && orderHolds(order, operator)}, nil
 	default:
-		return nil, fmt.Errorf("unknown logical operator: %s", logicOp.Operator)
+		errorMessage := fmt.Sprintf("unknown logical operator: %s", operator)
+		return nil, &InterpretingError{Message: errorMessage}
 	}
 }
 
+// isTruthy decides how any value acts as a condition. false, nada, 0, empty
+// strings, lists, objects and ranges are falsy, everything else is truthy.
 func isTruthy(val RuntimeVal) bool {
 	switch v := val.(type) {
 	case BoolVal:
@@ -51,12 +57,45 @@ func isTruthy(val RuntimeVal) bool {
 		return v.Value != 0
 	case NadaVal:
 		return false
+	case StringVal:
+		return v.Value != ""
+	case *ListVal:
+		return len(v.Elements) > 0
+	case ObjectVal:
+		return len(v.Properties) > 0
+	case RangeVal:
+		return v.Len() > 0
 	default:
 		return val != nil
 	}
 }
 
-func deepEqual(a, b RuntimeVal) bool {
+// seenPairs holds the pairs of lists or objects a comparison is already
+// inside of, keyed by their addresses. Meeting a pair again means both values
+// contain themselves somewhere.
+type seenPairs map[[2]uintptr]struct{}
+
+// enter records the pair a, b and reports whether it was already there
+func (seen seenPairs) enter(a, b uintptr) bool {
+	key := [2]uintptr{a, b}
+	if _, found := seen[key]; found {
+		return true
+	}
+	seen[key] = struct{}{}
+	return false
+}
+
+// Equal reports whether a == b in a0: numbers, strings and the like by
+// value, lists and objects by their contents, functions and classes by
+// being the same one
+func Equal(a, b RuntimeVal) bool {
+	return valuesEqual(a, b, nil)
+}
+
+// valuesEqual is Equal for values found inside the lists and objects in
+// seen. A pair met again is taken as equal, so values that contain themselves
+// compare like reflect.DeepEqual compares them instead of recursing forever
+func valuesEqual(a, b RuntimeVal, seen seenPairs) bool {
 	if a == nil && b == nil {
 		return true
 	}
@@ -69,6 +108,14 @@ func deepEqual(a, b RuntimeVal) bool {
 		if b, ok := b.(NumberVal); ok {
 			return a.Value == b.Value
 		}
+	case StringVal:
+		if b, ok := b.(StringVal); ok {
+			return a.Value == b.Value
+		}
+	case CharVal:
+		if b, ok := b.(CharVal); ok {
+			return a.Value == b.Value
+		}
 	case BoolVal:
 		if b, ok := b.(BoolVal); ok {
 			return a.Value == b.Value
@@ -79,21 +126,87 @@ func deepEqual(a, b RuntimeVal) bool {
 		}
 	case ObjectVal:
 		if b, ok := b.(ObjectVal); ok {
-			return objectsEqual(a.Properties, b.Properties)
+			return objectsEqual(a.Properties, b.Properties, seen)
+		}
+	case *ListVal:
+		if b, ok := b.(*ListVal); ok {
+			return a == b || listsEqual(a, b, seen)
+		}
+	case DateVal:
+		if b, ok := b.(DateVal); ok {
+			return a.Time.Equal(b.Time)
+		}
+	case RangeVal:
+		if b, ok := b.(RangeVal); ok {
+			return a == b
+		}
+	case ErrorVal:
+		if b, ok := b.(ErrorVal); ok {
+			return a.Message == b.Message && valuesEqual(a.Value, b.Value, seen)
+		}
+	case *ClassVal:
+		if b, ok := b.(*ClassVal); ok {
+			return a == b
+		}
+	case UserFunctionValue:
+		if b, ok := b.(UserFunctionValue); ok {
+			return sameUserFunction(a, b)
+		}
+	case NativeFunctionValue:
+		if b, ok := b.(NativeFunctionValue); ok {
+			return sameNative(a, b)
 		}
 	}
 
+	// values of different types are never equal
 	return false
 }
 
-func objectsEqual(a, b map[string]RuntimeVal) bool {
+// sameUserFunction is true when both values come from the same declaration
+// evaluated in the same scope
+func sameUserFunction(a, b UserFunctionValue) bool {
+	if a.Name != b.Name || a.DeclarationEnv != b.DeclarationEnv || a.Code != b.Code || len(a.Body) != len(b.Body) {
+		return false
+	}
+	return len(a.Body) == 0 || &a.Body[0] == &b.Body[0]
+}
+
+// sameNative is true when both values are the same native, methods only when
+// they were taken from the same value
+func sameNative(a, b NativeFunctionValue) bool {
+	if a.Name != b.Name || reflect.ValueOf(a.Call).Pointer() != reflect.ValueOf(b.Call).Pointer() {
+		return false
+	}
+	switch receiver := a.receiver.(type) {
+	case nil:
+		return b.receiver == nil
+	case *ListVal:
+		return receiver == b.receiver
+	case ObjectVal:
+		other, ok := b.receiver.(ObjectVal)
+		return ok && reflect.ValueOf(receiver.Properties).Pointer() == reflect.ValueOf(other.Properties).Pointer()
+	}
+	return Equal(a.receiver, b.receiver)
+}
+
+func objectsEqual(a, b map[string]RuntimeVal, seen seenPairs) bool {
 	if len(a) != len(b) {
 		return false
 	}
+	addrA, addrB := reflect.ValueOf(a).Pointer(), reflect.ValueOf(b).Pointer()
+	if addrA == addrB {
+		return true
+	}
+	if seen == nil {
+		seen = seenPairs{}
+	}
+	if seen.enter(addrA, addrB) {
+		return true
+	}
 
 	for key, valA := range a {
 		valB, ok := b[key]
-		if !ok || !deepEqual(valA, valB) {
+		if !ok || !valuesEqual(valA, valB, seen) {
 			return false
 		}
 	}
@@ -101,57 +214,123 @@ func objectsEqual(a, b map[string]RuntimeVal) bool {
 	return true
 }
 
-func lessThan(a, b RuntimeVal) bool {
-	if aNum, ok := a.(NumberVal); ok {
-		if bNum, ok := b.(NumberVal); ok {
-			return aNum.Value < bNum.Value
-		}
+func listsEqual(a, b *ListVal, seen seenPairs) bool {
+	if len(a.Elements) != len(b.Elements) {
+		return false
+	}
+	if seen == nil {
+		seen = seenPairs{}
+	}
+	if seen.enter(listAddr(a), listAddr(b)) {
+		return true
 	}
-	return false
-}
 
-func lessEqual(a, b RuntimeVal) bool {
-	if aNum, ok := a.(NumberVal); ok {
-		if bNum, ok := b.(NumberVal); ok {
-			return aNum.Value <= bNum.Value
+	for i := range a.Elements {
+		if !valuesEqual(a.Elements[i], b.Elements[i], seen) {
+			return false
 		}
 	}
-	return false
+
+	return true
+}
+
+// compareValues orders two values of the same kind: numbers, strings, chars
+// and dates by value, lists element by element. ok is false for anything
+// else, values of different types included, and every ordering between them
+// is false. Two lists that both contain themselves can't be ordered, that is
+// an error.
+func compareValues(a, b RuntimeVal) (order int, ok bool, err error) {
+	return compareSeen(a, b, nil)
 }
 
-func greaterThan(a, b RuntimeVal) bool {
-	if aNum, ok := a.(NumberVal); ok {
-		if bNum, ok := b.(NumberVal); ok {
-			return aNum.Value > bNum.Value
+// compareSeen is compareValues for values found inside the lists in seen
+func compareSeen(a, b RuntimeVal, seen seenPairs) (order int, ok bool, err error) {
+	switch a := a.(type) {
+	case NumberVal:
+		if b, isNum := b.(NumberVal); isNum {
+			return cmp.Compare(a.Value, b.Value), true, nil
+		}
+	case StringVal:
+		// strings compare lexicographically by code point
+		if b, isStr := b.(StringVal); isStr {
+			return strings.Compare(a.Value, b.Value), true, nil
+		}
+	case CharVal:
+		if b, isChar := b.(CharVal); isChar {
+			return cmp.Compare(a.Value, b.Value), true, nil
+		}
+	case DateVal:
+		if b, isDate := b.(DateVal); isDate {
+			return a.Time.Compare(b.Time), true, nil
+		}
+	case *ListVal:
+		if b, isList := b.(*ListVal); isList {
+			if a == b {
+				return 0, true, nil
+			}
+			return compareLists(a, b, seen)
 		}
 	}
-	return false
+	return 0, false, nil
 }
 
-func greaterEqual(a, b RuntimeVal) bool {
-	if aNum, ok := a.(NumberVal); ok {
-		if bNum, ok := b.(NumberVal); ok {
-			return aNum.Value >= bNum.Value
+// compareLists orders lists by their first differing element, a list that
+// runs out first is the smaller one
+func compareLists(a, b *ListVal, seen seenPairs) (int, bool, error) {
+	if seen == nil {
+		seen = seenPairs{}
+	}
+	if seen.enter(listAddr(a), listAddr(b)) {
+		return 0, false, &InterpretingError{Message: "Cannot order lists that contain themselves"}
+	}
+
+	for i := 0; i < len(a.Elements) && i < len(b.Elements); i++ {
+		order, ok, err := compareSeen(a.Elements[i], b.Elements[i], seen)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+		if order != 0 {
+			return order, true, nil
 		}
 	}
-	return false
+	return cmp.Compare(len(a.Elements), len(b.Elements)), true, nil
 }
 
-// Binary expression eval //
-func evalBinaryExpr(binOp f.BinaryExpr, env *Environment) (RuntimeVal, error) {
-	leftSide, err := Evaluate(binOp.Left, env)
-	if err != nil {
-		return nil, err
+// orderHolds says whether an order from compareValues satisfies one of <,
+// <=, > and >=
+func orderHolds(order int, operator string) bool {
+	switch operator {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
 	}
+}
 
-	rightSide, err := Evaluate(binOp.Right, env)
-	if err != nil {
-		return nil, err
-	}
+// listAddr is the address a list is known by in seenPairs
+func listAddr(list *ListVal) uintptr {
+	return reflect.ValueOf(list).Pointer()
+}
+
+// Binary expression eval //
+func evalBinaryExpr(binOp f.BinaryExpr, env *Environment) step {
+	return evalThen(binOp.Left, env, func(leftSide RuntimeVal) step {
+		return evalThen(binOp.Right, env, func(rightSide RuntimeVal) step {
+			return finish(applyBinary(leftSide, rightSide, binOp.Operator))
+		})
+	})
+}
 
+// applyBinary works out an arithmetic operator, anything but two numbers
+// gives nada
+func applyBinary(leftSide, rightSide RuntimeVal, operator string) (RuntimeVal, error) {
 	if leftNum, ok1 := leftSide.(NumberVal); ok1 {
 		if rightNum, ok2 := rightSide.(NumberVal); ok2 {
-			return evalNumericBinaryExpr(leftNum, rightNum, binOp.Operator)
+			return evalNumericBinaryExpr(leftNum, rightNum, operator)
 		}
 	}
 
@@ -170,14 +349,18 @@ func evalNumericBinaryExpr(leftSide NumberVal, rightSide NumberVal, operator str
 		result = leftSide.Value * rightSide.Value
 	case "/":
 		if rightSide.Value == 0 {
-			result = 0
-		} else {
-			result = leftSide.Value / rightSide.Value
+			errorMessage := fmt.Sprintf("Division by zero: %v / 0", leftSide)
+			return NumberVal{}, &InterpretingError{Message: errorMessage}
 		}
+		result = leftSide.Value / rightSide.Value
 	case "%":
-		leftInt := int(leftSide.Value)
-		rightInt := int(rightSide.Value)
-		result = float64(leftInt % rightInt)
+		// the remainder takes the sign of the left side, like in Go and C,
+		// so -7 % 3 is -1 and 7 % -3 is 1
+		if rightSide.Value == 0 {
+			errorMessage := fmt.Sprintf("Modulo by zero: %v %% 0", leftSide)
+			return NumberVal{}, &InterpretingError{Message: errorMessage}
+		}
+		result = math.Mod(leftSide.Value, rightSide.Value)
 	default:
 		errorMessage := fmt.Sprintf("Unknown operator %v", operator)
 		return NumberVal{}, &InterpretingError{Message: errorMessage}
@@ -187,17 +370,24 @@ func evalNumericBinaryExpr(leftSide NumberVal, rightSide NumberVal, operator str
 }
 
 // Unary expression eval //
-func evalUnaryExpr(uOp f.UnaryExpr, env *Environment) (RuntimeVal, error) {
-	operant, err := Evaluate(uOp.Operant, env)
-	if err != nil {
-		return nil, err
+func evalUnaryExpr(uOp f.UnaryExpr, env *Environment) step {
+	return evalThen(uOp.Operant, env, func(operant RuntimeVal) step {
+		return finish(applyUnary(operant, uOp.Operator))
+	})
+}
+
+func applyUnary(operant RuntimeVal, operator string) (RuntimeVal, error) {
+	// not works on any value by its truthiness
+	if operator == "!" {
+		return BoolVal{Value: !isTruthy(operant)}, nil
 	}
 
-	if operantNum, ok := operant.(NumberVal); ok {
-		return evalNumericUnaryExpr(operantNum, uOp.Operator), nil
+	operantNum, ok := operant.(NumberVal)
+	if !ok {
+		return nil, &TypeError{Operation: "unary " + operator, Expected: "a number", Got: TypeOf(operant)}
 	}
 
-	return NadaVal{}, nil
+	return evalNumericUnaryExpr(operantNum, operator), nil
 }
 
 func evalNumericUnaryExpr(operant NumberVal, operator string) RuntimeVal {
@@ -206,12 +396,6 @@ func evalNumericUnaryExpr(operant NumberVal, operator string) RuntimeVal {
 	switch operator {
 	case "-":
 		result = -operant.Value
-	case "!":
-		if result == 0 {
-			result = 1
-		} else {
-			result = 0
-		}
 	default:
 		return operant
 	}
@@ -228,147 +412,830 @@ func evalIdentifier(ident f.Identifier, env *Environment) (RuntimeVal, error) {
 	return value, nil
 }
 
-func evalObjectExpr(obj f.ObjectLiteral, env *Environment) (RuntimeVal, error) {
-	var err error
+func evalObjectExpr(obj f.ObjectLiteral, env *Environment) step {
 	object := ObjectVal{Properties: make(map[string]RuntimeVal)}
 
-	for _, property := range obj.Properties {
-		key := property.Key
-		value := property.Value
+	var next func(i int) step
+	next = func(i int) step {
+		for ; i < len(obj.Properties); i++ {
+			property := obj.Properties[i]
+			if property.Value != nil {
+				return evalThen(property.Value, env, func(runtimeVal RuntimeVal) step {
+					object.Properties[property.Key] = runtimeVal
+					return next(i + 1)
+				})
+			}
 
-		var runtimeVal RuntimeVal
-		if value == nil {
-			runtimeVal, err = env.LookupVar(key)
+			runtimeVal, err := env.LookupVar(property.Key)
 			if err != nil {
-				return nil, err
+				return finish(nil, err)
 			}
-		} else {
-			runtimeVal, err = Evaluate(value, env)
-			if err != nil {
-				return nil, err
+			object.Properties[property.Key] = runtimeVal
+		}
+		return finish(object, env.session.allocate(valueBytes(object)))
+	}
+	return next(0)
+}
+
+func evalMemberExpr(expr f.MemberExpr, env *Environment) step {
+	return evalThen(expr.Object, env, func(objVal RuntimeVal) step {
+		return evalMemberProperty(expr, env, func(prop memberProperty) step {
+			return finish(lookupMember(objVal, prop))
+		})
+	})
+}
+
+// memberProperty is the property a member expression refers to, Name for
+// obj.name and the already evaluated Key for obj[key]
+type memberProperty struct {
+	Name     string
+	Key      RuntimeVal
+	Computed bool
+}
+
+// evalMemberProperty works out which property a member expression refers to
+func evalMemberProperty(expr f.MemberExpr, env *Environment, then func(memberProperty) step) step {
+	if !expr.Computed {
+		ident, ok := expr.Property.(f.Identifier)
+		if !ok {
+			errorMessage := fmt.Sprintf("Expected Identifier for non-computed property, got %T", expr.Property)
+			return finish(nil, &InterpretingError{Message: errorMessage})
+		}
+		return then(memberProperty{Name: ident.Symbol})
+	}
+
+	return evalThen(expr.Property, env, func(key RuntimeVal) step {
+		return then(memberProperty{Key: key, Computed: true})
+	})
+}
+
+// lookupMember reads a property from an already evaluated object
+func lookupMember(objVal RuntimeVal, prop memberProperty) (RuntimeVal, error) {
+	switch v := objVal.(type) {
+	case StringVal:
+		return evalStringMember(v, prop)
+	case *ListVal:
+		return evalListMember(v, prop)
+	case ErrorVal:
+		return evalErrorMember(v, prop)
+	case DateVal:
+		return evalDateMember(v, prop)
+	case SuperVal:
+		key, err := memberKey(prop)
+		if err != nil {
+			return nil, err
+		}
+		method, owner, found := v.Class.FindMethod(key)
+		if !found {
+			errorMessage := fmt.Sprintf("%s has no method %s", v.Class.Name, key)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		return bindMethod(method, owner, v.Self), nil
+	}
+
+	obj, ok := objVal.(ObjectVal)
+	if !ok {
+		errorMessage := fmt.Sprintf("Attempted to access property of non-object value: %v", objVal)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+
+	key, err := memberKey(prop)
+	if err != nil {
+		return nil, err
+	}
+
+	val, exists := obj.Properties[key]
+	if !exists {
+		// instances fall back to the methods of their class
+		if obj.Class != nil {
+			if method, owner, found := obj.Class.FindMethod(key); found {
+				return bindMethod(method, owner, obj), nil
 			}
 		}
+		return NadaVal{}, nil
+	}
+
+	return val, nil
+}
+
+// memberKey is the name of the object property prop refers to
+func memberKey(prop memberProperty) (string, error) {
+	if !prop.Computed {
+		return prop.Name, nil
+	}
+
+	switch k := prop.Key.(type) {
+	case StringVal:
+		return k.Value, nil
+	case NumberVal:
+		return strconv.FormatFloat(k.Value, 'f', -1, 64), nil
+	default:
+		errorMessage := fmt.Sprintf("Invalid computed property key type: %s", TypeName(prop.Key))
+		return "", &InterpretingError{Message: errorMessage}
+	}
+}
+
+// bindSelf gives a method a scope where self is the object it was read from
+func bindSelf(method UserFunctionValue, self RuntimeVal) UserFunctionValue {
+	scope := NewEnvironment(method.DeclarationEnv)
+	scope.DeclareVar("self", self, true)
+	method.DeclarationEnv = scope
+	return method
+}
+
+// bindMethod is bindSelf for class methods, which also get super when the
+// class declaring them has a parent
+func bindMethod(method UserFunctionValue, owner *ClassVal, self ObjectVal) UserFunctionValue {
+	method = bindSelf(method, self)
+	if owner.Parent != nil {
+		method.DeclarationEnv.DeclareVar("super", SuperVal{Self: self, Class: owner.Parent}, true)
+	}
+	return method
+}
+
+// Evaluating String Members //
+func evalStringMember(str StringVal, prop memberProperty) (RuntimeVal, error) {
+	// strings are indexed by rune so non-ASCII text isn't cut mid character
+	runes := []rune(str.Value)
+
+	if !prop.Computed {
+		method, found := bindStringMethod(prop.Name, str)
+		if !found {
+			errorMessage := fmt.Sprintf("Unknown string method: %s", prop.Name)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		return method, nil
+	}
 
-		object.Properties[key] = runtimeVal
+	index, err := toIndex(prop.Key, len(runes)-1)
+	if err != nil {
+		return nil, err
 	}
 
-	return object, err
+	return StringVal{Value: string(runes[index])}, nil
 }
 
-func evalMemberExpr(expr f.MemberExpr, env *Environment) (RuntimeVal, error) {
-	objVal, err := Evaluate(expr.Object, env)
+// Evaluating List Members //
+func evalListMember(list *ListVal, prop memberProperty) (RuntimeVal, error) {
+	if !prop.Computed {
+		method, found := bindListMethod(prop.Name, list)
+		if !found {
+			errorMessage := fmt.Sprintf("Unknown list method: %s", prop.Name)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		return method, nil
+	}
+
+	index, err := toIndex(prop.Key, len(list.Elements)-1)
 	if err != nil {
 		return nil, err
 	}
 
-	obj, ok := objVal.(ObjectVal)
-	if !ok {
-		return nil, fmt.Errorf("Attempted to access property of non-object value: %v", objVal)
+	return list.Elements[index], nil
+}
+
+// Evaluating Date Members //
+func evalDateMember(date DateVal, prop memberProperty) (RuntimeVal, error) {
+	if prop.Computed {
+		return nil, &InterpretingError{Message: "Dates can only be accessed with methods like d.format()"}
 	}
 
-	var key string
+	method, found := bindDateMethod(prop.Name, date)
+	if !found {
+		errorMessage := fmt.Sprintf("Unknown date method: %s", prop.Name)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+	return method, nil
+}
 
-	if expr.Computed {
-		propVal, err := Evaluate(expr.Property, env)
-		if err != nil {
-			return nil, err
+// Evaluating Error Members //
+func evalErrorMember(errVal ErrorVal, prop memberProperty) (RuntimeVal, error) {
+	if prop.Computed {
+		return nil, &InterpretingError{Message: "Error properties can only be read with a dot"}
+	}
+
+	switch prop.Name {
+	case "message":
+		return StringVal{Value: errVal.Message}, nil
+	case "value":
+		if errVal.Value == nil {
+			return NadaVal{}, nil
 		}
+		return errVal.Value, nil
+	case "line":
+		return NumberVal{Value: float64(errVal.Line)}, nil
+	case "column":
+		return NumberVal{Value: float64(errVal.Column)}, nil
+	default:
+		errorMessage := fmt.Sprintf("Unknown error property: %s", prop.Name)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+}
 
-		switch k := propVal.(type) {
+// Evaluating Array Literals //
+func evalArrayExpr(arr f.ArrayLiteral, env *Environment) step {
+	return evalAll(arr.Elements, env, func(elements []RuntimeVal) step {
+		return finish(&ListVal{Elements: elements}, env.session.allocate(listBytes(len(elements))))
+	})
+}
+
+// Evaluating Slices //
+func evalSliceExpr(expr f.SliceExpr, env *Environment) step {
+	return evalThen(expr.Object, env, func(objVal RuntimeVal) step {
+		switch v := objVal.(type) {
 		case StringVal:
-			key = k.Value
-		case NumberVal:
-			key = strconv.FormatFloat(k.Value, 'f', -1, 64)
+			runes := []rune(v.Value)
+			return evalSliceBounds(expr, len(runes), env, func(start, end int) step {
+				slice := StringVal{Value: string(runes[start:end])}
+				return finish(slice, env.session.allocate(valueBytes(slice)))
+			})
+		case *ListVal:
+			return evalSliceBounds(expr, len(v.Elements), env, func(start, end int) step {
+				// slices are copies, changing one doesn't touch the original list
+				elements := make([]RuntimeVal, end-start)
+				copy(elements, v.Elements[start:end])
+				return finish(&ListVal{Elements: elements}, env.session.allocate(listBytes(len(elements))))
+			})
 		default:
-			return nil, fmt.Errorf("Invalid computed property key type: %T", propVal)
+			errorMessage := fmt.Sprintf("Cannot slice value: %v", objVal)
+			return finish(nil, &InterpretingError{Message: errorMessage})
 		}
+	})
+}
 
-	} else {
-		ident, ok := expr.Property.(f.Identifier)
-		if !ok {
-			return nil, fmt.Errorf("Expected Identifier for non-computed property, got %T", expr.Property)
+// evalSliceBounds works out the start and end of a slice over length items
+func evalSliceBounds(expr f.SliceExpr, length int, env *Environment, then func(start, end int) step) step {
+	// bound is the index node evaluates to, or otherwise when it's left out
+	bound := func(node f.Expr, otherwise int, then func(int) step) step {
+		if node == nil {
+			return then(otherwise)
 		}
-		key = ident.Symbol
+		return evalThen(node, env, func(val RuntimeVal) step {
+			index, err := toIndex(val, length)
+			if err != nil {
+				return finish(nil, err)
+			}
+			return then(index)
+		})
 	}
 
-	val, exists := obj.Properties[key]
-	if !exists {
-		return NadaVal{}, nil
+	return bound(expr.Start, 0, func(start int) step {
+		return bound(expr.End, length, func(end int) step {
+			if start > end {
+				errorMessage := fmt.Sprintf("Slice start %d is after slice end %d", start, end)
+				return finish(nil, &InterpretingError{Message: errorMessage})
+			}
+
+			return then(start, end)
+		})
+	})
+}
+
+// Evaluating Ranges //
+func evalRangeExpr(expr f.RangeExpr, env *Environment) step {
+	return evalThen(expr.Start, env, func(startVal RuntimeVal) step {
+		return evalThen(expr.End, env, func(endVal RuntimeVal) step {
+			return finish(makeRange(startVal, endVal))
+		})
+	})
+}
+
+// makeRange is start..end
+func makeRange(startVal, endVal RuntimeVal) (RuntimeVal, error) {
+	start, ok1 := startVal.(NumberVal)
+	end, ok2 := endVal.(NumberVal)
+	if !ok1 || !ok2 {
+		errorMessage := fmt.Sprintf("Range bounds must be numbers, got: %v..%v", startVal, endVal)
+		return nil, &InterpretingError{Message: errorMessage}
 	}
 
-	return val, nil
+	return RangeVal{Start: start.Value, End: end.Value, Step: 1}, nil
+}
+
+// toIndex converts an index value into an int between 0 and max inclusive
+func toIndex(val RuntimeVal, max int) (int, error) {
+	num, ok := val.(NumberVal)
+	if !ok || num.Value != math.Trunc(num.Value) {
+		errorMessage := fmt.Sprintf("Index must be a whole number, got: %v", val)
+		return 0, &InterpretingError{Message: errorMessage}
+	}
+
+	index := int(num.Value)
+	if index < 0 || index > max {
+		errorMessage := fmt.Sprintf("Index %d out of range", index)
+		return 0, &InterpretingError{Message: errorMessage}
+	}
+
+	return index, nil
 }
 
 // Evaluating Assignment Expression //
-func evalAssignmentExpr(node f.AssignmentExpr, env *Environment) (RuntimeVal, error) {
-	if node.Assignee.NodeType() != f.IdentifierNode {
-		errorMessage := fmt.Sprintf("Invalid left side of assignemt: %v", node.Assignee)
-		panic(errorMessage)
+func evalAssignmentExpr(node f.AssignmentExpr, env *Environment) step {
+	switch node.Assignee.(type) {
+	case f.ObjectPattern, f.ArrayPattern:
+		return evalThen(node.Value, env, func(value RuntimeVal) step {
+			err := destructure(node.Assignee, value, func(name string, val RuntimeVal) error {
+				_, err := env.AssignVal(name, val)
+				return err
+			})
+			if err != nil {
+				return finish(nil, err)
+			}
+
+			return finish(value, nil)
+		})
 	}
 
-	assigneeName := node.Assignee.(f.Identifier).Symbol
-	assigneeValue, err := Evaluate(node.Value, env)
-	if err != nil {
-		return nil, err
+	if member, ok := node.Assignee.(f.MemberExpr); ok {
+		return evalMemberAssignment(member, node.Value, env)
+	}
+
+	ident, ok := node.Assignee.(f.Identifier)
+	if !ok {
+		errorMessage := fmt.Sprintf("Invalid left side of assignment: %v", node.Assignee)
+		return finish(nil, &InterpretingError{Message: errorMessage})
 	}
 
-	valueToReturn, err := env.AssignVal(assigneeName, assigneeValue)
+	return evalThen(node.Value, env, func(assigneeValue RuntimeVal) step {
+		return finish(env.AssignVal(ident.Symbol, assigneeValue))
+	})
+}
+
+// Evaluating Member Assignment //
+func evalMemberAssignment(member f.MemberExpr, valueExpr f.Expr, env *Environment) step {
+	return evalThen(member.Object, env, func(objVal RuntimeVal) step {
+		return evalMemberProperty(member, env, func(prop memberProperty) step {
+			return evalThen(valueExpr, env, func(value RuntimeVal) step {
+				return finish(setMember(objVal, prop, value, env))
+			})
+		})
+	})
+}
+
+// setMember does obj.prop = value and obj["prop"] = value, setting the
+// property on the object and adding it if it's new, which every reference to
+// the object sees since objects share their property map. list[i] = value
+// does the same for an existing element of a list.
+func setMember(objVal RuntimeVal, prop memberProperty, value RuntimeVal, env *Environment) (RuntimeVal, error) {
+	switch target := objVal.(type) {
+	case *ListVal:
+		return setListElement(target, prop, value)
+	case StringVal:
+		return nil, &InterpretingError{Message: "Strings can't be changed in place, build a new one instead"}
+	}
+
+	obj, ok := objVal.(ObjectVal)
+	if !ok {
+		errorMessage := fmt.Sprintf("Cannot set property on non-object value: %v", objVal)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+
+	key, err := memberKey(prop)
 	if err != nil {
 		return nil, err
 	}
 
-	return valueToReturn, nil
-}
+	// a const variable holding an object can't be reassigned but its
+	// properties can change, freezing is what locks them
+	if env.session.isFrozen(obj) {
+		errorMessage := fmt.Sprintf("Cannot assign to property %s of frozen object", key)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
 
-func evalCallExpr(expr f.CallExpr, env *Environment) (RuntimeVal, error) {
-	var err error
-	args := make([]RuntimeVal, len(expr.Args))
-	for i, arg := range expr.Args {
-		args[i], err = Evaluate(arg, env)
-		if err != nil {
+	if _, exists := obj.Properties[key]; !exists {
+		if err := env.session.allocate(propertySize + len(key)); err != nil {
 			return nil, err
 		}
 	}
+	obj.Properties[key] = value
+	return value, nil
+}
+
+// setListElement replaces the element at an index, growing the list is left
+// to push and insert so a typo'd index can't leave holes in it
+func setListElement(list *ListVal, prop memberProperty, value RuntimeVal) (RuntimeVal, error) {
+	if !prop.Computed {
+		return nil, &InterpretingError{Message: "Cannot set properties on a list, use list[index] = value"}
+	}
 
-	fn, err := Evaluate(expr.Caller, env)
+	index, err := toIndex(prop.Key, len(list.Elements)-1)
 	if err != nil {
 		return nil, err
 	}
 
+	list.Elements[index] = value
+	return value, nil
+}
+
+func evalCallExpr(expr f.CallExpr, env *Environment) step {
+	return evalCallee(expr, env, func(fn RuntimeVal, args []RuntimeVal) step {
+		return callAt(fn, args, expr.Pos, env)
+	})
+}
+
+// callAt calls fn as a call written at pos, with a frame on the call stack
+func callAt(fn RuntimeVal, args []RuntimeVal, pos f.Position, env *Environment) step {
+	if err := env.session.hookCall(fn, args, pos); err != nil {
+		return finish(nil, err)
+	}
+	return env.session.call(calleeName(fn), pos, func() step {
+		if userFn, ok := fn.(UserFunctionValue); ok {
+			// the frame call just pushed is the one tail calls take over
+			return callUserFunction(userFn, args, len(env.session.stack)-1)
+		}
+		return callFunction(fn, args, env)
+	})
+}
+
+// evalCallee evaluates what a call expression calls and its arguments
+func evalCallee(expr f.CallExpr, env *Environment, then func(fn RuntimeVal, args []RuntimeVal) step) step {
+	return evalAll(expr.Args, env, func(args []RuntimeVal) step {
+		// for obj.method() keep hold of obj so the method can see it as self
+		member, ok := expr.Caller.(f.MemberExpr)
+		if !ok {
+			return evalThen(expr.Caller, env, func(fn RuntimeVal) step {
+				return then(fn, args)
+			})
+		}
+
+		return evalThen(member.Object, env, func(receiver RuntimeVal) step {
+			return evalMemberProperty(member, env, func(prop memberProperty) step {
+				fn, err := lookupMember(receiver, prop)
+				if err != nil {
+					return finish(nil, err)
+				}
+				return then(bindReceiver(fn, receiver), args)
+			})
+		})
+	})
+}
+
+// bindReceiver makes a function read from an object see it as self
+func bindReceiver(fn RuntimeVal, receiver RuntimeVal) RuntimeVal {
+	if method, ok := fn.(UserFunctionValue); ok {
+		if obj, ok := receiver.(ObjectVal); ok {
+			return bindSelf(method, obj)
+		}
+	}
+	return fn
+}
+
+// CallFunction calls any callable value (a0 functions, natives and classes)
+// with already evaluated arguments. It's how natives call back into a0, like
+// list.map calling the function it was given, env is the environment the
+// native itself was called with.
+func CallFunction(fn RuntimeVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+	return runSteps(callFunction(fn, args, env))
+}
+
+// callFunction is CallFunction as a step of the call that's making it
+func callFunction(fn RuntimeVal, args []RuntimeVal, env *Environment) step {
 	switch callableFn := fn.(type) {
 	case NativeFunctionValue:
-		result := callableFn.Call(args, env)
-		return result, nil
+		length := 0
+		if list, ok := callableFn.receiver.(*ListVal); ok {
+			length = len(list.Elements)
+		}
+		result, err := callableFn.Call(args, env)
+		if err != nil {
+			return finish(nil, nativeError(callableFn.Name, err))
+		}
+		return finish(result, env.session.allocateCall(callableFn, args, result, length))
 
 	case UserFunctionValue:
-		scope := NewEnvironment(callableFn.DeclarationEnv)
+		return callUserFunction(callableFn, args, noFrame)
 
-		// Creates the variables for the paremeters list
-		if len(callableFn.Parameters) != len(args) {
-			errorMessage := fmt.Sprintf("Args do not match amount of parameters in function call for: %s", callableFn.Name)
-			return nil, &InterpretingError{Message: errorMessage}
+	case *ClassVal:
+		return instantiate(callableFn, args)
+
+	default:
+		errorMessage := fmt.Sprintf("Cannot call value that is not a function: %v", fn)
+		return finish(nil, &InterpretingError{Message: errorMessage})
+	}
+}
+
+// nativeError lets natives fail with plain Go errors, like the ones from the
+// os package. Those become runtime errors naming the native, which Evaluate
+// then gives the position of the call, errors already meant for a0 pass
+// through as they are.
+func nativeError(name string, err error) error {
+	var interpErr *InterpretingError
+	var typeErr *TypeError
+	var thrown *ThrowError
+	var trace *TraceError
+	if errors.As(err, &interpErr) || errors.As(err, &typeErr) || errors.As(err, &thrown) ||
+		uncatchable(err) || errors.As(err, &trace) {
+		return err
+	}
+
+	errorMessage := fmt.Sprintf("%s: %v", name, err)
+	return &InterpretingError{Message: errorMessage}
+}
+
+// noFrame is passed to callUserFunction when no call stack frame stands for
+// the call, like when a native calls back into a0
+const noFrame = -1
+
+// tailCall is a call in tail position, return f(x), that is made by
+// callUserFunction after the function returning it has finished. Tail
+// recursion then runs in a loop instead of nesting calls, so it never hits
+// the recursion limit.
+type tailCall struct {
+	Fn   RuntimeVal
+	Args []RuntimeVal
+	Pos  f.Position
+}
+
+// callUserFunction runs a user function with already evaluated arguments,
+// along with any tail calls it ends in. frame is the index of the call stack
+// frame for the call, tail calls replace it, or noFrame.
+func callUserFunction(fn UserFunctionValue, args []RuntimeVal, frame int) step {
+	return runUserFunction(fn, args, frame, f.Position{})
+}
+
+// runUserFunction evaluates the body of fn as a task of its own and then
+// makes the tail call it returned, if any, in its place. tailPos is where the
+// call being made was written, for tail calls.
+func runUserFunction(fn UserFunctionValue, args []RuntimeVal, frame int, tailPos f.Position) step {
+	fail := func(err error) step {
+		if tailPos.Line() != 0 {
+			setErrorPosition(err, tailPos)
 		}
-		for i := 0; i < len(callableFn.Parameters); i++ {
-			varName := callableFn.Parameters[i]
-			scope.DeclareVar(varName, args[i], false)
+		return finish(nil, err)
+	}
+
+	if fn.Code != nil {
+		result, err := runCompiledFunction(fn, args, frame)
+		if err != nil {
+			return fail(err)
 		}
+		return finish(result, nil)
+	}
+
+	scope, err := bindParameters(fn, args)
+	if err != nil {
+		return fail(err)
+	}
+
+	return blockThenCatch(fn.Body, scope, func(result RuntimeVal, err error) step {
+		if err != nil {
+			return fail(err)
+		}
+		if err := strayLoopSignal(result); err != nil {
+			return finish(nil, err)
+		}
+
+		ret, ok := result.(ReturnSignal)
+		if !ok {
+			return finish(NadaVal{}, nil)
+		}
+		if ret.Tail == nil {
+			return finish(ret.Value, nil)
+		}
+
+		tail := ret.Tail
+		session := fn.DeclarationEnv.session
+		if err := session.hookCall(tail.Fn, tail.Args, tail.Pos); err != nil {
+			setErrorPosition(err, tail.Pos)
+			return finish(nil, err)
+		}
+		if frame != noFrame {
+			session.stack[frame] = Frame{Function: calleeName(tail.Fn), Pos: tail.Pos}
+		}
+
+		next, ok := tail.Fn.(UserFunctionValue)
+		if !ok {
+			// natives and classes don't nest any further, just call them
+			return enterCatch(func() step {
+				return callFunction(tail.Fn, tail.Args, fn.DeclarationEnv)
+			}, func(value RuntimeVal, err error) step {
+				if err != nil {
+					setErrorPosition(err, tail.Pos)
+				}
+				return finish(value, err)
+			})
+		}
+		return runUserFunction(next, tail.Args, frame, tail.Pos)
+	})
+}
+
+// bindParameters creates the scope a call to fn runs in, with a variable for
+// each parameter
+func bindParameters(fn UserFunctionValue, args []RuntimeVal) (*Environment, error) {
+	scope := NewEnvironment(fn.DeclarationEnv)
+
+	// Creates the variables for the paremeters list
+	if len(fn.Parameters) != len(args) {
+		errorMessage := fmt.Sprintf("Args do not match amount of parameters in function call for: %s", fn.Name)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+	for i := 0; i < len(fn.Parameters); i++ {
+		varName := fn.Parameters[i]
+		if _, err := scope.DeclareVar(varName, args[i], false); err != nil {
+			return nil, err
+		}
+		scope.parameters[varName] = struct{}{}
+	}
+
+	return scope, nil
+}
+
+// finishTailCall makes the tail call a return is waiting on, if any, for
+// places that need the returned value right away instead of leaving the call
+// to callUserFunction. catch gets the ReturnSignal with the value in it.
+func finishTailCall(ret ReturnSignal, env *Environment, catch func(RuntimeVal, error) step) step {
+	if ret.Tail == nil {
+		return catch(ret, nil)
+	}
+
+	tail := ret.Tail
+	return enterCatch(func() step {
+		return callAt(tail.Fn, tail.Args, tail.Pos, env)
+	}, func(value RuntimeVal, err error) step {
+		if err != nil {
+			setErrorPosition(err, tail.Pos)
+			return catch(ReturnSignal{}, err)
+		}
+		return catch(ReturnSignal{Value: value}, nil)
+	})
+}
+
+// Evaluating New Expressions //
+func evalNewExpr(expr f.NewExpr, env *Environment) step {
+	return evalThen(expr.Class, env, func(classVal RuntimeVal) step {
+		if _, ok := classVal.(*ClassVal); !ok {
+			return finish(nil, notAClass(classVal))
+		}
+
+		return evalAll(expr.Args, env, func(args []RuntimeVal) step {
+			return construct(classVal, args, expr.Pos, env)
+		})
+	})
+}
 
-		var result RuntimeVal = NadaVal{}
-		for _, stmt := range callableFn.Body {
-			result, err = Evaluate(stmt, scope)
+// construct is new class(args) written at pos
+func construct(classVal RuntimeVal, args []RuntimeVal, pos f.Position, env *Environment) step {
+	class, ok := classVal.(*ClassVal)
+	if !ok {
+		return finish(nil, notAClass(classVal))
+	}
+	if err := env.session.hookCall(class, args, pos); err != nil {
+		return finish(nil, err)
+	}
+	if err := env.session.allocate(objectBytes(0)); err != nil {
+		return finish(nil, err)
+	}
+
+	return env.session.call(class.Name, pos, func() step {
+		return instantiate(class, args)
+	})
+}
+
+func notAClass(val RuntimeVal) error {
+	errorMessage := fmt.Sprintf("Cannot use new on a value that is not a class: %v", val)
+	return &InterpretingError{Message: errorMessage}
+}
+
+// instantiate creates a new instance of a class and runs its init method
+func instantiate(class *ClassVal, args []RuntimeVal) step {
+	instance := ObjectVal{
+		Properties: make(map[string]RuntimeVal),
+		ObjectName: class.Name,
+		Class:      class,
+	}
+
+	init, owner, found := class.FindMethod("init")
+	if !found {
+		if len(args) > 0 {
+			errorMessage := fmt.Sprintf("%s has no init method but was given %d argument(s)", class.Name, len(args))
+			return finish(nil, &InterpretingError{Message: errorMessage})
+		}
+		return finish(instance, nil)
+	}
+
+	if len(init.Parameters) != len(args) {
+		errorMessage := fmt.Sprintf("%s.init expects %d argument(s) (%s) but got %d",
+			class.Name, len(init.Parameters), strings.Join(init.Parameters, ", "), len(args))
+		return finish(nil, &InterpretingError{Message: errorMessage})
+	}
+
+	return enterThen(func() step {
+		return callUserFunction(bindMethod(init, owner, instance), args, noFrame)
+	}, func(RuntimeVal) step {
+		return finish(instance, nil)
+	})
+}
+
+// Evaluating Match Expressions //
+func evalMatchExpr(expr f.MatchExpr, env *Environment) step {
+	return evalThen(expr.Subject, env, func(subject RuntimeVal) step {
+		var tryArm func(i int) step
+		tryArm = func(i int) step {
+			for ; i < len(expr.Arms); i++ {
+				arm := expr.Arms[i]
+				bindings := map[string]RuntimeVal{}
+				matched, err := matchPattern(arm.Pattern, subject, bindings, env)
+				if err != nil {
+					return finish(nil, err)
+				}
+				if !matched {
+					continue
+				}
+
+				// bindings only live inside the arm
+				armEnv := NewEnvironment(env)
+				for name, val := range bindings {
+					if _, err := armEnv.DeclareVar(name, val, false); err != nil {
+						return finish(nil, err)
+					}
+				}
+
+				if arm.Guard == nil {
+					return evalBlock(arm.Body, armEnv)
+				}
+				next := i + 1
+				return evalThen(arm.Guard, armEnv, func(guard RuntimeVal) step {
+					if !isTruthy(guard) {
+						return tryArm(next)
+					}
+					return evalBlock(arm.Body, armEnv)
+				})
+			}
+
+			// no arm matched
+			return finish(NadaVal{}, nil)
+		}
+		return tryArm(0)
+	})
+}
+
+// matchPattern reports whether value fits pattern, collecting the names the
+// pattern binds along the way
+func matchPattern(pattern f.Expr, value RuntimeVal, bindings map[string]RuntimeVal, env *Environment) (bool, error) {
+	switch p := pattern.(type) {
+	case f.Identifier:
+		switch p.Symbol {
+		case "_":
+			return true, nil
+		case "true", "false", "nada":
+			literal, err := env.LookupVar(p.Symbol)
 			if err != nil {
-				return nil, err
+				return false, err
+			}
+			return Equal(literal, value), nil
+		}
+
+		bindings[p.Symbol] = value
+		return true, nil
+
+	case f.NumericLiteral, f.StringLiteral, f.CharLiteral:
+		literal, err := Evaluate(p, env)
+		if err != nil {
+			return false, err
+		}
+		return Equal(literal, value), nil
+
+	case f.ObjectLiteral:
+		obj, ok := value.(ObjectVal)
+		if !ok {
+			return false, nil
+		}
+
+		for _, property := range p.Properties {
+			field, exists := obj.Properties[property.Key]
+			if !exists {
+				return false, nil
+			}
+
+			// {x} binds the field, {x: pattern} matches it
+			if property.Value == nil {
+				bindings[property.Key] = field
+				continue
 			}
 
-			if ret, ok := result.(ReturnValue); ok {
-				return ret.Value, nil
+			matched, err := matchPattern(property.Value, field, bindings, env)
+			if err != nil || !matched {
+				return false, err
 			}
 		}
+		return true, nil
 
-		return NadaVal{}, nil
+	case f.ArrayLiteral:
+		list, ok := value.(*ListVal)
+		if !ok || len(list.Elements) != len(p.Elements) {
+			return false, nil
+		}
+
+		for i, element := range p.Elements {
+			matched, err := matchPattern(element, list.Elements[i], bindings, env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
 
 	default:
-		errorMessage := fmt.Sprintf("Cannot call value that is not a function: %v", fn)
-		return nil, &InterpretingError{Message: errorMessage}
+		errorMessage := fmt.Sprintf("Invalid match pattern: %v", pattern)
+		return false, &InterpretingError{Message: errorMessage}
 	}
 }
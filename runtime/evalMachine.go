@@ -0,0 +1,147 @@
+package runtime
+
+import (
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+//////////////////
+// Eval Machine //
+//////////////////
+
+// step is what a task does next. Evaluating a node or making a call is a
+// task of its own on the machine's stack instead of a Go call, a task that
+// needs one asks for it with a step naming the node (or enter for a call)
+// and says how it carries on with the result in then. A step with neither is
+// the task being done with value or err.
+type step struct {
+	node  f.Stmt
+	env   *Environment
+	enter func() step // starts a task that isn't a node, like a call
+
+	then  func(RuntimeVal) step        // carries on with the result, errors end the task
+	catch func(RuntimeVal, error) step // carries on with the result or the error, used over then
+
+	value RuntimeVal
+	err   error
+}
+
+// task is one node being evaluated, or one call being made when node is nil
+type task struct {
+	node f.Stmt
+	now  step
+}
+
+// finish is the step for a task that's done
+func finish(value RuntimeVal, err error) step {
+	return step{value: value, err: err}
+}
+
+// evalThen evaluates node in env and carries on with its value
+func evalThen(node f.Stmt, env *Environment, then func(RuntimeVal) step) step {
+	return step{node: node, env: env, then: then}
+}
+
+// evalCatch evaluates node in env and carries on with its value or error
+func evalCatch(node f.Stmt, env *Environment, catch func(RuntimeVal, error) step) step {
+	return step{node: node, env: env, catch: catch}
+}
+
+// enterThen runs start as a task of its own and carries on with its result
+func enterThen(start func() step, then func(RuntimeVal) step) step {
+	return step{enter: start, then: then}
+}
+
+// enterCatch runs start as a task of its own and carries on with its result
+// or error
+func enterCatch(start func() step, catch func(RuntimeVal, error) step) step {
+	return step{enter: start, catch: catch}
+}
+
+// evalAll evaluates nodes in order and carries on with their values
+func evalAll(nodes []f.Expr, env *Environment, then func([]RuntimeVal) step) step {
+	values := make([]RuntimeVal, len(nodes))
+	var next func(i int) step
+	next = func(i int) step {
+		if i == len(nodes) {
+			return then(values)
+		}
+		return evalThen(nodes[i], env, func(value RuntimeVal) step {
+			values[i] = value
+			return next(i + 1)
+		})
+	}
+	return next(0)
+}
+
+// runSteps runs first and every task it leads to on a stack of its own until
+// first is done. Only natives calling back into a0, the VM and imports start
+// another run, so how deep a0 code nests and calls is limited by the a0 level
+// limits like the recursion depth and by memory, not by the Go stack.
+func runSteps(first step) (RuntimeVal, error) {
+	tasks := []task{{now: first}}
+	for {
+		current := &tasks[len(tasks)-1]
+		var next task
+		switch now := current.now; {
+		case now.node != nil:
+			next = startNode(now.node, now.env)
+		case now.enter != nil:
+			next = task{now: now.enter()}
+		default:
+			value, err := current.result()
+			tasks = tasks[:len(tasks)-1]
+			if len(tasks) == 0 {
+				return value, err
+			}
+			tasks[len(tasks)-1].resume(value, err)
+			continue
+		}
+
+		// most nodes, like literals and variables, are done as soon as they
+		// start and never need to go on the stack
+		if next.now.node == nil && next.now.enter == nil {
+			current.resume(next.result())
+			continue
+		}
+		tasks = append(tasks, next)
+	}
+}
+
+// result is what a task that's done ended with, errors get the position of
+// the node it was evaluating unless a deeper one already gave them theirs
+func (t *task) result() (RuntimeVal, error) {
+	if t.now.err != nil && t.node != nil {
+		if node, ok := t.node.(f.Positioned); ok {
+			setErrorPosition(t.now.err, node.Position())
+		}
+	}
+	return t.now.value, t.now.err
+}
+
+// resume carries on with the task that was waiting on a result
+func (t *task) resume(value RuntimeVal, err error) {
+	switch waiting := t.now; {
+	case waiting.catch != nil:
+		t.now = waiting.catch(value, err)
+	case err != nil:
+		t.now = finish(nil, err)
+	default:
+		t.now = waiting.then(value)
+	}
+}
+
+// startNode is the task evaluating astNode, every node counts as a step
+// against the budget and is where the profiler takes its samples
+func startNode(astNode f.Stmt, env *Environment) task {
+	s := env.session
+	if err := s.step(); err != nil {
+		return task{now: finish(nil, err)}
+	}
+	if s.profile != nil {
+		if node, ok := astNode.(f.Positioned); ok {
+			s.sample(node.Position(), env.File())
+		}
+	}
+
+	return task{node: astNode, now: evaluateNode(astNode, env)}
+}
@@ -1,36 +1,195 @@
 package runtime
 
 import (
+	"errors"
+	"fmt"
+
 	f "github.com/Mstr0A/a0-lang/frontend"
 )
 
 // Evaling the Program //
-func evalProgram(program f.Program, env *Environment) (RuntimeVal, error) {
-	var lastEvaluated RuntimeVal
-	var err error
+func evalProgram(program f.Program, env *Environment) step {
+	var next func(i int, lastEvaluated RuntimeVal) step
+	next = func(i int, lastEvaluated RuntimeVal) step {
+		if i == len(program.Body) {
+			return finish(lastEvaluated, nil)
+		}
 
-	for _, statement := range program.Body {
-		lastEvaluated, err = Evaluate(statement, env)
-		if err != nil {
-			return nil, err
+		statement := program.Body[i]
+		if err := env.session.hookStatement(statement); err != nil {
+			return finish(nil, err)
 		}
+		return evalThen(statement, env, func(result RuntimeVal) step {
+			if err := strayLoopSignal(result); err != nil {
+				return finish(nil, err)
+			}
+
+			// a top level return ends the program
+			if ret, ok := result.(ReturnSignal); ok {
+				return finishTailCall(ret, env, func(result RuntimeVal, err error) step {
+					if err != nil {
+						return finish(nil, err)
+					}
+					return finish(result.(ReturnSignal).Value, nil)
+				})
+			}
+			return next(i+1, result)
+		})
 	}
+	return next(0, nil)
+}
+
+// Evaluating Blocks //
+// evalBlock runs a list of statements and stops early when one of them
+// produces a control flow signal, handing it back so it can reach whoever
+// handles it (the enclosing function call for a return)
+func evalBlock(body []f.Stmt, env *Environment) step {
+	var next func(i int, lastEvaluated RuntimeVal) step
+	next = func(i int, lastEvaluated RuntimeVal) step {
+		if i == len(body) {
+			return finish(lastEvaluated, nil)
+		}
 
-	return lastEvaluated, nil
+		stmt := body[i]
+		if err := env.session.hookStatement(stmt); err != nil {
+			return finish(nil, err)
+		}
+		return evalThen(stmt, env, func(result RuntimeVal) step {
+			if isSignal(result) {
+				return finish(result, nil)
+			}
+			return next(i+1, result)
+		})
+	}
+	return next(0, NadaVal{})
+}
+
+// evalScopedBlock runs a block in a scope of its own, so what it declares is
+// gone when it finishes and may shadow names from outside it
+func evalScopedBlock(body []f.Stmt, env *Environment) step {
+	return evalBlock(body, NewEnvironment(env))
+}
+
+// blockThen runs a block as a task of its own, for loops and the like that
+// carry on after it
+func blockThen(body []f.Stmt, env *Environment, then func(RuntimeVal) step) step {
+	return enterThen(func() step { return evalBlock(body, env) }, then)
+}
+
+func isSignal(val RuntimeVal) bool {
+	switch val.(type) {
+	case ReturnSignal, BreakSignal, ContinueSignal:
+		return true
+	default:
+		return false
+	}
+}
+
+// loopControl looks at what a loop body ended with. When done is true the
+// loop stops and evaluates to value, a return keeps going up to the function
+// while a break ends there. Otherwise value is the body's result, with a
+// continue taken out so it doesn't cut short the block around the loop.
+func loopControl(result RuntimeVal) (done bool, value RuntimeVal) {
+	switch result.(type) {
+	case ReturnSignal:
+		return true, result
+	case BreakSignal:
+		return true, NadaVal{}
+	case ContinueSignal:
+		return false, NadaVal{}
+	default:
+		return false, result
+	}
+}
+
+// strayLoopSignal is the error for a break or continue that made it out of
+// every loop, up to a function body or the top of the program
+func strayLoopSignal(val RuntimeVal) error {
+	switch signal := val.(type) {
+	case BreakSignal:
+		return &InterpretingError{Message: "break used outside of a loop", Pos: signal.Pos}
+	case ContinueSignal:
+		return &InterpretingError{Message: "continue used outside of a loop", Pos: signal.Pos}
+	default:
+		return nil
+	}
 }
 
 // Evaluating Variable Declarations //
-func evalVarDeclaration(declaration f.VarDeclaration, env *Environment) (RuntimeVal, error) {
+func evalVarDeclaration(declaration f.VarDeclaration, env *Environment) step {
+	if declaration.Pattern != nil {
+		return evalThen(declaration.Value, env, func(evaluatedValue RuntimeVal) step {
+			err := destructure(declaration.Pattern, evaluatedValue, func(name string, val RuntimeVal) error {
+				env.warnShadowing(name, declaration.Pos)
+				_, err := env.DeclareVar(name, val, declaration.Constant)
+				return err
+			})
+			if err != nil {
+				return finish(nil, err)
+			}
+
+			return finish(evaluatedValue, nil)
+		})
+	}
+
+	env.warnShadowing(declaration.Identifier, declaration.Pos)
+
 	value := declaration.Value
 	if value == nil {
-		return env.DeclareVar(declaration.Identifier, NadaVal{}, declaration.Constant)
+		return finish(env.DeclareVar(declaration.Identifier, NadaVal{}, declaration.Constant))
 	} else {
-		evaluatedValue, err := Evaluate(declaration.Value, env)
+		return evalThen(declaration.Value, env, func(evaluatedValue RuntimeVal) step {
+			return finish(env.DeclareVar(declaration.Identifier, evaluatedValue, declaration.Constant))
+		})
+	}
+}
+
+// destructure pulls the values a pattern names out of value and hands each
+// one to bind. Names with nothing to bind to get nada.
+func destructure(pattern f.Expr, value RuntimeVal, bind func(name string, val RuntimeVal) error) error {
+	switch p := pattern.(type) {
+	case f.ObjectPattern:
+		obj, ok := value.(ObjectVal)
+		if !ok {
+			errorMessage := fmt.Sprintf("Cannot destructure non-object value with an object pattern: %v", value)
+			return &InterpretingError{Message: errorMessage}
+		}
+
+		for _, name := range p.Names {
+			val, exists := obj.Properties[name]
+			if !exists {
+				val = NadaVal{}
+			}
+			if err := bind(name, val); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case f.ArrayPattern:
+		if _, isObject := value.(ObjectVal); isObject {
+			return &InterpretingError{Message: "Cannot destructure an object with an array pattern"}
+		}
+
+		count, entryAt, err := entries(value)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		for i, name := range p.Names {
+			var val RuntimeVal = NadaVal{}
+			if i < count {
+				_, val = entryAt(i)
+			}
+			if err := bind(name, val); err != nil {
+				return err
+			}
 		}
+		return nil
 
-		return env.DeclareVar(declaration.Identifier, evaluatedValue, declaration.Constant)
+	default:
+		errorMessage := fmt.Sprintf("Invalid destructuring pattern: %v", pattern)
+		return &InterpretingError{Message: errorMessage}
 	}
 }
 
@@ -43,95 +202,376 @@ func evalFunctionDeclaration(declaration f.FunctionDeclaration, env *Environment
 		Body:           declaration.Body,
 	}
 
+	env.warnShadowing(declaration.Name, declaration.Pos)
 	return env.DeclareVar(declaration.Name, fn, true)
 }
 
-// Evaluating If Statements //
-func evalIfStmt(stmt f.IfStmt, env *Environment) (RuntimeVal, error) {
-	condVal, err := Evaluate(stmt.Condition, env)
-	if err != nil {
-		return nil, err
+// Evaluating Class Declarations //
+func evalClassDeclaration(declaration f.ClassDeclaration, env *Environment) step {
+	class := &ClassVal{
+		Name:    declaration.Name,
+		Methods: make(map[string]UserFunctionValue),
 	}
 
-	boolCond, ok := condVal.(BoolVal)
-	if !ok {
-		return nil, &InterpretingError{Message: "If statement condition must be a boolean"}
-	}
-
-	if boolCond.Value {
-		var lastEvaluated RuntimeVal = NadaVal{}
-		for _, s := range stmt.Body {
-			lastEvaluated, err = Evaluate(s, env)
-			if err != nil {
-				return nil, err
+	declare := func() step {
+		for _, method := range declaration.Methods {
+			class.Methods[method.Name] = UserFunctionValue{
+				Name:           method.Name,
+				Parameters:     method.Parameters,
+				DeclarationEnv: env,
+				Body:           method.Body,
 			}
 		}
-		return lastEvaluated, nil
+
+		env.warnShadowing(declaration.Name, declaration.Pos)
+		return finish(env.DeclareVar(declaration.Name, class, true))
+	}
+
+	if declaration.Parent == nil {
+		return declare()
 	}
+	return evalThen(declaration.Parent, env, func(parentVal RuntimeVal) step {
+		parent, ok := parentVal.(*ClassVal)
+		if !ok {
+			errorMessage := fmt.Sprintf("Class %s cannot inherit from a value that is not a class: %v", declaration.Name, parentVal)
+			return finish(nil, &InterpretingError{Message: errorMessage})
+		}
+		class.Parent = parent
+		return declare()
+	})
+}
 
-	return NadaVal{}, nil
+// Evaluating If Statements //
+func evalIfStmt(stmt f.IfStmt, env *Environment) step {
+	return evalThen(stmt.Condition, env, func(condVal RuntimeVal) step {
+		if isTruthy(condVal) {
+			return evalScopedBlock(stmt.Body, env)
+		}
+
+		return finish(NadaVal{}, nil)
+	})
 }
 
 // Evaluating While Loops //
-func evalWhileStmt(stmt f.WhileStmt, env *Environment) (RuntimeVal, error) {
-	var result RuntimeVal = NadaVal{}
+func evalWhileStmt(stmt f.WhileStmt, env *Environment) step {
+	var loop func(result RuntimeVal) step
+	loop = func(result RuntimeVal) step {
+		return evalThen(stmt.Condition, env, func(condVal RuntimeVal) step {
+			if !isTruthy(condVal) {
+				return finish(result, nil)
+			}
 
-	for {
-		condVal, err := Evaluate(stmt.Condition, env)
+			return blockThen(stmt.Body, NewEnvironment(env), func(result RuntimeVal) step {
+				done, value := loopControl(result)
+				if done {
+					return finish(value, nil)
+				}
+				return loop(value)
+			})
+		})
+	}
+	return loop(NadaVal{})
+}
+
+// Evaluating For Loops //
+func evalForStmt(stmt f.ForStmt, env *Environment) step {
+	return evalThen(stmt.Condition, env, func(countVal RuntimeVal) step {
+		count, err := forCount(countVal)
 		if err != nil {
-			return nil, err
+			return finish(nil, err)
 		}
 
-		boolCond, ok := condVal.(BoolVal)
-		if !ok {
-			return nil, &InterpretingError{Message: "While loop condition must be a boolean"}
+		var loop func(i int, lastEvaluated RuntimeVal) step
+		loop = func(i int, lastEvaluated RuntimeVal) step {
+			if i >= count {
+				return finish(lastEvaluated, nil)
+			}
+
+			return blockThen(stmt.Body, NewEnvironment(env), func(result RuntimeVal) step {
+				done, value := loopControl(result)
+				if done {
+					return finish(value, nil)
+				}
+				return loop(i+1, value)
+			})
 		}
+		return loop(0, NadaVal{})
+	})
+}
 
-		if !boolCond.Value {
-			break
+// forCount is how many times a for loop runs, a range runs the body once per
+// element so for (0..3) is the same as for (3)
+func forCount(countVal RuntimeVal) (int, error) {
+	switch v := countVal.(type) {
+	case NumberVal:
+		return int(v.Value), nil
+	case RangeVal:
+		if v.Step == 0 {
+			return 0, &InterpretingError{Message: "Range step cannot be zero"}
+		}
+		return v.Len(), nil
+	default:
+		return 0, &InterpretingError{Message: "For loop count must evaluate to a number or range"}
+	}
+}
+
+// Evaluating Foreach Loops //
+func evalForEachStmt(stmt f.ForEachStmt, env *Environment) step {
+	return evalThen(stmt.Iterable, env, func(iterable RuntimeVal) step {
+		count, entryAt, err := entries(iterable)
+		if err != nil {
+			return finish(nil, err)
 		}
 
-		for _, innerStmt := range stmt.Body {
-			result, err = Evaluate(innerStmt, env)
+		_, isObject := iterable.(ObjectVal)
+
+		var loop func(i int, lastEvaluated RuntimeVal) step
+		loop = func(i int, lastEvaluated RuntimeVal) step {
+			if i == count {
+				return finish(lastEvaluated, nil)
+			}
+			key, value := entryAt(i)
+
+			// every iteration gets its own scope so the loop variables can be redeclared
+			scope := NewEnvironment(env)
+			var err error
+			if stmt.Value != "" {
+				if _, err = scope.DeclareVar(stmt.Key, key, false); err == nil {
+					_, err = scope.DeclareVar(stmt.Value, value, false)
+				}
+			} else if isObject {
+				// a single variable walks the keys of an object
+				_, err = scope.DeclareVar(stmt.Key, key, false)
+			} else {
+				_, err = scope.DeclareVar(stmt.Key, value, false)
+			}
 			if err != nil {
-				return nil, err
+				return finish(nil, err)
 			}
+
+			return blockThen(stmt.Body, scope, func(result RuntimeVal) step {
+				done, value := loopControl(result)
+				if done {
+					return finish(value, nil)
+				}
+				return loop(i+1, value)
+			})
 		}
+		return loop(0, NadaVal{})
+	})
+}
+
+// entries gives how many key/value pairs a foreach loop walks over for a
+// value and a way to get each one. Pairs are made when they're asked for so
+// huge ranges don't have to be materialized.
+func entries(val RuntimeVal) (count int, entryAt func(i int) (key, value RuntimeVal), err error) {
+	switch v := val.(type) {
+	case ObjectVal:
+		// map order is random, sort the keys so loops are deterministic
+		keys := sortedKeys(v)
+
+		return len(keys), func(i int) (RuntimeVal, RuntimeVal) {
+			return StringVal{Value: keys[i]}, v.Properties[keys[i]]
+		}, nil
+	case *ListVal:
+		// like ranging over the slice, elements pushed during the loop aren't
+		// walked over
+		elements := v.Elements
+		return len(elements), func(i int) (RuntimeVal, RuntimeVal) {
+			return NumberVal{Value: float64(i)}, elements[i]
+		}, nil
+	case StringVal:
+		runes := []rune(v.Value)
+		return len(runes), func(i int) (RuntimeVal, RuntimeVal) {
+			return NumberVal{Value: float64(i)}, StringVal{Value: string(runes[i])}
+		}, nil
+	case RangeVal:
+		if v.Step == 0 {
+			return 0, nil, &InterpretingError{Message: "Range step cannot be zero"}
+		}
+
+		return v.Len(), func(i int) (RuntimeVal, RuntimeVal) {
+			return NumberVal{Value: float64(i)}, NumberVal{Value: v.At(i)}
+		}, nil
+	default:
+		errorMessage := fmt.Sprintf("Cannot iterate over value: %v", val)
+		return 0, nil, &InterpretingError{Message: errorMessage}
 	}
+}
+
+// Evaluating Try Statements //
+func evalTryStmt(stmt f.TryStmt, env *Environment) step {
+	return blockThenCatch(stmt.Body, NewEnvironment(env), func(result RuntimeVal, err error) step {
+		return finishTryTailCall(result, err, env, func(result RuntimeVal, err error) step {
+			if err == nil || !stmt.HasCatch || uncatchable(err) {
+				return runFinally(stmt, env, result, err)
+			}
+
+			scope := NewEnvironment(env)
+			caught := err
+			err = nil
+			if stmt.CatchName != "" {
+				_, err = scope.DeclareVar(stmt.CatchName, toErrorVal(caught), false)
+			}
+			if err != nil {
+				return runFinally(stmt, env, result, err)
+			}
+			return blockThenCatch(stmt.CatchBody, scope, func(result RuntimeVal, err error) step {
+				return finishTryTailCall(result, err, env, func(result RuntimeVal, err error) step {
+					return runFinally(stmt, env, result, err)
+				})
+			})
+		})
+	})
+}
 
-	return result, nil
+// blockThenCatch is blockThen carrying on with errors too
+func blockThenCatch(body []f.Stmt, env *Environment, catch func(RuntimeVal, error) step) step {
+	return enterCatch(func() step { return evalBlock(body, env) }, catch)
 }
 
-// Evaluating For Loops //
-func evalForStmt(stmt f.ForStmt, env *Environment) (RuntimeVal, error) {
-	countVal, err := Evaluate(stmt.Condition, env)
-	if err != nil {
-		return nil, err
+// runFinally runs the finally block of stmt, if it has one, after the try or
+// catch block ended with result and err
+func runFinally(stmt f.TryStmt, env *Environment, result RuntimeVal, err error) step {
+	if len(stmt.FinallyBody) == 0 {
+		return finish(result, err)
 	}
 
-	numVal, ok := countVal.(NumberVal)
-	if !ok {
-		return nil, &InterpretingError{Message: "For loop count must evaluate to a number"}
+	return blockThenCatch(stmt.FinallyBody, NewEnvironment(env), func(finallyResult RuntimeVal, finallyErr error) step {
+		if finallyErr != nil {
+			return finish(nil, finallyErr)
+		}
+		// a return from finally wins over whatever the try or catch did
+		if isSignal(finallyResult) {
+			return finish(finallyResult, nil)
+		}
+		return finish(result, err)
+	})
+}
+
+// finishTryTailCall makes a return f(x) from a try or catch block call f
+// before leaving the block, so catch sees its errors and finally runs after it
+func finishTryTailCall(result RuntimeVal, err error, env *Environment, catch func(RuntimeVal, error) step) step {
+	ret, ok := result.(ReturnSignal)
+	if err != nil || !ok {
+		return catch(result, err)
 	}
+	return finishTailCall(ret, env, catch)
+}
 
-	var lastEvaluated RuntimeVal
-	for i := 0; i < int(numVal.Value); i++ {
-		for _, s := range stmt.Body {
-			lastEvaluated, err = Evaluate(s, env)
-			if err != nil {
-				return nil, err
-			}
+// toErrorVal turns any error raised while evaluating into the value a catch
+// block sees, so runtime errors can be caught the same way as thrown ones
+func toErrorVal(err error) ErrorVal {
+	var thrown *ThrowError
+	if errors.As(err, &thrown) {
+		return thrown.Thrown
+	}
+
+	var interpErr *InterpretingError
+	if errors.As(err, &interpErr) {
+		return ErrorVal{
+			Message: interpErr.Message,
+			Value:   StringVal{Value: interpErr.Message},
+			Line:    interpErr.Pos.Line(),
+			Column:  interpErr.Pos.Column(),
+		}
+	}
+
+	var typeErr *TypeError
+	if errors.As(err, &typeErr) {
+		return ErrorVal{
+			Message: typeErr.message(),
+			Value:   StringVal{Value: string(typeErr.Got)},
+			Line:    typeErr.Pos.Line(),
+			Column:  typeErr.Pos.Column(),
 		}
 	}
 
-	return lastEvaluated, nil
+	return ErrorVal{Message: err.Error(), Value: StringVal{Value: err.Error()}}
+}
+
+// Evaluating Throw Statements //
+func evalThrowStmt(stmt f.ThrowStmt, env *Environment) step {
+	return evalThen(stmt.Value, env, func(value RuntimeVal) step {
+		return finish(nil, throwValue(value, stmt.Pos))
+	})
+}
+
+// throwValue is the error for throwing value from pos
+func throwValue(value RuntimeVal, pos f.Position) error {
+	errVal, ok := value.(ErrorVal)
+	if !ok {
+		errVal = ErrorVal{Message: value.String(), Value: value}
+	}
+
+	// rethrowing a caught error keeps where it was first thrown
+	if errVal.Line == 0 {
+		errVal.Line = pos.Line()
+		errVal.Column = pos.Column()
+		errVal.pos = pos
+	}
+
+	return &ThrowError{Thrown: errVal}
+}
+
+// Evaluating Assert Statements //
+// a failed assert throws like any other error so tests can catch it
+func evalAssertStmt(stmt f.AssertStmt, env *Environment) step {
+	return evalThen(stmt.Condition, env, func(condVal RuntimeVal) step {
+		if isTruthy(condVal) {
+			return finish(NadaVal{}, nil)
+		}
+
+		message := "Assertion failed"
+		if stmt.Message == nil {
+			return finish(nil, assertionError(stmt, message, condVal))
+		}
+		return evalThen(stmt.Message, env, func(messageVal RuntimeVal) step {
+			message += ": " + messageVal.String()
+			return finish(nil, assertionError(stmt, message, condVal))
+		})
+	})
+}
+
+// assertionError is what a failed assert throws
+func assertionError(stmt f.AssertStmt, message string, condVal RuntimeVal) error {
+	return &ThrowError{Thrown: ErrorVal{
+		Message: message,
+		Value:   condVal,
+		Line:    stmt.Pos.Line(),
+		Column:  stmt.Pos.Column(),
+		pos:     stmt.Pos,
+	}}
 }
 
 // Evaluating Return Statements //
-func evalReturnStmt(stmt f.ReturnStmt, env *Environment) (RuntimeVal, error) {
-	val, err := Evaluate(stmt.Value, env)
-	if err != nil {
-		return nil, err
+// Evaluating Break and Continue //
+func evalBreakStmt(stmt f.BreakStmt) (RuntimeVal, error) {
+	return BreakSignal{Pos: stmt.Pos}, nil
+}
+
+func evalContinueStmt(stmt f.ContinueStmt) (RuntimeVal, error) {
+	return ContinueSignal{Pos: stmt.Pos}, nil
+}
+
+func evalReturnStmt(stmt f.ReturnStmt, env *Environment) step {
+	if stmt.Value == nil {
+		return finish(ReturnSignal{Value: NadaVal{}}, nil)
 	}
-	return ReturnValue{Value: val}, nil
+
+	// the call in return f(x) is left to callUserFunction, so it doesn't
+	// nest inside the call that's returning
+	if call, ok := stmt.Value.(f.CallExpr); ok {
+		return evalCallee(call, env, func(fn RuntimeVal, args []RuntimeVal) step {
+			return finish(ReturnSignal{Tail: &tailCall{Fn: fn, Args: args, Pos: call.Pos}}, nil)
+		})
+	}
+
+	return evalThen(stmt.Value, env, func(val RuntimeVal) step {
+		// a return inside the value (a match arm block) already ended the function
+		if isSignal(val) {
+			return finish(val, nil)
+		}
+		return finish(ReturnSignal{Value: val}, nil)
+	})
 }
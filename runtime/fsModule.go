@@ -0,0 +1,149 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+/////////////////////////
+// Path and FS Modules //
+/////////////////////////
+
+// pathModule builds the global path object, pure string manipulation using
+// the host's separator
+func pathModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "path"}
+
+	// join("a", "b", "c.a0") is a/b/c.a0
+	module.Properties["join"] = NativeFunctionValue{
+		Name: "path.join",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			parts := make([]string, len(args))
+			for i := range args {
+				part, ok := stringArg(args, i)
+				if !ok {
+					return nil, &TypeError{Operation: "path.join", Expected: "strings", Got: TypeOf(args[i])}
+				}
+				parts[i] = part
+			}
+			return StringVal{Value: filepath.Join(parts...)}, nil
+		},
+	}
+
+	module.Properties["base"] = pathFunction("path.base", filepath.Base)
+	module.Properties["dir"] = pathFunction("path.dir", filepath.Dir)
+	module.Properties["ext"] = pathFunction("path.ext", filepath.Ext)
+
+	return module
+}
+
+// pathFunction wraps a one argument path function as a native
+func pathFunction(name string, fn func(string) string) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			path, ok := stringArg(args, 0)
+			if !ok || len(args) != 1 {
+				errorMessage := fmt.Sprintf("%s expects a path", name)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return StringVal{Value: fn(path)}, nil
+		},
+	}
+}
+
+// fsModule builds the global fs object. Relative paths are relative to the
+// working directory, not the script.
+func fsModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "fs"}
+
+	// listDir(path) is the sorted names of the entries in a directory
+	module.Properties["listDir"] = fsFunction("fs.listDir", func(path string) (RuntimeVal, error) {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]RuntimeVal, len(entries))
+		for i, entry := range entries {
+			names[i] = StringVal{Value: entry.Name()}
+		}
+		return &ListVal{Elements: names}, nil
+	})
+
+	// mkdir(path) creates the directory along with any missing parents
+	module.Properties["mkdir"] = fsFunction("fs.mkdir", func(path string) (RuntimeVal, error) {
+		return NadaVal{}, os.MkdirAll(path, 0755)
+	})
+
+	// remove(path) deletes a file or an empty directory
+	module.Properties["remove"] = fsFunction("fs.remove", func(path string) (RuntimeVal, error) {
+		return NadaVal{}, os.Remove(path)
+	})
+
+	// stat(path) is {name, size, isDir, modified} with modified in unix
+	// milliseconds, nada when nothing is there
+	module.Properties["stat"] = fsFunction("fs.stat", func(path string) (RuntimeVal, error) {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return NadaVal{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		stat := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "stat"}
+		stat.Properties["name"] = StringVal{Value: info.Name()}
+		stat.Properties["size"] = NumberVal{Value: float64(info.Size())}
+		stat.Properties["isDir"] = BoolVal{Value: info.IsDir()}
+		stat.Properties["modified"] = NumberVal{Value: float64(info.ModTime().UnixMilli())}
+		return stat, nil
+	})
+
+	// readFile(path) is the whole file as a string
+	module.Properties["readFile"] = fsFunction("fs.readFile", func(path string) (RuntimeVal, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal{Value: string(content)}, nil
+	})
+
+	// writeFile(path, text) replaces the file with text, creating it if needed
+	module.Properties["writeFile"] = NativeFunctionValue{
+		Name: "fs.writeFile",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			path, ok := stringArg(args, 0)
+			if !ok || len(args) != 2 {
+				return nil, &InterpretingError{Message: "fs.writeFile expects a path and the text to write"}
+			}
+
+			err := os.WriteFile(path, []byte(args[1].String()), 0o644)
+			if err != nil {
+				return nil, err
+			}
+			return NadaVal{}, nil
+		},
+	}
+
+	return module
+}
+
+// fsFunction wraps a filesystem operation on one path as a native, the Go
+// errors it returns become runtime errors scripts can catch
+func fsFunction(name string, fn func(path string) (RuntimeVal, error)) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			path, ok := stringArg(args, 0)
+			if !ok || len(args) != 1 {
+				errorMessage := fmt.Sprintf("%s expects a path", name)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return fn(path)
+		},
+	}
+}
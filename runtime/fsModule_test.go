@@ -0,0 +1,131 @@
+package runtime_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestPathFunctions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`path.join("a", "b", "c.a0")`, filepath.Join("a", "b", "c.a0")},
+		{`path.join("a", "../b")`, "b"},
+		{`path.join()`, ""},
+		{`path.base(path.join("a", "b.a0"))`, "b.a0"},
+		{`path.dir(path.join("a", "b.a0"))`, "a"},
+		{`path.dir("b.a0")`, "."},
+		{`path.ext("b.tar.gz")`, ".gz"},
+		{`path.ext("b")`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), r.StringVal{Value: tt.want})
+		})
+	}
+}
+
+func TestFSRoundTrip(t *testing.T) {
+	dir := strconv.Quote(t.TempDir())
+	src := `const dir = ` + dir + `
+const nested = path.join(dir, "a", "b")
+fs.mkdir(nested)
+fs.mkdir(nested)
+fs.writeFile(path.join(nested, "z.txt"), "hi")
+fs.writeFile(path.join(nested, "y.txt"), 42)
+println(fs.listDir(nested))
+println(fs.readFile(path.join(nested, "y.txt")))
+const file = fs.stat(path.join(nested, "z.txt"))
+println(file.name, file.size, file.isDir, file.modified > 0)
+println(fs.stat(nested).isDir)
+println(fs.stat(path.join(dir, "missing")))
+fs.remove(path.join(nested, "z.txt"))
+println(fs.listDir(nested))
+`
+	testutil.AssertOutput(t, src, `["y.txt", "z.txt"]
+42
+z.txt 2 false true
+true
+nada
+["y.txt"]
+`)
+}
+
+func TestFSErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "full"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "full", "f"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	at := func(name string) string { return strconv.Quote(filepath.Join(dir, name)) }
+
+	tests := []struct {
+		src      string
+		typeErr  bool // a TypeError rather than an InterpretingError
+		contains string
+	}{
+		{"path.join(1)", true, ""},
+		{`path.join("a", nada)`, true, ""},
+		{"path.base()", false, "path.base expects a path"},
+		{`path.ext("a", "b")`, false, "path.ext expects a path"},
+		{"path.dir(1)", false, "path.dir expects a path"},
+		{"fs.readFile()", false, "fs.readFile expects a path"},
+		{"fs.listDir(1)", false, "fs.listDir expects a path"},
+		{`fs.writeFile("x")`, false, "fs.writeFile expects a path and the text to write"},
+		{"fs.readFile(" + at("missing") + ")", false, "fs.readFile: open"},
+		{"fs.readFile(" + at("full") + ")", false, "fs.readFile:"},
+		{"fs.listDir(" + at("missing") + ")", false, "fs.listDir: open"},
+		{"fs.listDir(" + at("file") + ")", false, "fs.listDir:"},
+		{"fs.mkdir(" + at("file") + ")", false, "fs.mkdir:"},
+		{"fs.remove(" + at("missing") + ")", false, "fs.remove:"},
+		{"fs.remove(" + at("full") + ")", false, "fs.remove:"},
+		{`fs.writeFile(` + at("missing/file") + `, "x")`, false, "fs.writeFile: open"},
+		{"fs.stat(" + at("file/inside") + ")", false, "fs.stat:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var typeErr *r.TypeError
+			var interpErr *r.InterpretingError
+			switch {
+			case err == nil:
+				t.Fatal("got no error")
+			case tt.typeErr && !errors.As(err, &typeErr):
+				t.Fatalf("got %v, want a type error", err)
+			case !tt.typeErr && !errors.As(err, &interpErr):
+				t.Fatalf("got %v, want a runtime error", err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+
+	// failing calls leave the tree as it was
+	testutil.AssertValue(t, testutil.MustEval(t, "fs.listDir("+strconv.Quote(dir)+")"), &r.ListVal{Elements: []r.RuntimeVal{
+		r.StringVal{Value: "file"}, r.StringVal{Value: "full"},
+	}})
+}
+
+func TestFSErrorsCanBeCaught(t *testing.T) {
+	missing := strconv.Quote(filepath.Join(t.TempDir(), "missing"))
+	testutil.AssertOutput(t, `try {
+    fs.readFile(`+missing+`)
+} catch (e) {
+    println("caught", e.message.contains("no such file") or e.message.contains("cannot find"))
+}
+`, "caught true\n")
+}
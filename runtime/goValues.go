@@ -0,0 +1,457 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+///////////////
+// Go Values //
+///////////////
+
+var (
+	runtimeValType = reflect.TypeFor[RuntimeVal]()
+	timeType       = reflect.TypeFor[time.Time]()
+)
+
+// ToValue converts a Go value to the a0 value it stands for: numbers to
+// numbers, strings, bools, slices and arrays to lists, maps with string keys
+// and structs to objects, time.Time to a date and nil to nada. A nil slice is
+// an empty list, nil maps and pointers are nada, and a value that contains
+// itself through a pointer, map or slice is an error. Struct fields
+// are named by their a0 tag, like json tags:
+//
+//	type Point struct {
+//		X     float64 `a0:"x"`
+//		Label string  `a0:"label,omitempty"`
+//		cache []int   // unexported fields are left out
+//		Debug bool    `a0:"-"`
+//	}
+func ToValue(v any) (RuntimeVal, error) {
+	return fromGoValue(reflect.ValueOf(v), make(map[goRef]bool))
+}
+
+// FromValue stores val in the Go value target points to, converted the
+// other way from ToValue. Properties a struct has no field for are ignored,
+// fields without a property are left at their zero value.
+func FromValue(val RuntimeVal, target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("FromValue needs a non-nil pointer to store into, not %T", target)
+	}
+	t := ptr.Type().Elem()
+	if err := checkGoType(t); err != nil {
+		return err
+	}
+	goVal, ok := toGoValue(val, t)
+	if !ok {
+		return fmt.Errorf("can't store a %s in %s, it expects %s", TypeOf(val), t, describeGoType(t))
+	}
+	ptr.Elem().Set(goVal)
+	return nil
+}
+
+// fieldName is what the property for field is called, ok is false for
+// fields objects leave out
+func fieldName(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("a0")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, options == "omitempty", true
+}
+
+// checkGoType reports why values of t can't go between Go and a0, if they
+// can't
+func checkGoType(t reflect.Type) error {
+	return checkGoTypeSeen(t, make(map[reflect.Type]bool))
+}
+
+// checkGoTypeSeen is checkGoType for types that refer to themselves, a type
+// in seen is already being checked further up
+func checkGoTypeSeen(t reflect.Type, seen map[reflect.Type]bool) error {
+	if t == runtimeValType || t == timeType || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return nil
+	case reflect.Slice, reflect.Pointer:
+		return checkGoTypeSeen(t.Elem(), seen)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("%s has no a0 value, object keys are strings", t)
+		}
+		return checkGoTypeSeen(t.Elem(), seen)
+	case reflect.Struct:
+		var errs []error
+		for i := range t.NumField() {
+			if _, _, ok := fieldName(t.Field(i)); ok {
+				if err := checkGoTypeSeen(t.Field(i).Type, seen); err != nil {
+					errs = append(errs, fmt.Errorf("field %s: %w", t.Field(i).Name, err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no a0 value", t)
+}
+
+// describeGoType names what a0 value goes into t, for type errors
+func describeGoType(t reflect.Type) string {
+	switch {
+	case t == runtimeValType:
+		return "a value"
+	case t == timeType:
+		return "a date"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Int32:
+		return "a whole number or a char"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a bool"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Struct:
+		if t.Name() != "" {
+			return "a " + t.Name() + " object"
+		}
+		return "an object"
+	case reflect.Map:
+		return "an object"
+	case reflect.Pointer:
+		return describeGoType(t.Elem()) + " or nada"
+	}
+	return "a value"
+}
+
+// toGoValue converts val to a Go value of type t, ok is false when val
+// doesn't fit in it
+func toGoValue(val RuntimeVal, t reflect.Type) (reflect.Value, bool) {
+	if t == runtimeValType {
+		goVal := reflect.New(t).Elem()
+		goVal.Set(reflect.ValueOf(&val).Elem())
+		return goVal, true
+	}
+	if t == timeType {
+		date, ok := val.(DateVal)
+		return reflect.ValueOf(date.Time), ok
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		num, ok := val.(NumberVal)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(num.Value).Convert(t), true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if char, ok := val.(CharVal); ok && t.Kind() == reflect.Int32 {
+			return reflect.ValueOf(char.Value).Convert(t), true
+		}
+		num, ok := val.(NumberVal)
+		if !ok || num.Value != math.Trunc(num.Value) {
+			return reflect.Value{}, false
+		}
+		goVal := reflect.New(t).Elem()
+		if num.Value < math.MinInt64 || num.Value >= math.MaxInt64 || goVal.OverflowInt(int64(num.Value)) {
+			return reflect.Value{}, false
+		}
+		goVal.SetInt(int64(num.Value))
+		return goVal, true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, ok := val.(NumberVal)
+		if !ok || num.Value != math.Trunc(num.Value) || num.Value < 0 {
+			return reflect.Value{}, false
+		}
+		goVal := reflect.New(t).Elem()
+		if num.Value >= math.MaxUint64 || goVal.OverflowUint(uint64(num.Value)) {
+			return reflect.Value{}, false
+		}
+		goVal.SetUint(uint64(num.Value))
+		return goVal, true
+
+	case reflect.String:
+		switch v := val.(type) {
+		case StringVal:
+			return reflect.ValueOf(v.Value).Convert(t), true
+		case CharVal:
+			return reflect.ValueOf(string(v.Value)).Convert(t), true
+		}
+		return reflect.Value{}, false
+
+	case reflect.Bool:
+		b, ok := val.(BoolVal)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(b.Value).Convert(t), true
+
+	case reflect.Slice:
+		if _, isNada := val.(NadaVal); isNada {
+			return reflect.Zero(t), true
+		}
+		list, ok := val.(*ListVal)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		slice := reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+		for i, element := range list.Elements {
+			goElement, ok := toGoValue(element, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			slice.Index(i).Set(goElement)
+		}
+		return slice, true
+
+	case reflect.Map:
+		if _, isNada := val.(NadaVal); isNada {
+			return reflect.Zero(t), true
+		}
+		obj, ok := val.(ObjectVal)
+		if !ok || t.Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		goMap := reflect.MakeMapWithSize(t, len(obj.Properties))
+		for key, property := range obj.Properties {
+			goProperty, ok := toGoValue(property, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			goMap.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), goProperty)
+		}
+		return goMap, true
+
+	case reflect.Pointer:
+		if _, isNada := val.(NadaVal); isNada {
+			return reflect.Zero(t), true
+		}
+		goElem, ok := toGoValue(val, t.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(goElem)
+		return ptr, true
+
+	case reflect.Struct:
+		obj, ok := val.(ObjectVal)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		goStruct := reflect.New(t).Elem()
+		for i := range t.NumField() {
+			name, _, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			property, exists := obj.Properties[name]
+			if !exists {
+				continue
+			}
+			goField, ok := toGoValue(property, t.Field(i).Type)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			goStruct.Field(i).Set(goField)
+		}
+		return goStruct, true
+
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return reflect.Value{}, false
+		}
+		goVal := reflect.New(t).Elem()
+		if natural := naturalGoValue(val); natural != nil {
+			goVal.Set(reflect.ValueOf(natural))
+		}
+		return goVal, true
+	}
+	return reflect.Value{}, false
+}
+
+// naturalGoValue is val as the Go value it's closest to when the host asks
+// for any: float64, string, rune, bool, []any, map[string]any, nil for nada,
+// and the RuntimeVal itself for functions and the like
+func naturalGoValue(val RuntimeVal) any {
+	switch v := val.(type) {
+	case NumberVal:
+		return v.Value
+	case StringVal:
+		return v.Value
+	case CharVal:
+		return v.Value
+	case BoolVal:
+		return v.Value
+	case NadaVal:
+		return nil
+	case *ListVal:
+		elements := make([]any, len(v.Elements))
+		for i, element := range v.Elements {
+			elements[i] = naturalGoValue(element)
+		}
+		return elements
+	case ObjectVal:
+		properties := make(map[string]any, len(v.Properties))
+		for key, property := range v.Properties {
+			properties[key] = naturalGoValue(property)
+		}
+		return properties
+	}
+	return val
+}
+
+// goRef is a pointer, map or slice ToValue is partway through converting
+type goRef struct {
+	ptr    uintptr
+	goType reflect.Type
+	length int
+}
+
+// enterRef marks the pointer, map or slice goVal as being converted, it fails
+// when goVal is already being converted further up, which means it contains
+// itself. leave unmarks it once it's done.
+func enterRef(goVal reflect.Value, visiting map[goRef]bool) (leave func(), err error) {
+	ref := goRef{ptr: goVal.Pointer(), goType: goVal.Type()}
+	if goVal.Kind() == reflect.Slice {
+		ref.length = goVal.Len()
+	}
+	if visiting[ref] {
+		return nil, fmt.Errorf("a %s that contains itself, which has no a0 value", goVal.Type())
+	}
+	visiting[ref] = true
+	return func() { delete(visiting, ref) }, nil
+}
+
+// fromGoValue converts a Go value to the a0 value it stands for, visiting
+// holds the pointers, maps and slices it's inside of
+func fromGoValue(goVal reflect.Value, visiting map[goRef]bool) (RuntimeVal, error) {
+	if !goVal.IsValid() {
+		return NadaVal{}, nil
+	}
+	if goVal.Type().Implements(runtimeValType) {
+		if goVal.Kind() == reflect.Interface && goVal.IsNil() {
+			return NadaVal{}, nil
+		}
+		return goVal.Interface().(RuntimeVal), nil
+	}
+	if goVal.Type() == timeType {
+		return DateVal{Time: goVal.Interface().(time.Time)}, nil
+	}
+
+	switch goVal.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NumberVal{Value: float64(goVal.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NumberVal{Value: float64(goVal.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return NumberVal{Value: goVal.Float()}, nil
+	case reflect.String:
+		return StringVal{Value: goVal.String()}, nil
+	case reflect.Bool:
+		return BoolVal{Value: goVal.Bool()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if goVal.Kind() == reflect.Slice && goVal.Len() > 0 {
+			leave, err := enterRef(goVal, visiting)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		elements := make([]RuntimeVal, goVal.Len())
+		for i := range elements {
+			element, err := fromGoValue(goVal.Index(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &ListVal{Elements: elements}, nil
+
+	case reflect.Map:
+		if goVal.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("a %s, which has no a0 value since object keys are strings", goVal.Type())
+		}
+		if goVal.IsNil() {
+			return NadaVal{}, nil
+		}
+		leave, err := enterRef(goVal, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		properties := make(map[string]RuntimeVal, goVal.Len())
+		iter := goVal.MapRange()
+		for iter.Next() {
+			property, err := fromGoValue(iter.Value(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			properties[iter.Key().String()] = property
+		}
+		return ObjectVal{Properties: properties}, nil
+
+	case reflect.Struct:
+		t := goVal.Type()
+		properties := make(map[string]RuntimeVal, t.NumField())
+		for i := range t.NumField() {
+			name, omitEmpty, ok := fieldName(t.Field(i))
+			if !ok || (omitEmpty && goVal.Field(i).IsZero()) {
+				continue
+			}
+			property, err := fromGoValue(goVal.Field(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			properties[name] = property
+		}
+		return ObjectVal{Properties: properties}, nil
+
+	case reflect.Interface:
+		if goVal.IsNil() {
+			return NadaVal{}, nil
+		}
+		return fromGoValue(goVal.Elem(), visiting)
+
+	case reflect.Pointer:
+		if goVal.IsNil() {
+			return NadaVal{}, nil
+		}
+		leave, err := enterRef(goVal, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return fromGoValue(goVal.Elem(), visiting)
+	}
+	return nil, fmt.Errorf("a %s, which has no a0 value", goVal.Type())
+}
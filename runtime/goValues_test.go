@@ -0,0 +1,164 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+type point struct {
+	X     float64 `a0:"x"`
+	Label string  `a0:"label,omitempty"`
+	cache []int
+	Debug bool `a0:"-"`
+}
+
+type node struct {
+	Name string `a0:"name"`
+	Next *node  `a0:"next"`
+}
+
+func num(n float64) r.NumberVal { return r.NumberVal{Value: n} }
+
+func str(s string) r.StringVal { return r.StringVal{Value: s} }
+
+func TestToValue(t *testing.T) {
+	when := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   any
+		want r.RuntimeVal
+	}{
+		{"int", 42, num(42)},
+		{"uint8", uint8(7), num(7)},
+		{"float", 1.5, num(1.5)},
+		{"string", "hi", str("hi")},
+		{"bool", true, r.BoolVal{Value: true}},
+		{"nil", nil, r.NadaVal{}},
+		{"slice", []int{1, 2}, &r.ListVal{Elements: []r.RuntimeVal{num(1), num(2)}}},
+		{"array", [2]string{"a", "b"}, &r.ListVal{Elements: []r.RuntimeVal{str("a"), str("b")}}},
+		{"nil slice", []int(nil), &r.ListVal{Elements: []r.RuntimeVal{}}},
+		{"map", map[string]int{"a": 1}, r.ObjectVal{Properties: map[string]r.RuntimeVal{"a": num(1)}}},
+		{"nil map", map[string]int(nil), r.NadaVal{}},
+		{"nil pointer", (*point)(nil), r.NadaVal{}},
+		{"struct", point{X: 1, cache: []int{1}, Debug: true}, r.ObjectVal{Properties: map[string]r.RuntimeVal{"x": num(1)}}},
+		{"struct with omitempty set", &point{Label: "p"}, r.ObjectVal{Properties: map[string]r.RuntimeVal{"x": num(0), "label": str("p")}}},
+		{"date", when, r.DateVal{Time: when}},
+		{"runtime value", str("kept"), str("kept")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ToValue(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if date, ok := tt.want.(r.DateVal); ok {
+				if got, ok := got.(r.DateVal); !ok || !got.Time.Equal(date.Time) {
+					t.Errorf("got %v, want %v", got, date)
+				}
+				return
+			}
+			testutil.AssertValue(t, got, tt.want)
+		})
+	}
+}
+
+func TestToValueCycles(t *testing.T) {
+	loop := &node{Name: "a"}
+	loop.Next = &node{Name: "b", Next: loop}
+
+	selfMap := map[string]any{}
+	selfMap["self"] = selfMap
+
+	selfSlice := []any{nil}
+	selfSlice[0] = selfSlice
+
+	for name, in := range map[string]any{"pointers": loop, "map": selfMap, "slice": selfSlice} {
+		t.Run(name, func(t *testing.T) {
+			_, err := r.ToValue(in)
+			if err == nil || !strings.Contains(err.Error(), "contains itself") {
+				t.Errorf("got %v, want an error for the cycle", err)
+			}
+		})
+	}
+}
+
+// a value reached twice without a cycle is converted both times
+func TestToValueShared(t *testing.T) {
+	shared := &node{Name: "leaf"}
+	got, err := r.ToValue([]*node{shared, shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := r.ObjectVal{Properties: map[string]r.RuntimeVal{"name": str("leaf"), "next": r.NadaVal{}}}
+	testutil.AssertValue(t, got, &r.ListVal{Elements: []r.RuntimeVal{leaf, leaf}})
+}
+
+func TestToValueUnsupported(t *testing.T) {
+	for name, in := range map[string]any{
+		"int keys": map[int]string{1: "a"},
+		"channel":  make(chan int),
+		"func":     func() {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := r.ToValue(in); err == nil {
+				t.Errorf("converting a %T didn't fail", in)
+			}
+		})
+	}
+}
+
+func TestFromValue(t *testing.T) {
+	val := testutil.MustEval(t, `{ x: 2, label: "p", extra: true }`)
+	var p point
+	if err := r.FromValue(val, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.X != 2 || p.Label != "p" {
+		t.Errorf("got %+v, want x 2 and label p", p)
+	}
+
+	var list []int
+	if err := r.FromValue(testutil.MustEval(t, "[1, 2, 3]"), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[2] != 3 {
+		t.Errorf("got %v, want [1 2 3]", list)
+	}
+
+	var natural any
+	if err := r.FromValue(testutil.MustEval(t, `{ a: [1, "b"] }`), &natural); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := natural.(map[string]any); !ok || len(m["a"].([]any)) != 2 {
+		t.Errorf("got %#v, want map[a:[1 b]]", natural)
+	}
+}
+
+func TestFromValueErrors(t *testing.T) {
+	var n int
+	tests := []struct {
+		name   string
+		val    r.RuntimeVal
+		target any
+		want   string
+	}{
+		{"not a pointer", num(1), n, "non-nil pointer"},
+		{"wrong kind", str("a"), &n, "can't store a String in int"},
+		{"fraction", num(1.5), &n, "expects a whole number"},
+		{"overflow", num(1e300), new(int8), "expects a whole number"},
+		{"negative unsigned", num(-1), new(uint), "expects a whole number"},
+		{"unsupported type", num(1), new(chan int), "has no a0 value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.FromValue(tt.val, tt.target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+///////////
+// Hooks //
+///////////
+
+// Hooks are callbacks a host sets to watch a program as it runs, for
+// auditing, showing live variables or enforcing its own rules. Any of them
+// can be nil. A hook returning an error stops what was about to happen with
+// that error, which a0 code can catch unless it's a *LimitError.
+type Hooks struct {
+	// OnDeclare is called before name is declared with value, in whichever
+	// scope the declaration is in, parameters and loop variables included
+	OnDeclare func(name string, value RuntimeVal, constant bool) error
+	// OnAssign is called before value is assigned to the variable name
+	OnAssign func(name string, value RuntimeVal) error
+	// OnLookup is called before the variable name is read
+	OnLookup func(name string) error
+	// OnCall is called before each call the program makes, to a function,
+	// method, native or class, with the name it shows up as in stack traces
+	// and where the call is. Natives calling back into a0, like a callback
+	// given to map, don't count.
+	OnCall func(name string, args []RuntimeVal, pos f.Position) error
+	// OnStatement is called before each statement runs. Code running as
+	// bytecode on the VM has no statements left, it isn't called for that.
+	OnStatement func(stmt f.Stmt) error
+}
+
+// SetHooks sets the hooks for the program running in env and everything it
+// imports, nil removes them
+func (env *Environment) SetHooks(hooks *Hooks) {
+	env.session.hooks = hooks
+}
+
+// hookCall tells the OnCall hook about a call to fn, if there is one
+func (s *session) hookCall(fn RuntimeVal, args []RuntimeVal, pos f.Position) error {
+	if s.hooks == nil || s.hooks.OnCall == nil {
+		return nil
+	}
+	return hookError(s.hooks.OnCall(calleeName(fn), args, pos))
+}
+
+// hookStatement tells the OnStatement hook about stmt, if there is one
+func (s *session) hookStatement(stmt f.Stmt) error {
+	if s.hooks == nil || s.hooks.OnStatement == nil {
+		return nil
+	}
+	return hookError(s.hooks.OnStatement(stmt))
+}
+
+// hookError is an error from a hook as the program sees it, the same as one
+// from a host function
+func hookError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return hostError(err)
+}
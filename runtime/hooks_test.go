@@ -0,0 +1,129 @@
+package runtime_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestHooksSeeVariables(t *testing.T) {
+	var events []string
+	env := r.NewEnvironment(nil)
+	env.SetHooks(&r.Hooks{
+		OnDeclare: func(name string, value r.RuntimeVal, constant bool) error {
+			events = append(events, fmt.Sprintf("declare %s = %v (%t)", name, value, constant))
+			return nil
+		},
+		OnAssign: func(name string, value r.RuntimeVal) error {
+			events = append(events, fmt.Sprintf("assign %s = %v", name, value))
+			return nil
+		},
+		OnLookup: func(name string) error {
+			events = append(events, "lookup "+name)
+			return nil
+		},
+	})
+
+	if _, err := testutil.EvalIn("const a = 1\nvar b = 2\nb = a", env); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"declare a = 1 (true)", "declare b = 2 (false)", "lookup a", "assign b = 1"}
+	if !slices.Equal(events, want) {
+		t.Errorf("got %q, want %q", events, want)
+	}
+}
+
+func TestHooksSeeCallsAndStatements(t *testing.T) {
+	var calls []string
+	statements := 0
+	env := r.NewEnvironment(nil)
+	env.SetHooks(&r.Hooks{
+		OnCall: func(name string, args []r.RuntimeVal, pos f.Position) error {
+			calls = append(calls, fmt.Sprintf("%s/%d at %d:%d", name, len(args), pos.Line(), pos.Column()))
+			return nil
+		},
+		OnStatement: func(stmt f.Stmt) error {
+			statements++
+			return nil
+		},
+	})
+
+	src := "fun add(a, b) { return a + b }\nadd(1, len([2]))"
+	if _, err := testutil.EvalIn(src, env); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"len/1 at 2:11", "add/2 at 2:4"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("got %q, want %q", calls, want)
+	}
+	// the two top level statements and the return
+	if statements != 3 {
+		t.Errorf("OnStatement was called %d times, want 3", statements)
+	}
+}
+
+func TestHookErrors(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	env.SetHooks(&r.Hooks{
+		OnLookup: func(name string) error {
+			if name == "secret" {
+				return errors.New("secret is off limits")
+			}
+			return nil
+		},
+		OnCall: func(name string, args []r.RuntimeVal, pos f.Position) error {
+			if name == "halt" {
+				return &r.LimitError{Message: "halted by the host"}
+			}
+			return nil
+		},
+	})
+
+	// errors from hooks can be caught like a host function's
+	got, err := testutil.EvalIn(`const secret = 1
+try { secret } catch (e) { "caught" }`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.StringVal{Value: "caught"})
+
+	// limit errors can't
+	_, err = testutil.EvalIn(`fun halt() {}
+try { halt() } catch (e) { "caught" }`, env)
+	var limit *r.LimitError
+	if !errors.As(err, &limit) || !strings.Contains(err.Error(), "halted by the host") {
+		t.Errorf("got %v, want the hook's limit error", err)
+	}
+}
+
+func TestHooksOnTheVM(t *testing.T) {
+	var calls []string
+	env := r.NewEnvironment(nil)
+	env.SetHooks(&r.Hooks{
+		OnCall: func(name string, args []r.RuntimeVal, pos f.Position) error {
+			calls = append(calls, name)
+			return nil
+		},
+	})
+
+	program, err := testutil.Parse("fun twice(n) { return n * 2 }\ntwice(twice(1))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := r.Compile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Execute(chunk, env); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"twice", "twice"}; !slices.Equal(calls, want) {
+		t.Errorf("got %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+////////////////
+// ID Natives //
+////////////////
+
+// setupIDNatives declares uuid and randomHex. They read from crypto/rand,
+// not the random module, so ids stay unpredictable even when it's seeded.
+func setupIDNatives(env *Environment) {
+	// uuid() is a random version 4 UUID like "3b241101-e2bb-4255-8caf-4136c566a962"
+	env.DeclareVar("uuid", NativeFunctionValue{
+		Name: "uuid",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 0 {
+				errorMessage := fmt.Sprintf("uuid expects no arguments but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			var id [16]byte
+			if _, err := rand.Read(id[:]); err != nil {
+				return nil, randomnessError("uuid", err)
+			}
+			id[6] = (id[6] & 0x0f) | 0x40 // version 4
+			id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+			text := hex.EncodeToString(id[:])
+			return StringVal{Value: fmt.Sprintf("%s-%s-%s-%s-%s", text[0:8], text[8:12], text[12:16], text[16:20], text[20:])}, nil
+		},
+	}, true)
+
+	// randomHex(n) is n random hex digits
+	env.DeclareVar("randomHex", NativeFunctionValue{
+		Name: "randomHex",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, &InterpretingError{Message: "randomHex expects a whole number of digits"}
+			}
+			// capped like the strings string methods build
+			if num, isNum := args[0].(NumberVal); isNum && num.Value > maxStringLength {
+				errorMessage := fmt.Sprintf("randomHex can make at most %d digits but was asked for %s", maxStringLength, num)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			length, ok := countArg(args, 0)
+			if !ok {
+				return nil, &InterpretingError{Message: "randomHex expects a whole number of digits"}
+			}
+
+			buf := make([]byte, (length+1)/2)
+			if _, err := rand.Read(buf); err != nil {
+				return nil, randomnessError("randomHex", err)
+			}
+			return StringVal{Value: hex.EncodeToString(buf)[:length]}, nil
+		},
+	}, true)
+}
+
+// randomnessError is how a native reports the system's randomness failing,
+// rather than handing out an id that isn't random
+func randomnessError(name string, err error) error {
+	errorMessage := fmt.Sprintf("%s: cannot read random bytes: %v", name, err)
+	return &InterpretingError{Message: errorMessage}
+}
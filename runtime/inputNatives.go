@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+///////////////////
+// Input Natives //
+///////////////////
+
+// setupInputNatives declares input, which reads what the program is given
+// on standard input, or whatever the host set with SetInput
+func setupInputNatives(env *Environment) {
+	// input() reads a line without its line ending, input("Name? ") writes
+	// the prompt first. It gives nada once the input has ended.
+	env.DeclareVar("input", NativeFunctionValue{
+		Name: "input",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) > 1 {
+				errorMessage := fmt.Sprintf("input expects an optional prompt but got %d arguments", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			if len(args) == 1 {
+				if _, err := printTo(env.session.stdout, args, "", ""); err != nil {
+					return nil, err
+				}
+			}
+
+			line, err := env.session.input().ReadString('\n')
+			if err == io.EOF && line == "" {
+				return NadaVal{}, nil
+			}
+			if err != nil && err != io.EOF {
+				return nil, &InterpretingError{Message: "input failed: " + err.Error()}
+			}
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+			return StringVal{Value: line}, nil
+		},
+	}, true)
+}
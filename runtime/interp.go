@@ -0,0 +1,351 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+////////////
+// Interp //
+////////////
+
+// Interp is a0 embedded in a Go program: a global scope with everything a0
+// programs start out with, that the host adds its own functions to and runs
+// source in. Settings like SetMaxSteps are on its Env.
+//
+// An Interp runs one thing at a time and belongs to one goroutine at a time,
+// Run fails with ErrConcurrentUse when it notices another goroutine using it.
+// Programs that run a0 on many goroutines give each its own Interp, which
+// Globals makes cheap.
+type Interp struct {
+	env *Environment
+}
+
+// ErrConcurrentUse is returned when an Interp is used while it's already
+// running something on another goroutine. Like Go's check for concurrent map
+// writes it's best effort, not a lock, it catches the mistake rather than
+// making it safe.
+var ErrConcurrentUse = errors.New("runtime: Interp used by two goroutines at once")
+
+func NewInterp() *Interp {
+	return &Interp{env: NewEnvironment(nil)}
+}
+
+// enter marks the session as running until leave is called, or fails when
+// it already is. A host function called by the running program can still use
+// it, but only from the goroutine the program runs on, goroutines it starts
+// are as much a concurrent use as any other.
+func (s *session) enter() (leave func(), err error) {
+	id := goroutineID()
+	if s.running.CompareAndSwap(false, true) {
+		s.owner.Store(id)
+		return func() {
+			s.owner.Store(0)
+			s.running.Store(false)
+		}, nil
+	}
+	if s.hostCalls.Load() > 0 && s.owner.Load() == id {
+		return func() {}, nil
+	}
+	return nil, ErrConcurrentUse
+}
+
+// goroutineID is the number Go gives the calling goroutine in stack dumps.
+// Go keeps it hidden on purpose, but enter only compares it with itself.
+func goroutineID() uint64 {
+	var buf [64]byte
+	dump := buf[:runtime.Stack(buf[:], false)]
+	dump = bytes.TrimPrefix(dump, []byte("goroutine "))
+	dump, _, _ = bytes.Cut(dump, []byte(" "))
+	id, _ := strconv.ParseUint(string(dump), 10, 64)
+	return id
+}
+
+// Env is the global scope source runs in
+func (in *Interp) Env() *Environment {
+	return in.env
+}
+
+// Run lexes, parses and runs source in the global scope, giving back the
+// value of its last statement. What it declares stays around for the next
+// Run.
+func (in *Interp) Run(source string) (RuntimeVal, error) {
+	leave, err := in.env.session.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	tokens, err := f.NewLexer(strings.NewReader(source)).Lex()
+	if err != nil {
+		return nil, err
+	}
+	program, err := f.NewParser(tokens).ProduceAst()
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(program, in.env)
+}
+
+// Call calls the function, method or class the program declared as name with
+// args converted the way ToValue does it, so scripts can define handlers like
+//
+//	fun onEvent(kind, data) { ... }
+//
+// for the host to call with
+//
+//	in.Call("onEvent", "click", map[string]any{"x": 3})
+//
+// A method is named through its object, like "handlers.onEvent", and sees
+// that object as self. A throw the function doesn't catch comes back as a
+// *ThrowError.
+func (in *Interp) Call(name string, args ...any) (RuntimeVal, error) {
+	path := strings.Split(name, ".")
+	fn, err := in.env.LookupVar(path[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, prop := range path[1:] {
+		receiver := fn
+		fn, err = lookupMember(receiver, memberProperty{Name: prop, Key: StringVal{Value: prop}})
+		if err != nil {
+			return nil, err
+		}
+		fn = bindReceiver(fn, receiver)
+	}
+	return hostCall(fn, in.env, args)
+}
+
+// Call calls fn from Go with args converted the way ToValue does it, for
+// functions the host got hold of as values, like a callback a script passed
+// to a host function. Like Interp.Run it fails with ErrConcurrentUse while
+// the program fn belongs to is running on another goroutine.
+func (fn UserFunctionValue) Call(args ...any) (RuntimeVal, error) {
+	if fn.DeclarationEnv == nil {
+		return nil, errors.New("Call: the function doesn't belong to a program")
+	}
+	return hostCall(fn, fn.DeclarationEnv, args)
+}
+
+// hostCall makes a call from Go to fn, which belongs to the program running
+// in env
+func hostCall(fn RuntimeVal, env *Environment, args []any) (RuntimeVal, error) {
+	values := make([]RuntimeVal, len(args))
+	for i, arg := range args {
+		value, err := ToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+
+	leave, err := env.session.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	return runSteps(callAt(fn, values, f.Position{}, env))
+}
+
+// RegisterFunc declares a global constant name that calls the Go function
+// fn, like
+//
+//	in.RegisterFunc("add", func(a, b float64) float64 { return a + b })
+//
+// Arguments are converted to fn's parameter types the way FromValue does it,
+// and RuntimeVal parameters take a0 values as they are. fn may return
+// nothing, a value, an error or a value and an error, a value is converted
+// back like ToValue does and an error becomes a runtime error a0 code can
+// catch, so does a panic in fn. Calls with the wrong number or types of
+// arguments fail where they're made.
+func (in *Interp) RegisterFunc(name string, fn any) error {
+	native, err := wrapFunc(name, fn)
+	if err != nil {
+		return fmt.Errorf("RegisterFunc %s: %w", name, err)
+	}
+	_, err = in.env.DeclareVar(name, native, true)
+	return err
+}
+
+/////////////
+// Globals //
+/////////////
+
+// Globals is a global scope set up once, with the host's functions added to
+// it, for any number of Interps to start from. Starting from it copies the
+// names instead of setting up every native again, and the native modules are
+// shared since programs can't change them. Globals can't change once an
+// Interp has started from it, after that Interps on different goroutines can
+// start from it at the same time.
+type Globals struct {
+	env    *Environment
+	sealed atomic.Bool
+}
+
+func NewGlobals() *Globals {
+	return &Globals{env: NewEnvironment(nil)}
+}
+
+// RegisterFunc adds a Go function to g the way Interp.RegisterFunc does
+func (g *Globals) RegisterFunc(name string, fn any) error {
+	if g.sealed.Load() {
+		return fmt.Errorf("RegisterFunc %s: Globals can't change once Interps have started from them", name)
+	}
+	native, err := wrapFunc(name, fn)
+	if err != nil {
+		return fmt.Errorf("RegisterFunc %s: %w", name, err)
+	}
+	_, err = g.env.DeclareVar(name, native, true)
+	return err
+}
+
+// SetPolicy restricts the natives of g the way Environment.SetPolicy does,
+// for every Interp started from it
+func (g *Globals) SetPolicy(policy Policy) error {
+	if g.sealed.Load() {
+		return errors.New("SetPolicy: Globals can't change once Interps have started from them")
+	}
+	return g.env.SetPolicy(policy)
+}
+
+// NewInterp starts an Interp from g. What its programs declare and the
+// settings on its Env are its own, no other Interp sees them.
+func (g *Globals) NewInterp() *Interp {
+	g.sealed.Store(true)
+	return &Interp{env: g.env.copyGlobal()}
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// wrapFunc makes the native function RegisterFunc declares, its errors say
+// what's wrong with fn and leave naming it to the caller
+func wrapFunc(name string, fn any) (NativeFunctionValue, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return NativeFunctionValue{}, fmt.Errorf("%T is not a function", fn)
+	}
+
+	fnType := value.Type()
+	for i := range fnType.NumIn() {
+		param := fnType.In(i)
+		if fnType.IsVariadic() && i == fnType.NumIn()-1 {
+			param = param.Elem()
+		}
+		if err := checkGoType(param); err != nil {
+			return NativeFunctionValue{}, fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+	}
+	returnsError := fnType.NumOut() > 0 && fnType.Out(fnType.NumOut()-1) == errorType
+	results := fnType.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return NativeFunctionValue{}, fmt.Errorf("returns %d values, functions can return a value, an error or both", fnType.NumOut())
+	}
+	if results == 1 {
+		if err := checkGoType(fnType.Out(0)); err != nil {
+			return NativeFunctionValue{}, fmt.Errorf("result: %w", err)
+		}
+	}
+
+	fixed := fnType.NumIn()
+	if fnType.IsVariadic() {
+		fixed--
+	}
+
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) < fixed || (!fnType.IsVariadic() && len(args) > fixed) {
+				expected := fmt.Sprintf("%d %s", fixed, plural(fixed, "argument"))
+				if fnType.IsVariadic() {
+					expected = "at least " + expected
+				}
+				errorMessage := fmt.Sprintf("%s expects %s but got %d", name, expected, len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			goArgs := make([]reflect.Value, len(args))
+			for i, arg := range args {
+				param := fnType.In(min(i, fnType.NumIn()-1))
+				if i >= fixed {
+					param = param.Elem()
+				}
+				goArg, ok := toGoValue(arg, param)
+				if !ok {
+					return nil, &TypeError{
+						Operation: fmt.Sprintf("argument %d of %s", i+1, name),
+						Expected:  describeGoType(param),
+						Got:       TypeOf(arg),
+					}
+				}
+				goArgs[i] = goArg
+			}
+
+			out, err := callHostFunc(name, value, goArgs, env)
+			if err != nil {
+				return nil, err
+			}
+			if returnsError {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return nil, hostError(err)
+				}
+			}
+			if results == 0 {
+				return NadaVal{}, nil
+			}
+			result, err := fromGoValue(out[0], make(map[goRef]bool))
+			if err != nil {
+				errorMessage := fmt.Sprintf("%s returned %s", name, err)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return result, nil
+		},
+	}, nil
+}
+
+// callHostFunc calls a function the host registered, a panic in it becomes
+// an error of the program calling it instead of taking the host down
+func callHostFunc(name string, fn reflect.Value, args []reflect.Value, env *Environment) (out []reflect.Value, err error) {
+	// the host function may run source in the Interp calling it
+	env.session.hostCalls.Add(1)
+	defer env.session.hostCalls.Add(-1)
+	defer func() {
+		if rec := recover(); rec != nil {
+			errorMessage := fmt.Sprintf("%s panicked: %v", name, rec)
+			out, err = nil, &InterpretingError{Message: errorMessage}
+		}
+	}()
+	return fn.Call(args), nil
+}
+
+// hostError is how an error from a host function reaches a0 code, the
+// runtime's own errors go through as they are so exit, limits and throws
+// keep working
+func hostError(err error) error {
+	switch err.(type) {
+	case *InterpretingError, *TypeError, *ThrowError, *ExitError, *LimitError:
+		return err
+	}
+	return &InterpretingError{Message: err.Error()}
+}
+
+func isFunc(value any) bool {
+	return reflect.ValueOf(value).Kind() == reflect.Func
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
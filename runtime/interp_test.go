@@ -0,0 +1,278 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHostFuncPanic(t *testing.T) {
+	in := NewInterp()
+	if err := in.RegisterFunc("boom", func() { panic("out of cheese") }); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := in.Run("boom()")
+	if err == nil || !strings.Contains(err.Error(), "boom panicked: out of cheese") {
+		t.Fatalf("got error %v, want boom panicked", err)
+	}
+	if calls := in.env.session.hostCalls.Load(); calls != 0 {
+		t.Errorf("%d host calls left in progress after the panic", calls)
+	}
+
+	// the panic is an error the program can catch like any other
+	val, err := in.Run(`try { boom() } catch (e) { "caught" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := val.(StringVal); !ok || s.Value != "caught" {
+		t.Errorf("got %v, want caught", val)
+	}
+}
+
+func TestHostFuncReentry(t *testing.T) {
+	in := NewInterp()
+	in.RegisterFunc("nested", func() float64 {
+		val, err := in.Run("1 + 1")
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		return val.(NumberVal).Value
+	})
+	// a goroutine the host function starts is not the program's goroutine
+	in.RegisterFunc("elsewhere", func() bool {
+		done := make(chan error)
+		go func() {
+			_, err := in.Run("1")
+			done <- err
+		}()
+		return <-done == ErrConcurrentUse
+	})
+
+	val, err := in.Run("nested() + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := val.(NumberVal); !ok || n.Value != 3 {
+		t.Errorf("got %v, want 3", val)
+	}
+
+	val, err = in.Run("elsewhere()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := val.(BoolVal); !ok || !b.Value {
+		t.Errorf("a goroutine started by a host function could run source in the Interp")
+	}
+}
+
+func TestGlobalsIsolateInterps(t *testing.T) {
+	g := NewGlobals()
+	if err := g.RegisterFunc("twice", func(n float64) float64 { return n * 2 }); err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := g.NewInterp(), g.NewInterp()
+	if _, err := first.Run("val mine = twice(21)"); err != nil {
+		t.Fatal(err)
+	}
+	val, err := first.Run("mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := val.(NumberVal); !ok || n.Value != 42 {
+		t.Errorf("got %v, want 42", val)
+	}
+	if _, err := second.Run("mine"); err == nil {
+		t.Error("an Interp saw what another one declared")
+	}
+
+	// settings on one Interp's Env are its own too
+	first.Env().SetMaxSteps(10)
+	if _, err := second.Run("for (i in 0..100) {}"); err != nil {
+		t.Errorf("another Interp's step limit applied: %v", err)
+	}
+}
+
+func TestGlobalsSealed(t *testing.T) {
+	g := NewGlobals()
+	g.NewInterp()
+	if err := g.RegisterFunc("late", func() {}); err == nil {
+		t.Error("RegisterFunc worked after an Interp started from the Globals")
+	}
+	if err := g.SetPolicy(Policy{Deny: []string{"fs"}}); err == nil {
+		t.Error("SetPolicy worked after an Interp started from the Globals")
+	}
+}
+
+func TestGlobalsOnManyGoroutines(t *testing.T) {
+	g := NewGlobals()
+	g.RegisterFunc("square", func(n float64) float64 { return n * n })
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in := g.NewInterp()
+			val, err := in.Run(fmt.Sprintf("var total = 0\nfor (n in 0..100) { total = total + square(%d) }\ntotal", i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if n := val.(NumberVal).Value; n != float64(100*i*i) {
+				errs <- fmt.Errorf("goroutine %d got %v", i, n)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	in := NewInterp()
+	started, release := make(chan struct{}), make(chan struct{})
+	in.RegisterFunc("wait", func() {
+		close(started)
+		<-release
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := in.Run("wait()")
+		done <- err
+	}()
+	<-started
+	if _, err := in.Run("1"); err != ErrConcurrentUse {
+		t.Errorf("got %v running on another goroutine, want ErrConcurrentUse", err)
+	}
+	if _, err := in.Call("print"); err != ErrConcurrentUse {
+		t.Errorf("got %v calling on another goroutine, want ErrConcurrentUse", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// once it's done another goroutine can have it
+	if _, err := in.Run("1"); err != nil {
+		t.Errorf("got %v after the first run ended", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	in := NewInterp()
+	_, err := in.Run(`fun describe(kind, data) { return " at ".join([kind, toString(data.x)]) }
+class Counter {
+    fun init(start) { self.n = start }
+    fun add(by) { self.n = self.n + by
+        return self.n }
+}
+val counter = Counter(10)
+fun twice(n) { return n * 2 }
+val handlers = { twice: twice }
+fun fail() { throw "no" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []any
+		want RuntimeVal
+	}{
+		{"describe", []any{"click", map[string]any{"x": 3}}, StringVal{Value: "click at 3"}},
+		{"counter.add", []any{5}, NumberVal{Value: 15}},
+		{"handlers.twice", []any{21}, NumberVal{Value: 42}},
+	}
+	for _, tt := range tests {
+		got, err := in.Call(tt.name, tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.String() != tt.want.String() {
+			t.Errorf("%s got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// calling a class makes an instance
+	instance, err := in.Call("Counter", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj, ok := instance.(ObjectVal); !ok || obj.Properties["n"].String() != "1" {
+		t.Errorf("got %v, want a Counter at 1", instance)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	in := NewInterp()
+	in.Run("fun fail() { throw \"no\" }\nfun one(a) { return a }")
+
+	_, err := in.Call("fail")
+	var thrown *ThrowError
+	if !errors.As(err, &thrown) || thrown.Thrown.Message != "no" {
+		t.Errorf("got %v, want the throw", err)
+	}
+	if _, err := in.Call("missing"); err == nil {
+		t.Error("calling an undeclared name didn't fail")
+	}
+	if _, err := in.Call("one", make(chan int)); err == nil || !strings.Contains(err.Error(), "argument 1") {
+		t.Errorf("got %v, want the argument that has no a0 value", err)
+	}
+	if _, err := in.Call("one", 1, 2); err == nil {
+		t.Error("calling with too many arguments didn't fail")
+	}
+	if _, err := (UserFunctionValue{}).Call(); err == nil {
+		t.Error("calling a function without a program didn't fail")
+	}
+}
+
+// a callback a script hands to the host can be called once the script is
+// done, or by the host function it was handed to
+func TestUserFunctionCall(t *testing.T) {
+	in := NewInterp()
+	var saved UserFunctionValue
+	err := in.RegisterFunc("each", func(callback RuntimeVal) (float64, error) {
+		fn, ok := callback.(UserFunctionValue)
+		if !ok {
+			return 0, errors.New("each expects a function")
+		}
+		saved = fn
+		total := 0.0
+		for i := range 3 {
+			val, err := fn.Call(i)
+			if err != nil {
+				return 0, err
+			}
+			total += val.(NumberVal).Value
+		}
+		return total, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := in.Run("var calls = 0\nfun tens(i) { calls = calls + 1\n    return i * 10 }\neach(tens)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := val.(NumberVal); !ok || n.Value != 30 {
+		t.Errorf("got %v, want 30", val)
+	}
+
+	if _, err := saved.Call(5); err != nil {
+		t.Fatal(err)
+	}
+	if calls, _ := in.Run("calls"); calls.String() != "4" {
+		t.Errorf("the callback ran %v times, want 4", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 
 	f "github.com/Mstr0A/a0-lang/frontend"
@@ -8,27 +9,152 @@ import (
 
 type InterpretingError struct {
 	Message string
+	Pos     f.Position // the zero Position until Evaluate attaches where it happened
 }
 
 func (e *InterpretingError) Error() string {
-	return fmt.Sprintf("Interpretation Error: %s", e.Message)
+	if e.Pos.Line() == 0 {
+		return fmt.Sprintf("Runtime Error: %s", e.Message)
+	}
+	return fmt.Sprintf("Runtime Error at (%d, %d): %s", e.Pos.Line(), e.Pos.Column(), e.Message)
+}
+
+// TypeError is a runtime error for a value of the wrong type reaching an
+// operation that can't handle it
+type TypeError struct {
+	Operation string
+	Expected  string
+	Got       ValueType
+	Pos       f.Position
+}
+
+func (e *TypeError) Error() string {
+	if e.Pos.Line() == 0 {
+		return fmt.Sprintf("Type Error: %s", e.message())
+	}
+	return fmt.Sprintf("Type Error at (%d, %d): %s", e.Pos.Line(), e.Pos.Column(), e.message())
+}
+
+func (e *TypeError) message() string {
+	return fmt.Sprintf("%s expects %s but got %s", e.Operation, e.Expected, e.Got)
+}
+
+// ExitError is returned when a program calls exit or os.exit, it unwinds
+// everything (try statements don't catch it) so the host can exit with Code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitStatus is the status a host should exit with after a program ended
+// with err: 0 when it ran to the end, the code it passed to exit, or 1 for
+// an error nothing caught
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	return 1
+}
+
+// LimitError is returned when a program goes past a limit the host put on it,
+// like the step budget. Like ExitError it can't be caught, the program has to
+// stop.
+type LimitError struct {
+	Message string
+	Pos     f.Position
+}
+
+func (e *LimitError) Error() string {
+	if e.Pos.Line() == 0 {
+		return fmt.Sprintf("Runtime Error: %s", e.Message)
+	}
+	return fmt.Sprintf("Runtime Error at (%d, %d): %s", e.Pos.Line(), e.Pos.Column(), e.Message)
+}
+
+// uncatchable reports whether err has to end the program, try statements
+// leave those alone
+func uncatchable(err error) bool {
+	var exit *ExitError
+	var limit *LimitError
+	return errors.As(err, &exit) || errors.As(err, &limit)
+}
+
+// ThrowError carries a thrown a0 error up through Evaluate until a try
+// statement catches it, or out to the host when nothing does
+type ThrowError struct {
+	Thrown ErrorVal
+}
+
+func (e *ThrowError) Error() string {
+	return fmt.Sprintf("Uncaught Error at (%d, %d): %s", e.Thrown.Line, e.Thrown.Column, e.Thrown.Message)
 }
 
 // Main Eval //
+// Evaluate runs astNode on the eval machine (see runSteps) instead of
+// recursing in Go for every nested node and call, so deep programs are only
+// limited by the a0 level limits, like the recursion depth, and by memory.
 func Evaluate(astNode f.Stmt, env *Environment) (RuntimeVal, error) {
+	return runSteps(evalThen(astNode, env, func(value RuntimeVal) step {
+		return finish(value, nil)
+	}))
+}
+
+// setErrorPosition records where a runtime error happened, unless a node
+// deeper in the tree already did since that one is more precise
+func setErrorPosition(err error, pos f.Position) {
+	var interpErr *InterpretingError
+	if errors.As(err, &interpErr) && interpErr.Pos.Line() == 0 {
+		interpErr.Pos = pos
+	}
+
+	var typeErr *TypeError
+	if errors.As(err, &typeErr) && typeErr.Pos.Line() == 0 {
+		typeErr.Pos = pos
+	}
+
+	var limit *LimitError
+	if errors.As(err, &limit) && limit.Pos.Line() == 0 {
+		limit.Pos = pos
+	}
+
+	// natives like assertEqual throw without knowing where they were called
+	var thrown *ThrowError
+	if errors.As(err, &thrown) && thrown.Thrown.Line == 0 {
+		thrown.Thrown.Line, thrown.Thrown.Column = pos.Line(), pos.Column()
+		thrown.Thrown.pos = pos
+	}
+}
+
+// evaluateNode is how the task evaluating astNode starts
+func evaluateNode(astNode f.Stmt, env *Environment) step {
 	switch castedNode := astNode.(type) {
 	case f.Program:
 		return evalProgram(castedNode, env)
 	case f.NumericLiteral:
-		return NumberVal{Value: castedNode.Value}, nil
+		return finish(NumberVal{Value: castedNode.Value}, nil)
 	case f.StringLiteral:
-		return StringVal{Value: castedNode.Value}, nil
+		return finish(StringVal{Value: castedNode.Value}, nil)
+	case f.CharLiteral:
+		return finish(CharVal{Value: castedNode.Value}, nil)
 	case f.Identifier:
-		return evalIdentifier(castedNode, env)
+		return finish(evalIdentifier(castedNode, env))
 	case f.ObjectLiteral:
 		return evalObjectExpr(castedNode, env)
+	case f.ArrayLiteral:
+		return evalArrayExpr(castedNode, env)
 	case f.MemberExpr:
 		return evalMemberExpr(castedNode, env)
+	case f.SliceExpr:
+		return evalSliceExpr(castedNode, env)
+	case f.RangeExpr:
+		return evalRangeExpr(castedNode, env)
 	case f.BinaryExpr:
 		return evalBinaryExpr(castedNode, env)
 	case f.UnaryExpr:
@@ -36,24 +162,46 @@ func Evaluate(astNode f.Stmt, env *Environment) (RuntimeVal, error) {
 	case f.VarDeclaration:
 		return evalVarDeclaration(castedNode, env)
 	case f.FunctionDeclaration:
-		return evalFunctionDeclaration(castedNode, env)
+		return finish(evalFunctionDeclaration(castedNode, env))
+	case f.ClassDeclaration:
+		return evalClassDeclaration(castedNode, env)
 	case f.AssignmentExpr:
 		return evalAssignmentExpr(castedNode, env)
 	case f.CallExpr:
 		return evalCallExpr(castedNode, env)
+	case f.NewExpr:
+		return evalNewExpr(castedNode, env)
+	case f.MatchExpr:
+		return evalMatchExpr(castedNode, env)
 	case f.LogicalExpr:
 		return evalLogicalExpr(castedNode, env)
+	case f.BenchStmt:
+		// bench blocks only run under RunBenchmarks
+		return finish(NadaVal{}, nil)
 	case f.IfStmt:
 		return evalIfStmt(castedNode, env)
 	case f.WhileStmt:
 		return evalWhileStmt(castedNode, env)
 	case f.ForStmt:
 		return evalForStmt(castedNode, env)
+	case f.ForEachStmt:
+		return evalForEachStmt(castedNode, env)
 	case f.ReturnStmt:
 		return evalReturnStmt(castedNode, env)
+	case f.BreakStmt:
+		return finish(evalBreakStmt(castedNode))
+	case f.ContinueStmt:
+		return finish(evalContinueStmt(castedNode))
+	case f.ImportStmt:
+		return finish(evalImportStmt(castedNode, env))
+	case f.TryStmt:
+		return evalTryStmt(castedNode, env)
+	case f.ThrowStmt:
+		return evalThrowStmt(castedNode, env)
+	case f.AssertStmt:
+		return evalAssertStmt(castedNode, env)
 	default:
 		errorMessage := fmt.Sprintf("AST Node has not been added for interpretation: %v", castedNode)
-		err := &InterpretingError{Message: errorMessage}
-		return nil, err
+		return finish(nil, &InterpretingError{Message: errorMessage})
 	}
 }
@@ -0,0 +1,54 @@
+package runtime_test
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// smallStack caps the Go stack far below what the programs in these tests
+// would need if evaluation recursed in Go for every node and call, going
+// past it crashes the test binary
+const smallStack = 1 << 20
+
+// evalOnSmallStack parses src and evaluates it in env with the Go stack
+// capped at smallStack
+func evalOnSmallStack(t *testing.T, src string, env *r.Environment) (r.RuntimeVal, error) {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer debug.SetMaxStack(debug.SetMaxStack(smallStack))
+	return r.Evaluate(program, env)
+}
+
+func TestDeepRecursion(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	env.SetMaxDepth(0)
+	got, err := evalOnSmallStack(t, `
+fun depth(n) {
+    if (n == 0) { return 0 }
+    return 1 + depth(n - 1)
+}
+depth(100000)
+`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: 100000})
+}
+
+func TestDeeplyNestedExpression(t *testing.T) {
+	const levels = 20000
+	src := strings.Repeat("(1 + ", levels) + "0" + strings.Repeat(")", levels)
+	got, err := evalOnSmallStack(t, src, r.NewEnvironment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: levels})
+}
@@ -0,0 +1,173 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+/////////////////
+// JSON Module //
+/////////////////
+
+// deeper than this is treated as a cycle when stringifying
+const maxJSONDepth = 1000
+
+// the most spaces an indent can be, the same as JavaScript allows
+const maxJSONIndent = 10
+
+// jsonModule builds the global json object with parse and stringify
+func jsonModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "json"}
+
+	// parse(text) turns JSON into objects, lists, strings, numbers, bools
+	// and nada for null
+	module.Properties["parse"] = NativeFunctionValue{
+		Name: "json.parse",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			text, ok := stringArg(args, 0)
+			if !ok || len(args) != 1 {
+				return nil, &InterpretingError{Message: "json.parse expects a string"}
+			}
+
+			var decoded any
+			if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+				errorMessage := fmt.Sprintf("json.parse: %v", err)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return fromJSON(decoded), nil
+		},
+	}
+
+	// stringify(value) is compact, stringify(value, 2) or
+	// stringify(value, "\t") indents nested values
+	module.Properties["stringify"] = NativeFunctionValue{
+		Name: "json.stringify",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 || len(args) > 2 {
+				return nil, &InterpretingError{Message: "json.stringify expects (value, indent)"}
+			}
+
+			indent := ""
+			if len(args) == 2 {
+				switch v := args[1].(type) {
+				case NumberVal:
+					if !(v.Value >= 0 && v.Value <= maxJSONIndent) || v.Value != math.Trunc(v.Value) {
+						errorMessage := fmt.Sprintf("json.stringify expects an indent from 0 to %d spaces but got %v", maxJSONIndent, v)
+						return nil, &InterpretingError{Message: errorMessage}
+					}
+					indent = strings.Repeat(" ", int(v.Value))
+				case StringVal:
+					indent = v.Value
+				default:
+					return nil, &TypeError{Operation: "json.stringify", Expected: "a number or string indent", Got: TypeOf(v)}
+				}
+			}
+
+			data, err := toJSON(args[0], 0)
+			if err != nil {
+				return nil, err
+			}
+
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", indent)
+			if err := encoder.Encode(data); err != nil {
+				errorMessage := fmt.Sprintf("json.stringify: %v", err)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return StringVal{Value: strings.TrimSuffix(buf.String(), "\n")}, nil
+		},
+	}
+
+	return module
+}
+
+// fromJSON converts what encoding/json decodes into runtime values
+func fromJSON(data any) RuntimeVal {
+	switch v := data.(type) {
+	case map[string]any:
+		obj := ObjectVal{Properties: make(map[string]RuntimeVal, len(v))}
+		for key, value := range v {
+			obj.Properties[key] = fromJSON(value)
+		}
+		return obj
+	case []any:
+		elements := make([]RuntimeVal, len(v))
+		for i, value := range v {
+			elements[i] = fromJSON(value)
+		}
+		return &ListVal{Elements: elements}
+	case string:
+		return StringVal{Value: v}
+	case float64:
+		return NumberVal{Value: v}
+	case bool:
+		return BoolVal{Value: v}
+	default:
+		return NadaVal{}
+	}
+}
+
+// toJSON converts a runtime value into something encoding/json can encode,
+// functions and classes have no JSON form
+func toJSON(val RuntimeVal, depth int) (any, error) {
+	if depth > maxJSONDepth {
+		return nil, &InterpretingError{Message: "json.stringify: value is nested too deeply or contains itself"}
+	}
+
+	switch v := val.(type) {
+	case NadaVal:
+		return nil, nil
+	case BoolVal:
+		return v.Value, nil
+	case NumberVal:
+		if math.IsInf(v.Value, 0) || math.IsNaN(v.Value) {
+			errorMessage := fmt.Sprintf("json.stringify: %v has no JSON form", v)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		return v.Value, nil
+	case StringVal:
+		return v.Value, nil
+	case CharVal:
+		return string(v.Value), nil
+	case DateVal:
+		return v.String(), nil
+	case ObjectVal:
+		obj := make(map[string]any, len(v.Properties))
+		for key, value := range v.Properties {
+			data, err := toJSON(value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = data
+		}
+		return obj, nil
+	case *ListVal:
+		list := make([]any, len(v.Elements))
+		for i, element := range v.Elements {
+			data, err := toJSON(element, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = data
+		}
+		return list, nil
+	case RangeVal:
+		// every number written takes at least a digit and a comma
+		if v.Len() > maxStringLength/2 {
+			errorMessage := fmt.Sprintf("json.stringify: a range of %d numbers would make a string longer than %d bytes", v.Len(), maxStringLength)
+			return nil, &InterpretingError{Message: errorMessage}
+		}
+		list := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			list = append(list, v.At(i))
+		}
+		return list, nil
+	default:
+		return nil, &TypeError{Operation: "json.stringify", Expected: "data (objects, lists, strings, numbers, bools or nada)", Got: TypeOf(val)}
+	}
+}
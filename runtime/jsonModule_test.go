@@ -0,0 +1,85 @@
+package runtime_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`json.stringify(json.parse("{\"a\": [1, 2.5, \"x\", true, null], \"b\": {}}"))`, `{"a":[1,2.5,"x",true,null],"b":{}}`},
+		{`json.stringify({b: 1, a: "<&>"})`, `{"a":"<&>","b":1}`},
+		{`json.stringify([1, [2, []]], 2)`, "[\n  1,\n  [\n    2,\n    []\n  ]\n]"},
+		{`json.stringify({a: 1}, "\t")`, "{\n\t\"a\": 1\n}"},
+		{`json.stringify(range(3))`, `[0,1,2]`},
+		{`json.stringify('c')`, `"c"`},
+		{`json.stringify(nada)`, `null`},
+		{`json.stringify(date.make(2024, 1, 2, "UTC"))`, `"2024-01-02T00:00:00Z"`},
+		{`json.stringify(json.parse("\"\\u00e9\\n\""))`, `"é\n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), r.StringVal{Value: tt.want})
+		})
+	}
+}
+
+func TestJSONParseValues(t *testing.T) {
+	testutil.AssertValue(t, testutil.MustEval(t, `json.parse("[1, \"a\", false, null]")`), &r.ListVal{Elements: []r.RuntimeVal{
+		r.NumberVal{Value: 1}, r.StringVal{Value: "a"}, r.BoolVal{Value: false}, r.NadaVal{},
+	}})
+	testutil.AssertValue(t, testutil.MustEval(t, `json.parse("{\"n\": {\"m\": 3}}").n.m`), r.NumberVal{Value: 3})
+	testutil.AssertValue(t, testutil.MustEval(t, `json.parse("  7  ")`), r.NumberVal{Value: 7})
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		typeErr  bool // a TypeError rather than an InterpretingError
+		contains string
+	}{
+		{"json.parse()", false, "json.parse expects a string"},
+		{"json.parse(1)", false, "json.parse expects a string"},
+		{`json.parse("{}", 1)`, false, "json.parse expects a string"},
+		{`json.parse("{\"a\": }")`, false, "json.parse: invalid character"},
+		{`json.parse("[1, 2")`, false, "json.parse: unexpected end of JSON input"},
+		{`json.parse("1e400")`, false, "json.parse:"},
+		{"json.stringify()", false, "json.stringify expects (value, indent)"},
+		{"json.stringify(1, 2, 3)", false, "json.stringify expects (value, indent)"},
+		{"json.stringify(1, true)", true, ""},
+		{"json.stringify(1, -1)", false, "indent from 0 to 10 spaces"},
+		{"json.stringify(1, 1.5)", false, "indent from 0 to 10 spaces"},
+		{"json.stringify(1, 1e12)", false, "indent from 0 to 10 spaces"},
+		{"json.stringify(1, math.sqrt(-1))", false, "indent from 0 to 10 spaces"},
+		{"json.stringify(math.sqrt(-1))", false, "NaN has no JSON form"},
+		{"json.stringify([math.pow(10, 400)])", false, "has no JSON form"},
+		{"json.stringify(range(1e12))", false, "would make a string longer than"},
+		{"json.stringify({f: print})", true, ""},
+		{"var l = []\nl.push(l)\njson.stringify(l)", false, "nested too deeply or contains itself"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var typeErr *r.TypeError
+			var interpErr *r.InterpretingError
+			switch {
+			case err == nil:
+				t.Fatal("got no error")
+			case tt.typeErr && !errors.As(err, &typeErr):
+				t.Fatalf("got %v, want a type error", err)
+			case !tt.typeErr && !errors.As(err, &interpErr):
+				t.Fatalf("got %v, want a runtime error", err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
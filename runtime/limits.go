@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+////////////
+// Limits //
+////////////
+
+// SetMaxSteps gives the program a budget of evaluation steps, every node
+// evaluated and every bytecode instruction run is one. Going over it stops
+// the program with an error try can't catch, so an endless loop can't hang
+// the host. 0 or less removes the limit.
+func (env *Environment) SetMaxSteps(steps int) {
+	env.session.maxSteps = max(steps, 0)
+}
+
+// SetMaxMemory stops the program with an error try can't catch once the
+// strings, lists and objects it has made add up to more than bytes. They're
+// counted where they're made: literals, slices, new properties and elements,
+// and whatever natives give back, sized roughly and without looking inside
+// the values a native returns. Nothing is taken off when a value is dropped,
+// so like SetMaxSteps it's a budget for the whole run rather than a cap on
+// what the program holds at once. 0 or less removes the limit.
+func (env *Environment) SetMaxMemory(bytes int64) {
+	s := env.session
+	s.maxMemory = uint64(max(bytes, 0))
+	s.allocated = 0
+}
+
+// step counts one evaluation step against the limits the host set
+func (s *session) step() error {
+	if s.stopped.Load() {
+		errorMessage := "Execution cancelled"
+		if errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+			errorMessage = "Execution timed out"
+		}
+		return &LimitError{Message: errorMessage}
+	}
+	if s.maxSteps == 0 {
+		return nil
+	}
+	s.steps++
+
+	if s.steps > s.maxSteps {
+		errorMessage := fmt.Sprintf("Execution budget exceeded (%d steps)", s.maxSteps)
+		return &LimitError{Message: errorMessage}
+	}
+	return nil
+}
+
+// rough sizes of the parts of a0 values, for the memory limit
+const (
+	valueSize    = 16 // a value in a list or object, an interface
+	stringHeader = 16
+	listHeader   = 32
+	objectHeader = 48
+	propertySize = 48 // a map entry, its key's header and its value
+)
+
+func stringBytes(s string) int {
+	return stringHeader + len(s)
+}
+
+func listBytes(length int) int {
+	return listHeader + length*valueSize
+}
+
+func objectBytes(properties int) int {
+	return objectHeader + properties*propertySize
+}
+
+// valueBytes is how much making val took, not counting the values inside it
+func valueBytes(val RuntimeVal) int {
+	switch v := val.(type) {
+	case StringVal:
+		return stringBytes(v.Value)
+	case *ListVal:
+		return listBytes(len(v.Elements))
+	case ObjectVal:
+		size := objectBytes(len(v.Properties))
+		for key := range v.Properties {
+			size += len(key)
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+// allocate counts bytes of strings, lists or objects the program made
+// against the memory limit
+func (s *session) allocate(bytes int) error {
+	if s.maxMemory == 0 {
+		return nil
+	}
+
+	s.allocated += uint64(bytes)
+	if s.allocated > s.maxMemory {
+		errorMessage := fmt.Sprintf("Memory limit exceeded (%d bytes made, the limit is %d)", s.allocated, s.maxMemory)
+		return &LimitError{Message: errorMessage}
+	}
+	return nil
+}
+
+// allocateCall counts what a call to the native fn made: the value it gave
+// back, unless that's its receiver or one of args handed back, and what it
+// added to the list it's a method of, which was length long before the call
+func (s *session) allocateCall(fn NativeFunctionValue, args []RuntimeVal, result RuntimeVal, length int) error {
+	if s.maxMemory == 0 {
+		return nil
+	}
+
+	bytes := 0
+	if list, ok := fn.receiver.(*ListVal); ok && len(list.Elements) > length {
+		bytes += (len(list.Elements) - length) * valueSize
+	}
+	handedBack := sameValue(result, fn.receiver) || slices.ContainsFunc(args, func(arg RuntimeVal) bool {
+		return sameValue(result, arg)
+	})
+	if !handedBack {
+		bytes += valueBytes(result)
+	}
+	return s.allocate(bytes)
+}
+
+// sameValue is true when a and b are the same string, list or object
+func sameValue(a, b RuntimeVal) bool {
+	switch v := a.(type) {
+	case StringVal:
+		other, ok := b.(StringVal)
+		return ok && v.Value == other.Value
+	case *ListVal:
+		return v == b
+	case ObjectVal:
+		other, ok := b.(ObjectVal)
+		return ok && reflect.ValueOf(v.Properties).Pointer() == reflect.ValueOf(other.Properties).Pointer()
+	default:
+		return false
+	}
+}
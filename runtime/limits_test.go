@@ -0,0 +1,95 @@
+package runtime_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// runLimited runs src evaluated and compiled in environments set up by limit
+// and gives back both errors
+func runLimited(t *testing.T, src string, limit func(env *r.Environment)) (evalErr, vmErr error) {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := r.NewEnvironment(nil)
+	env.SetOutput(io.Discard, io.Discard)
+	limit(env)
+	_, evalErr = r.Evaluate(program, env)
+
+	chunk, err := r.Compile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env = r.NewEnvironment(nil)
+	env.SetOutput(io.Discard, io.Discard)
+	limit(env)
+	_, vmErr = r.Execute(chunk, env)
+	return evalErr, vmErr
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"object built in a loop", `var o = {}
+var i = 0
+while (true) { o[i] = i
+    i = i + 1 }`},
+		{"list pushed to", `val xs = []
+while (true) { xs.push(1, 2, 3) }`},
+		{"list literals", `var xs = []
+while (true) { xs = [xs, xs, xs, xs] }`},
+		{"strings from a native", `var s = "abc"
+while (true) { s = s.repeat(2) }`},
+		{"slices", `val xs = [1, 2, 3, 4, 5, 6, 7, 8]
+while (true) { xs[1:] }`},
+		{"caught by nothing", `try { val xs = []
+    while (true) { xs.push(xs.length()) } } catch (e) { 0 }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evalErr, vmErr := runLimited(t, tt.src, func(env *r.Environment) {
+				env.SetMaxMemory(1 << 20)
+			})
+			for _, err := range []error{evalErr, vmErr} {
+				var limit *r.LimitError
+				if !errors.As(err, &limit) || !strings.Contains(err.Error(), "Memory limit exceeded") {
+					t.Errorf("got %v, want the memory limit", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimitAllowsSmallPrograms(t *testing.T) {
+	src := `val xs = []
+for (i in 0..100) { xs.push({ n: i, name: "item" }) }
+xs[50:].length()`
+	evalErr, vmErr := runLimited(t, src, func(env *r.Environment) {
+		env.SetMaxMemory(1 << 20)
+	})
+	if evalErr != nil || vmErr != nil {
+		t.Errorf("got %v evaluated and %v compiled, want no error", evalErr, vmErr)
+	}
+}
+
+// handing back a value a native was given doesn't make anything new
+func TestMemoryLimitSkipsValuesHandedBack(t *testing.T) {
+	src := `val xs = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+for (i in 0..10000) { xs.reverse() }`
+	evalErr, vmErr := runLimited(t, src, func(env *r.Environment) {
+		env.SetMaxMemory(4096)
+	})
+	if evalErr != nil || vmErr != nil {
+		t.Errorf("got %v evaluated and %v compiled, want no error", evalErr, vmErr)
+	}
+}
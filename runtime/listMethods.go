@@ -0,0 +1,271 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+)
+
+//////////////////
+// List Methods //
+//////////////////
+
+// listMethod is a native method on lists, list is the receiver. Unlike string
+// methods these can fail, since map, filter and reduce run a0 code.
+type listMethod func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error)
+
+// listMethods are the methods every list value has. push, pop, insert,
+// removeAt, reverse and sort change the list in place, the rest build new
+// values.
+var listMethods map[string]listMethod
+
+// filled in init since map, filter and reduce call back into the evaluator,
+// which looks methods up in this map
+func init() {
+	listMethods = map[string]listMethod{
+		"length": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return NumberVal{Value: float64(len(list.Elements))}, nil
+		},
+		"push": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			list.Elements = append(list.Elements, args...)
+			return list, nil
+		},
+		"pop": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(list.Elements) == 0 {
+				return NadaVal{}, nil
+			}
+
+			last := list.Elements[len(list.Elements)-1]
+			list.Elements = list.Elements[:len(list.Elements)-1]
+			return last, nil
+		},
+		"insert": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 2 {
+				return nil, listArgsError("insert", "(index, value)", len(args))
+			}
+
+			// inserting at the length appends
+			index, err := toIndex(args[0], len(list.Elements))
+			if err != nil {
+				return nil, err
+			}
+
+			list.Elements = append(list.Elements, nil)
+			copy(list.Elements[index+1:], list.Elements[index:])
+			list.Elements[index] = args[1]
+			return list, nil
+		},
+		"removeAt": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, listArgsError("removeAt", "(index)", len(args))
+			}
+
+			index, err := toIndex(args[0], len(list.Elements)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			removed := list.Elements[index]
+			list.Elements = append(list.Elements[:index], list.Elements[index+1:]...)
+			return removed, nil
+		},
+		"slice": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 || len(args) > 2 {
+				return nil, listArgsError("slice", "(start, end)", len(args))
+			}
+
+			start, err := toIndex(args[0], len(list.Elements))
+			if err != nil {
+				return nil, err
+			}
+
+			end := len(list.Elements)
+			if len(args) == 2 {
+				end, err = toIndex(args[1], len(list.Elements))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if start > end {
+				start = end
+			}
+			return &ListVal{Elements: append([]RuntimeVal{}, list.Elements[start:end]...)}, nil
+		},
+		"concat": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			elements := append([]RuntimeVal{}, list.Elements...)
+			for _, arg := range args {
+				other, ok := arg.(*ListVal)
+				if !ok {
+					errorMessage := fmt.Sprintf("list.concat expects lists but got %v", arg)
+					return nil, &InterpretingError{Message: errorMessage}
+				}
+				elements = append(elements, other.Elements...)
+			}
+			return &ListVal{Elements: elements}, nil
+		},
+		"reverse": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			for i, j := 0, len(list.Elements)-1; i < j; i, j = i+1, j-1 {
+				list.Elements[i], list.Elements[j] = list.Elements[j], list.Elements[i]
+			}
+			return list, nil
+		},
+		"index": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, listArgsError("index", "(value)", len(args))
+			}
+
+			for i, element := range list.Elements {
+				if Equal(element, args[0]) {
+					return NumberVal{Value: float64(i)}, nil
+				}
+			}
+			return NumberVal{Value: -1}, nil
+		},
+		"contains": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, listArgsError("contains", "(value)", len(args))
+			}
+
+			for _, element := range list.Elements {
+				if Equal(element, args[0]) {
+					return BoolVal{Value: true}, nil
+				}
+			}
+			return BoolVal{Value: false}, nil
+		},
+		"map": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, listArgsError("map", "(fn)", len(args))
+			}
+
+			mapped := make([]RuntimeVal, len(list.Elements))
+			for i, element := range list.Elements {
+				result, err := CallFunction(args[0], []RuntimeVal{element}, env)
+				if err != nil {
+					return nil, err
+				}
+				mapped[i] = result
+			}
+			return &ListVal{Elements: mapped}, nil
+		},
+		"filter": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) != 1 {
+				return nil, listArgsError("filter", "(fn)", len(args))
+			}
+
+			kept := []RuntimeVal{}
+			for _, element := range list.Elements {
+				keep, err := CallFunction(args[0], []RuntimeVal{element}, env)
+				if err != nil {
+					return nil, err
+				}
+				if isTruthy(keep) {
+					kept = append(kept, element)
+				}
+			}
+			return &ListVal{Elements: kept}, nil
+		},
+		"reduce": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			// reduce(fn) starts from the first element, reduce(fn, initial) from initial
+			if len(args) == 0 || len(args) > 2 {
+				return nil, listArgsError("reduce", "(fn, initial)", len(args))
+			}
+
+			elements := list.Elements
+			var acc RuntimeVal
+			if len(args) == 2 {
+				acc = args[1]
+			} else {
+				if len(elements) == 0 {
+					return nil, &InterpretingError{Message: "Cannot reduce an empty list without an initial value"}
+				}
+				acc, elements = elements[0], elements[1:]
+			}
+
+			for _, element := range elements {
+				result, err := CallFunction(args[0], []RuntimeVal{acc, element}, env)
+				if err != nil {
+					return nil, err
+				}
+				acc = result
+			}
+			return acc, nil
+		},
+		"sort": func(list *ListVal, args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			// sort() orders values the way < does, sort(fn) asks fn(a, b)
+			// for a number below 0 when a goes first, above 0 when b does
+			if len(args) > 1 {
+				return nil, listArgsError("sort", "() or (fn)", len(args))
+			}
+
+			var sortErr error
+			compare := func(a, b RuntimeVal) int {
+				if sortErr != nil {
+					return 0
+				}
+
+				if len(args) == 0 {
+					order, ok, err := compareValues(a, b)
+					if err != nil {
+						sortErr = err
+						return 0
+					}
+					if !ok {
+						errorMessage := fmt.Sprintf("Cannot sort %s and %s values together", TypeName(a), TypeName(b))
+						sortErr = &InterpretingError{Message: errorMessage}
+					}
+					return order
+				}
+
+				result, err := CallFunction(args[0], []RuntimeVal{a, b}, env)
+				if err != nil {
+					sortErr = err
+					return 0
+				}
+				order, ok := result.(NumberVal)
+				if !ok {
+					sortErr = &TypeError{Operation: "list.sort", Expected: "a comparator returning a number", Got: TypeOf(result)}
+					return 0
+				}
+				switch {
+				case order.Value < 0:
+					return -1
+				case order.Value > 0:
+					return 1
+				}
+				return 0
+			}
+
+			// sort a copy so a failed sort leaves the list as it was
+			sorted := slices.Clone(list.Elements)
+			slices.SortStableFunc(sorted, compare)
+			if sortErr != nil {
+				return nil, sortErr
+			}
+			list.Elements = sorted
+			return list, nil
+		},
+	}
+}
+
+func listArgsError(method string, params string, got int) error {
+	errorMessage := fmt.Sprintf("list.%s expects %s but got %d argument(s)", method, params, got)
+	return &InterpretingError{Message: errorMessage}
+}
+
+// bindListMethod turns a list method into a function value that already knows
+// its receiver, like bindStringMethod
+func bindListMethod(name string, list *ListVal) (NativeFunctionValue, bool) {
+	method, found := listMethods[name]
+	if !found {
+		return NativeFunctionValue{}, false
+	}
+
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return method(list, args, env)
+		},
+		receiver: list,
+	}, true
+}
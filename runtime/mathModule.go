@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+)
+
+/////////////////
+// Math Module //
+/////////////////
+
+// mathModule builds the global math object, math.sqrt(2), math.pi and so on
+func mathModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "math"}
+
+	unary := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"log":   math.Log,
+	}
+	for name, fn := range unary {
+		module.Properties[name] = numberFunction("math."+name, fn)
+	}
+
+	module.Properties["pow"] = NativeFunctionValue{
+		Name: "math.pow",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, err := numberParams("math.pow", args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return NumberVal{Value: math.Pow(nums[0], nums[1])}, nil
+		},
+	}
+
+	module.Properties["min"] = numberReducer("math.min", math.Min)
+	module.Properties["max"] = numberReducer("math.max", math.Max)
+
+	module.Properties["pi"] = NumberVal{Value: math.Pi}
+	module.Properties["e"] = NumberVal{Value: math.E}
+
+	return module
+}
+
+// numberFunction wraps a one argument float function as a native
+func numberFunction(name string, fn func(float64) float64) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, err := numberParams(name, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return NumberVal{Value: fn(nums[0])}, nil
+		},
+	}
+}
+
+// numberReducer folds its arguments with fn, math.min(3, 1, 2) or
+// math.min([3, 1, 2])
+func numberReducer(name string, fn func(float64, float64) float64) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 1 {
+				if list, ok := args[0].(*ListVal); ok {
+					args = list.Elements
+				}
+			}
+
+			if len(args) == 0 {
+				errorMessage := fmt.Sprintf("%s expects at least 1 number but got none", name)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			nums, err := numberParams(name, args, len(args))
+			if err != nil {
+				return nil, err
+			}
+
+			result := nums[0]
+			for _, num := range nums[1:] {
+				result = fn(result, num)
+			}
+			return NumberVal{Value: result}, nil
+		},
+	}
+}
+
+// numberArgs unwraps args that must all be numbers
+func numberArgs(args []RuntimeVal) ([]float64, bool) {
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		num, ok := arg.(NumberVal)
+		if !ok {
+			return nil, false
+		}
+		nums[i] = num.Value
+	}
+	return nums, true
+}
+
+// numberParams checks a native got want arguments and that they're all
+// numbers
+func numberParams(name string, args []RuntimeVal, want int) ([]float64, error) {
+	if len(args) != want {
+		errorMessage := fmt.Sprintf("%s expects %d %s but got %d", name, want, plural(want, "argument"), len(args))
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		num, ok := arg.(NumberVal)
+		if !ok {
+			return nil, &TypeError{Operation: fmt.Sprintf("argument %d of %s", i+1, name), Expected: "a number", Got: TypeOf(arg)}
+		}
+		nums[i] = num.Value
+	}
+	return nums, nil
+}
@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+/////////////
+// Modules //
+/////////////
+
+// moduleLoader runs imported files and remembers what they export, so a
+// module imported from several places only runs once per program
+type moduleLoader struct {
+	cache   map[string]ObjectVal
+	loading map[string]bool // modules that are mid import, to catch cycles
+}
+
+func newModuleLoader() *moduleLoader {
+	return &moduleLoader{
+		cache:   make(map[string]ObjectVal),
+		loading: make(map[string]bool),
+	}
+}
+
+// Evaluating Imports //
+func evalImportStmt(stmt f.ImportStmt, env *Environment) (RuntimeVal, error) {
+	if !env.session.importAllowed() {
+		errorMessage := fmt.Sprintf("Can't import %s, importing isn't allowed here", stmt.Path)
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+
+	path, err := resolveModule(stmt.Path, env.File(), env.session.modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	module, err := env.session.loadModule(path, stmt.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.DeclareVar(stmt.Name, module, true)
+}
+
+// moduleFolder is the folder a project keeps shared modules in, looked for
+// next to the importing file and in every folder above it
+const moduleFolder = "a0_modules"
+
+// resolveModule finds the file an import refers to, the .a0 extension can be
+// left out. Absolute paths are used as they are and paths starting with ./ or
+// ../ are relative to the importing file (or the working directory when there
+// is none). Any other name is looked for, in order, next to the importing
+// file, in an a0_modules folder next to it or above it, and in each folder of
+// searchPath.
+func resolveModule(importPath string, importingFile string, searchPath []string) (string, error) {
+	if filepath.Ext(importPath) == "" {
+		importPath += ".a0"
+	}
+
+	baseDir := "."
+	if importingFile != "" {
+		baseDir = filepath.Dir(importingFile)
+	}
+	baseDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(importPath):
+		candidates = []string{importPath}
+	case isExplicitlyRelative(importPath):
+		candidates = []string{filepath.Join(baseDir, importPath)}
+	default:
+		candidates = append(candidates, filepath.Join(baseDir, importPath))
+		for dir := baseDir; ; dir = filepath.Dir(dir) {
+			candidates = append(candidates, filepath.Join(dir, moduleFolder, importPath))
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+		for _, dir := range searchPath {
+			if dir == "" {
+				continue
+			}
+			absDir, err := filepath.Abs(dir)
+			if err != nil {
+				return "", err
+			}
+			candidates = append(candidates, filepath.Join(absDir, importPath))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	errorMessage := fmt.Sprintf("Module not found: %s, searched: %s", importPath, strings.Join(candidates, ", "))
+	return "", &InterpretingError{Message: errorMessage}
+}
+
+func isExplicitlyRelative(path string) bool {
+	slashed := filepath.ToSlash(path)
+	return strings.HasPrefix(slashed, "./") || strings.HasPrefix(slashed, "../")
+}
+
+// loadModule runs the module at path in its own environment and returns its
+// top level variables as an object
+func (s *session) loadModule(path string, name string) (ObjectVal, error) {
+	loader := s.modules
+
+	if module, cached := loader.cache[path]; cached {
+		return module, nil
+	}
+
+	if loader.loading[path] {
+		errorMessage := fmt.Sprintf("Import cycle detected while loading %s", path)
+		return ObjectVal{}, &InterpretingError{Message: errorMessage}
+	}
+	loader.loading[path] = true
+	defer delete(loader.loading, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ObjectVal{}, err
+	}
+	defer file.Close()
+
+	lexer := f.NewLexer(file)
+	if s.strict {
+		lexer.UseStrictKeywords()
+	}
+	tokens, err := lexer.Lex()
+	if err != nil {
+		return ObjectVal{}, err
+	}
+
+	program, err := f.NewParser(tokens).ProduceAst()
+	if err != nil {
+		return ObjectVal{}, err
+	}
+
+	// modules get their own globals so they can't see or clobber the
+	// importer's variables, but they share the session and its module cache
+	globals := NewEnvironment(nil)
+	s.restrict(globals)
+	moduleEnv := NewEnvironment(globals)
+	moduleEnv.session = s
+	moduleEnv.SetFile(path)
+
+	_, err = Evaluate(program, moduleEnv)
+	if err != nil {
+		return ObjectVal{}, err
+	}
+
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: name}
+	for varName, value := range moduleEnv.variables {
+		module.Properties[varName] = value
+	}
+
+	// the module's own code keeps working with its variables, changing the
+	// object from outside would only confuse things
+	s.freeze(module)
+	loader.cache[path] = module
+	return module, nil
+}
@@ -0,0 +1,322 @@
+package runtime_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// writeTree writes files, named by slash separated paths, under a temp dir
+// and returns the dir
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// runFile runs the file at name under root and returns what it printed
+func runFile(t *testing.T, root, name string, setup func(env *r.Environment)) (string, error) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	env := r.NewEnvironment(nil)
+	env.SetOutput(&out, io.Discard)
+	env.SetFile(path)
+	if setup != nil {
+		setup(env)
+	}
+	_, err = testutil.EvalIn(string(src), env)
+	return out.String(), err
+}
+
+func TestImportRunsOnce(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.a0": `import "lib"
+import "./lib.a0" as again
+import "user"
+println(lib.double(21), again.count, user.seen)
+`,
+		"lib.a0": `println("loading lib")
+var count = 1
+fun double(x) {
+    return x * 2
+}
+`,
+		"user.a0": `import "lib"
+val seen = lib.count + 1
+`,
+	})
+
+	out, err := runFile(t, root, "main.a0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "loading lib\n42 1 2\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestImportedModulesAreIsolated(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.a0": `var secret = "main"
+import "peek"
+println(peek.seen, secret)
+peek.seen = "changed"
+`,
+		"peek.a0": `var secret = "module"
+val seen = secret
+`,
+	})
+
+	out, err := runFile(t, root, "main.a0", nil)
+	if err == nil || !strings.Contains(err.Error(), "Cannot assign to property seen of frozen object") {
+		t.Errorf("assigning to a module's variable gave %v, want a frozen object error", err)
+	}
+	if want := "module main\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		contains []string
+	}{
+		{
+			"missing module",
+			map[string]string{"main.a0": `import "nowhere"`},
+			[]string{"Module not found: nowhere.a0"},
+		},
+		{
+			"error in the module",
+			map[string]string{"main.a0": `import "bad"`, "bad.a0": `throw error("bad module")`},
+			[]string{"bad module"},
+		},
+		{
+			"syntax error in the module",
+			map[string]string{"main.a0": `import "broken"`, "broken.a0": `var = 1`},
+			[]string{"Expected identifier name"},
+		},
+		{
+			"module imports itself",
+			map[string]string{"main.a0": `import "self"`, "self.a0": `import "self"`},
+			[]string{"Import cycle detected while loading", "self.a0"},
+		},
+		{
+			"modules import each other",
+			map[string]string{"main.a0": `import "a"`, "a.a0": `import "b"`, "b.a0": `import "a"`},
+			[]string{"Import cycle detected while loading", "a.a0"},
+		},
+		{
+			"longer cycle",
+			map[string]string{"main.a0": `import "a"`, "a.a0": `import "b"`, "b.a0": `import "c"`, "c.a0": `import "./a"`},
+			[]string{"Import cycle detected while loading", "a.a0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			_, err := runFile(t, root, "main.a0", nil)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportCycleCanBeCaught(t *testing.T) {
+	// a failed import isn't cached or left marked as loading, so importing it
+	// again runs into the same cycle
+	root := writeTree(t, map[string]string{
+		"main.a0": `try {
+    import "a"
+} catch (e) {
+    println("caught", e.message.contains("Import cycle"))
+}
+try {
+    import "a"
+} catch (e) {
+    println("caught again", e.message.contains("Import cycle"))
+}
+`,
+		"a.a0": `import "b"`,
+		"b.a0": `import "a"`,
+	})
+
+	out, err := runFile(t, root, "main.a0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "caught true\ncaught again true\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestImportResolutionOrder(t *testing.T) {
+	main := "import \"util\"\nprintln(util.from)\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"next to the file first", map[string]string{
+			"app/main.a0":            main,
+			"app/util.a0":            `val from = "next to"`,
+			"app/a0_modules/util.a0": `val from = "a0_modules"`,
+			"path/util.a0":           `val from = "search path"`,
+		}, "next to"},
+		{"then a0_modules", map[string]string{
+			"app/main.a0":            main,
+			"app/a0_modules/util.a0": `val from = "a0_modules"`,
+			"a0_modules/util.a0":     `val from = "parent a0_modules"`,
+			"path/util.a0":           `val from = "search path"`,
+		}, "a0_modules"},
+		{"then a0_modules above", map[string]string{
+			"app/main.a0":        main,
+			"a0_modules/util.a0": `val from = "parent a0_modules"`,
+			"path/util.a0":       `val from = "search path"`,
+		}, "parent a0_modules"},
+		{"then the search path", map[string]string{
+			"app/main.a0":  main,
+			"path/util.a0": `val from = "search path"`,
+		}, "search path"},
+		{"folders named like the module are skipped", map[string]string{
+			"app/main.a0":            main,
+			"app/util.a0/keep":       "",
+			"app/a0_modules/util.a0": `val from = "a0_modules"`,
+		}, "a0_modules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			t.Setenv("A0_PATH", filepath.Join(root, "path"))
+			out, err := runFile(t, root, "app/main.a0", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want+"\n" {
+				t.Errorf("got %q, want %q", out, tt.want+"\n")
+			}
+		})
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app/main.a0":    "import \"util\"\nprintln(util.from)\n",
+		"first/util.a0":  `val from = "first"`,
+		"second/util.a0": `val from = "second"`,
+		"lib/util.a0":    `val from = "lib"`,
+	})
+	sep := string(filepath.ListSeparator)
+
+	// A0_PATH is read when the environment is made, empty entries skipped
+	t.Setenv("A0_PATH", sep+filepath.Join(root, "missing")+sep+filepath.Join(root, "second")+sep+filepath.Join(root, "first"))
+	out, err := runFile(t, root, "app/main.a0", nil)
+	if err != nil || out != "second\n" {
+		t.Errorf("with A0_PATH got %q, %v, want %q", out, err, "second\n")
+	}
+
+	// SetModulePath replaces it
+	out, err = runFile(t, root, "app/main.a0", func(env *r.Environment) {
+		env.SetModulePath([]string{filepath.Join(root, "first")})
+	})
+	if err != nil || out != "first\n" {
+		t.Errorf("with SetModulePath got %q, %v, want %q", out, err, "first\n")
+	}
+
+	// relative entries are relative to the working directory
+	t.Chdir(root)
+	t.Setenv("A0_PATH", "lib")
+	out, err = runFile(t, root, "app/main.a0", nil)
+	if err != nil || out != "lib\n" {
+		t.Errorf("with a relative A0_PATH got %q, %v, want %q", out, err, "lib\n")
+	}
+}
+
+func TestImportExplicitPaths(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app/main.a0":            "import \"./util\"\nprintln(util.from)\n",
+		"app/up.a0":              "import \"../shared/util\"\nprintln(util.from)\n",
+		"app/nested/deep.a0":     "import \"../util.a0\"\nprintln(util.from)\n",
+		"app/a0_modules/util.a0": `val from = "a0_modules"`,
+		"shared/util.a0":         `val from = "shared"`,
+		"lib/util.a0":            `val from = "lib"`,
+	})
+	t.Setenv("A0_PATH", filepath.Join(root, "lib"))
+
+	// ./ only looks next to the importing file, never in a0_modules or A0_PATH
+	_, err := runFile(t, root, "app/main.a0", nil)
+	if err == nil || !strings.Contains(err.Error(), "Module not found: ./util.a0") {
+		t.Errorf("got %v, want a module not found error", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "a0_modules") {
+		t.Errorf("%q searched a0_modules for a ./ path", err)
+	}
+
+	out, err := runFile(t, root, "app/up.a0", nil)
+	if err != nil || out != "shared\n" {
+		t.Errorf("../ got %q, %v, want %q", out, err, "shared\n")
+	}
+	if err := os.WriteFile(filepath.Join(root, "app", "util.a0"), []byte(`val from = "app"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runFile(t, root, "app/nested/deep.a0", nil)
+	if err != nil || out != "app\n" {
+		t.Errorf("../ from a nested file got %q, %v, want %q", out, err, "app\n")
+	}
+
+	absolute := writeTree(t, map[string]string{
+		"main.a0": "import " + strconv.Quote(filepath.Join(root, "shared", "util.a0")) + "\nprintln(util.from)\n",
+	})
+	out, err = runFile(t, absolute, "main.a0", nil)
+	if err != nil || out != "shared\n" {
+		t.Errorf("an absolute path got %q, %v, want %q", out, err, "shared\n")
+	}
+}
+
+func TestImportNotFoundListsSearched(t *testing.T) {
+	root := writeTree(t, map[string]string{"app/main.a0": `import "util"`})
+	t.Setenv("A0_PATH", filepath.Join(root, "lib"))
+
+	_, err := runFile(t, root, "app/main.a0", nil)
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, searched := range []string{
+		filepath.Join(root, "app", "util.a0"),
+		filepath.Join(root, "app", "a0_modules", "util.a0"),
+		filepath.Join(root, "a0_modules", "util.a0"),
+		filepath.Join(root, "lib", "util.a0"),
+	} {
+		if !strings.Contains(err.Error(), searched) {
+			t.Errorf("%q doesn't list %s", err, searched)
+		}
+	}
+}
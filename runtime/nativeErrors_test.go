@@ -0,0 +1,45 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestNativeGoErrorsArePositioned(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	env.SetOutput(failingWriter{}, failingWriter{})
+
+	_, err := testutil.EvalIn("val x = 1\nprintln(x)", env)
+	var interpErr *r.InterpretingError
+	if !errors.As(err, &interpErr) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	if interpErr.Message != "println: disk full" {
+		t.Errorf("got message %q, want println: disk full", interpErr.Message)
+	}
+	if interpErr.Pos.Line() != 2 {
+		t.Errorf("error on line %d, want 2 where println was called", interpErr.Pos.Line())
+	}
+}
+
+func TestNativeErrorsCanBeCaught(t *testing.T) {
+	got := testutil.MustEval(t, `
+var message = ""
+try {
+    fs.readFile("/there/is/no/such/file")
+} catch (e) {
+    message = e.message
+}
+message != ""
+`)
+	testutil.AssertValue(t, got, r.BoolVal{Value: true})
+}
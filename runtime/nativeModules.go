@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+////////////////////
+// Native Modules //
+////////////////////
+
+// Module is a native module a host or a package adds to a0: its functions,
+// taking and returning Go values the way RegisterFunc's do, and any other
+// values it holds, converted like ToValue does, by name. Each global scope
+// converts the values for itself, so a program changing a list it got from a
+// module doesn't change it for the others. RuntimeVal members are used as
+// they are.
+//
+//	runtime.RegisterModule("redis", runtime.Module{
+//		"get": client.Get,
+//		"set": client.Set,
+//		"defaultPort": 6379,
+//	})
+type Module map[string]any
+
+// registeredModules are the modules RegisterModule added, every global scope
+// made after that gets them
+var registeredModules = struct {
+	sync.Mutex
+	modules map[string]Module
+}{modules: make(map[string]Module)}
+
+// RegisterModule adds module to every global scope made from now on, next to
+// math and the other builtin modules, so packages can ship modules for a0,
+// a database client say, that any program embedding a0 gets by importing
+// the package. It's meant to be called from an init function. Registering a
+// name a builtin or another module already has fails.
+func RegisterModule(name string, module Module) error {
+	// built once here only to report what can't be converted
+	if _, err := buildModule(name, module); err != nil {
+		return err
+	}
+	taken := NewEnvironment(nil).variables
+
+	registeredModules.Lock()
+	defer registeredModules.Unlock()
+	if _, exists := taken[name]; exists {
+		return fmt.Errorf("RegisterModule %s: the name is taken", name)
+	}
+	if _, exists := registeredModules.modules[name]; exists {
+		return fmt.Errorf("RegisterModule %s: the name is taken", name)
+	}
+	registeredModules.modules[name] = maps.Clone(module)
+	return nil
+}
+
+// setupRegisteredModules declares the modules RegisterModule added in env
+func setupRegisteredModules(env *Environment) {
+	registeredModules.Lock()
+	defer registeredModules.Unlock()
+	for _, name := range slices.Sorted(maps.Keys(registeredModules.modules)) {
+		// RegisterModule checked it builds, a value it holds can only fail
+		// now if the host changed it after, the module is left out then
+		module, err := buildModule(name, registeredModules.modules[name])
+		if err != nil {
+			continue
+		}
+		env.session.freeze(module)
+		env.DeclareVar(name, module, true)
+	}
+}
+
+// RegisterModule declares module as a global constant name of in, frozen
+// like the builtin modules. Unlike modules from runtime.RegisterModule, the
+// files the program imports don't see it.
+func (in *Interp) RegisterModule(name string, module Module) error {
+	return declareModule(in.env, name, module)
+}
+
+// RegisterModule adds a module to g the way Interp.RegisterModule does
+func (g *Globals) RegisterModule(name string, module Module) error {
+	if g.sealed.Load() {
+		return fmt.Errorf("RegisterModule %s: Globals can't change once Interps have started from them", name)
+	}
+	return declareModule(g.env, name, module)
+}
+
+func declareModule(env *Environment, name string, module Module) error {
+	obj, err := buildModule(name, module)
+	if err != nil {
+		return err
+	}
+	env.session.freeze(obj)
+	_, err = env.DeclareVar(name, obj, true)
+	return err
+}
+
+// buildModule makes the object programs see module as, its functions named
+// like name.function in stack traces
+func buildModule(name string, module Module) (ObjectVal, error) {
+	if name == "" {
+		return ObjectVal{}, fmt.Errorf("RegisterModule: the module needs a name")
+	}
+	obj := ObjectVal{Properties: make(map[string]RuntimeVal, len(module)), ObjectName: name}
+	for member, value := range module {
+		var converted RuntimeVal
+		var err error
+		switch v := value.(type) {
+		case RuntimeVal:
+			converted = v
+		default:
+			if isFunc(value) {
+				converted, err = wrapFunc(name+"."+member, value)
+			} else {
+				converted, err = ToValue(value)
+			}
+		}
+		if err != nil {
+			return ObjectVal{}, fmt.Errorf("RegisterModule %s: %s: %w", name, member, err)
+		}
+		obj.Properties[member] = converted
+	}
+	return obj, nil
+}
@@ -0,0 +1,41 @@
+package runtime_test
+
+import (
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestRegisteredModuleValuesPerScope(t *testing.T) {
+	err := r.RegisterModule("shelf", r.Module{
+		"items":  []int{1, 2},
+		"double": func(n float64) float64 { return n * 2 },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := r.NewEnvironment(nil)
+	got, err := testutil.EvalIn("shelf.items.push(3)\nlen(shelf.items)", first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: 3})
+
+	// another program's list is its own
+	second := r.NewEnvironment(nil)
+	got, err = testutil.EvalIn("len(shelf.items)", second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: 2})
+
+	testutil.AssertValue(t, testutil.MustEval(t, "shelf.double(21)"), r.NumberVal{Value: 42})
+}
+
+func TestRegisterModuleNameTaken(t *testing.T) {
+	if err := r.RegisterModule("math", r.Module{"pi": 3}); err == nil {
+		t.Error("registering a module as math didn't fail")
+	}
+}
@@ -0,0 +1,116 @@
+package runtime_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestNativeArgumentErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		typeErr  bool // a TypeError rather than an InterpretingError
+		contains string
+	}{
+		{"range()", false, "range expects 1 to 3 arguments but got 0"},
+		{`range("a")`, true, ""},
+		{"ord()", false, "ord expects 1 argument"},
+		{"ord(1)", true, ""},
+		{`ord("ab")`, false, "single character"},
+		{"chr(-1)", false, "code point"},
+		{"chr(1.5)", false, "code point"},
+		{"chr(true)", true, ""},
+		{"error()", false, "error expects 1 or 2 arguments"},
+		{`"a,b".split()`, false, "string.split expects (separator)"},
+		{`"a,b".split(1)`, true, ""},
+		{`"abc".upper(1)`, false, "string.upper expects no arguments"},
+		{`"abc".replace("a")`, false, "string.replace expects (old, new)"},
+		{`",".join("abc")`, true, ""},
+		{`"x".repeat(-1)`, false, "whole number"},
+		{`"x".repeat(math.sqrt(-1))`, false, "whole number"},
+		{`"x".repeat("3")`, true, ""},
+		{`"x".padLeft(5, "ab")`, false, "single character"},
+		{"math.sqrt()", false, "math.sqrt expects 1 argument"},
+		{`math.sqrt("4")`, true, ""},
+		{"math.pow(2)", false, "math.pow expects 2 arguments"},
+		{"math.min()", false, "at least 1 number"},
+		{`math.max(1, "2")`, true, ""},
+		{"random.random(1)", false, "random.random expects 0 arguments"},
+		{"random.randint(5, 1)", false, "no whole number"},
+		{"random.randint(0, 1e300)", false, "expects bounds from"},
+		{"random.randint(-1e300, 0)", false, "expects bounds from"},
+		{"random.randint(math.sqrt(-1), 1)", false, "expects bounds from"},
+		{"random.randint(0, math.pow(10, 400))", false, "expects bounds from"},
+		{`random.choice("abc")`, true, ""},
+		{"random.shuffle()", false, "random.shuffle expects 1 argument"},
+		{`random.seed("x")`, true, ""},
+		{"randomHex()", false, "whole number of digits"},
+		{"randomHex(-1)", false, "whole number of digits"},
+		{"randomHex(math.sqrt(-1))", false, "whole number of digits"},
+		{"randomHex(1e12)", false, "at most 268435456 digits"},
+		{"randomHex(1e300)", false, "at most 268435456 digits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var typeErr *r.TypeError
+			var interpErr *r.InterpretingError
+			switch {
+			case err == nil:
+				t.Fatal("got no error")
+			case tt.typeErr && !errors.As(err, &typeErr):
+				t.Fatalf("got %v, want a type error", err)
+			case !tt.typeErr && !errors.As(err, &interpErr):
+				t.Fatalf("got %v, want a runtime error", err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestNativesStillWork(t *testing.T) {
+	tests := []struct {
+		src  string
+		want r.RuntimeVal
+	}{
+		{"ord('a')", r.NumberVal{Value: 97}},
+		{`ord("a")`, r.NumberVal{Value: 97}},
+		{"chr(97)", r.CharVal{Value: 'a'}},
+		{`chr("a")`, r.CharVal{Value: 'a'}},
+		{`"x".repeat(3)`, r.StringVal{Value: "xxx"}},
+		{`"7".padLeft(3, "0")`, r.StringVal{Value: "007"}},
+		{"math.min([3, 1, 2])", r.NumberVal{Value: 1}},
+		{"random.choice([])", r.NadaVal{}},
+		{"randomHex(7).length()", r.NumberVal{Value: 7}},
+		{"random.randint(9007199254740992, 9007199254740992)", r.NumberVal{Value: 1 << 53}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), tt.want)
+		})
+	}
+}
+
+func TestStringLengthCap(t *testing.T) {
+	for _, src := range []string{
+		`"x".repeat(1e12)`,
+		`"ab".repeat(2e8)`,
+		`"x".padLeft(1e12)`,
+		`"x".padRight(1e12, "-")`,
+		`"ab".repeat(1e300)`,
+		`"ab".repeat(math.pow(10, 400))`,
+		`"x".padLeft(1e300)`,
+	} {
+		t.Run(src, func(t *testing.T) {
+			_, err := testutil.Eval(src)
+			if err == nil || !strings.Contains(err.Error(), "would make a string longer than") {
+				t.Errorf("got %v, want the string to be too long", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+////////////////////
+// Object Natives //
+////////////////////
+
+// setupObjectNatives declares keys, values, has, remove, merge, clone, freeze
+// and isFrozen
+func setupObjectNatives(env *Environment) {
+	// keys(obj) lists the property names in sorted order
+	env.DeclareVar("keys", NativeFunctionValue{
+		Name: "keys",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("keys", args, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := sortedKeys(obj)
+			elements := make([]RuntimeVal, len(keys))
+			for i, key := range keys {
+				elements[i] = StringVal{Value: key}
+			}
+			return &ListVal{Elements: elements}, nil
+		},
+	}, true)
+
+	// values(obj) lists the property values in the same order as keys(obj)
+	env.DeclareVar("values", NativeFunctionValue{
+		Name: "values",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("values", args, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := sortedKeys(obj)
+			elements := make([]RuntimeVal, len(keys))
+			for i, key := range keys {
+				elements[i] = obj.Properties[key]
+			}
+			return &ListVal{Elements: elements}, nil
+		},
+	}, true)
+
+	// has(obj, key) reports whether obj has its own property key
+	env.DeclareVar("has", NativeFunctionValue{
+		Name: "has",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("has", args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			_, exists := obj.Properties[args[1].String()]
+			return BoolVal{Value: exists}, nil
+		},
+	}, true)
+
+	// remove(obj, key) deletes key from obj and returns its value, nada if
+	// it wasn't there
+	env.DeclareVar("remove", NativeFunctionValue{
+		Name: "remove",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("remove", args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			key := args[1].String()
+			if env.session.isFrozen(obj) {
+				errorMessage := fmt.Sprintf("Cannot remove property %s of frozen object", key)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			removed, exists := obj.Properties[key]
+			if !exists {
+				return NadaVal{}, nil
+			}
+			delete(obj.Properties, key)
+			return removed, nil
+		},
+	}, true)
+
+	// merge(a, b) is a new object with the properties of both, b wins ties
+	env.DeclareVar("merge", NativeFunctionValue{
+		Name: "merge",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			first, err := objectArg("merge", args, 2)
+			if err != nil {
+				return nil, err
+			}
+			second, ok := args[1].(ObjectVal)
+			if !ok {
+				errorMessage := fmt.Sprintf("merge expects two objects but got %v", args[1])
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			merged := cloneObject(first)
+			for key, value := range second.Properties {
+				merged.Properties[key] = value
+			}
+			return merged, nil
+		},
+	}, true)
+
+	// clone(obj) is a shallow copy, nested objects and lists are shared
+	env.DeclareVar("clone", NativeFunctionValue{
+		Name: "clone",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("clone", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return cloneObject(obj), nil
+		},
+	}, true)
+
+	// freeze(obj) stops obj from changing, through any reference to it, and
+	// returns it
+	env.DeclareVar("freeze", NativeFunctionValue{
+		Name: "freeze",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("freeze", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			env.session.freeze(obj)
+			return obj, nil
+		},
+	}, true)
+
+	// isFrozen(obj) reports whether obj was frozen
+	env.DeclareVar("isFrozen", NativeFunctionValue{
+		Name: "isFrozen",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			obj, err := objectArg("isFrozen", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return BoolVal{Value: env.session.isFrozen(obj)}, nil
+		},
+	}, true)
+}
+
+// freeze marks obj as frozen. Every copy of an object shares its property
+// map, so it's the map that gets tracked, and keeping hold of it here means
+// its address can't be reused by a new object later.
+func (s *session) freeze(obj ObjectVal) {
+	if obj.Properties != nil {
+		s.frozen[reflect.ValueOf(obj.Properties).Pointer()] = obj.Properties
+	}
+}
+
+func (s *session) isFrozen(obj ObjectVal) bool {
+	if obj.Properties == nil {
+		return false
+	}
+	_, frozen := s.frozen[reflect.ValueOf(obj.Properties).Pointer()]
+	return frozen
+}
+
+// objectArg checks a native got want arguments and that the first one is an
+// object
+func objectArg(name string, args []RuntimeVal, want int) (ObjectVal, error) {
+	if len(args) != want {
+		errorMessage := fmt.Sprintf("%s expects %d argument(s) but got %d", name, want, len(args))
+		return ObjectVal{}, &InterpretingError{Message: errorMessage}
+	}
+
+	obj, ok := args[0].(ObjectVal)
+	if !ok {
+		errorMessage := fmt.Sprintf("%s expects an object but got %v", name, args[0])
+		return ObjectVal{}, &InterpretingError{Message: errorMessage}
+	}
+	return obj, nil
+}
+
+func sortedKeys(obj ObjectVal) []string {
+	keys := make([]string, 0, len(obj.Properties))
+	for key := range obj.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cloneObject copies the property map so the copy can change independently,
+// class instances stay instances of the same class
+func cloneObject(obj ObjectVal) ObjectVal {
+	copied := obj
+	copied.Properties = make(map[string]RuntimeVal, len(obj.Properties))
+	for key, value := range obj.Properties {
+		copied.Properties[key] = value
+	}
+	return copied
+}
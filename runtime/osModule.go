@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+///////////////
+// OS Module //
+///////////////
+
+// osModule builds the global os object for talking to the process the
+// program runs in
+func osModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "os"}
+
+	// getenv(name) is the variable's value, nada when it isn't set
+	module.Properties["getenv"] = NativeFunctionValue{
+		Name: "os.getenv",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			name, ok := stringArg(args, 0)
+			if !ok || len(args) != 1 {
+				return nil, &InterpretingError{Message: "os.getenv expects a variable name"}
+			}
+
+			value, exists := os.LookupEnv(name)
+			if !exists {
+				return NadaVal{}, nil
+			}
+			return StringVal{Value: value}, nil
+		},
+	}
+
+	module.Properties["setenv"] = NativeFunctionValue{
+		Name: "os.setenv",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			name, okName := stringArg(args, 0)
+			if !okName || len(args) != 2 {
+				return nil, &InterpretingError{Message: "os.setenv expects (name, value)"}
+			}
+
+			if err := os.Setenv(name, args[1].String()); err != nil {
+				errorMessage := fmt.Sprintf("os.setenv failed: %v", err)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return NadaVal{}, nil
+		},
+	}
+
+	// args() lists the command line arguments given after the script
+	module.Properties["args"] = NativeFunctionValue{
+		Name: "os.args",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			elements := make([]RuntimeVal, len(env.session.args))
+			for i, arg := range env.session.args {
+				elements[i] = StringVal{Value: arg}
+			}
+			return &ListVal{Elements: elements}, nil
+		},
+	}
+
+	module.Properties["cwd"] = NativeFunctionValue{
+		Name: "os.cwd",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			dir, err := os.Getwd()
+			if err != nil {
+				errorMessage := fmt.Sprintf("os.cwd failed: %v", err)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			return StringVal{Value: dir}, nil
+		},
+	}
+
+	module.Properties["exit"] = exitFunction("os.exit")
+
+	return module
+}
+
+// the largest exit code every system reports as it was given
+const maxExitCode = 255
+
+// exitFunction is os.exit, and the global exit under its own name.
+// exit(code) stops the program, exit() is exit(0).
+func exitFunction(name string) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, ok := numberArgs(args)
+			if !ok || len(nums) > 1 {
+				return nil, &InterpretingError{Message: name + " expects an optional whole number code"}
+			}
+
+			code := 0
+			if len(nums) == 1 {
+				if !(nums[0] >= 0 && nums[0] <= maxExitCode) || nums[0] != math.Trunc(nums[0]) {
+					errorMessage := fmt.Sprintf("%s expects a whole number code from 0 to %d but got %v", name, maxExitCode, nums[0])
+					return nil, &InterpretingError{Message: errorMessage}
+				}
+				code = int(nums[0])
+			}
+			return nil, &ExitError{Code: code}
+		},
+	}
+}
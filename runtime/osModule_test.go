@@ -0,0 +1,115 @@
+package runtime_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestEnvVariables(t *testing.T) {
+	t.Setenv("A0_TEST_SET", "from go")
+	t.Setenv("A0_TEST_NEW", "")
+	os.Unsetenv("A0_TEST_NEW")
+
+	testutil.AssertValue(t, testutil.MustEval(t, `os.getenv("A0_TEST_SET")`), r.StringVal{Value: "from go"})
+	testutil.AssertValue(t, testutil.MustEval(t, `os.getenv("A0_TEST_NEW")`), r.NadaVal{})
+
+	testutil.AssertValue(t, testutil.MustEval(t, `os.setenv("A0_TEST_NEW", 42)`), r.NadaVal{})
+	if got := os.Getenv("A0_TEST_NEW"); got != "42" {
+		t.Errorf("after os.setenv the process sees %q, want %q", got, "42")
+	}
+	testutil.AssertValue(t, testutil.MustEval(t, `os.getenv("A0_TEST_NEW")`), r.StringVal{Value: "42"})
+}
+
+func TestArgs(t *testing.T) {
+	testutil.AssertValue(t, testutil.MustEval(t, "os.args()"), &r.ListVal{})
+
+	env := r.NewEnvironment(nil)
+	env.SetArgs([]string{"-v", "two words"})
+	got, err := testutil.EvalIn("os.args()", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, &r.ListVal{Elements: []r.RuntimeVal{
+		r.StringVal{Value: "-v"}, r.StringVal{Value: "two words"},
+	}})
+}
+
+func TestCwd(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	want, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, testutil.MustEval(t, "os.cwd()"), r.StringVal{Value: want})
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		src  string
+		code int
+	}{
+		{"exit()", 0},
+		{"os.exit(0)", 0},
+		{"exit(3)", 3},
+		{"os.exit(255)", 255},
+		{"try { exit(2) } catch (e) { exit(9) }", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var exit *r.ExitError
+			if !errors.As(err, &exit) {
+				t.Fatalf("got %v, want an exit", err)
+			}
+			if got := r.ExitStatus(err); got != tt.code {
+				t.Errorf("ExitStatus = %d, want %d", got, tt.code)
+			}
+		})
+	}
+}
+
+func TestOSArgumentErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		contains string
+	}{
+		{"os.getenv()", "os.getenv expects a variable name"},
+		{"os.getenv(1)", "os.getenv expects a variable name"},
+		{`os.getenv("A", "B")`, "os.getenv expects a variable name"},
+		{`os.setenv("A0_TEST_BAD")`, "os.setenv expects (name, value)"},
+		{`os.setenv(1, "x")`, "os.setenv expects (name, value)"},
+		{`os.setenv("", "x")`, "os.setenv failed"},
+		{`exit("1")`, "exit expects an optional whole number code"},
+		{"exit(1, 2)", "exit expects an optional whole number code"},
+		{"os.exit(1.5)", "os.exit expects a whole number code from 0 to 255 but got 1.5"},
+		{"os.exit(-1)", "but got -1"},
+		{"exit(256)", "but got 256"},
+		{"exit(1e300)", "but got 1e+300"},
+		{"exit(math.pow(10, 400))", "but got +Inf"},
+		{"exit(math.sqrt(-1))", "but got NaN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := testutil.Eval(tt.src)
+			var interpErr *r.InterpretingError
+			if !errors.As(err, &interpErr) {
+				t.Fatalf("got %v, want a runtime error", err)
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("got %q, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+
+	// a bad code is an error like any other, so it can be caught
+	testutil.AssertOutput(t, `try { exit(-1) } catch (e) { println("caught") }`, "caught\n")
+	if got := r.ExitStatus(errors.New("boom")); got != 1 {
+		t.Errorf("ExitStatus of an error = %d, want 1", got)
+	}
+}
@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+////////////
+// Policy //
+////////////
+
+// Policy decides which natives a program gets, for playgrounds and hosts that
+// run code they don't trust. Entries name a global like "print", a native
+// module like "fs", one function of a module like "os.getenv", "import" for
+// importing files, or one of the groups in PolicyGroups like "@filesystem".
+type Policy struct {
+	// Allow lists what programs get, empty gives them everything Deny doesn't
+	// take away
+	Allow []string
+	// Deny lists what programs don't get, even when Allow has it
+	Deny []string
+}
+
+// PolicyGroups are the names a policy can use to cover a kind of access at
+// once. a0 has no natives for running commands or using the network yet,
+// denying @exec and @network anyway keeps a policy safe as they're added.
+var PolicyGroups = map[string][]string{
+	"@filesystem": {"fs", "os.cwd", "import"},
+	"@env":        {"os.getenv", "os.setenv", "os.args"},
+	"@exec":       {},
+	"@network":    {},
+}
+
+// Check reports groups p uses that don't exist, which SetPolicy fails with
+func (p Policy) Check() error {
+	for _, entry := range append(p.Allow, p.Deny...) {
+		if _, ok := PolicyGroups[entry]; strings.HasPrefix(entry, "@") && !ok {
+			return fmt.Errorf("unknown policy group %s", entry)
+		}
+	}
+	return nil
+}
+
+// allows reports whether p lets programs have name, a global or a module
+// function written as module.function
+func (p Policy) allows(name string) bool {
+	return (len(p.Allow) == 0 || policyCovers(p.Allow, name)) && !policyCovers(p.Deny, name)
+}
+
+func policyCovers(entries []string, name string) bool {
+	for _, entry := range entries {
+		if group, ok := PolicyGroups[entry]; ok {
+			if policyCovers(group, name) {
+				return true
+			}
+			continue
+		}
+		if entry == name || strings.HasPrefix(name, entry+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPolicy takes away the natives policy doesn't allow from the global
+// scope env, and from the global scope of every module the program imports.
+// It covers everything the global scope holds when it's called, host
+// functions too, so hosts that want theirs left alone register them after.
+// Calling it again takes away more, what's gone doesn't come back.
+func (env *Environment) SetPolicy(policy Policy) error {
+	if err := policy.Check(); err != nil {
+		return err
+	}
+	env.session.policies = append(env.session.policies, policy)
+	env.session.restrict(env.globalScope())
+	return nil
+}
+
+// restrict applies every policy set in s to the global scope env. An
+// imported module's global scope has a session of its own, the modules there
+// are frozen in that one.
+func (s *session) restrict(env *Environment) {
+	for _, policy := range s.policies {
+		for name, value := range env.variables {
+			switch v := value.(type) {
+			case NativeFunctionValue:
+				if !policy.allows(name) {
+					env.remove(name)
+				}
+			case ObjectVal:
+				// only the native modules are frozen in the global scope
+				if !env.session.isFrozen(v) {
+					continue
+				}
+				kept := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: v.ObjectName}
+				for prop, fn := range v.Properties {
+					if policy.allows(name + "." + prop) {
+						kept.Properties[prop] = fn
+					}
+				}
+				switch len(kept.Properties) {
+				case 0:
+					env.remove(name)
+				case len(v.Properties):
+				default:
+					// the module is shared with other programs, so it's
+					// replaced rather than changed
+					env.session.freeze(kept)
+					s.freeze(kept)
+					env.variables[name] = kept
+				}
+			}
+		}
+	}
+}
+
+// importAllowed reports whether the policies set in s let programs import
+// files
+func (s *session) importAllowed() bool {
+	for _, policy := range s.policies {
+		if !policy.allows("import") {
+			return false
+		}
+	}
+	return true
+}
+
+func (env *Environment) remove(name string) {
+	delete(env.variables, name)
+	delete(env.constants, name)
+}
@@ -0,0 +1,79 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// has reports which of names a program running in env can see
+func has(t *testing.T, env *r.Environment, names ...string) map[string]bool {
+	t.Helper()
+	seen := make(map[string]bool)
+	for _, name := range names {
+		// a global that's gone can't be looked up, a module function that's
+		// gone is nada like any missing property
+		got, err := testutil.EvalIn(name+" != nada", env)
+		seen[name] = err == nil && got.String() == "true"
+	}
+	return seen
+}
+
+func TestPolicyDeny(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	if err := env.SetPolicy(r.Policy{Deny: []string{"@filesystem", "os.getenv"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"fs": false, "os.cwd": false, "os.getenv": false, "os.setenv": true, "print": true, "math.sqrt": true}
+	got := has(t, env, "fs", "os.cwd", "os.getenv", "os.setenv", "print", "math.sqrt")
+	for name, visible := range want {
+		if got[name] != visible {
+			t.Errorf("%s visible: %t, want %t", name, got[name], visible)
+		}
+	}
+
+	_, err := testutil.EvalIn(`import "other.a0"`, env)
+	if err == nil || !strings.Contains(err.Error(), "importing isn't allowed here") {
+		t.Errorf("got %v importing, want it refused", err)
+	}
+
+	// the native modules are shared, taking from one program's doesn't take
+	// from another's
+	other := has(t, r.NewEnvironment(nil), "os.cwd", "os.getenv")
+	if !other["os.cwd"] || !other["os.getenv"] {
+		t.Errorf("a policy on one program took %v from another", other)
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	if err := env.SetPolicy(r.Policy{Allow: []string{"print", "math"}, Deny: []string{"math.random"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"print": true, "math.sqrt": true, "math.random": false, "fs": false, "len": false}
+	got := has(t, env, "print", "math.sqrt", "math.random", "fs", "len")
+	for name, visible := range want {
+		if got[name] != visible {
+			t.Errorf("%s visible: %t, want %t", name, got[name], visible)
+		}
+	}
+}
+
+func TestPolicyUnknownGroup(t *testing.T) {
+	err := r.NewEnvironment(nil).SetPolicy(r.Policy{Deny: []string{"@disk"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown policy group @disk") {
+		t.Errorf("got %v, want the unknown group", err)
+	}
+}
+
+func TestGlobalsPolicy(t *testing.T) {
+	g := r.NewGlobals()
+	if err := g.SetPolicy(r.Policy{Deny: []string{"fs"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NewInterp().Run("fs"); err == nil {
+		t.Error("an Interp started from Globals without fs still had it")
+	}
+}
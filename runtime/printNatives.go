@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+///////////////////
+// Print Natives //
+///////////////////
+
+// setupPrintNatives declares print, println and printWith. Arguments are
+// separated by a space, print leaves the line open and println ends it.
+func setupPrintNatives(env *Environment) {
+	env.DeclareVar("print", printNative("print", " ", ""), true)
+	env.DeclareVar("println", printNative("println", " ", "\n"), true)
+
+	// printWith({sep: ", ", end: "\n"}, a, b) picks its own separator and
+	// ending, whichever is left out keeps print's default, and with
+	// {to: "stderr"} writes to standard error
+	env.DeclareVar("printWith", NativeFunctionValue{
+		Name: "printWith",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 {
+				return nil, &InterpretingError{Message: "printWith expects an options object first"}
+			}
+
+			options, ok := args[0].(ObjectVal)
+			if !ok {
+				return nil, &TypeError{Operation: "printWith", Expected: "an options object", Got: TypeOf(args[0])}
+			}
+
+			sep, err := printOption(options, "sep", " ")
+			if err != nil {
+				return nil, err
+			}
+			end, err := printOption(options, "end", "")
+			if err != nil {
+				return nil, err
+			}
+			to, err := printOption(options, "to", "stdout")
+			if err != nil {
+				return nil, err
+			}
+
+			switch to {
+			case "stdout":
+				return printTo(env.session.stdout, args[1:], sep, end)
+			case "stderr":
+				return printTo(env.session.stderr, args[1:], sep, end)
+			default:
+				errorMessage := fmt.Sprintf("printWith to expects \"stdout\" or \"stderr\" but got %q", to)
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+		},
+	}, true)
+}
+
+// printNative builds a print function with a fixed separator and ending
+func printNative(name string, sep string, end string) NativeFunctionValue {
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return printTo(env.session.stdout, args, sep, end)
+		},
+	}
+}
+
+// printTo writes args to w, with sep between them and end after them
+func printTo(w io.Writer, args []RuntimeVal, sep string, end string) (RuntimeVal, error) {
+	var builder strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			builder.WriteString(sep)
+		}
+		builder.WriteString(arg.String())
+	}
+	builder.WriteString(end)
+
+	_, err := io.WriteString(w, builder.String())
+	if err != nil {
+		return nil, err
+	}
+	return NadaVal{}, nil
+}
+
+// printOption reads a text option from a printWith options object
+func printOption(options ObjectVal, key string, fallback string) (string, error) {
+	value, exists := options.Properties[key]
+	if !exists {
+		return fallback, nil
+	}
+
+	switch v := value.(type) {
+	case StringVal:
+		return v.Value, nil
+	case CharVal:
+		return string(v.Value), nil
+	default:
+		return "", &TypeError{Operation: "printWith " + key, Expected: "a string", Got: TypeOf(value)}
+	}
+}
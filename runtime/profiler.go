@@ -0,0 +1,267 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+//////////////
+// Profiler //
+//////////////
+
+// profiler samples which a0 function and line is running. A ticker only
+// raises a flag, the sample itself is taken by the code running the program
+// the next time it reaches a node with a position, so nothing reads the call
+// stack while it changes. The ticker can fall behind on a busy machine, so
+// each sample counts for the time since the one before it rather than for
+// one interval.
+type profiler struct {
+	due       atomic.Bool
+	ticker    *time.Ticker
+	done      chan struct{}
+	interval  time.Duration
+	start     time.Time
+	last      time.Time              // when the last sample was taken
+	locations map[profileLine]uint64 // location ids, from 1
+	functions map[string]*profileFunction
+	samples   map[string]*profileSample // by the location ids joined
+}
+
+type profileLine struct {
+	function string
+	line     int
+}
+
+type profileFunction struct {
+	id   uint64
+	file string // where it was first seen running, empty if only as a caller
+}
+
+type profileSample struct {
+	locations []uint64 // innermost first
+	count     int64
+	time      time.Duration
+}
+
+// topLevelName stands for code outside any function in profiles
+const topLevelName = "(top level)"
+
+// DefaultProfileInterval is how often StartProfile samples unless told otherwise
+const DefaultProfileInterval = time.Millisecond
+
+// StartProfile starts sampling the running a0 code every interval, until
+// StopProfile writes out what was collected
+func (env *Environment) StartProfile(interval time.Duration) {
+	env.StopProfile(io.Discard)
+
+	p := &profiler{
+		ticker:    time.NewTicker(interval),
+		done:      make(chan struct{}),
+		interval:  interval,
+		start:     time.Now(),
+		last:      time.Now(),
+		locations: make(map[profileLine]uint64),
+		functions: make(map[string]*profileFunction),
+		samples:   make(map[string]*profileSample),
+	}
+	go func() {
+		for {
+			select {
+			case <-p.ticker.C:
+				p.due.Store(true)
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	env.session.profile = p
+}
+
+// StopProfile stops sampling and writes the profile to w in the gzipped
+// protobuf format go tool pprof and most flame graph tools read. It does
+// nothing when no profile was started.
+func (env *Environment) StopProfile(w io.Writer) error {
+	p := env.session.profile
+	if p == nil {
+		return nil
+	}
+	p.ticker.Stop()
+	close(p.done)
+	env.session.profile = nil
+
+	out := gzip.NewWriter(w)
+	if _, err := out.Write(p.encode(time.Since(p.start))); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
+
+// sample records the call stack as it is now, with the innermost function at
+// pos in file, when the ticker asked for one
+func (s *session) sample(pos f.Position, file string) {
+	p := s.profile
+	if !p.due.Swap(false) {
+		return
+	}
+
+	// the innermost function is at pos, every caller is at the line it made
+	// the call from
+	caller := func(i int) string {
+		if i < 0 {
+			return topLevelName
+		}
+		return s.stack[i].Function
+	}
+	locations := make([]uint64, 0, len(s.stack)+1)
+	locations = append(locations, p.location(caller(len(s.stack)-1), pos.Line(), file))
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		locations = append(locations, p.location(caller(i-1), s.stack[i].Pos.Line(), ""))
+	}
+
+	var key strings.Builder
+	for _, id := range locations {
+		key.WriteString(strconv.FormatUint(id, 10))
+		key.WriteByte(' ')
+	}
+	sample, exists := p.samples[key.String()]
+	if !exists {
+		sample = &profileSample{locations: locations}
+		p.samples[key.String()] = sample
+	}
+	now := time.Now()
+	sample.count++
+	sample.time += now.Sub(p.last)
+	p.last = now
+}
+
+func (p *profiler) location(function string, line int, file string) uint64 {
+	fn, exists := p.functions[function]
+	if !exists {
+		fn = &profileFunction{id: uint64(len(p.functions) + 1)}
+		p.functions[function] = fn
+	}
+	if fn.file == "" {
+		fn.file = file
+	}
+
+	key := profileLine{function: function, line: line}
+	id, exists := p.locations[key]
+	if !exists {
+		id = uint64(len(p.locations) + 1)
+		p.locations[key] = id
+	}
+	return id
+}
+
+// encode builds the profile message of pprof's profile.proto by hand, the
+// format is small enough that it isn't worth a protobuf dependency
+func (p *profiler) encode(duration time.Duration) []byte {
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		index, exists := strs[s]
+		if !exists {
+			index = int64(len(table))
+			strs[s] = index
+			table = append(table, s)
+		}
+		return uint64(index)
+	}
+	valueType := func(kind, unit string) *protoBuffer {
+		var msg protoBuffer
+		msg.uint64Field(1, str(kind))
+		msg.uint64Field(2, str(unit))
+		return &msg
+	}
+
+	var profile protoBuffer
+	profile.messageField(1, valueType("samples", "count"))
+	profile.messageField(1, valueType("cpu", "nanoseconds"))
+
+	for _, sample := range p.samples {
+		var msg protoBuffer
+		msg.packedField(1, sample.locations)
+		msg.packedField(2, []uint64{uint64(sample.count), uint64(sample.time.Nanoseconds())})
+		profile.messageField(2, &msg)
+	}
+
+	for key, id := range p.locations {
+		var line protoBuffer
+		line.uint64Field(1, p.functions[key.function].id)
+		line.uint64Field(2, uint64(key.line))
+
+		var msg protoBuffer
+		msg.uint64Field(1, id)
+		msg.messageField(4, &line)
+		profile.messageField(4, &msg)
+	}
+
+	for name, fn := range p.functions {
+		var msg protoBuffer
+		msg.uint64Field(1, fn.id)
+		msg.uint64Field(2, str(name))
+		msg.uint64Field(3, str(name))
+		msg.uint64Field(4, str(fn.file))
+		profile.messageField(5, &msg)
+	}
+
+	profile.uint64Field(9, uint64(p.start.UnixNano()))
+	profile.uint64Field(10, uint64(duration.Nanoseconds()))
+	profile.messageField(11, valueType("cpu", "nanoseconds"))
+	profile.uint64Field(12, uint64(p.interval.Nanoseconds()))
+
+	// the string table goes last, everything above has added to it by now
+	for _, s := range table {
+		profile.stringField(6, s)
+	}
+	return profile.Bytes()
+}
+
+// protoBuffer writes protobuf fields, just the wire types profile.proto uses
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(n uint64) {
+	b.Write(binary.AppendUvarint(nil, n))
+}
+
+// uint64Field writes a varint field, zero is left out like protobuf does
+func (b *protoBuffer) uint64Field(field int, n uint64) {
+	if n == 0 {
+		return
+	}
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(n)
+}
+
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *protoBuffer) stringField(field int, s string) {
+	b.bytesField(field, []byte(s))
+}
+
+func (b *protoBuffer) messageField(field int, msg *protoBuffer) {
+	b.bytesField(field, msg.Bytes())
+}
+
+func (b *protoBuffer) packedField(field int, values []uint64) {
+	var packed protoBuffer
+	for _, value := range values {
+		packed.varint(value)
+	}
+	b.bytesField(field, packed.Bytes())
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+)
+
+///////////////////
+// Random Module //
+///////////////////
+
+// randomModule builds the global random object. Every function draws from
+// the session of the environment it's called from, so seeding affects the
+// whole program, imported modules included.
+func randomModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "random"}
+
+	// random() is a number in [0, 1)
+	module.Properties["random"] = NativeFunctionValue{
+		Name: "random.random",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if _, err := numberParams("random.random", args, 0); err != nil {
+				return nil, err
+			}
+			return NumberVal{Value: env.session.random.Float64()}, nil
+		},
+	}
+
+	// randint(a, b) is a whole number from a to b, both included
+	module.Properties["randint"] = NativeFunctionValue{
+		Name: "random.randint",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, err := numberParams("random.randint", args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			low, high := math.Ceil(nums[0]), math.Floor(nums[1])
+			if !(math.Abs(low) <= maxRandint && math.Abs(high) <= maxRandint) {
+				errorMessage := fmt.Sprintf("random.randint expects bounds from -%d to %d but got %v and %v", maxRandint, maxRandint, nums[0], nums[1])
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			if low > high {
+				errorMessage := fmt.Sprintf("random.randint: there's no whole number from %v to %v", nums[0], nums[1])
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			offset := env.session.random.Int64N(int64(high-low) + 1)
+			return NumberVal{Value: low + float64(offset)}, nil
+		},
+	}
+
+	// choice(list) is a random element, nada for an empty list
+	module.Properties["choice"] = NativeFunctionValue{
+		Name: "random.choice",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			list, err := listParam("random.choice", args)
+			if err != nil {
+				return nil, err
+			}
+			if len(list.Elements) == 0 {
+				return NadaVal{}, nil
+			}
+			return list.Elements[env.session.random.IntN(len(list.Elements))], nil
+		},
+	}
+
+	// shuffle(list) shuffles the list in place and returns it
+	module.Properties["shuffle"] = NativeFunctionValue{
+		Name: "random.shuffle",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			list, err := listParam("random.shuffle", args)
+			if err != nil {
+				return nil, err
+			}
+			env.session.random.Shuffle(len(list.Elements), func(i, j int) {
+				list.Elements[i], list.Elements[j] = list.Elements[j], list.Elements[i]
+			})
+			return list, nil
+		},
+	}
+
+	// seed(n) makes every following draw reproducible
+	module.Properties["seed"] = NativeFunctionValue{
+		Name: "random.seed",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, err := numberParams("random.seed", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			env.SeedRandom(uint64(int64(nums[0])))
+			return NadaVal{}, nil
+		},
+	}
+
+	return module
+}
+
+// maxRandint is the furthest from 0 randint's bounds can be. Every whole
+// number up to it is a number a0 can hold, and the span between two bounds
+// inside it always fits in an int64. NaN bounds fail the check too.
+const maxRandint = 1 << 53
+
+// listParam checks a native got one argument and that it's a list
+func listParam(name string, args []RuntimeVal) (*ListVal, error) {
+	if len(args) != 1 {
+		errorMessage := fmt.Sprintf("%s expects 1 argument but got %d", name, len(args))
+		return nil, &InterpretingError{Message: errorMessage}
+	}
+	list, ok := args[0].(*ListVal)
+	if !ok {
+		return nil, &TypeError{Operation: name, Expected: "a list", Got: TypeOf(args[0])}
+	}
+	return list, nil
+}
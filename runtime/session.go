@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+/////////////
+// Session //
+/////////////
+
+// session is the state shared by every environment of one running program,
+// including the environments of the modules it imports
+type session struct {
+	ctx       context.Context // cancelling it interrupts blocking natives like sleep
+	modules   *moduleLoader
+	random    *rand.Rand
+	source    *rand.PCG                         // backs random, kept so it can be reseeded
+	args      []string                          // command line arguments for os.args
+	stack     []Frame                           // calls in progress, outermost first
+	warn      io.Writer                         // where shadowing warnings go, nil turns them off
+	maxDepth  int                               // most calls in progress at once, 0 for no limit
+	frozen    map[uintptr]map[string]RuntimeVal // property maps of frozen objects
+	strict    bool                              // imported modules are lexed with strict keywords
+	timings   map[string]*callTiming            // calls by function name, nil unless timing is on
+	profile   *profiler                         // nil unless a profile is being taken
+	steps     int                               // evaluation steps taken so far
+	maxSteps  int                               // most steps the program may take, 0 for no limit
+	stopped   atomic.Bool                       // ctx is done, read on every step so it has to be cheap
+	unwatch   func() bool                       // stops updating stopped for the current ctx
+	maxMemory uint64                            // most bytes of values the program may make, 0 for no limit
+	allocated uint64                            // bytes of values made so far, see allocate
+
+	modulePath []string // extra folders imports are searched in, from A0_PATH
+
+	running   atomic.Bool   // an Interp is running a program in the session
+	owner     atomic.Uint64 // goroutine the program runs on, see enter
+	hostCalls atomic.Int32  // host functions from RegisterFunc in progress
+	hooks     *Hooks        // set by the host with SetHooks, nil when there are none
+
+	stdout io.Writer // where print and the like write, see SetOutput
+	stderr io.Writer
+	stdin  *bufio.Reader // what input reads, made when it's first needed
+
+	policies []Policy // set by the host with SetPolicy, all of them apply
+}
+
+// DefaultMaxDepth is how deep calls can nest before a program fails with a
+// recursion error, well before the Go stack would run out
+const DefaultMaxDepth = 10000
+
+func newSession() *session {
+	source := rand.NewPCG(rand.Uint64(), rand.Uint64())
+	return &session{
+		ctx:      context.Background(),
+		modules:  newModuleLoader(),
+		random:   rand.New(source),
+		source:   source,
+		maxDepth: DefaultMaxDepth,
+		frozen:   make(map[uintptr]map[string]RuntimeVal),
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+
+		modulePath: filepath.SplitList(os.Getenv("A0_PATH")),
+	}
+}
+
+// SetContext lets the host cancel a running program or give it a deadline.
+// Once ctx is done the program stops at its next step with an error try
+// can't catch, saying where it was, and any sleep in progress wakes up.
+func (env *Environment) SetContext(ctx context.Context) {
+	s := env.session
+	if s.unwatch != nil {
+		s.unwatch()
+	}
+	s.ctx = ctx
+	s.stopped.Store(ctx.Err() != nil)
+	s.unwatch = context.AfterFunc(ctx, func() {
+		s.stopped.Store(true)
+	})
+}
+
+// SeedRandom makes the random module produce the same sequence on every run,
+// for reproducible tests and simulations
+func (env *Environment) SeedRandom(seed uint64) {
+	env.session.source.Seed(seed, seed)
+}
+
+// SetArgs sets the command line arguments the program sees through os.args
+func (env *Environment) SetArgs(args []string) {
+	env.session.args = args
+}
+
+// SetOutput sets where the program's output goes, what print writes to
+// stdout and printWith({to: "stderr"}) to stderr. nil is the process's own
+// standard output or error.
+func (env *Environment) SetOutput(stdout, stderr io.Writer) {
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	env.session.stdout, env.session.stderr = stdout, stderr
+}
+
+// SetInput sets what input() reads the program's input from, so hosts and
+// tests can give a program its lines. nil is the process's standard input.
+func (env *Environment) SetInput(r io.Reader) {
+	if r == nil {
+		r = os.Stdin
+	}
+	env.session.stdin = bufio.NewReader(r)
+}
+
+// input is what input() reads from
+func (s *session) input() *bufio.Reader {
+	if s.stdin == nil {
+		s.stdin = bufio.NewReader(os.Stdin)
+	}
+	return s.stdin
+}
+
+// CaptureOutput runs run with the program's output going into buffers
+// rather than where it was going, and gives back what it wrote to each
+func (env *Environment) CaptureOutput(run func() error) (stdout, stderr string, err error) {
+	s := env.session
+	oldOut, oldErr := s.stdout, s.stderr
+	var outBuf, errBuf strings.Builder
+	s.stdout, s.stderr = &outBuf, &errBuf
+	defer func() { s.stdout, s.stderr = oldOut, oldErr }()
+
+	err = run()
+	return outBuf.String(), errBuf.String(), err
+}
+
+// SetShadowWarnings turns on warnings, written to w, for declarations that
+// hide a global or a parameter of an enclosing function. nil turns them off.
+func (env *Environment) SetShadowWarnings(w io.Writer) {
+	env.session.warn = w
+}
+
+// SetStrictKeywords makes imported modules reject the playful keyword aliases
+// the same way f.Lexer.UseStrictKeywords does for the main file
+func (env *Environment) SetStrictKeywords(strict bool) {
+	env.session.strict = strict
+}
+
+// SetModulePath replaces the folders imports are searched in after the
+// importing file's own folder and a0_modules, which default to the
+// A0_PATH environment variable
+func (env *Environment) SetModulePath(dirs []string) {
+	env.session.modulePath = dirs
+}
+
+// SetMaxDepth limits how deeply calls may nest, a program going deeper fails
+// with a catchable error. 0 or less removes the limit.
+func (env *Environment) SetMaxDepth(depth int) {
+	env.session.maxDepth = max(depth, 0)
+}
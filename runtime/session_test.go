@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestSetOutput(t *testing.T) {
+	var stdout, stderr strings.Builder
+	env := r.NewEnvironment(nil)
+	env.SetOutput(&stdout, &stderr)
+
+	src := `print("a", 1)
+println("b")
+printWith({ sep: "-", end: "!" }, "c", "d")
+printWith({ to: "stderr" }, "oops")`
+	if _, err := testutil.EvalIn(src, env); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a 1b\nc-d!"; stdout.String() != want {
+		t.Errorf("stdout got %q, want %q", stdout.String(), want)
+	}
+	if want := "oops"; stderr.String() != want {
+		t.Errorf("stderr got %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestCaptureOutput(t *testing.T) {
+	var outer strings.Builder
+	env := r.NewEnvironment(nil)
+	env.SetOutput(&outer, &outer)
+
+	stdout, stderr, err := env.CaptureOutput(func() error {
+		_, err := testutil.EvalIn(`print("out")
+printWith({ to: "stderr" }, "err")
+throw "done"`, env)
+		return err
+	})
+	if stdout != "out" || stderr != "err" {
+		t.Errorf("captured %q and %q, want out and err", stdout, stderr)
+	}
+	var thrown *r.ThrowError
+	if !errors.As(err, &thrown) {
+		t.Errorf("got %v, want the program's throw", err)
+	}
+
+	// output goes back where it was going after the capture
+	if _, err := testutil.EvalIn(`print("after")`, env); err != nil {
+		t.Fatal(err)
+	}
+	if outer.String() != "after" {
+		t.Errorf("got %q written outside the capture, want only after", outer.String())
+	}
+}
+
+func TestSetInput(t *testing.T) {
+	var stdout strings.Builder
+	env := r.NewEnvironment(nil)
+	env.SetOutput(&stdout, nil)
+	env.SetInput(strings.NewReader("Ada\r\nsecond\nlast"))
+
+	got, err := testutil.EvalIn(`[input("Name? "), input(), input(), input()]`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &r.ListVal{Elements: []r.RuntimeVal{
+		r.StringVal{Value: "Ada"},
+		r.StringVal{Value: "second"},
+		r.StringVal{Value: "last"},
+		r.NadaVal{},
+	}}
+	testutil.AssertValue(t, got, want)
+	if stdout.String() != "Name? " {
+		t.Errorf("got %q written, want the prompt", stdout.String())
+	}
+}
+
+func TestInputErrors(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	env.SetInput(iotest.ErrReader(errors.New("gone")))
+	if _, err := testutil.EvalIn("input()", env); err == nil || !strings.Contains(err.Error(), "input failed: gone") {
+		t.Errorf("got %v, want the reader's error", err)
+	}
+
+	env.SetInput(strings.NewReader(""))
+	if _, err := testutil.EvalIn(`input("a", "b")`, env); err == nil {
+		t.Error("input with two arguments didn't fail")
+	}
+}
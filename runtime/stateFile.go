@@ -0,0 +1,768 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+/////////////////
+// State Files //
+/////////////////
+
+// A saved state is JSON. Lists, objects, classes and the scopes closures
+// keep are written once each in tables and referred to by ref, 1 + their
+// index, so values shared between variables stay shared and cycles are fine.
+// Functions are saved as their source, the AST of their body (or their
+// bytecode if they were compiled), and natives and imported modules by name,
+// they're looked up again when the state is loaded.
+
+// StateVersion is the version of the saved state format this build writes
+// and reads
+const StateVersion = 1
+
+type savedState struct {
+	Version int            `json:"version"`
+	Globals []savedVar     `json:"globals"`
+	Scopes  []savedScope   `json:"scopes,omitempty"`
+	Lists   [][]savedValue `json:"lists,omitempty"`
+	Objects []savedObject  `json:"objects,omitempty"`
+	Classes []savedClass   `json:"classes,omitempty"`
+}
+
+type savedVar struct {
+	Name      string     `json:"name"`
+	Value     savedValue `json:"value"`
+	Constant  bool       `json:"constant,omitempty"`
+	Parameter bool       `json:"parameter,omitempty"`
+}
+
+type savedValue struct {
+	Type  ValueType `json:"type"`
+	Value string    `json:"value,omitempty"` // numbers, strings, chars, bools, dates and ranges as text
+	Ref   int       `json:"ref,omitempty"`   // lists, objects and classes
+	// Native is the name of a native function or module
+	Native string `json:"native,omitempty"`
+	// Module is the path of an imported module, for the module itself or a
+	// function or class it exports, which Native then names
+	Module   string         `json:"module,omitempty"`
+	Error    *savedError    `json:"error,omitempty"`
+	Function *savedFunction `json:"function,omitempty"`
+}
+
+type savedError struct {
+	Message string     `json:"message"`
+	Value   savedValue `json:"value"`
+	Line    int        `json:"line,omitempty"`
+	Column  int        `json:"column,omitempty"`
+}
+
+type savedFunction struct {
+	Name       string          `json:"name"`
+	Parameters []string        `json:"parameters"`
+	Scope      int             `json:"scope,omitempty"` // 0 for the global scope
+	Body       json.RawMessage `json:"body,omitempty"`  // the body as a Program from f.MarshalAST
+	Code       []byte          `json:"code,omitempty"`  // the compiled body as a .a0c file
+}
+
+type savedObject struct {
+	Name       string     `json:"name,omitempty"`
+	Class      int        `json:"class,omitempty"`
+	Frozen     bool       `json:"frozen,omitempty"`
+	Properties []savedVar `json:"properties"`
+}
+
+type savedClass struct {
+	Name    string          `json:"name"`
+	Parent  int             `json:"parent,omitempty"`
+	Methods []savedFunction `json:"methods"`
+}
+
+type savedScope struct {
+	Parent    int        `json:"parent,omitempty"` // 0 for the global scope
+	File      string     `json:"file,omitempty"`
+	Variables []savedVar `json:"variables"`
+}
+
+// builtinConstants are the values every global scope starts with that aren't
+// natives
+var builtinConstants = map[string]bool{"nada": true, "true": true, "false": true}
+
+//////////////////
+// Saving State //
+//////////////////
+
+// SaveState writes what programs declared in the global scope of env to w,
+// for a session or a game to be picked up again later with LoadState. The
+// natives, builtin modules and host functions every scope starts with are
+// left out. Closures are saved along with the scopes they keep, and values
+// shared between variables are still shared once loaded. Natives are saved
+// by name, the ones that can't be found that way, like methods taken from a
+// value, make it fail.
+func (env *Environment) SaveState(w io.Writer) error {
+	saver := &stateSaver{
+		global:  env.globalScope(),
+		session: env.session,
+		lists:   make(map[*ListVal]int),
+		objects: make(map[uintptr]int),
+		classes: make(map[*ClassVal]int),
+		scopes:  make(map[*Environment]int),
+	}
+	saver.state.Version = StateVersion
+
+	globals, err := saver.variables(saver.global, func(name string, value RuntimeVal) bool {
+		switch v := value.(type) {
+		case NativeFunctionValue:
+			return v.Name != name || v.receiver != nil
+		case ObjectVal:
+			return !(saver.nativeModule(v) && v.ObjectName == name)
+		}
+		return !builtinConstants[name]
+	})
+	if err != nil {
+		return err
+	}
+	saver.state.Globals = globals
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(saver.state)
+}
+
+type stateSaver struct {
+	state   savedState
+	global  *Environment
+	session *session
+	lists   map[*ListVal]int
+	objects map[uintptr]int // by the pointer of their property map
+	classes map[*ClassVal]int
+	scopes  map[*Environment]int
+}
+
+// globalScope is the global scope env belongs to
+func (env *Environment) globalScope() *Environment {
+	for !env.global {
+		env = env.parent
+	}
+	return env
+}
+
+// variables saves the variables of scope keep says to, sorted by name so the
+// same state is always written the same way
+func (s *stateSaver) variables(scope *Environment, keep func(name string, value RuntimeVal) bool) ([]savedVar, error) {
+	saved := []savedVar{}
+	for _, name := range scope.Names() {
+		value := scope.variables[name]
+		if !keep(name, value) {
+			continue
+		}
+		encoded, err := s.value(value)
+		if err != nil {
+			return nil, fmt.Errorf("saving %s: %w", name, err)
+		}
+		_, constant := scope.constants[name]
+		_, parameter := scope.parameters[name]
+		saved = append(saved, savedVar{Name: name, Value: encoded, Constant: constant, Parameter: parameter})
+	}
+	return saved, nil
+}
+
+func (s *stateSaver) value(value RuntimeVal) (savedValue, error) {
+	saved := savedValue{Type: value.ValueType()}
+
+	switch v := value.(type) {
+	case NumberVal:
+		saved.Value = strconv.FormatFloat(v.Value, 'g', -1, 64)
+	case StringVal:
+		saved.Value = v.Value
+	case CharVal:
+		saved.Value = string(v.Value)
+	case BoolVal:
+		saved.Value = strconv.FormatBool(v.Value)
+	case NadaVal:
+	case DateVal:
+		saved.Value = v.Time.Format(time.RFC3339Nano)
+	case RangeVal:
+		saved.Value = strings.Join([]string{
+			strconv.FormatFloat(v.Start, 'g', -1, 64),
+			strconv.FormatFloat(v.End, 'g', -1, 64),
+			strconv.FormatFloat(v.Step, 'g', -1, 64),
+		}, " ")
+	case ErrorVal:
+		payload, err := s.value(v.Value)
+		if err != nil {
+			return saved, err
+		}
+		saved.Error = &savedError{Message: v.Message, Value: payload, Line: v.Line, Column: v.Column}
+	case NativeFunctionValue:
+		// methods bound to a value and natives made by other natives, like
+		// the ones a timer has, can't be found again by their name
+		found, ok := lookupNative(s.global, v.Name)
+		if native, isNative := found.(NativeFunctionValue); !ok || !isNative || v.receiver != nil || !sameNative(native, v) {
+			return saved, fmt.Errorf("native %s can't be saved, only the ones every global scope has can", v.Name)
+		}
+		saved.Native = v.Name
+	case *ListVal:
+		ref, err := s.list(v)
+		saved.Ref = ref
+		return saved, err
+	case ObjectVal:
+		if path, ok := s.modulePath(v); ok {
+			saved.Module = path
+			saved.Native = v.ObjectName
+			return saved, nil
+		}
+		if s.nativeModule(v) {
+			saved.Native = v.ObjectName
+			return saved, nil
+		}
+		ref, err := s.object(v)
+		saved.Ref = ref
+		return saved, err
+	case *ClassVal:
+		if path, ok := s.moduleExport(v); ok {
+			saved.Module = path
+			saved.Native = v.Name
+			return saved, nil
+		}
+		ref, err := s.class(v)
+		saved.Ref = ref
+		return saved, err
+	case UserFunctionValue:
+		if v.DeclarationEnv != nil && v.DeclarationEnv.globalScope() != s.global {
+			if path, ok := s.moduleExport(v); ok {
+				saved.Module = path
+				saved.Native = v.Name
+				return saved, nil
+			}
+		}
+		fn, err := s.function(v)
+		saved.Function = &fn
+		return saved, err
+	default:
+		return saved, fmt.Errorf("%s values can't be saved", value.ValueType())
+	}
+	return saved, nil
+}
+
+func (s *stateSaver) list(list *ListVal) (int, error) {
+	if ref, seen := s.lists[list]; seen {
+		return ref, nil
+	}
+	s.state.Lists = append(s.state.Lists, nil)
+	ref := len(s.state.Lists)
+	s.lists[list] = ref
+
+	elements := make([]savedValue, len(list.Elements))
+	for i, element := range list.Elements {
+		saved, err := s.value(element)
+		if err != nil {
+			return 0, err
+		}
+		elements[i] = saved
+	}
+	s.state.Lists[ref-1] = elements
+	return ref, nil
+}
+
+func (s *stateSaver) object(obj ObjectVal) (int, error) {
+	key := reflect.ValueOf(obj.Properties).Pointer()
+	if ref, seen := s.objects[key]; seen && key != 0 {
+		return ref, nil
+	}
+	s.state.Objects = append(s.state.Objects, savedObject{})
+	ref := len(s.state.Objects)
+	s.objects[key] = ref
+
+	saved := savedObject{Name: obj.ObjectName, Frozen: s.session.isFrozen(obj), Properties: []savedVar{}}
+	if obj.Class != nil {
+		class, err := s.class(obj.Class)
+		if err != nil {
+			return 0, err
+		}
+		saved.Class = class
+	}
+	for _, name := range slices.Sorted(maps.Keys(obj.Properties)) {
+		value, err := s.value(obj.Properties[name])
+		if err != nil {
+			return 0, fmt.Errorf("property %s: %w", name, err)
+		}
+		saved.Properties = append(saved.Properties, savedVar{Name: name, Value: value})
+	}
+	s.state.Objects[ref-1] = saved
+	return ref, nil
+}
+
+func (s *stateSaver) class(class *ClassVal) (int, error) {
+	if ref, seen := s.classes[class]; seen {
+		return ref, nil
+	}
+	s.state.Classes = append(s.state.Classes, savedClass{})
+	ref := len(s.state.Classes)
+	s.classes[class] = ref
+
+	saved := savedClass{Name: class.Name, Methods: []savedFunction{}}
+	if class.Parent != nil {
+		parent, err := s.class(class.Parent)
+		if err != nil {
+			return 0, err
+		}
+		saved.Parent = parent
+	}
+	for _, name := range slices.Sorted(maps.Keys(class.Methods)) {
+		method, err := s.function(class.Methods[name])
+		if err != nil {
+			return 0, err
+		}
+		saved.Methods = append(saved.Methods, method)
+	}
+	s.state.Classes[ref-1] = saved
+	return ref, nil
+}
+
+func (s *stateSaver) function(fn UserFunctionValue) (savedFunction, error) {
+	saved := savedFunction{Name: fn.Name, Parameters: fn.Parameters}
+	if fn.Parameters == nil {
+		saved.Parameters = []string{}
+	}
+
+	if fn.Code != nil {
+		var code bytes.Buffer
+		if err := WriteChunk(&code, fn.Code); err != nil {
+			return saved, err
+		}
+		saved.Code = code.Bytes()
+	} else {
+		body, err := f.MarshalAST(f.Program{Body: fn.Body})
+		if err != nil {
+			return saved, err
+		}
+		saved.Body = body
+	}
+
+	scope, err := s.scope(fn.DeclarationEnv)
+	if err != nil {
+		return saved, fmt.Errorf("function %s: %w", fn.Name, err)
+	}
+	saved.Scope = scope
+	return saved, nil
+}
+
+// scope saves the scope a closure keeps, 0 being the global scope
+func (s *stateSaver) scope(env *Environment) (int, error) {
+	if env == nil || env == s.global {
+		return 0, nil
+	}
+	if env.global {
+		return 0, fmt.Errorf("it was declared in another global scope, only what imported modules export can be saved from there")
+	}
+	if ref, seen := s.scopes[env]; seen {
+		return ref, nil
+	}
+	s.state.Scopes = append(s.state.Scopes, savedScope{})
+	ref := len(s.state.Scopes)
+	s.scopes[env] = ref
+
+	parent, err := s.scope(env.parent)
+	if err != nil {
+		return 0, err
+	}
+	variables, err := s.variables(env, func(string, RuntimeVal) bool { return true })
+	if err != nil {
+		return 0, err
+	}
+	s.state.Scopes[ref-1] = savedScope{Parent: parent, File: env.file, Variables: variables}
+	return ref, nil
+}
+
+// nativeModule reports whether obj is a native module, a builtin one like
+// math or one a host registered
+func (s *stateSaver) nativeModule(obj ObjectVal) bool {
+	if !s.session.isFrozen(obj) || obj.Class != nil {
+		return false
+	}
+	natives := 0
+	for _, value := range obj.Properties {
+		switch value.(type) {
+		case NativeFunctionValue:
+			natives++
+		case UserFunctionValue:
+			return false
+		}
+	}
+	return natives > 0
+}
+
+// modulePath finds the file obj was imported from, if it's an imported module
+func (s *stateSaver) modulePath(obj ObjectVal) (string, bool) {
+	key := reflect.ValueOf(obj.Properties).Pointer()
+	for path, module := range s.session.modules.cache {
+		if reflect.ValueOf(module.Properties).Pointer() == key {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// moduleExport finds the imported module that exports value under its own
+// name, a function or a class declared in the module
+func (s *stateSaver) moduleExport(value RuntimeVal) (string, bool) {
+	for path, module := range s.session.modules.cache {
+		switch v := value.(type) {
+		case UserFunctionValue:
+			if fn, ok := module.Properties[v.Name].(UserFunctionValue); ok && fn.DeclarationEnv == v.DeclarationEnv {
+				return path, true
+			}
+		case *ClassVal:
+			if class, ok := module.Properties[v.Name].(*ClassVal); ok && class == v {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+///////////////////
+// Loading State //
+///////////////////
+
+// LoadState reads a state written by SaveState into the global scope of env,
+// replacing variables of the same name. Natives are looked up by name in env
+// and imported modules are imported again, so loading fails when the host
+// hasn't registered the functions the state uses or a module has gone.
+func (env *Environment) LoadState(r io.Reader) error {
+	var state savedState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("reading state: %w", err)
+	}
+	if state.Version != StateVersion {
+		return fmt.Errorf("reading state: it was saved in version %d of the format, this build reads version %d", state.Version, StateVersion)
+	}
+
+	loader := &stateLoader{state: &state, global: env.globalScope(), session: env.session}
+
+	// everything that can be referred to is made first, then filled in, so
+	// references in any order and cycles work out
+	loader.lists = make([]*ListVal, len(state.Lists))
+	for i := range loader.lists {
+		loader.lists[i] = &ListVal{}
+	}
+	loader.classes = make([]*ClassVal, len(state.Classes))
+	for i, saved := range state.Classes {
+		loader.classes[i] = &ClassVal{Name: saved.Name, Methods: make(map[string]UserFunctionValue)}
+	}
+	// objects are copied around as they are, so their class has to be set
+	// before anything refers to them
+	loader.objects = make([]ObjectVal, len(state.Objects))
+	for i, saved := range state.Objects {
+		loader.objects[i] = ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: saved.Name}
+		if saved.Class != 0 {
+			class, err := loader.class(saved.Class)
+			if err != nil {
+				return fmt.Errorf("reading state: %w", err)
+			}
+			loader.objects[i].Class = class
+		}
+	}
+	loader.scopes = make([]*Environment, len(state.Scopes))
+	for i, saved := range state.Scopes {
+		loader.scopes[i] = &Environment{
+			variables:  make(map[string]RuntimeVal),
+			constants:  make(map[string]struct{}),
+			parameters: make(map[string]struct{}),
+			session:    env.session,
+			file:       saved.File,
+		}
+	}
+
+	if err := loader.fill(); err != nil {
+		return fmt.Errorf("reading state: %w", err)
+	}
+
+	values := make([]RuntimeVal, len(state.Globals))
+	for i, saved := range state.Globals {
+		value, err := loader.value(saved.Value)
+		if err != nil {
+			return fmt.Errorf("reading state: %s: %w", saved.Name, err)
+		}
+		values[i] = value
+	}
+	// nothing changes until the whole state has been read
+	for i, saved := range state.Globals {
+		loader.global.remove(saved.Name)
+		loader.global.setVar(saved.Name, values[i])
+		if saved.Constant {
+			loader.global.constants[saved.Name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+type stateLoader struct {
+	state   *savedState
+	global  *Environment
+	session *session
+	lists   []*ListVal
+	objects []ObjectVal
+	classes []*ClassVal
+	scopes  []*Environment
+}
+
+// fill gives the lists, objects, classes and scopes made for the state
+// their contents
+func (l *stateLoader) fill() error {
+	for i, saved := range l.state.Scopes {
+		scope := l.scopes[i]
+		parent, err := l.scope(saved.Parent)
+		if err != nil {
+			return err
+		}
+		scope.parent = parent
+		if err := l.variables(scope.variables, saved.Variables); err != nil {
+			return err
+		}
+		for _, variable := range saved.Variables {
+			if variable.Constant {
+				scope.constants[variable.Name] = struct{}{}
+			}
+			if variable.Parameter {
+				scope.parameters[variable.Name] = struct{}{}
+			}
+		}
+	}
+
+	for i, elements := range l.state.Lists {
+		list := l.lists[i]
+		list.Elements = make([]RuntimeVal, len(elements))
+		for j, saved := range elements {
+			element, err := l.value(saved)
+			if err != nil {
+				return err
+			}
+			list.Elements[j] = element
+		}
+	}
+
+	for i, saved := range l.state.Classes {
+		class := l.classes[i]
+		if saved.Parent != 0 {
+			parent, err := l.class(saved.Parent)
+			if err != nil {
+				return err
+			}
+			class.Parent = parent
+		}
+		for _, method := range saved.Methods {
+			fn, err := l.function(method)
+			if err != nil {
+				return err
+			}
+			class.Methods[method.Name] = fn
+		}
+	}
+
+	for i, saved := range l.state.Objects {
+		obj := l.objects[i]
+		if err := l.variables(obj.Properties, saved.Properties); err != nil {
+			return err
+		}
+		if saved.Frozen {
+			l.session.freeze(obj)
+		}
+	}
+	return nil
+}
+
+func (l *stateLoader) variables(into map[string]RuntimeVal, saved []savedVar) error {
+	for _, variable := range saved {
+		value, err := l.value(variable.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", variable.Name, err)
+		}
+		into[variable.Name] = value
+	}
+	return nil
+}
+
+func (l *stateLoader) value(saved savedValue) (RuntimeVal, error) {
+	switch saved.Type {
+	case NumberType:
+		number, err := strconv.ParseFloat(saved.Value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return NumberVal{Value: number}, nil
+	case StringType:
+		return StringVal{Value: saved.Value}, nil
+	case CharType:
+		runes := []rune(saved.Value)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("%q is not one character", saved.Value)
+		}
+		return CharVal{Value: runes[0]}, nil
+	case BoolType:
+		return BoolVal{Value: saved.Value == "true"}, nil
+	case NadaType:
+		return NadaVal{}, nil
+	case DateType:
+		date, err := time.Parse(time.RFC3339Nano, saved.Value)
+		if err != nil {
+			return nil, err
+		}
+		return DateVal{Time: date}, nil
+	case RangeType:
+		var bounds [3]float64
+		parts := strings.Fields(saved.Value)
+		if len(parts) != len(bounds) {
+			return nil, fmt.Errorf("%q is not a range", saved.Value)
+		}
+		for i, part := range parts {
+			bound, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = bound
+		}
+		return RangeVal{Start: bounds[0], End: bounds[1], Step: bounds[2]}, nil
+	case ErrorType:
+		if saved.Error == nil {
+			return nil, fmt.Errorf("error value without its error")
+		}
+		payload, err := l.value(saved.Error.Value)
+		if err != nil {
+			return nil, err
+		}
+		return ErrorVal{Message: saved.Error.Message, Value: payload, Line: saved.Error.Line, Column: saved.Error.Column}, nil
+	case NativeFunctionType:
+		return l.native(saved.Native)
+	case ListType:
+		if saved.Ref < 1 || saved.Ref > len(l.lists) {
+			return nil, fmt.Errorf("no list %d", saved.Ref)
+		}
+		return l.lists[saved.Ref-1], nil
+	case ObjectType:
+		if saved.Module != "" {
+			return l.module(saved.Module, saved.Native)
+		}
+		if saved.Native != "" {
+			return l.native(saved.Native)
+		}
+		if saved.Ref < 1 || saved.Ref > len(l.objects) {
+			return nil, fmt.Errorf("no object %d", saved.Ref)
+		}
+		return l.objects[saved.Ref-1], nil
+	case ClassType:
+		if saved.Module != "" {
+			return l.export(saved.Module, saved.Native)
+		}
+		return l.class(saved.Ref)
+	case UserFunctionType:
+		if saved.Module != "" {
+			return l.export(saved.Module, saved.Native)
+		}
+		if saved.Function == nil {
+			return nil, fmt.Errorf("function value without its function")
+		}
+		return l.function(*saved.Function)
+	}
+	return nil, fmt.Errorf("%s values can't be loaded", saved.Type)
+}
+
+func (l *stateLoader) class(ref int) (*ClassVal, error) {
+	if ref < 1 || ref > len(l.classes) {
+		return nil, fmt.Errorf("no class %d", ref)
+	}
+	return l.classes[ref-1], nil
+}
+
+func (l *stateLoader) scope(ref int) (*Environment, error) {
+	if ref == 0 {
+		return l.global, nil
+	}
+	if ref < 0 || ref > len(l.scopes) {
+		return nil, fmt.Errorf("no scope %d", ref)
+	}
+	return l.scopes[ref-1], nil
+}
+
+func (l *stateLoader) function(saved savedFunction) (UserFunctionValue, error) {
+	scope, err := l.scope(saved.Scope)
+	if err != nil {
+		return UserFunctionValue{}, err
+	}
+	fn := UserFunctionValue{Name: saved.Name, Parameters: saved.Parameters, DeclarationEnv: scope}
+
+	if saved.Code != nil {
+		code, err := ReadChunk(bytes.NewReader(saved.Code))
+		if err != nil {
+			return fn, fmt.Errorf("function %s: %w", saved.Name, err)
+		}
+		fn.Code = code
+		return fn, nil
+	}
+	node, err := f.UnmarshalAST(saved.Body)
+	if err != nil {
+		return fn, fmt.Errorf("function %s: %w", saved.Name, err)
+	}
+	program, ok := node.(f.Program)
+	if !ok {
+		return fn, fmt.Errorf("function %s: its body is a %s, not a Program", saved.Name, node.NodeType())
+	}
+	fn.Body = program.Body
+	return fn, nil
+}
+
+// native looks up a native function or module by name, module functions
+// being named like math.sqrt
+func (l *stateLoader) native(name string) (RuntimeVal, error) {
+	if value, ok := lookupNative(l.global, name); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("native %s isn't there to load", name)
+}
+
+// lookupNative finds a native saved as name in the global scope global, a
+// global like print or a module function like math.sqrt
+func lookupNative(global *Environment, name string) (RuntimeVal, bool) {
+	if value, ok := global.variables[name]; ok {
+		return value, true
+	}
+	if module, function, ok := strings.Cut(name, "."); ok {
+		if obj, ok := global.variables[module].(ObjectVal); ok {
+			if value, ok := obj.Properties[function]; ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (l *stateLoader) module(path string, name string) (ObjectVal, error) {
+	if !l.session.importAllowed() {
+		return ObjectVal{}, fmt.Errorf("can't import %s, importing isn't allowed here", path)
+	}
+	return l.session.loadModule(path, name)
+}
+
+// export is what the module at path exports as name
+func (l *stateLoader) export(path string, name string) (RuntimeVal, error) {
+	module, err := l.module(path, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if err != nil {
+		return nil, err
+	}
+	value, ok := module.Properties[name]
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't export %s anymore", path, name)
+	}
+	return value, nil
+}
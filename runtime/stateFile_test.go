@@ -0,0 +1,57 @@
+package runtime_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestSaveStateNatives(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	if _, err := testutil.EvalIn("val say = println\nval root = math.sqrt", env); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved bytes.Buffer
+	if err := env.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded := r.NewEnvironment(nil)
+	if err := loaded.LoadState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	got, err := testutil.EvalIn("root(16)", loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: 4})
+}
+
+func TestSaveStateUnresolvableNatives(t *testing.T) {
+	tests := []struct {
+		src      string
+		variable string
+	}{
+		{`val shout = "abc".upper`, "shout"},
+		{`val upper = "abc".upper`, "upper"},
+		{"val elapsed = time.timer().elapsed", "elapsed"},
+		{"val stopwatch = time.timer()", "stopwatch"},
+		{"val pushers = [[1].push]", "pushers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			env := r.NewEnvironment(nil)
+			if _, err := testutil.EvalIn(tt.src, env); err != nil {
+				t.Fatal(err)
+			}
+			var saved bytes.Buffer
+			err := env.SaveState(&saved)
+			if err == nil || !strings.Contains(err.Error(), "saving "+tt.variable+":") {
+				t.Errorf("got error %v, want one naming %s", err, tt.variable)
+			}
+		})
+	}
+}
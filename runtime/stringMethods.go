@@ -0,0 +1,288 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+////////////////////
+// String Methods //
+////////////////////
+
+// maxStringLength is the most bytes a string method builds, so a count like
+// "x".repeat(1e12) fails instead of taking all the memory there is
+const maxStringLength = 1 << 28
+
+// stringMethod is a native method on strings, str is the receiver
+type stringMethod func(str string, args []RuntimeVal) (RuntimeVal, error)
+
+// stringMethods are the methods every string value has, s.upper() and so on
+var stringMethods = map[string]stringMethod{
+	"length": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 0 {
+			return nil, stringArgsError("length", "no arguments", len(args))
+		}
+		return NumberVal{Value: float64(len([]rune(str)))}, nil
+	},
+	"upper": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 0 {
+			return nil, stringArgsError("upper", "no arguments", len(args))
+		}
+		return StringVal{Value: strings.ToUpper(str)}, nil
+	},
+	"lower": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 0 {
+			return nil, stringArgsError("lower", "no arguments", len(args))
+		}
+		return StringVal{Value: strings.ToLower(str)}, nil
+	},
+	"split": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, stringArgsError("split", "(separator)", len(args))
+		}
+		sep, err := stringParam("split", args, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(str, sep)
+		elements := make([]RuntimeVal, len(parts))
+		for i, part := range parts {
+			elements[i] = StringVal{Value: part}
+		}
+		return &ListVal{Elements: elements}, nil
+	},
+	"contains": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, stringArgsError("contains", "(text)", len(args))
+		}
+		sub, err := stringParam("contains", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return BoolVal{Value: strings.Contains(str, sub)}, nil
+	},
+	"join": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		// ", ".join(list) puts the receiver between the elements
+		if len(args) != 1 {
+			return nil, stringArgsError("join", "(list)", len(args))
+		}
+		list, ok := args[0].(*ListVal)
+		if !ok {
+			return nil, &TypeError{Operation: "string.join", Expected: "a list", Got: TypeOf(args[0])}
+		}
+
+		parts := make([]string, len(list.Elements))
+		for i, element := range list.Elements {
+			parts[i] = element.String()
+		}
+		return StringVal{Value: strings.Join(parts, str)}, nil
+	},
+	"trim": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 0 {
+			return nil, stringArgsError("trim", "no arguments", len(args))
+		}
+		return StringVal{Value: strings.TrimSpace(str)}, nil
+	},
+	"replace": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 2 {
+			return nil, stringArgsError("replace", "(old, new)", len(args))
+		}
+		old, err := stringParam("replace", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := stringParam("replace", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal{Value: strings.ReplaceAll(str, old, replacement)}, nil
+	},
+	"startsWith": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, stringArgsError("startsWith", "(prefix)", len(args))
+		}
+		prefix, err := stringParam("startsWith", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return BoolVal{Value: strings.HasPrefix(str, prefix)}, nil
+	},
+	"endsWith": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, stringArgsError("endsWith", "(suffix)", len(args))
+		}
+		suffix, err := stringParam("endsWith", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return BoolVal{Value: strings.HasSuffix(str, suffix)}, nil
+	},
+	"indexOf": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		// counted in characters like indexing, -1 when sub isn't there
+		if len(args) != 1 {
+			return nil, stringArgsError("indexOf", "(text)", len(args))
+		}
+		sub, err := stringParam("indexOf", args, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		index := strings.Index(str, sub)
+		if index < 0 {
+			return NumberVal{Value: -1}, nil
+		}
+		return NumberVal{Value: float64(utf8.RuneCountInString(str[:index]))}, nil
+	},
+	"repeat": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		if len(args) != 1 {
+			return nil, stringArgsError("repeat", "(count)", len(args))
+		}
+		count, err := countParam("repeat", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStringLength("repeat", len(str), count); err != nil {
+			return nil, err
+		}
+		return StringVal{Value: strings.Repeat(str, count)}, nil
+	},
+	"padLeft": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		padding, err := padFill("padLeft", str, args)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal{Value: padding + str}, nil
+	},
+	"padRight": func(str string, args []RuntimeVal) (RuntimeVal, error) {
+		padding, err := padFill("padRight", str, args)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal{Value: str + padding}, nil
+	},
+}
+
+func stringArgsError(method string, params string, got int) error {
+	errorMessage := fmt.Sprintf("string.%s expects %s but got %d argument(s)", method, params, got)
+	return &InterpretingError{Message: errorMessage}
+}
+
+// stringParam reads argument i of a string method as a string
+func stringParam(method string, args []RuntimeVal, i int) (string, error) {
+	text, ok := stringArg(args, i)
+	if !ok {
+		return "", &TypeError{Operation: "string." + method, Expected: "a string", Got: TypeOf(args[i])}
+	}
+	return text, nil
+}
+
+// countParam reads argument i of a string method as a count
+func countParam(method string, args []RuntimeVal, i int) (int, error) {
+	num, ok := args[i].(NumberVal)
+	if !ok {
+		return 0, &TypeError{Operation: "string." + method, Expected: "a number", Got: TypeOf(args[i])}
+	}
+	if num.Value > maxStringLength {
+		errorMessage := fmt.Sprintf("string.%s would make a string longer than %d bytes", method, maxStringLength)
+		return 0, &InterpretingError{Message: errorMessage}
+	}
+	count, ok := countArg(args, i)
+	if !ok {
+		errorMessage := fmt.Sprintf("string.%s expects a whole number that isn't negative but got %s", method, args[i])
+		return 0, &InterpretingError{Message: errorMessage}
+	}
+	return count, nil
+}
+
+// countArg reads args[i] as a whole number from 0 to maxStringLength, so it
+// always fits in an int
+func countArg(args []RuntimeVal, i int) (int, bool) {
+	if i >= len(args) {
+		return 0, false
+	}
+
+	num, ok := args[i].(NumberVal)
+	if !ok || num.Value < 0 || num.Value > maxStringLength || num.Value != math.Trunc(num.Value) {
+		return 0, false
+	}
+	return int(num.Value), true
+}
+
+// checkStringLength fails when count copies of size bytes would go over
+// maxStringLength
+func checkStringLength(method string, size int, count int) error {
+	if size > 0 && count > maxStringLength/size {
+		errorMessage := fmt.Sprintf("string.%s would make a string longer than %d bytes", method, maxStringLength)
+		return &InterpretingError{Message: errorMessage}
+	}
+	return nil
+}
+
+// padFill is what padLeft(width, fill) and padRight(width, fill) add to str
+// to make it width characters long, fill defaults to a space
+func padFill(method string, str string, args []RuntimeVal) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", stringArgsError(method, "(width) or (width, fill)", len(args))
+	}
+	width, err := countParam(method, args, 0)
+	if err != nil {
+		return "", err
+	}
+
+	fill := " "
+	if len(args) == 2 {
+		fill, err = stringParam(method, args, 1)
+		if err != nil {
+			return "", err
+		}
+		if utf8.RuneCountInString(fill) != 1 {
+			errorMessage := fmt.Sprintf("string.%s expects a single character to fill with but got %q", method, fill)
+			return "", &InterpretingError{Message: errorMessage}
+		}
+	}
+
+	missing := width - utf8.RuneCountInString(str)
+	if missing <= 0 {
+		return "", nil
+	}
+	if err := checkStringLength(method, len(fill), missing); err != nil {
+		return "", err
+	}
+	return strings.Repeat(fill, missing), nil
+}
+
+// stringArg reads args[i] as a string, chars count as one character strings
+func stringArg(args []RuntimeVal, i int) (string, bool) {
+	if i >= len(args) {
+		return "", false
+	}
+
+	switch v := args[i].(type) {
+	case StringVal:
+		return v.Value, true
+	case CharVal:
+		return string(v.Value), true
+	default:
+		return "", false
+	}
+}
+
+// bindStringMethod turns a string method into a function value that already
+// knows its receiver, so s.upper can be passed around and called later
+func bindStringMethod(name string, str StringVal) (NativeFunctionValue, bool) {
+	method, found := stringMethods[name]
+	if !found {
+		return NativeFunctionValue{}, false
+	}
+
+	return NativeFunctionValue{
+		Name: name,
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return method(str.Value, args)
+		},
+		receiver: str,
+	}, true
+}
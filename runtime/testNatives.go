@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//////////////////
+// Test Natives //
+//////////////////
+
+// setupTestNatives declares the assertions tests use besides assert. A
+// failed one throws like assert does, so it can be caught, and the error
+// points at the call that failed.
+func setupTestNatives(env *Environment) {
+	// assertEqual(actual, expected) or assertEqual(actual, expected, message)
+	env.DeclareVar("assertEqual", NativeFunctionValue{
+		Name: "assertEqual",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) < 2 || len(args) > 3 {
+				errorMessage := fmt.Sprintf("assertEqual expects 2 or 3 arguments but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			if Equal(args[0], args[1]) {
+				return NadaVal{}, nil
+			}
+			return nil, assertionFailed(fmt.Sprintf("expected %s but got %s", describeValue(args[1]), describeValue(args[0])), args[2:], args[0])
+		},
+	}, true)
+
+	// assertNotEqual(actual, unexpected) or assertNotEqual(actual, unexpected, message)
+	env.DeclareVar("assertNotEqual", NativeFunctionValue{
+		Name: "assertNotEqual",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) < 2 || len(args) > 3 {
+				errorMessage := fmt.Sprintf("assertNotEqual expects 2 or 3 arguments but got %d", len(args))
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+			if !Equal(args[0], args[1]) {
+				return NadaVal{}, nil
+			}
+			return nil, assertionFailed(fmt.Sprintf("expected anything but %s", describeValue(args[1])), args[2:], args[0])
+		},
+	}, true)
+
+	// assertThrows(fn, args...) calls fn with args and gives back the error
+	// it threw, failing when it didn't throw one
+	env.DeclareVar("assertThrows", NativeFunctionValue{
+		Name: "assertThrows",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 {
+				return nil, &InterpretingError{Message: "assertThrows expects a function to call"}
+			}
+			result, err := CallFunction(args[0], args[1:], env)
+			if err != nil {
+				if uncatchable(err) {
+					return nil, err
+				}
+				return toErrorVal(err), nil
+			}
+			return nil, assertionFailed("expected an error but the call returned "+describeValue(result), nil, result)
+		},
+	}, true)
+}
+
+// assertionFailed is the error a failed assertion throws, with the message
+// the test gave if there is one
+func assertionFailed(reason string, message []RuntimeVal, value RuntimeVal) error {
+	text := "Assertion failed: " + reason
+	if len(message) > 0 {
+		text = "Assertion failed: " + message[0].String() + ", " + reason
+	}
+	return &ThrowError{Thrown: ErrorVal{Message: text, Value: value}}
+}
+
+// describeValue shows a value in an assertion message, quoting strings so
+// "1" and 1 can be told apart
+func describeValue(value RuntimeVal) string {
+	switch v := value.(type) {
+	case StringVal:
+		return strconv.Quote(v.Value)
+	case CharVal:
+		return strconv.QuoteRune(v.Value)
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"strings"
+	"time"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+/////////////
+// Testing //
+/////////////
+
+// TestPrefix starts the name of every function RunTests runs
+const TestPrefix = "test_"
+
+// TestResult is how one test function went. Message is empty when it
+// passed, otherwise it says why it failed and Line and Column where.
+type TestResult struct {
+	Name     string
+	Message  string
+	Line     int
+	Column   int
+	Duration time.Duration
+}
+
+func (t TestResult) Passed() bool {
+	return t.Message == ""
+}
+
+// RunTests runs program in env and then calls each of its top level
+// functions named test_something, in the order they're declared. A test
+// passes when its function returns and fails on any error, the tests after
+// it still run. err is set when the program itself fails before any test
+// runs, or when a test is stopped by os.exit or a limit, which ends the run.
+func RunTests(program f.Program, env *Environment) ([]TestResult, error) {
+	if _, err := Evaluate(program, env); err != nil {
+		return nil, err
+	}
+
+	var results []TestResult
+	for _, name := range prefixedFunctions(program, TestPrefix) {
+		fn, err := env.LookupVar(name)
+		if err != nil {
+			return results, err
+		}
+
+		start := time.Now()
+		_, err = CallFunction(fn, nil, env)
+		result := TestResult{Name: name, Duration: time.Since(start)}
+		if err != nil {
+			if uncatchable(err) {
+				return results, err
+			}
+			failure := toErrorVal(err)
+			result.Message, result.Line, result.Column = failure.Message, failure.Line, failure.Column
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// prefixedFunctions names the top level functions of program that start
+// with prefix, in the order they're declared
+func prefixedFunctions(program f.Program, prefix string) []string {
+	var names []string
+	for _, stmt := range program.Body {
+		if declaration, ok := stmt.(f.FunctionDeclaration); ok && strings.HasPrefix(declaration.Name, prefix) {
+			names = append(names, declaration.Name)
+		}
+	}
+	return names
+}
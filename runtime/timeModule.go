@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/////////////////
+// Time Module //
+/////////////////
+
+// maxSleepMillis is the longest sleep whose nanoseconds still fit in an int64
+const maxSleepMillis = float64(math.MaxInt64 / int64(time.Millisecond))
+
+// named layouts time.format accepts besides Go's reference time layouts
+var timeLayouts = map[string]string{
+	"iso":      time.RFC3339,
+	"datetime": time.DateTime,
+	"date":     time.DateOnly,
+	"time":     time.TimeOnly,
+}
+
+// timeModule builds the global time object. Timestamps are milliseconds since
+// the Unix epoch, the same numbers unixMillis returns.
+func timeModule() ObjectVal {
+	module := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "time"}
+
+	// now() is the current timestamp with a fractional part, unixMillis()
+	// rounded down to whole milliseconds
+	module.Properties["now"] = NativeFunctionValue{
+		Name: "time.now",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return NumberVal{Value: float64(time.Now().UnixNano()) / float64(time.Millisecond)}, nil
+		},
+	}
+
+	module.Properties["unixMillis"] = NativeFunctionValue{
+		Name: "time.unixMillis",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			return NumberVal{Value: float64(time.Now().UnixMilli())}, nil
+		},
+	}
+
+	// sleep(ms) pauses the program, cut short with an error when the host
+	// cancels the session context. ms has to fit in a time.Duration, about
+	// 292 years, so it can't wrap around into a sleep that ends at once
+	module.Properties["sleep"] = NativeFunctionValue{
+		Name: "time.sleep",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			nums, ok := numberArgs(args)
+			if !ok || len(nums) != 1 {
+				return nil, &InterpretingError{Message: "time.sleep expects a number of milliseconds"}
+			}
+			if !(nums[0] >= 0 && nums[0] <= maxSleepMillis) {
+				errorMessage := fmt.Sprintf("time.sleep expects from 0 to %v milliseconds but got %v", maxSleepMillis, nums[0])
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+
+			timer := time.NewTimer(time.Duration(nums[0] * float64(time.Millisecond)))
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+				return NadaVal{}, nil
+			case <-env.session.ctx.Done():
+				errorMessage := fmt.Sprintf("time.sleep interrupted: %v", env.session.ctx.Err())
+				return nil, &InterpretingError{Message: errorMessage}
+			}
+		},
+	}
+
+	// format(ts, layout) formats a timestamp in local time. layout is one of
+	// iso, datetime, date or time, or a Go layout like "02 Jan 2006"
+	module.Properties["format"] = NativeFunctionValue{
+		Name: "time.format",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			if len(args) == 0 || len(args) > 2 {
+				return nil, &InterpretingError{Message: "time.format expects (timestamp, layout)"}
+			}
+
+			ts, ok := args[0].(NumberVal)
+			if !ok {
+				return nil, &TypeError{Operation: "time.format", Expected: "a timestamp", Got: TypeOf(args[0])}
+			}
+
+			layout := time.RFC3339
+			if len(args) == 2 {
+				text, ok := stringArg(args, 1)
+				if !ok {
+					return nil, &TypeError{Operation: "time.format", Expected: "a layout string", Got: TypeOf(args[1])}
+				}
+				layout = layoutFor(text)
+			}
+
+			return StringVal{Value: time.UnixMilli(int64(ts.Value)).Format(layout)}, nil
+		},
+	}
+
+	// timer() starts a stopwatch, t.elapsed() is the milliseconds since then
+	module.Properties["timer"] = NativeFunctionValue{
+		Name: "time.timer",
+		Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+			start := time.Now()
+			timer := ObjectVal{Properties: make(map[string]RuntimeVal), ObjectName: "timer"}
+			timer.Properties["elapsed"] = NativeFunctionValue{
+				Name: "timer.elapsed",
+				Call: func(args []RuntimeVal, env *Environment) (RuntimeVal, error) {
+					return NumberVal{Value: float64(time.Since(start)) / float64(time.Millisecond)}, nil
+				},
+				receiver: timer,
+			}
+			return timer, nil
+		},
+	}
+
+	return module
+}
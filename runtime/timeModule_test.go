@@ -0,0 +1,38 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestTimeNowIsMilliseconds(t *testing.T) {
+	before := float64(time.Now().UnixMilli())
+	got, ok := testutil.MustEval(t, "time.now()").(r.NumberVal)
+	after := float64(time.Now().UnixMilli()) + 1
+	if !ok || got.Value < before || got.Value > after {
+		t.Errorf("time.now() = %v, want between %v and %v", got, before, after)
+	}
+
+	// now and format agree on what a timestamp is
+	testutil.AssertValue(t,
+		testutil.MustEval(t, `time.format(time.now(), "date") == time.format(time.unixMillis(), "date")`),
+		r.BoolVal{Value: true})
+}
+
+func TestSleepRejectsBadDurations(t *testing.T) {
+	for _, src := range []string{
+		"time.sleep(-1)",
+		"time.sleep(math.sqrt(-1))",
+		"time.sleep(1e300)",
+		"time.sleep(math.pow(10, 400))",
+	} {
+		if _, err := testutil.Eval(src); err == nil || !strings.Contains(err.Error(), "time.sleep expects from 0") {
+			t.Errorf("%s: got %v, want a range error", src, err)
+		}
+	}
+	testutil.AssertValue(t, testutil.MustEval(t, "time.sleep(0)"), r.NadaVal{})
+}
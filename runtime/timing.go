@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+////////////
+// Timing //
+////////////
+
+// callTiming adds up the calls made to one function while timing is on
+type callTiming struct {
+	calls  int
+	total  time.Duration // time spent inside the calls, including what they called
+	active int           // calls to it in progress, only the outermost is timed so recursion isn't counted twice
+	start  time.Time     // when the outermost call in progress started
+}
+
+// SetTiming turns on counting and timing every call, by function name, for
+// WriteTimings to report. Turning it off forgets what was recorded.
+func (env *Environment) SetTiming(on bool) {
+	if on {
+		env.session.timings = make(map[string]*callTiming)
+	} else {
+		env.session.timings = nil
+	}
+}
+
+// WriteTimings writes a table of every function called since timing was
+// turned on, with how often it was called and how long the calls took,
+// slowest first
+func (env *Environment) WriteTimings(w io.Writer) error {
+	timings := env.session.timings
+	names := slices.Sorted(maps.Keys(timings))
+	slices.SortStableFunc(names, func(a, b string) int {
+		return cmp.Compare(timings[b].total, timings[a].total)
+	})
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Function\tCalls\tTotal\tAverage")
+	for _, name := range names {
+		timing := timings[name]
+		average := timing.total / time.Duration(timing.calls)
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\n", name, timing.calls, roundDuration(timing.total), roundDuration(average))
+	}
+	return table.Flush()
+}
+
+// roundDuration keeps durations readable, nobody needs nanoseconds on a call
+// that took milliseconds
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d
+	}
+}
+
+// startTiming counts a call to name that's starting, when timing is on
+func (s *session) startTiming(name string) {
+	if s.timings == nil {
+		return
+	}
+
+	timing, exists := s.timings[name]
+	if !exists {
+		timing = &callTiming{}
+		s.timings[name] = timing
+	}
+	timing.calls++
+	if timing.active == 0 {
+		timing.start = time.Now()
+	}
+	timing.active++
+}
+
+// stopTiming ends a call startTiming counted
+func (s *session) stopTiming(name string) {
+	if s.timings == nil {
+		return
+	}
+
+	timing := s.timings[name]
+	timing.active--
+	if timing.active == 0 {
+		timing.total += time.Since(timing.start)
+	}
+}
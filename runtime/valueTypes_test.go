@@ -0,0 +1,230 @@
+package runtime_test
+
+import (
+	"fmt"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// one value of every type a0 code can hold, declared by valueKindsSetup
+var valueKinds = []struct {
+	name       string
+	typ        r.ValueType
+	comparable bool // whether < and friends order it against itself
+}{
+	{"number", r.NumberType, true},
+	{"str", r.StringType, true},
+	{"char", r.CharType, true},
+	{"nothing", r.NadaType, false},
+	{"flag", r.BoolType, false},
+	{"obj", r.ObjectType, false},
+	{"span", r.RangeType, false},
+	{"when", r.DateType, true},
+	{"list", r.ListType, true},
+	{"err", r.ErrorType, false},
+	{"Dog", r.ClassType, false},
+	{"greet", r.UserFunctionType, false},
+	{"print", r.NativeFunctionType, false},
+}
+
+const valueKindsSetup = `
+val number = 1
+val str = "a"
+val char = 'a'
+val nothing = nada
+val flag = true
+val obj = { a: 1 }
+val span = 0..3
+val when = date.make(2024, 1, 31)
+val list = [1, 2]
+val err = error("boom")
+class Dog {}
+fun greet() {}
+`
+
+func valueKindsEnv(t *testing.T) *r.Environment {
+	t.Helper()
+	env := r.NewEnvironment(nil)
+	if _, err := testutil.EvalIn(valueKindsSetup, env); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func evalIn(t *testing.T, src string, env *r.Environment) r.RuntimeVal {
+	t.Helper()
+	val, err := testutil.EvalIn(src, env)
+	if err != nil {
+		t.Fatalf("unexpected error evaluating %q: %v", src, err)
+	}
+	return val
+}
+
+func TestTypeOfEveryKind(t *testing.T) {
+	env := valueKindsEnv(t)
+	for _, kind := range valueKinds {
+		t.Run(string(kind.typ), func(t *testing.T) {
+			val := evalIn(t, kind.name, env)
+			if got := r.TypeOf(val); got != kind.typ {
+				t.Errorf("TypeOf(%s) = %s, want %s", kind.name, got, kind.typ)
+			}
+			if got := r.TypeName(val); got != string(kind.typ) {
+				t.Errorf("TypeName(%s) = %s, want %s", kind.name, got, kind.typ)
+			}
+			got := evalIn(t, fmt.Sprintf("type(%s)", kind.name), env)
+			testutil.AssertValue(t, got, r.StringVal{Value: string(kind.typ)})
+		})
+	}
+
+	if got := r.TypeOf(nil); got != r.NadaType {
+		t.Errorf("TypeOf(nil) = %s, want %s", got, r.NadaType)
+	}
+}
+
+func TestEqualityAcrossKinds(t *testing.T) {
+	env := valueKindsEnv(t)
+	for _, a := range valueKinds {
+		for _, b := range valueKinds {
+			src := fmt.Sprintf("%s == %s", a.name, b.name)
+			got := evalIn(t, src, env)
+			testutil.AssertValue(t, got, r.BoolVal{Value: a.name == b.name})
+
+			src = fmt.Sprintf("%s != %s", a.name, b.name)
+			got = evalIn(t, src, env)
+			testutil.AssertValue(t, got, r.BoolVal{Value: a.name != b.name})
+		}
+	}
+}
+
+func TestEqualityByValue(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"1 == 1.0", true},
+		{`"a" == "a"`, true},
+		{`'a' == 'b'`, false},
+		{`'a' == "a"`, false},
+		{"nada == nada", true},
+		{"true == false", false},
+		{"{ a: [1, 2] } == { a: [1, 2] }", true},
+		{"{ a: 1 } == { a: 1, b: 2 }", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"0..3 == 0..3", true},
+		{"0..3 == 0..4", false},
+		{"date.make(2024, 1, 31) == date.make(2024, 1, 31)", true},
+		{`error("a") == error("a")`, true},
+		{`error("a") == error("b")`, false},
+		{"1 == true", false},
+		{`0 == nada`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), r.BoolVal{Value: tt.want})
+		})
+	}
+}
+
+func TestOrderingAcrossKinds(t *testing.T) {
+	env := valueKindsEnv(t)
+	for _, a := range valueKinds {
+		for _, b := range valueKinds {
+			ordered := a.comparable && a.name == b.name
+			for _, op := range []string{"<=", ">="} {
+				src := fmt.Sprintf("%s %s %s", a.name, op, b.name)
+				got := evalIn(t, src, env)
+				testutil.AssertValue(t, got, r.BoolVal{Value: ordered})
+			}
+			for _, op := range []string{"<", ">"} {
+				src := fmt.Sprintf("%s %s %s", a.name, op, b.name)
+				got := evalIn(t, src, env)
+				testutil.AssertValue(t, got, r.BoolVal{Value: false})
+			}
+		}
+	}
+}
+
+func TestOrderingByValue(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"1 < 2", true},
+		{`"abc" < "abd"`, true},
+		{`'b' > 'a'`, true},
+		{"date.make(2024, 1, 1) < date.make(2024, 1, 2)", true},
+		{"[1, 2] < [1, 3]", true},
+		{"[1, 2] < [1, 2, 0]", true},
+		{`[1, "a"] < [1, 2]`, false},
+		{`1 < "2"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.AssertValue(t, testutil.MustEval(t, tt.src), r.BoolVal{Value: tt.want})
+		})
+	}
+}
+
+// cyclicSetup builds two lists and two objects that each contain themselves
+const cyclicSetup = `
+var l = [1]
+l.push(l)
+var m = [1]
+m.push(m)
+var a = { x: 1 }
+a.self = a
+var b = { x: 1 }
+b.self = b
+`
+
+func TestEqualityOfCyclicValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"l == l", true},
+		{"l == m", true},
+		{"l != m", false},
+		{"a == a", true},
+		{"a == b", true},
+		{"l == [1, l]", true},
+		{"l == [2, l]", false},
+		{"[l].contains(m)", true},
+		{"l <= l", true},
+		{"l < l", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			env := r.NewEnvironment(nil)
+			evalIn(t, cyclicSetup, env)
+			testutil.AssertValue(t, evalIn(t, tt.src, env), r.BoolVal{Value: tt.want})
+		})
+	}
+}
+
+func TestOrderingOfCyclicValuesFails(t *testing.T) {
+	for _, src := range []string{"l < m", "m >= l", "[l, m].sort()"} {
+		t.Run(src, func(t *testing.T) {
+			env := r.NewEnvironment(nil)
+			evalIn(t, cyclicSetup, env)
+			got := evalIn(t, fmt.Sprintf(`try { %s } catch (e) { e.message }`, src), env)
+			testutil.AssertValue(t, got, r.StringVal{Value: "Cannot order lists that contain themselves"})
+		})
+	}
+}
+
+func TestPrintingCyclicLists(t *testing.T) {
+	testutil.AssertOutput(t, `
+var l = [1, "a"]
+l.push(l)
+println(l)
+println(toString(l))
+l.push({ back: l })
+println(l)
+val shared = [2]
+println([shared, shared])
+`, "[1, \"a\", [...]]\n[1, \"a\", [...]]\n[1, \"a\", [...], User Object ()]\n[[2], [2]]\n")
+}
@@ -2,7 +2,10 @@ package runtime
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	f "github.com/Mstr0A/a0-lang/frontend"
 )
@@ -12,17 +15,28 @@ import (
 ////////////////
 
 // ValueType //
+// Every kind of runtime value has one of these tags, they double as the type
+// names a0 code sees through type() and error messages
 type ValueType string
 
 const (
 	NumberType         ValueType = "Number"
 	StringType         ValueType = "String"
+	CharType           ValueType = "Char"
 	NadaType           ValueType = "Nada"
 	BoolType           ValueType = "Bool"
 	ObjectType         ValueType = "Object"
+	RangeType          ValueType = "Range"
+	DateType           ValueType = "Date"
+	ListType           ValueType = "List"
+	ErrorType          ValueType = "Error"
+	ClassType          ValueType = "Class"
+	SuperType          ValueType = "Super"
 	NativeFunctionType ValueType = "NativeFunction"
 	UserFunctionType   ValueType = "UserFunction"
 	ReturnSignalType   ValueType = "ReturnSignal"
+	BreakSignalType    ValueType = "BreakSignal"
+	ContinueSignalType ValueType = "ContinueSignal"
 )
 
 // Runtime Value //
@@ -31,6 +45,19 @@ type RuntimeVal interface {
 	String() string
 }
 
+// TypeOf is the type of any runtime value, a missing (nil) value is nada
+func TypeOf(val RuntimeVal) ValueType {
+	if val == nil {
+		return NadaType
+	}
+	return val.ValueType()
+}
+
+// TypeName is the name of the type of val as a0 code sees it
+func TypeName(val RuntimeVal) string {
+	return string(TypeOf(val))
+}
+
 // Number Value //
 type NumberVal struct {
 	Value float64
@@ -44,19 +71,32 @@ func (n NumberVal) String() string {
 	return strconv.FormatFloat(n.Value, 'f', -1, 64)
 }
 
-// Number Value //
+// String Value //
 type StringVal struct {
 	Value string
 }
 
 func (s StringVal) ValueType() ValueType {
-	return NumberType
+	return StringType
 }
 
 func (s StringVal) String() string {
 	return s.Value
 }
 
+// Char Value //
+type CharVal struct {
+	Value rune
+}
+
+func (c CharVal) ValueType() ValueType {
+	return CharType
+}
+
+func (c CharVal) String() string {
+	return string(c.Value)
+}
+
 // Nada Value //
 type NadaVal struct{}
 
@@ -85,6 +125,7 @@ func (b BoolVal) String() string {
 type ObjectVal struct {
 	Properties map[string]RuntimeVal
 	ObjectName string
+	Class      *ClassVal // set for class instances, methods are looked up here
 }
 
 func (o ObjectVal) ValueType() ValueType {
@@ -95,12 +136,177 @@ func (o ObjectVal) String() string {
 	return fmt.Sprintf("User Object (%s)", o.ObjectName)
 }
 
+// List Value //
+// ListVal is always passed around as a pointer so every reference to a list
+// sees the same elements, the same way objects share their property map
+type ListVal struct {
+	Elements []RuntimeVal
+}
+
+func (l *ListVal) ValueType() ValueType {
+	return ListType
+}
+
+func (l *ListVal) String() string {
+	return l.print(map[*ListVal]bool{})
+}
+
+// print writes the list out, printing holds the lists already being printed
+// further up so a list that contains itself shows [...] there instead of
+// recursing forever
+func (l *ListVal) print(printing map[*ListVal]bool) string {
+	if printing[l] {
+		return "[...]"
+	}
+	printing[l] = true
+	defer delete(printing, l)
+
+	parts := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		// quote strings so ["a, b"] and ["a", "b"] print differently
+		switch v := element.(type) {
+		case StringVal:
+			parts[i] = strconv.Quote(v.Value)
+		case CharVal:
+			parts[i] = strconv.QuoteRune(v.Value)
+		case *ListVal:
+			parts[i] = v.print(printing)
+		default:
+			parts[i] = element.String()
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// Error Value //
+// ErrorVal is what throw raises and catch binds. Value is the payload that
+// was thrown, Line and Column are where it was thrown from (0 when unknown).
+type ErrorVal struct {
+	Message string
+	Value   RuntimeVal
+	Line    int
+	Column  int
+	pos     f.Position // Line and Column along with the offset, for diagnostics
+}
+
+func (e ErrorVal) ValueType() ValueType {
+	return ErrorType
+}
+
+func (e ErrorVal) String() string {
+	return fmt.Sprintf("Error: %s", e.Message)
+}
+
+// Range Value //
+// RangeVal counts from Start up to, but not including, End in steps of Step.
+// A negative Step counts down instead.
+type RangeVal struct {
+	Start float64
+	End   float64
+	Step  float64
+}
+
+func (rv RangeVal) ValueType() ValueType {
+	return RangeType
+}
+
+func (rv RangeVal) String() string {
+	return fmt.Sprintf("range(%s, %s, %s)",
+		strconv.FormatFloat(rv.Start, 'f', -1, 64),
+		strconv.FormatFloat(rv.End, 'f', -1, 64),
+		strconv.FormatFloat(rv.Step, 'f', -1, 64),
+	)
+}
+
+// Len is the number of values in the range
+func (rv RangeVal) Len() int {
+	if rv.Step == 0 {
+		return 0
+	}
+	count := math.Ceil((rv.End - rv.Start) / rv.Step)
+	if count < 0 {
+		return 0
+	}
+	return int(count)
+}
+
+// At is the i-th value of the range
+func (rv RangeVal) At(i int) float64 {
+	return rv.Start + float64(i)*rv.Step
+}
+
+// Date Value //
+// DateVal is an instant in time along with the time zone it's shown in
+type DateVal struct {
+	Time time.Time
+}
+
+func (d DateVal) ValueType() ValueType {
+	return DateType
+}
+
+func (d DateVal) String() string {
+	return d.Time.Format(time.RFC3339)
+}
+
+// Class Value //
+// ClassVal is used through a pointer so every instance points at the same class
+type ClassVal struct {
+	Name    string
+	Parent  *ClassVal // methods missing here are looked up on the parent
+	Methods map[string]UserFunctionValue
+}
+
+func (c *ClassVal) ValueType() ValueType {
+	return ClassType
+}
+
+func (c *ClassVal) String() string {
+	return fmt.Sprintf("Class (%s)", c.Name)
+}
+
+// FindMethod looks a method up on the class and then its parents, owner is
+// the class that actually declares it
+func (c *ClassVal) FindMethod(name string) (method UserFunctionValue, owner *ClassVal, found bool) {
+	for class := c; class != nil; class = class.Parent {
+		if method, found := class.Methods[name]; found {
+			return method, class, true
+		}
+	}
+	return UserFunctionValue{}, nil, false
+}
+
+// Super Value //
+// SuperVal is what super refers to inside a method, it reads methods from the
+// parent of the class declaring the method but keeps self the same
+type SuperVal struct {
+	Self  ObjectVal
+	Class *ClassVal
+}
+
+func (s SuperVal) ValueType() ValueType {
+	return SuperType
+}
+
+func (s SuperVal) String() string {
+	return fmt.Sprintf("Super (%s)", s.Class.Name)
+}
+
 // Function Value //
-type FunctionCall func(args []RuntimeVal, env *Environment) RuntimeVal
+
+// FunctionCall is how a native runs. It fails by returning an error instead of
+// printing one: an InterpretingError or TypeError is reported as it is, any
+// other Go error, like one from the os package, becomes a runtime error named
+// after the native, and either way it gets the position of the call and can
+// be caught by try.
+type FunctionCall func(args []RuntimeVal, env *Environment) (RuntimeVal, error)
 
 type NativeFunctionValue struct {
 	Call FunctionCall
 	Name string
+	// receiver is the value a method like s.upper was taken from, nil for
+	// natives that aren't methods
+	receiver RuntimeVal
 }
 
 func (nf NativeFunctionValue) ValueType() ValueType {
@@ -111,11 +317,18 @@ func (nf NativeFunctionValue) String() string {
 	return fmt.Sprintf("Native Function (%s)", nf.Name)
 }
 
+// UserFunctionValue is a function declared in a0 code. It is a closure over
+// DeclarationEnv: the environment is captured by reference, not copied, so
+// the function sees later assignments to captured variables and its own
+// assignments are visible to everything else sharing that environment. Every
+// call gets a fresh child scope of DeclarationEnv for its parameters, which is
+// why two closures returned from separate calls don't share state.
 type UserFunctionValue struct {
 	Name           string
 	Parameters     []string
 	DeclarationEnv *Environment
 	Body           []f.Stmt
+	Code           *Chunk // the compiled body, run by the VM instead of Body when set
 }
 
 func (uf UserFunctionValue) ValueType() ValueType {
@@ -126,26 +339,53 @@ func (uf UserFunctionValue) String() string {
 	return fmt.Sprintf("User Function (%s)", uf.Name)
 }
 
-// Return Value //
-type ReturnValue struct {
+// Control Flow Signals //
+// Signals are what blocks evaluate to when a return, break or continue cuts
+// them short. evalBlock stops at the first one and hands it up until a
+// function call (return) or loop (break, continue) takes care of it. They
+// never end up in variables.
+
+// ReturnSignal carries the returned value out of a function. Tail is set
+// instead of Value for return f(x), the call is made once the function
+// returning it has finished.
+type ReturnSignal struct {
 	Value RuntimeVal
+	Tail  *tailCall
 }
 
-func (r ReturnValue) ValueType() ValueType {
+func (r ReturnSignal) ValueType() ValueType {
 	return ReturnSignalType
 }
 
-func (r ReturnValue) String() string {
+func (r ReturnSignal) String() string {
 	if r.Value == nil {
 		return "return <nil>"
 	}
 	return fmt.Sprintf("return %v", r.Value)
 }
 
-// I don't know why this specifically needs the error interface
-func (r ReturnValue) Error() string {
-	if r.Value == nil {
-		return "<nil>"
-	}
-	return fmt.Sprintf("%v", r.Value)
+// BreakSignal leaves the innermost loop, Pos is where the break is
+type BreakSignal struct {
+	Pos f.Position
+}
+
+func (b BreakSignal) ValueType() ValueType {
+	return BreakSignalType
+}
+
+func (b BreakSignal) String() string {
+	return "break"
+}
+
+// ContinueSignal moves the innermost loop on to its next iteration
+type ContinueSignal struct {
+	Pos f.Position
+}
+
+func (c ContinueSignal) ValueType() ValueType {
+	return ContinueSignalType
+}
+
+func (c ContinueSignal) String() string {
+	return "continue"
 }
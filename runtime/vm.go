@@ -0,0 +1,382 @@
+package runtime
+
+import (
+	"fmt"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+)
+
+/////////////////////
+// Bytecode Runner //
+/////////////////////
+
+var binaryOperators = invertOpcodes(binaryOpcodes)
+var logicalOperators = invertOpcodes(logicalOpcodes)
+var unaryOperators = invertOpcodes(unaryOpcodes)
+
+func invertOpcodes(opcodes map[string]Opcode) map[Opcode]string {
+	operators := make(map[Opcode]string, len(opcodes))
+	for operator, op := range opcodes {
+		operators[op] = operator
+	}
+	return operators
+}
+
+// Execute runs a chunk made by Compile in env and returns what the program
+// it was compiled from evaluates to
+func Execute(chunk *Chunk, env *Environment) (RuntimeVal, error) {
+	v := &vm{session: env.session}
+	v.frames = []*vmFrame{{chunk: chunk, env: env, call: noFrame}}
+	return v.run()
+}
+
+// runCompiledFunction is callUserFunction for functions the compiler made,
+// frame is the call stack frame standing for the call or noFrame
+func runCompiledFunction(fn UserFunctionValue, args []RuntimeVal, frame int) (RuntimeVal, error) {
+	scope, err := bindParameters(fn, args)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vm{session: fn.DeclarationEnv.session}
+	v.frames = []*vmFrame{{chunk: fn.Code, env: scope, call: frame}}
+	return v.run()
+}
+
+// vm runs compiled code. Calls from one compiled function to another get a
+// frame of their own on the vm instead of a nested run, so they don't grow
+// the Go stack, everything else is called the way Evaluate calls it.
+type vm struct {
+	session *session
+	stack   []RuntimeVal
+	frames  []*vmFrame // the first one is the code run was started with
+}
+
+type vmFrame struct {
+	name  string // the function called, for timing
+	chunk *Chunk
+	ip    int // the next instruction
+	env   *Environment
+	base  int // how much of the stack was in use when the frame was entered
+	call  int // the call stack frame for the call, or noFrame
+}
+
+func (v *vm) push(val RuntimeVal) {
+	v.stack = append(v.stack, val)
+}
+
+func (v *vm) pop() RuntimeVal {
+	val := v.stack[len(v.stack)-1]
+	v.stack = v.stack[:len(v.stack)-1]
+	return val
+}
+
+func (v *vm) popN(n int) []RuntimeVal {
+	values := make([]RuntimeVal, n)
+	copy(values, v.stack[len(v.stack)-n:])
+	v.stack = v.stack[:len(v.stack)-n]
+	return values
+}
+
+func (v *vm) run() (RuntimeVal, error) {
+	for {
+		frame := v.frames[len(v.frames)-1]
+		if frame.ip >= len(frame.chunk.Code) {
+			if v.leave(NadaVal{}) {
+				return NadaVal{}, nil
+			}
+			continue
+		}
+
+		chunk := frame.chunk
+		instruction := chunk.Code[frame.ip]
+		frame.ip++
+		if err := v.session.step(); err != nil {
+			return nil, v.fail(err, instruction.Pos)
+		}
+		if v.session.profile != nil && instruction.Pos.Line() != 0 {
+			v.session.sample(instruction.Pos, frame.env.File())
+		}
+
+		var result RuntimeVal
+		var err error
+
+		switch instruction.Op {
+		case OpConstant:
+			result = chunk.Constants[instruction.Operand].(RuntimeVal)
+		case OpNada:
+			result = NadaVal{}
+		case OpPop:
+			v.pop()
+			continue
+		case OpSwap:
+			last := len(v.stack) - 1
+			v.stack[last], v.stack[last-1] = v.stack[last-1], v.stack[last]
+			continue
+
+		case OpGetVar:
+			result, err = frame.env.LookupVar(constantName(chunk, instruction))
+		case OpSetVar:
+			result, err = frame.env.AssignVal(constantName(chunk, instruction), v.pop())
+		case OpDeclareVar, OpDeclareConst:
+			name := constantName(chunk, instruction)
+			frame.env.warnShadowing(name, instruction.Pos)
+			result, err = frame.env.DeclareVar(name, v.pop(), instruction.Op == OpDeclareConst)
+
+		case OpGetProperty:
+			result, err = lookupMember(v.pop(), memberProperty{Name: constantName(chunk, instruction)})
+		case OpGetIndex:
+			key := v.pop()
+			result, err = lookupMember(v.pop(), memberProperty{Key: key, Computed: true})
+		case OpSetProperty:
+			value := v.pop()
+			result, err = setMember(v.pop(), memberProperty{Name: constantName(chunk, instruction)}, value, frame.env)
+		case OpSetIndex:
+			value, key := v.pop(), v.pop()
+			result, err = setMember(v.pop(), memberProperty{Key: key, Computed: true}, value, frame.env)
+		case OpGetMethod:
+			receiver := v.pop()
+			result, err = lookupMember(receiver, memberProperty{Name: constantName(chunk, instruction)})
+			result = bindReceiver(result, receiver)
+		case OpGetMethodIndex:
+			key, receiver := v.pop(), v.pop()
+			result, err = lookupMember(receiver, memberProperty{Key: key, Computed: true})
+			result = bindReceiver(result, receiver)
+
+		case OpCall:
+			fn := v.pop()
+			args := v.popN(instruction.Operand)
+			if userFn, ok := fn.(UserFunctionValue); ok && userFn.Code != nil {
+				if err := v.enter(userFn, args, instruction.Pos); err != nil {
+					return nil, v.fail(err, instruction.Pos)
+				}
+				continue
+			}
+			result, err = runSteps(callAt(fn, args, instruction.Pos, frame.env))
+		case OpTailCall:
+			fn := v.pop()
+			args := v.popN(instruction.Operand)
+			if err := v.session.hookCall(fn, args, instruction.Pos); err != nil {
+				return nil, v.fail(err, instruction.Pos)
+			}
+			if frame.call != noFrame {
+				v.session.stack[frame.call] = Frame{Function: calleeName(fn), Pos: instruction.Pos}
+			}
+			if userFn, ok := fn.(UserFunctionValue); ok && userFn.Code != nil {
+				// the call takes over the frame of the function returning it
+				scope, err := bindParameters(userFn, args)
+				if err != nil {
+					return nil, v.fail(err, instruction.Pos)
+				}
+				v.stack = v.stack[:frame.base]
+				frame.chunk, frame.ip, frame.env = userFn.Code, 0, scope
+				continue
+			}
+			if userFn, ok := fn.(UserFunctionValue); ok {
+				result, err = runSteps(callUserFunction(userFn, args, frame.call))
+			} else {
+				result, err = CallFunction(fn, args, frame.env)
+			}
+			if err != nil {
+				return nil, v.fail(err, instruction.Pos)
+			}
+			if v.leave(result) {
+				return result, nil
+			}
+			continue
+		case OpNew:
+			args := v.popN(instruction.Operand)
+			result, err = runSteps(construct(v.pop(), args, instruction.Pos, frame.env))
+
+		case OpArray:
+			result = &ListVal{Elements: v.popN(instruction.Operand)}
+			err = v.session.allocate(listBytes(instruction.Operand))
+		case OpObject:
+			pairs := v.popN(2 * instruction.Operand)
+			object := ObjectVal{Properties: make(map[string]RuntimeVal, instruction.Operand)}
+			for i := 0; i < len(pairs) && err == nil; i += 2 {
+				key, ok := pairs[i].(StringVal)
+				if !ok {
+					err = &TypeError{Operation: "object key", Expected: "a string", Got: TypeOf(pairs[i])}
+					break
+				}
+				object.Properties[key.Value] = pairs[i+1]
+			}
+			if err == nil {
+				err = v.session.allocate(valueBytes(object))
+			}
+			result = object
+		case OpRange:
+			end, start := v.pop(), v.pop()
+			result, err = makeRange(start, end)
+
+		case OpAdd, OpSubtract, OpMultiply, OpDivide, OpModulo:
+			right, left := v.pop(), v.pop()
+			result, err = applyBinary(left, right, binaryOperators[instruction.Op])
+		case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpAnd, OpOr:
+			right, left := v.pop(), v.pop()
+			result, err = applyLogical(left, right, logicalOperators[instruction.Op])
+		case OpNegate, OpNot:
+			result, err = applyUnary(v.pop(), unaryOperators[instruction.Op])
+
+		case OpJump:
+			frame.ip = instruction.Operand
+			continue
+		case OpJumpIfFalse:
+			if !isTruthy(v.pop()) {
+				frame.ip = instruction.Operand
+			}
+			continue
+		case OpForCount:
+			var count int
+			count, err = forCount(v.pop())
+			result = NumberVal{Value: float64(count)}
+		case OpForNext:
+			remaining, ok := v.stack[len(v.stack)-2].(NumberVal)
+			if !ok {
+				err = &TypeError{Operation: "for loop count", Expected: "a number", Got: TypeOf(v.stack[len(v.stack)-2])}
+				return nil, v.fail(err, instruction.Pos)
+			}
+			if remaining.Value <= 0 {
+				frame.ip = instruction.Operand
+			} else {
+				v.stack[len(v.stack)-2] = NumberVal{Value: remaining.Value - 1}
+			}
+			continue
+
+		case OpPushScope:
+			frame.env = NewEnvironment(frame.env)
+			continue
+		case OpPopScope:
+			frame.env = frame.env.parent
+			continue
+
+		case OpThrow:
+			err = throwValue(v.pop(), instruction.Pos)
+
+		case OpFunction:
+			proto := chunk.Constants[instruction.Operand].(*FunctionProto)
+			result = UserFunctionValue{
+				Name:           proto.Name,
+				Parameters:     proto.Parameters,
+				DeclarationEnv: frame.env,
+				Code:           proto.Code,
+			}
+
+		case OpEval:
+			result, err = Evaluate(chunk.Constants[instruction.Operand].(f.Stmt), frame.env)
+			if err == nil && isSignal(result) {
+				// a break or continue can't be for a compiled loop, Compile
+				// leaves those loops to Evaluate too, so only a return ends up
+				// doing anything
+				if err := strayLoopSignal(result); err != nil {
+					return nil, v.fail(err, instruction.Pos)
+				}
+				ret, err := runSteps(finishTailCall(result.(ReturnSignal), frame.env, finish))
+				if err != nil {
+					return nil, v.fail(err, instruction.Pos)
+				}
+				value := ret.(ReturnSignal).Value
+				if v.leave(value) {
+					return value, nil
+				}
+				continue
+			}
+
+		case OpReturn:
+			value := v.pop()
+			if v.leave(value) {
+				return value, nil
+			}
+			continue
+
+		default:
+			errorMessage := fmt.Sprintf("Unknown opcode %s", instruction.Op)
+			err = &InterpretingError{Message: errorMessage}
+		}
+
+		if err != nil {
+			return nil, v.fail(err, instruction.Pos)
+		}
+		if result == nil {
+			result = NadaVal{}
+		}
+		v.push(result)
+	}
+}
+
+// enter starts a call to a compiled function made at pos in a new frame
+func (v *vm) enter(fn UserFunctionValue, args []RuntimeVal, pos f.Position) error {
+	if err := v.session.hookCall(fn, args, pos); err != nil {
+		return err
+	}
+	if err := v.session.pushFrame(fn.Name, pos); err != nil {
+		return err
+	}
+	call := len(v.session.stack) - 1
+	v.session.startTiming(fn.Name)
+
+	scope, err := bindParameters(fn, args)
+	if err != nil {
+		v.session.stopTiming(fn.Name)
+		err = v.session.trace(err)
+		v.session.stack = v.session.stack[:call]
+		return err
+	}
+
+	v.frames = append(v.frames, &vmFrame{name: fn.Name, chunk: fn.Code, env: scope, base: len(v.stack), call: call})
+	return nil
+}
+
+// leave ends the innermost frame, handing value to the code that called it.
+// It's true when that was the frame run started with, so run is done.
+func (v *vm) leave(value RuntimeVal) bool {
+	if len(v.frames) == 1 {
+		return true
+	}
+
+	frame := v.frames[len(v.frames)-1]
+	v.frames = v.frames[:len(v.frames)-1]
+	v.stack = v.stack[:frame.base]
+	v.session.stack = v.session.stack[:frame.call]
+	v.session.stopTiming(frame.name)
+	v.push(value)
+	return false
+}
+
+// fail gives err the position of the instruction at pos, or of the calls
+// leading to it, and unwinds the frames run has entered along with their
+// call stack frames
+func (v *vm) fail(err error, pos f.Position) error {
+	// instructions like POP have no position of their own, the one closest
+	// before them is still on the right line
+	frame := v.frames[len(v.frames)-1]
+	for i := frame.ip - 1; pos.Line() == 0 && i >= 0; i-- {
+		pos = frame.chunk.Code[i].Pos
+	}
+	if pos.Line() != 0 {
+		setErrorPosition(err, pos)
+	}
+	if len(v.frames) == 1 {
+		return err
+	}
+
+	for i := len(v.frames) - 2; i >= 0; i-- {
+		caller := v.frames[i]
+		if callPos := caller.chunk.Code[caller.ip-1].Pos; callPos.Line() != 0 {
+			setErrorPosition(err, callPos)
+		}
+	}
+	err = v.session.trace(err)
+
+	for _, frame := range v.frames[1:] {
+		v.session.stopTiming(frame.name)
+	}
+	v.session.stack = v.session.stack[:v.frames[1].call]
+	v.frames = v.frames[:1]
+	return err
+}
+
+func constantName(chunk *Chunk, instruction Instruction) string {
+	return chunk.Constants[instruction.Operand].(StringVal).Value
+}
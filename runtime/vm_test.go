@@ -0,0 +1,211 @@
+package runtime_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+// outcome is everything a run of a program gives back
+type outcome struct {
+	output string
+	value  string
+	err    string
+	limit  bool
+}
+
+func (o outcome) String() string {
+	return fmt.Sprintf("output %q, value %s, error %q", o.output, o.value, o.err)
+}
+
+// runEngine runs src in a fresh environment set up by setup, compiled to
+// bytecode when vm is set
+func runEngine(t *testing.T, src string, vm bool, setup func(env *r.Environment)) outcome {
+	t.Helper()
+	program, err := testutil.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := r.NewEnvironment(nil)
+	if setup != nil {
+		setup(env)
+	}
+
+	var value r.RuntimeVal
+	stdout, _, err := env.CaptureOutput(func() error {
+		if !vm {
+			value, err = r.Evaluate(program, env)
+			return err
+		}
+		chunk, err := r.Compile(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err = r.Execute(chunk, env)
+		return err
+	})
+
+	result := outcome{output: stdout}
+	if value != nil {
+		result.value = value.String()
+	}
+	if err != nil {
+		var limit *r.LimitError
+		result.err, result.limit = err.Error(), errors.As(err, &limit)
+	}
+	return result
+}
+
+func TestVMMatchesEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // what the program prints
+	}{
+		{"arithmetic", `println(1 + 2 * 3, 10 % 4, -(2 - 5), 7 / 2)`, "7 2 3 3.5\n"},
+		{"comparisons and logic", `println(1 < 2, 2 <= 1, "a" == "a", not true, true and false, false or true)`, "true false true false false true\n"},
+		{"if", `val x = 5
+if (x > 10) { println("big") }
+if (x > 3) { println("medium") }`, "medium\n"},
+		{"while with break and continue", `var i = 0
+var total = 0
+while (true) {
+    i = i + 1
+    if (i % 2 == 0) { continue }
+    if (i > 9) { break }
+    total = total + i
+}
+println(total)`, "25\n"},
+		{"for over a range", `var total = 0
+for (i in 0..5) { total = total + i }
+println(total)`, "10\n"},
+		{"foreach over lists and objects", `for (x in [1, 2]) { print(x) }
+for (k, v in { a: 3 }) { print(k, v) }
+println()`, "12a 3\n"},
+		{"nested loops", `for (i in 0..3) {
+    for (j in 0..3) {
+        if (j > i) { break }
+        print(i * 10 + j, "")
+    }
+}
+println()`, "0 10 11 20 21 22 \n"},
+		{"recursion", `fun fib(n) { if (n < 2) { return n }
+    return fib(n - 1) + fib(n - 2) }
+println(fib(15))`, "610\n"},
+		{"deep tail calls", `fun count(n, acc) { if (n == 0) { return acc }
+    return count(n - 1, acc + 1) }
+println(count(100000, 0))`, "100000\n"},
+		{"closures", `fun makeCounter() {
+    var n = 0
+    fun inc() { n = n + 1
+        return n }
+    return inc
+}
+val a = makeCounter()
+val b = makeCounter()
+a()
+println(a(), b())`, "2 1\n"},
+		{"closures over loop variables", `val fns = []
+for (i in 0..3) {
+    fun get() { return i }
+    fns.push(get)
+}
+println(fns[0](), fns[2]())`, "0 2\n"},
+		{"try catch finally", `fun risky(n) { if (n > 1) { throw error("too big", { n: n }) }
+    return n }
+for (n in 0..3) {
+    try { println(risky(n)) } catch (e) { println(e.message, e.value.n) } finally { println("done", n) }
+}`, "0\ndone 0\n1\ndone 1\ntoo big 2\ndone 2\n"},
+		{"throw from deep inside calls", `fun inner() { throw "oops" }
+fun outer() { return inner() + 1 }
+try { outer() } catch (e) { println(e.message, e.line) }`, "oops 1\n"},
+		{"runtime errors are caught", `try { nope } catch (e) { println(e.message) }
+try { 3() } catch (e) { println(e.message) }`, "Variable nope does not exist\nCannot call value that is not a function: 3\n"},
+		{"return from inside loops and try", `fun find(xs, want) {
+    for (x in xs) {
+        try { if (x == want) { return "found" } } finally { print("") }
+    }
+    return "missing"
+}
+println(find([1, 2, 3], 2), find([1], 5))`, "found missing\n"},
+		{"classes", `class Animal {
+    fun init(name) { self.name = name }
+    fun speak() { return " says ".join([self.name, self.sound()]) }
+    fun sound() { return "..." }
+}
+class Dog : Animal {
+    fun init(name) { super.init(name)
+        self.tricks = 0 }
+    fun sound() { return "Woof" }
+}
+val d = new Dog("Rex")
+d.tricks = d.tricks + 1
+println(d.speak(), d.tricks, Animal("Cat").speak())`, "Rex says Woof 1 Cat says ...\n"},
+		{"methods through objects", `fun hello() { return self.name }
+val o = { name: "o", hello: hello }
+println(o.hello())`, "o\n"},
+		{"match", `fun describe(msg) {
+    return match (msg) {
+        {type: "say", text} -> text,
+        [first, _] -> first,
+        0 -> "zero",
+        _ -> "unknown"
+    }
+}
+println(describe({ type: "say", text: "hi" }), describe([1, 2]), describe(0), describe(true))`, "hi 1 zero unknown\n"},
+		{"lists and objects", `val xs = [3, 1, 2]
+xs[0] = 5
+xs.push(4)
+val o = { a: { b: 1 } }
+o.a.b = o.a.b + 1
+o["c"] = xs[1:3]
+println(xs, o.a.b, o.c, len(o))`, "[5, 1, 2, 4] 2 [1, 2] 2\n"},
+		{"uncaught throws", `fun f() { throw "bad" }
+println("before")
+f()`, "before\n"},
+		{"the last value", `val x = 2
+x * 21`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := runEngine(t, tt.src, false, nil)
+			compiled := runEngine(t, tt.src, true, nil)
+			if evaluated != compiled {
+				t.Errorf("evaluated: %v\ncompiled:  %v", evaluated, compiled)
+			}
+			if evaluated.output != tt.want {
+				t.Errorf("printed %q, want %q", evaluated.output, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMMatchesEvaluateOnLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		setup func(env *r.Environment)
+	}{
+		{"steps", `while (true) {}`, func(env *r.Environment) { env.SetMaxSteps(1000) }},
+		{"steps inside try", `try { while (true) {} } catch (e) { println("caught") }`, func(env *r.Environment) { env.SetMaxSteps(1000) }},
+		{"recursion depth", `fun down(n) { return 1 + down(n + 1) }
+down(0)`, func(env *r.Environment) { env.SetMaxDepth(100) }},
+		{"memory", `val xs = []
+while (true) { xs.push("item") }`, func(env *r.Environment) { env.SetMaxMemory(1 << 16) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := runEngine(t, tt.src, false, tt.setup)
+			compiled := runEngine(t, tt.src, true, tt.setup)
+			if evaluated.output != "" || compiled.output != "" {
+				t.Errorf("the limit was caught: evaluated %v, compiled %v", evaluated, compiled)
+			}
+			if evaluated.err == "" || compiled.err == "" {
+				t.Fatalf("the limit wasn't hit: evaluated %v, compiled %v", evaluated, compiled)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+//////////////
+// Terminal //
+//////////////
+
+// makeRaw switches the terminal fd to raw mode, where keys arrive one at a
+// time and aren't echoed, so the line editor can handle them itself.
+// restore puts the terminal back the way it was.
+func makeRaw(fd uintptr) (restore func(), err error) {
+	var old syscall.Termios
+	if err := termios(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.BRKINT | syscall.INPCK | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() { termios(fd, ioctlSetTermios, &old) }, nil
+}
+
+func termios(fd, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
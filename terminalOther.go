@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// makeRaw isn't available here, the REPL reads plain lines instead
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.New("the line editor isn't supported on this system")
+}
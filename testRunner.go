@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+)
+
+/////////////
+// a0 test //
+/////////////
+
+// testFileSuffix ends the names of the files a0 test looks for in folders
+const testFileSuffix = "_test.a0"
+
+// runTests runs the tests of every file in a global scope of its own and
+// prints how each file went, the failures with where they happened. It
+// reports whether every test passed.
+func runTests(sources []sourceFile, programs []f.Program, newEnv func() *r.Environment) bool {
+	passed := true
+	for i, source := range sources {
+		if isCompiledFile(source.path) {
+			fmt.Printf("FAIL\t%s\tcompiled files can't be tested\n", source.path)
+			passed = false
+			continue
+		}
+
+		env := newEnv()
+		env.SetFile(source.path)
+		start := time.Now()
+		results, err := r.RunTests(programs[i], env)
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		failed := 0
+		for _, result := range results {
+			if result.Passed() {
+				continue
+			}
+			failed++
+			fmt.Printf("--- FAIL: %s (%s:%d:%d)\n", result.Name, source.path, result.Line, result.Column)
+			fmt.Printf("    %s\n", result.Message)
+		}
+
+		switch {
+		case err != nil:
+			// the file didn't run or a test stopped the whole run
+			fmt.Printf("FAIL\t%s\t%s\n", source.path, err)
+			passed = false
+		case failed > 0:
+			fmt.Printf("FAIL\t%s\t%d of %d failed (%s)\n", source.path, failed, len(results), elapsed)
+			passed = false
+		case len(results) == 0:
+			fmt.Printf("ok  \t%s\tno tests\n", source.path)
+		default:
+			fmt.Printf("ok  \t%s\t%d passed (%s)\n", source.path, len(results), elapsed)
+		}
+	}
+	return passed
+}
+
+//////////////
+// a0 bench //
+//////////////
+
+// runBenchmarks runs the benchmarks of every file in a global scope of its
+// own and prints a line for each, in the layout of go test -bench so runs
+// before and after a change line up. It reports whether none failed.
+func runBenchmarks(sources []sourceFile, programs []f.Program, newEnv func() *r.Environment, benchTime time.Duration) bool {
+	passed := true
+	for i, source := range sources {
+		if isCompiledFile(source.path) {
+			fmt.Printf("FAIL\t%s\tcompiled files can't be benchmarked\n", source.path)
+			passed = false
+			continue
+		}
+
+		env := newEnv()
+		env.SetFile(source.path)
+		start := time.Now()
+		results, err := r.RunBenchmarks(programs[i], env, benchTime)
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		width := 0
+		for _, result := range results {
+			width = max(width, len(result.Name))
+		}
+		failed := false
+		for _, result := range results {
+			if result.Failed() {
+				failed = true
+				fmt.Printf("--- FAIL: %s (%s:%d:%d)\n", result.Name, source.path, result.Line, result.Column)
+				fmt.Printf("    %s\n", result.Message)
+				continue
+			}
+			fmt.Printf("%-*s\t%10d\t%12d ns/op\t%10d B/op\t%8d allocs/op\n",
+				width, result.Name, result.Runs, result.PerOp.Nanoseconds(), result.BytesPerOp, result.AllocsPerOp)
+		}
+
+		switch {
+		case err != nil:
+			fmt.Printf("FAIL\t%s\t%s\n", source.path, err)
+			passed = false
+		case failed:
+			fmt.Printf("FAIL\t%s\t%s\n", source.path, elapsed)
+			passed = false
+		case len(results) == 0:
+			fmt.Printf("ok  \t%s\tno benchmarks\n", source.path)
+		default:
+			fmt.Printf("ok  \t%s\t%s\n", source.path, elapsed)
+		}
+	}
+	return passed
+}
@@ -0,0 +1,159 @@
+package testutil
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+)
+
+// set with `go test ./... -update-golden` to rewrite the .golden files
+var updateGolden = flag.Bool("update-golden", false, "Rewrite golden files with the current output")
+
+/////////////
+// Helpers //
+/////////////
+
+// Lex runs the lexer over a source string
+func Lex(src string) ([]f.TokenItem, error) {
+	return f.NewLexer(strings.NewReader(src)).Lex()
+}
+
+// Parse lexes and parses a source string into a Program
+func Parse(src string) (f.Program, error) {
+	tokens, err := Lex(src)
+	if err != nil {
+		return f.Program{}, err
+	}
+	return f.NewParser(tokens).ProduceAst()
+}
+
+// Eval runs a source string in a fresh global environment
+func Eval(src string) (r.RuntimeVal, error) {
+	return EvalIn(src, r.NewEnvironment(nil))
+}
+
+// EvalIn runs a source string in the given environment, so state can be
+// carried across several snippets
+func EvalIn(src string, env *r.Environment) (result r.RuntimeVal, err error) {
+	program, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// a panic is an interpreter bug, report it as an error so a single bad
+	// snippet doesn't take the whole test binary down
+	defer func() {
+		if rec := recover(); rec != nil {
+			result, err = nil, fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	return r.Evaluate(program, env)
+}
+
+// MustEval is Eval but fails the test on error
+func MustEval(t testing.TB, src string) r.RuntimeVal {
+	t.Helper()
+	val, err := Eval(src)
+	if err != nil {
+		t.Fatalf("unexpected error evaluating %q: %v", src, err)
+	}
+	return val
+}
+
+// Run evaluates a source string in a fresh global environment and returns
+// everything it printed to standard output
+func Run(src string) (string, error) {
+	env := r.NewEnvironment(nil)
+	stdout, _, err := env.CaptureOutput(func() error {
+		_, err := EvalIn(src, env)
+		return err
+	})
+	return stdout, err
+}
+
+/////////////////
+// Comparisons //
+/////////////////
+
+// AssertValue fails the test when got and want differ the way == in a0
+// tells them apart
+func AssertValue(t testing.TB, got, want r.RuntimeVal) {
+	t.Helper()
+	if !r.Equal(got, want) {
+		t.Errorf("got %T(%v), want %T(%v)", got, got, want, want)
+	}
+}
+
+// AssertOutput runs src and fails the test when its output differs from want
+func AssertOutput(t testing.TB, src, want string) {
+	t.Helper()
+	got, err := Run(src)
+	if err != nil {
+		t.Fatalf("unexpected error running %q: %v", src, err)
+	}
+	if got != want {
+		t.Errorf("output mismatch\n--- got ---\n%s\n--- want ---\n%s", got, want)
+	}
+}
+
+//////////////////
+// Golden Files //
+//////////////////
+
+// RunGolden runs every a0 program matching pattern as a subtest and compares
+// its output against the file next to it with a ".golden" suffix. Errors are
+// part of the output so failing programs can be golden tested too.
+func RunGolden(t *testing.T, pattern string) {
+	t.Helper()
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no files match %q", pattern)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Run(string(src))
+			if err != nil {
+				got += err.Error() + "\n"
+			}
+
+			AssertGolden(t, file+".golden", got)
+		})
+	}
+}
+
+// AssertGolden fails the test when got differs from the contents of the
+// golden file at path, or writes got there with -update-golden
+func AssertGolden(t testing.TB, path, got string) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file (run with -update-golden): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output mismatch for %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package testutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	r "github.com/Mstr0A/a0-lang/runtime"
+	"github.com/Mstr0A/a0-lang/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	testutil.RunGolden(t, "testdata/*.a0")
+}
+
+// recorder is a testing.TB that notes failures instead of failing the test
+// it was made from
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (rec *recorder) Helper() {}
+
+func (rec *recorder) Errorf(format string, args ...any) {
+	rec.failures = append(rec.failures, fmt.Sprintf(format, args...))
+}
+
+func (rec *recorder) Fatalf(format string, args ...any) {
+	rec.Errorf(format, args...)
+}
+
+func TestAssertValue(t *testing.T) {
+	list := func(elements ...r.RuntimeVal) *r.ListVal { return &r.ListVal{Elements: elements} }
+	tests := []struct {
+		name      string
+		got, want r.RuntimeVal
+		equal     bool
+	}{
+		{"numbers", r.NumberVal{Value: 1}, r.NumberVal{Value: 1}, true},
+		{"different numbers", r.NumberVal{Value: 1}, r.NumberVal{Value: 2}, false},
+		{"different types", r.NumberVal{Value: 1}, r.StringVal{Value: "1"}, false},
+		{"lists by contents", list(r.NumberVal{Value: 1}), list(r.NumberVal{Value: 1}), true},
+		{"lists of different lengths", list(), list(r.NadaVal{}), false},
+		{"nothing", nil, nil, true},
+		{"nothing and nada", nil, r.NadaVal{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{TB: t}
+			testutil.AssertValue(rec, tt.got, tt.want)
+			if failed := len(rec.failures) > 0; failed == tt.equal {
+				t.Errorf("AssertValue(%v, %v) failed: %t, want %t", tt.got, tt.want, failed, !tt.equal)
+			}
+		})
+	}
+}
+
+func TestAssertOutput(t *testing.T) {
+	rec := &recorder{TB: t}
+	testutil.AssertOutput(rec, `println("hi")`, "hi\n")
+	if len(rec.failures) != 0 {
+		t.Errorf("matching output failed: %q", rec.failures)
+	}
+
+	testutil.AssertOutput(rec, `println("hi")`, "bye\n")
+	if len(rec.failures) != 1 {
+		t.Errorf("different output gave %d failures, want 1", len(rec.failures))
+	}
+
+	rec.failures = nil
+	testutil.AssertOutput(rec, `throw "bad"`, "")
+	if len(rec.failures) == 0 {
+		t.Error("a program that failed passed")
+	}
+}
+
+func TestRun(t *testing.T) {
+	out, err := testutil.Run(`print("a")
+printWith({ to: "stderr" }, "not stdout")
+print("b")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "ab" {
+		t.Errorf("got %q, want only standard output", out)
+	}
+
+	if _, err := testutil.Run("val x = "); err == nil {
+		t.Error("running source that doesn't parse didn't fail")
+	}
+}
+
+func TestEvalIn(t *testing.T) {
+	env := r.NewEnvironment(nil)
+	if _, err := testutil.EvalIn("var total = 1", env); err != nil {
+		t.Fatal(err)
+	}
+	got, err := testutil.EvalIn("total = total + 1\ntotal", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValue(t, got, r.NumberVal{Value: 2})
+
+	if _, err := testutil.Eval("total"); err == nil {
+		t.Error("a fresh environment saw another's variable")
+	}
+}
+
+func TestLex(t *testing.T) {
+	tokens, err := testutil.Lex(`val x = "s"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// val, x, =, the string and the end of the file
+	if len(tokens) != 5 {
+		t.Errorf("got %d tokens, want 5", len(tokens))
+	}
+}
+
+func TestParse(t *testing.T) {
+	program, err := testutil.Parse("val x = 1\nprintln(x)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(program.Body) != 2 {
+		t.Errorf("got %d statements, want 2", len(program.Body))
+	}
+	if _, err := testutil.Parse("val = 1"); err == nil {
+		t.Error("parsing a declaration without a name didn't fail")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	f "github.com/Mstr0A/a0-lang/frontend"
+	r "github.com/Mstr0A/a0-lang/runtime"
+)
+
+/////////////
+// Version //
+/////////////
+
+// a0 version prints the language version this build runs and what it was
+// built from, for bug reports
+func versionCommand(fs *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		fmt.Printf("a0 %s\n", f.LanguageVersion)
+
+		build, commit := "unknown", ""
+		if info, ok := debug.ReadBuildInfo(); ok {
+			build = info.Main.Version
+			var revision, when string
+			modified := false
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					revision = setting.Value
+				case "vcs.time":
+					when = setting.Value
+				case "vcs.modified":
+					modified = setting.Value == "true"
+				}
+			}
+			if revision != "" {
+				commit = revision
+				if when != "" {
+					commit += " " + when
+				}
+				if modified {
+					commit += " (modified)"
+				}
+			}
+		}
+		fmt.Printf("build:    %s\n", build)
+		if commit != "" {
+			fmt.Printf("commit:   %s\n", commit)
+		}
+		fmt.Printf("bytecode: format %d\n", r.ChunkVersion)
+		fmt.Printf("go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		return 0
+	}
+}